@@ -0,0 +1,259 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrNotFound = errors.New("api key not found")
+	ErrInvalid  = errors.New("api key not valid")
+)
+
+// DefaultOptions are used by the API for the API keys.
+var DefaultOptions = Options{
+	Key: "api-keys",
+}
+
+type Options struct {
+	Key string // Key is the hash of the API keys by ID
+}
+
+// RateLimit is the amount of requests a client can make within the period, there is no limit when Max is 0.
+type RateLimit struct {
+	Max    int64
+	Period time.Duration
+}
+
+type rateLimitJSON struct {
+	Max    int64  `json:"max"`
+	Period string `json:"period"`
+}
+
+// MarshalJSON writes the period as a Go duration, e.g. "1h0m0s".
+func (rl RateLimit) MarshalJSON() ([]byte, error) {
+	return json.Marshal(rateLimitJSON{Max: rl.Max, Period: rl.Period.String()})
+}
+
+// UnmarshalJSON reads the period as a Go duration, e.g. "1h". It can be omitted when there is no limit.
+func (rl *RateLimit) UnmarshalJSON(data []byte) error {
+	var decoded rateLimitJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	if decoded.Period == "" {
+		rl.Max, rl.Period = decoded.Max, 0
+
+		return nil
+	}
+
+	period, err := time.ParseDuration(decoded.Period)
+	if err != nil {
+		return fmt.Errorf("rate limit period is not a duration: %w", err)
+	}
+
+	rl.Max, rl.Period = decoded.Max, period
+
+	return nil
+}
+
+// Key is an API client. Its secret is only known when it is issued, the store keeps its hash.
+type Key struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	MessageTypes []string  `json:"message_types,omitempty"` // MessageTypes are the ones allowed, every one when it is empty
+	RateLimit    RateLimit `json:"rate_limit"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Allows tells if the client can send notifications of the message type.
+func (k Key) Allows(messageType string) bool {
+	if len(k.MessageTypes) == 0 {
+		return true
+	}
+
+	for _, allowed := range k.MessageTypes {
+		if allowed == messageType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// record is how a Key is kept, along with the hash of its secret.
+type record struct {
+	Key
+	SecretHash string `json:"secret_hash"`
+}
+
+func NewStore(db *redis.Client, options Options) Store {
+	return Store{
+		db:      db,
+		options: options,
+	}
+}
+
+// RedisHash is an abstraction for redis.Client making it mockeable
+type RedisHash interface {
+	HSet(ctx context.Context, key string, values ...interface{}) *redis.IntCmd
+	HGet(ctx context.Context, key, field string) *redis.StringCmd
+	HGetAll(ctx context.Context, key string) *redis.MapStringStringCmd
+	HDel(ctx context.Context, key string, fields ...string) *redis.IntCmd
+}
+
+// Store keeps the API keys in a hash by ID. The tokens given to the clients are the ID and the secret
+// separated by a dot, and only the SHA-256 of the secret is stored: it is random, so it needs no slow hash.
+type Store struct {
+	db      RedisHash
+	options Options
+}
+
+// Issue creates the key with a new ID and secret, and returns it along with the token of the client.
+// The token cannot be read again.
+func (s Store) Issue(ctx context.Context, key Key) (Key, string, error) {
+	id, err := randomString(8, hex.EncodeToString)
+	if err != nil {
+		return Key{}, "", err
+	}
+
+	secret, err := randomString(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return Key{}, "", err
+	}
+
+	key.ID = id
+	key.CreatedAt = time.Now().UTC()
+
+	payload, err := json.Marshal(record{Key: key, SecretHash: hashSecret(secret)})
+	if err != nil {
+		return Key{}, "", fmt.Errorf("error marshalling api key due to: %w", err)
+	}
+
+	if err = s.db.HSet(ctx, s.options.Key, key.ID, payload).Err(); err != nil {
+		return Key{}, "", fmt.Errorf("error saving api key %s due to: %w", key.ID, err)
+	}
+
+	return key, key.ID + "." + secret, nil
+}
+
+// Authenticate returns the key of the token, or ErrInvalid when it is malformed, revoked or its secret is wrong.
+func (s Store) Authenticate(ctx context.Context, token string) (Key, error) {
+	id, secret, ok := strings.Cut(token, ".")
+	if !ok || id == "" || secret == "" {
+		return Key{}, ErrInvalid
+	}
+
+	rec, err := s.get(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return Key{}, ErrInvalid
+	}
+
+	if err != nil {
+		return Key{}, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(rec.SecretHash)) != 1 {
+		return Key{}, ErrInvalid
+	}
+
+	return rec.Key, nil
+}
+
+// Revoke deletes the key, its token is not valid anymore.
+func (s Store) Revoke(ctx context.Context, id string) error {
+	deleted, err := s.db.HDel(ctx, s.options.Key, id).Result()
+	if err != nil {
+		return fmt.Errorf("error revoking api key %s due to: %w", id, err)
+	}
+
+	if deleted == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// List returns every key, the oldest first.
+func (s Store) List(ctx context.Context) ([]Key, error) {
+	payloads, err := s.db.HGetAll(ctx, s.options.Key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error listing api keys due to: %w", err)
+	}
+
+	keys := make([]Key, 0, len(payloads))
+	for id, payload := range payloads {
+		var rec record
+		if err = json.Unmarshal([]byte(payload), &rec); err != nil {
+			return nil, fmt.Errorf("error unmarshalling api key %s due to: %w", id, err)
+		}
+
+		keys = append(keys, rec.Key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+
+	return keys, nil
+}
+
+func (s Store) get(ctx context.Context, id string) (record, error) {
+	payload, err := s.db.HGet(ctx, s.options.Key, id).Result()
+	if errors.Is(err, redis.Nil) {
+		return record{}, ErrNotFound
+	}
+
+	if err != nil {
+		return record{}, fmt.Errorf("error getting api key %s due to: %w", id, err)
+	}
+
+	var rec record
+	if err = json.Unmarshal([]byte(payload), &rec); err != nil {
+		return record{}, fmt.Errorf("error unmarshalling api key %s due to: %w", id, err)
+	}
+
+	return rec, nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func randomString(size int, encode func([]byte) string) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating api key due to: %w", err)
+	}
+
+	return encode(b), nil
+}
+
+type keyContextKey struct{}
+
+// WithKey returns a copy of the context carrying the key of the authenticated client.
+func WithKey(ctx context.Context, key Key) context.Context {
+	return context.WithValue(ctx, keyContextKey{}, key)
+}
+
+// FromContext returns the key of the authenticated client, ok is false when the request was not authenticated.
+func FromContext(ctx context.Context) (Key, bool) {
+	key, ok := ctx.Value(keyContextKey{}).(Key)
+
+	return key, ok
+}
@@ -0,0 +1,195 @@
+package apikey
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"user_news_api/apikey/mocks"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreIssue(t *testing.T) {
+	mockRedis := mocks.NewRedisHash(t)
+
+	var saved string
+	mockRedis.On("HSet", mock.Anything, "api-keys", mock.AnythingOfType("string"), mock.AnythingOfType("[]uint8")).
+		Run(func(args mock.Arguments) { saved = string(args.Get(3).([]byte)) }).
+		Return(redis.NewIntResult(1, nil)).Once()
+
+	store := Store{db: mockRedis, options: DefaultOptions}
+
+	key, token, err := store.Issue(context.Background(), Key{
+		Name:         "newsletter",
+		MessageTypes: []string{"news"},
+		RateLimit:    RateLimit{Max: 100, Period: time.Hour},
+	})
+	require.NoError(t, err)
+
+	id, secret, ok := strings.Cut(token, ".")
+	require.True(t, ok)
+	assert.Equal(t, key.ID, id)
+	assert.Len(t, id, 16)
+	assert.NotEmpty(t, secret)
+	assert.False(t, key.CreatedAt.IsZero())
+	assert.Equal(t, "newsletter", key.Name)
+
+	var rec record
+	require.NoError(t, json.Unmarshal([]byte(saved), &rec))
+	assert.Equal(t, key.ID, rec.ID)
+	assert.Equal(t, RateLimit{Max: 100, Period: time.Hour}, rec.RateLimit)
+	assert.Equal(t, hashSecret(secret), rec.SecretHash)
+	assert.NotContains(t, saved, secret)
+}
+
+func TestStoreAuthenticate(t *testing.T) {
+	key := Key{ID: "abc", Name: "newsletter", MessageTypes: []string{"news"}, CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	// the secret is saved as its SHA-256 hash
+	saved := `{"id":"abc","name":"newsletter","message_types":["news"],"rate_limit":{"max":0,"period":"0s"},` +
+		`"created_at":"2024-01-01T00:00:00Z","secret_hash":"2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b"}`
+
+	tests := []struct {
+		name          string
+		token         string
+		mockApplier   func(mockRedis *mocks.RedisHash)
+		expected      Key
+		expectedError error
+	}{
+		{
+			name:  "valid token",
+			token: "abc.secret",
+			mockApplier: func(mockRedis *mocks.RedisHash) {
+				mockRedis.On("HGet", mock.Anything, "api-keys", "abc").Return(redis.NewStringResult(saved, nil)).Once()
+			},
+			expected: key,
+		},
+		{
+			name:          "malformed token",
+			token:         "secret",
+			mockApplier:   func(mockRedis *mocks.RedisHash) {},
+			expectedError: ErrInvalid,
+		},
+		{
+			name:  "wrong secret",
+			token: "abc.other",
+			mockApplier: func(mockRedis *mocks.RedisHash) {
+				mockRedis.On("HGet", mock.Anything, "api-keys", "abc").Return(redis.NewStringResult(saved, nil)).Once()
+			},
+			expectedError: ErrInvalid,
+		},
+		{
+			name:  "revoked key",
+			token: "abc.secret",
+			mockApplier: func(mockRedis *mocks.RedisHash) {
+				mockRedis.On("HGet", mock.Anything, "api-keys", "abc").Return(redis.NewStringResult("", redis.Nil)).Once()
+			},
+			expectedError: ErrInvalid,
+		},
+		{
+			name:  "redis error",
+			token: "abc.secret",
+			mockApplier: func(mockRedis *mocks.RedisHash) {
+				mockRedis.On("HGet", mock.Anything, "api-keys", "abc").Return(redis.NewStringResult("", errors.New("error"))).Once()
+			},
+			expectedError: fmt.Errorf("error getting api key abc due to: %w", errors.New("error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisHash(t)
+
+			tt.mockApplier(mockRedis)
+
+			store := Store{db: mockRedis, options: DefaultOptions}
+
+			result, err := store.Authenticate(context.Background(), tt.token)
+
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestStoreRevoke(t *testing.T) {
+	tests := []struct {
+		name          string
+		mockApplier   func(mockRedis *mocks.RedisHash)
+		expectedError error
+	}{
+		{
+			name: "revoked",
+			mockApplier: func(mockRedis *mocks.RedisHash) {
+				mockRedis.On("HDel", mock.Anything, "api-keys", "abc").Return(redis.NewIntResult(1, nil)).Once()
+			},
+		},
+		{
+			name: "not found",
+			mockApplier: func(mockRedis *mocks.RedisHash) {
+				mockRedis.On("HDel", mock.Anything, "api-keys", "abc").Return(redis.NewIntResult(0, nil)).Once()
+			},
+			expectedError: ErrNotFound,
+		},
+		{
+			name: "redis error",
+			mockApplier: func(mockRedis *mocks.RedisHash) {
+				mockRedis.On("HDel", mock.Anything, "api-keys", "abc").Return(redis.NewIntResult(0, errors.New("error"))).Once()
+			},
+			expectedError: fmt.Errorf("error revoking api key abc due to: %w", errors.New("error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisHash(t)
+
+			tt.mockApplier(mockRedis)
+
+			store := Store{db: mockRedis, options: DefaultOptions}
+
+			assert.Equal(t, tt.expectedError, store.Revoke(context.Background(), "abc"))
+		})
+	}
+}
+
+func TestStoreList(t *testing.T) {
+	older := Key{ID: "abc", Name: "older", CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	newer := Key{ID: "def", Name: "newer", RateLimit: RateLimit{Max: 10, Period: time.Minute}, CreatedAt: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)}
+
+	mockRedis := mocks.NewRedisHash(t)
+	mockRedis.On("HGetAll", mock.Anything, "api-keys").Return(redis.NewMapStringStringResult(map[string]string{
+		"def": `{"id":"def","name":"newer","rate_limit":{"max":10,"period":"1m0s"},"created_at":"2024-02-01T00:00:00Z","secret_hash":"2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b"}`,
+		"abc": `{"id":"abc","name":"older","rate_limit":{"max":0,"period":"0s"},"created_at":"2024-01-01T00:00:00Z","secret_hash":"2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b"}`,
+	}, nil)).Once()
+
+	store := Store{db: mockRedis, options: DefaultOptions}
+
+	keys, err := store.List(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []Key{older, newer}, keys)
+}
+
+func TestKeyAllows(t *testing.T) {
+	assert.True(t, Key{}.Allows("news"))
+	assert.True(t, Key{MessageTypes: []string{"news", "status"}}.Allows("status"))
+	assert.False(t, Key{MessageTypes: []string{"news"}}.Allows("marketing"))
+}
+
+func TestRateLimitJSON(t *testing.T) {
+	payload, err := json.Marshal(RateLimit{Max: 100, Period: time.Hour})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"max":100,"period":"1h0m0s"}`, string(payload))
+
+	var rl RateLimit
+	require.NoError(t, json.Unmarshal([]byte(`{"max":5,"period":"30s"}`), &rl))
+	assert.Equal(t, RateLimit{Max: 5, Period: 30 * time.Second}, rl)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"max":5,"period":"often"}`), &rl))
+}
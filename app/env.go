@@ -0,0 +1,343 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"user_news_api/channel"
+	"user_news_api/delivery"
+	"user_news_api/handler"
+	"user_news_api/notifier"
+	"user_news_api/ratelimiter"
+	"user_news_api/unsubscribe"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// getMailServers returns the providers sending the mails. When NOTIFIER_SINK is set, the mails are kept by a sink
+// instead, and the mailbox reading them is returned unless the sink only prints them.
+func getMailServers() ([]notifier.Options, handler.Mailbox, error) {
+	sink, sinkEnabled := getSinkOptions()
+	if !sinkEnabled {
+		return getProvidersOptions(), nil, nil
+	}
+
+	options := notifier.Options{Name: primaryProvider, Username: sink.Sender}
+
+	switch sink.Kind {
+	case notifier.SinkFile:
+		dialer, err := notifier.NewFileDialer(sink.Dir)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		options.Dialer = dialer
+
+		return []notifier.Options{options}, dialer, nil
+	case notifier.SinkMemory:
+		dialer := notifier.NewMemoryDialer()
+		options.Dialer = dialer
+
+		return []notifier.Options{options}, dialer, nil
+	}
+
+	options.Dialer = notifier.NewLogDialer(slog.Default())
+
+	return []notifier.Options{options}, nil, nil
+}
+
+// defaultSinkSender is the sender of the mails kept by a sink when NOTIFIER_SENDER is not set.
+const defaultSinkSender = "news@localhost"
+
+type sinkOptions struct {
+	Kind   string
+	Dir    string
+	Sender string
+}
+
+// getSinkOptions reads the development sink of NOTIFIER_SINK: "log", "file" or "memory".
+// The file sink writes into NOTIFIER_SINK_DIR. No mail server credentials are needed with a sink.
+func getSinkOptions() (sinkOptions, bool) {
+	kind := os.Getenv("NOTIFIER_SINK")
+	if kind == "" {
+		return sinkOptions{}, false
+	}
+
+	if kind != notifier.SinkLog && kind != notifier.SinkFile && kind != notifier.SinkMemory {
+		panic("notifier sink is not valid")
+	}
+
+	dir := os.Getenv("NOTIFIER_SINK_DIR")
+	if kind == notifier.SinkFile && dir == "" {
+		panic("notifier sink dir is empty")
+	}
+
+	sender := os.Getenv("NOTIFIER_SENDER")
+	if sender == "" {
+		sender = defaultSinkSender
+	}
+
+	return sinkOptions{Kind: kind, Dir: dir, Sender: sender}, true
+}
+
+func getNotifierOptions() notifier.Options {
+	host := os.Getenv("NOTIFIER_HOST")
+	if host == "" {
+		panic("notifier host is empty")
+	}
+
+	portStr := os.Getenv("NOTIFIER_PORT")
+	if portStr == "" {
+		panic("notifier port is empty")
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		panic("notifier port is not a number")
+	}
+
+	sender := os.Getenv("NOTIFIER_SENDER")
+	if sender == "" {
+		panic("notifier sender is empty")
+	}
+
+	password := os.Getenv("NOTIFIER_PASSWORD")
+	if password == "" {
+		panic("notifier password is empty")
+	}
+
+	return notifier.Options{
+		Name:     primaryProvider,
+		Host:     host,
+		Port:     port,
+		Username: sender,
+		Password: password,
+	}
+}
+
+// getDKIMOptions enables signing the mails when DKIM_KEY_PATH is set, then DKIM_DOMAIN and DKIM_SELECTOR are required.
+// DKIM_HEADERS chooses the signed header fields, separated by commas.
+func getDKIMOptions() (notifier.DKIMOptions, bool) {
+	keyPath := os.Getenv("DKIM_KEY_PATH")
+	if keyPath == "" {
+		return notifier.DKIMOptions{}, false
+	}
+
+	domain := os.Getenv("DKIM_DOMAIN")
+	if domain == "" {
+		panic("dkim domain is empty")
+	}
+
+	selector := os.Getenv("DKIM_SELECTOR")
+	if selector == "" {
+		panic("dkim selector is empty")
+	}
+
+	var headers []string
+	if headersStr := os.Getenv("DKIM_HEADERS"); headersStr != "" {
+		for _, header := range strings.Split(headersStr, ",") {
+			headers = append(headers, strings.TrimSpace(header))
+		}
+	}
+
+	return notifier.DKIMOptions{
+		Domain:   domain,
+		Selector: selector,
+		KeyPath:  keyPath,
+		Headers:  headers,
+	}, true
+}
+
+// primaryProvider is the name of the mail server set with the NOTIFIER_* variables.
+const primaryProvider = "primary"
+
+// getProvidersOptions returns the primary mail server followed by the relays of NOTIFIER_PROVIDERS,
+// a JSON list of notifier options (e.g. [{"name": "backup", "host": "smtp.example.com", "port": 587, ...}]).
+func getProvidersOptions() []notifier.Options {
+	options := []notifier.Options{getNotifierOptions()}
+
+	providers := os.Getenv("NOTIFIER_PROVIDERS")
+	if providers == "" {
+		return options
+	}
+
+	var relays []notifier.Options
+	if err := json.Unmarshal([]byte(providers), &relays); err != nil {
+		panic("notifier providers must be a JSON list of providers")
+	}
+
+	names := map[string]bool{primaryProvider: true}
+
+	for _, relay := range relays {
+		switch {
+		case relay.Name == "":
+			panic("notifier provider name is empty")
+		case names[relay.Name]:
+			panic(fmt.Sprintf("notifier provider %s is repeated", relay.Name))
+		case relay.Host == "":
+			panic(fmt.Sprintf("notifier provider %s host is empty", relay.Name))
+		case relay.Port <= 0:
+			panic(fmt.Sprintf("notifier provider %s port is not valid", relay.Name))
+		case relay.Username == "":
+			panic(fmt.Sprintf("notifier provider %s sender is empty", relay.Name))
+		}
+
+		names[relay.Name] = true
+	}
+
+	return append(options, relays...)
+}
+
+func getRedisOptions() *redis.Options {
+	addr := os.Getenv("REDIS_ADDRESS")
+	if addr == "" {
+		panic("redis address is empty")
+	}
+
+	// password is not validated due to local environment use case.
+	password := os.Getenv("REDIS_PASSWORD")
+
+	return &redis.Options{
+		Addr:     addr,
+		Password: password,
+	}
+}
+
+// getLimiterConfigs overrides the policy applied over the rate limit with LIMIT_POLICIES,
+// a list of message type and policy pairs separated by commas (e.g. "News=digest,Status=reject").
+func getLimiterConfigs() map[string]ratelimiter.Config {
+	configs := make(map[string]ratelimiter.Config, len(ratelimiter.DefaultConfigs))
+	for msgType, config := range ratelimiter.DefaultConfigs {
+		configs[msgType] = config
+	}
+
+	setTransactionalTypes(configs)
+
+	policies := os.Getenv("LIMIT_POLICIES")
+	if policies == "" {
+		return configs
+	}
+
+	for _, pair := range strings.Split(policies, ",") {
+		msgType, policy, ok := strings.Cut(pair, "=")
+		if !ok {
+			panic(fmt.Sprintf("limit policy %s must be a message type and a policy separated by =", pair))
+		}
+
+		msgType = strings.TrimSpace(msgType)
+
+		config, ok := configs[msgType]
+		if !ok {
+			panic(fmt.Sprintf("limit policy message type %s is not a valid message type", msgType))
+		}
+
+		config.OnLimit = ratelimiter.OnLimitPolicy(strings.TrimSpace(policy))
+		if !config.OnLimit.Valid() {
+			panic(fmt.Sprintf("limit policy %s is not valid for message type %s", config.OnLimit, msgType))
+		}
+
+		configs[msgType] = config
+	}
+
+	return configs
+}
+
+// setTransactionalTypes replaces the transactional message types with the ones listed in TRANSACTIONAL_TYPES,
+// separated by commas. An empty list keeps the default ones, and "none" makes every message type optional.
+func setTransactionalTypes(configs map[string]ratelimiter.Config) {
+	transactionalTypes := os.Getenv("TRANSACTIONAL_TYPES")
+	if transactionalTypes == "" {
+		return
+	}
+
+	transactional := make(map[string]bool)
+
+	if transactionalTypes != "none" {
+		for _, msgType := range strings.Split(transactionalTypes, ",") {
+			msgType = strings.TrimSpace(msgType)
+			if _, ok := configs[msgType]; !ok {
+				panic(fmt.Sprintf("transactional type %s is not a valid message type", msgType))
+			}
+
+			transactional[msgType] = true
+		}
+	}
+
+	for msgType, config := range configs {
+		config.Transactional = transactional[msgType]
+		configs[msgType] = config
+	}
+}
+
+// getUnsubscribeOptions enables the unsubscribe links when UNSUBSCRIBE_SECRET is set,
+// UNSUBSCRIBE_BASE_URL is then required because the links are opened from the mails.
+func getUnsubscribeOptions() (unsubscribe.Options, bool) {
+	secret := os.Getenv("UNSUBSCRIBE_SECRET")
+	if secret == "" {
+		return unsubscribe.Options{}, false
+	}
+
+	baseURL := os.Getenv("UNSUBSCRIBE_BASE_URL")
+	if baseURL == "" {
+		panic("unsubscribe base url is empty")
+	}
+
+	if _, err := url.ParseRequestURI(baseURL); err != nil {
+		panic("unsubscribe base url is not valid")
+	}
+
+	return unsubscribe.Options{BaseURL: baseURL, Secret: secret}, true
+}
+
+// getChannels enables the webhook channels whose URL is set, WEBHOOK_URL for the generic one
+// and SLACK_WEBHOOK_URL for a Slack incoming webhook. Email is always enabled.
+func getChannels() channel.Registry {
+	var channels []channel.Channel
+
+	if webhookURL := os.Getenv("WEBHOOK_URL"); webhookURL != "" {
+		if _, err := url.ParseRequestURI(webhookURL); err != nil {
+			panic("webhook url is not valid")
+		}
+
+		channels = append(channels, channel.NewWebhook(webhookURL))
+	}
+
+	if slackURL := os.Getenv("SLACK_WEBHOOK_URL"); slackURL != "" {
+		if _, err := url.ParseRequestURI(slackURL); err != nil {
+			panic("slack webhook url is not valid")
+		}
+
+		channels = append(channels, channel.NewSlack(slackURL))
+	}
+
+	return channel.NewRegistry(channels...)
+}
+
+func getRecordsRetention() time.Duration {
+	retentionStr := os.Getenv("NOTIFICATION_RETENTION")
+	if retentionStr == "" {
+		return delivery.DefaultRetention
+	}
+
+	retention, err := time.ParseDuration(retentionStr)
+	if err != nil || retention <= 0 {
+		panic("notification retention must be a positive duration")
+	}
+
+	return retention
+}
+
+func digestEnabled(configs map[string]ratelimiter.Config) bool {
+	for _, config := range configs {
+		if config.OnLimit == ratelimiter.OnLimitDigest {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,790 @@
+package app
+
+import (
+	"os"
+	"testing"
+	"time"
+	"user_news_api/delivery"
+	"user_news_api/notifier"
+	"user_news_api/ratelimiter"
+	"user_news_api/unsubscribe"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetNotifierOptions(t *testing.T) {
+	tests := []struct {
+		name         string
+		envVars      map[string]string
+		expectedOpts notifier.Options
+		expectPanic  bool
+		panicMessage string
+	}{
+		{
+			name: "All environment variables set correctly",
+			envVars: map[string]string{
+				"NOTIFIER_HOST":     "smtp.example.com",
+				"NOTIFIER_PORT":     "587",
+				"NOTIFIER_SENDER":   "user@example.com",
+				"NOTIFIER_PASSWORD": "password",
+			},
+			expectedOpts: notifier.Options{
+				Name:     "primary",
+				Host:     "smtp.example.com",
+				Port:     587,
+				Username: "user@example.com",
+				Password: "password",
+			},
+			expectPanic: false,
+		},
+		{
+			name: "Notifier host is empty",
+			envVars: map[string]string{
+				"NOTIFIER_PORT":     "587",
+				"NOTIFIER_SENDER":   "user@example.com",
+				"NOTIFIER_PASSWORD": "password",
+			},
+			expectPanic:  true,
+			panicMessage: "notifier host is empty",
+		},
+		{
+			name: "Notifier port is empty",
+			envVars: map[string]string{
+				"NOTIFIER_HOST":     "smtp.example.com",
+				"NOTIFIER_SENDER":   "user@example.com",
+				"NOTIFIER_PASSWORD": "password",
+			},
+			expectPanic:  true,
+			panicMessage: "notifier port is empty",
+		},
+		{
+			name: "Notifier port is not a number",
+			envVars: map[string]string{
+				"NOTIFIER_HOST":     "smtp.example.com",
+				"NOTIFIER_PORT":     "not a number",
+				"NOTIFIER_SENDER":   "user@example.com",
+				"NOTIFIER_PASSWORD": "password",
+			},
+			expectPanic:  true,
+			panicMessage: "notifier port is not a number",
+		},
+		{
+			name: "Notifier sender is empty",
+			envVars: map[string]string{
+				"NOTIFIER_HOST":     "smtp.example.com",
+				"NOTIFIER_PORT":     "587",
+				"NOTIFIER_PASSWORD": "password",
+			},
+			expectPanic:  true,
+			panicMessage: "notifier sender is empty",
+		},
+		{
+			name: "Notifier password is empty",
+			envVars: map[string]string{
+				"NOTIFIER_HOST":   "smtp.example.com",
+				"NOTIFIER_PORT":   "587",
+				"NOTIFIER_SENDER": "user@example.com",
+			},
+			expectPanic:  true,
+			panicMessage: "notifier password is empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.envVars {
+				require.NoError(t, os.Setenv(key, value))
+			}
+
+			defer func() {
+				for key := range tt.envVars {
+					require.NoError(t, os.Unsetenv(key))
+				}
+			}()
+
+			if tt.expectPanic {
+				defer func() {
+					if r := recover(); r != nil {
+						assert.Equal(t, tt.panicMessage, r)
+					} else {
+						t.Errorf("Expected panic with message: %s", tt.panicMessage)
+					}
+				}()
+			}
+
+			assert.Equal(t, tt.expectedOpts, getNotifierOptions())
+		})
+	}
+}
+
+func TestGetProvidersOptions(t *testing.T) {
+	primaryEnv := map[string]string{
+		"NOTIFIER_HOST":     "smtp.example.com",
+		"NOTIFIER_PORT":     "587",
+		"NOTIFIER_SENDER":   "user@example.com",
+		"NOTIFIER_PASSWORD": "password",
+	}
+	primary := notifier.Options{
+		Name:     "primary",
+		Host:     "smtp.example.com",
+		Port:     587,
+		Username: "user@example.com",
+		Password: "password",
+	}
+
+	tests := []struct {
+		name         string
+		providers    string
+		expectedOpts []notifier.Options
+		expectPanic  bool
+		panicMessage string
+	}{
+		{
+			name:         "Only the primary provider",
+			providers:    "",
+			expectedOpts: []notifier.Options{primary},
+		},
+		{
+			name: "Relays",
+			providers: `[{"name":"backup","host":"smtp.backup.com","port":465,"username":"backup@example.com",` +
+				`"password":"secret","priority":1,"weight":2}]`,
+			expectedOpts: []notifier.Options{primary, {
+				Name:     "backup",
+				Host:     "smtp.backup.com",
+				Port:     465,
+				Username: "backup@example.com",
+				Password: "secret",
+				Priority: 1,
+				Weight:   2,
+			}},
+		},
+		{
+			name:         "Not a JSON list",
+			providers:    `{"name":"backup"}`,
+			expectPanic:  true,
+			panicMessage: "notifier providers must be a JSON list of providers",
+		},
+		{
+			name:         "Relay without name",
+			providers:    `[{"host":"smtp.backup.com","port":465,"username":"backup@example.com"}]`,
+			expectPanic:  true,
+			panicMessage: "notifier provider name is empty",
+		},
+		{
+			name:         "Repeated name",
+			providers:    `[{"name":"primary","host":"smtp.backup.com","port":465,"username":"backup@example.com"}]`,
+			expectPanic:  true,
+			panicMessage: "notifier provider primary is repeated",
+		},
+		{
+			name:         "Relay without host",
+			providers:    `[{"name":"backup","port":465,"username":"backup@example.com"}]`,
+			expectPanic:  true,
+			panicMessage: "notifier provider backup host is empty",
+		},
+		{
+			name:         "Relay without port",
+			providers:    `[{"name":"backup","host":"smtp.backup.com","username":"backup@example.com"}]`,
+			expectPanic:  true,
+			panicMessage: "notifier provider backup port is not valid",
+		},
+		{
+			name:         "Relay without sender",
+			providers:    `[{"name":"backup","host":"smtp.backup.com","port":465}]`,
+			expectPanic:  true,
+			panicMessage: "notifier provider backup sender is empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range primaryEnv {
+				require.NoError(t, os.Setenv(key, value))
+			}
+			require.NoError(t, os.Setenv("NOTIFIER_PROVIDERS", tt.providers))
+
+			defer func() {
+				for key := range primaryEnv {
+					require.NoError(t, os.Unsetenv(key))
+				}
+				require.NoError(t, os.Unsetenv("NOTIFIER_PROVIDERS"))
+			}()
+
+			if tt.expectPanic {
+				defer func() {
+					if r := recover(); r != nil {
+						assert.Equal(t, tt.panicMessage, r)
+					} else {
+						t.Errorf("Expected panic with message: %s", tt.panicMessage)
+					}
+				}()
+			}
+
+			assert.Equal(t, tt.expectedOpts, getProvidersOptions())
+		})
+	}
+}
+
+func TestGetSinkOptions(t *testing.T) {
+	tests := []struct {
+		name            string
+		envVars         map[string]string
+		expectedOpts    sinkOptions
+		expectedEnabled bool
+		expectPanic     bool
+		panicMessage    string
+	}{
+		{
+			name:    "Disabled",
+			envVars: map[string]string{},
+		},
+		{
+			name:            "Log sink with the default sender",
+			envVars:         map[string]string{"NOTIFIER_SINK": "log"},
+			expectedOpts:    sinkOptions{Kind: notifier.SinkLog, Sender: defaultSinkSender},
+			expectedEnabled: true,
+		},
+		{
+			name: "File sink",
+			envVars: map[string]string{
+				"NOTIFIER_SINK":     "file",
+				"NOTIFIER_SINK_DIR": "/tmp/mails",
+				"NOTIFIER_SENDER":   "sender@example.com",
+			},
+			expectedOpts:    sinkOptions{Kind: notifier.SinkFile, Dir: "/tmp/mails", Sender: "sender@example.com"},
+			expectedEnabled: true,
+		},
+		{
+			name:         "File sink without dir",
+			envVars:      map[string]string{"NOTIFIER_SINK": "file"},
+			expectPanic:  true,
+			panicMessage: "notifier sink dir is empty",
+		},
+		{
+			name:         "Unknown sink",
+			envVars:      map[string]string{"NOTIFIER_SINK": "smtp"},
+			expectPanic:  true,
+			panicMessage: "notifier sink is not valid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.envVars {
+				require.NoError(t, os.Setenv(key, value))
+			}
+
+			defer func() {
+				for key := range tt.envVars {
+					require.NoError(t, os.Unsetenv(key))
+				}
+			}()
+
+			if tt.expectPanic {
+				defer func() {
+					if r := recover(); r != nil {
+						assert.Equal(t, tt.panicMessage, r)
+					} else {
+						t.Errorf("Expected panic with message: %s", tt.panicMessage)
+					}
+				}()
+			}
+
+			options, enabled := getSinkOptions()
+
+			assert.Equal(t, tt.expectedOpts, options)
+			assert.Equal(t, tt.expectedEnabled, enabled)
+		})
+	}
+}
+
+func TestGetDKIMOptions(t *testing.T) {
+	tests := []struct {
+		name            string
+		envVars         map[string]string
+		expectedOpts    notifier.DKIMOptions
+		expectedEnabled bool
+		expectPanic     bool
+		panicMessage    string
+	}{
+		{
+			name:    "Disabled",
+			envVars: map[string]string{},
+		},
+		{
+			name: "Enabled",
+			envVars: map[string]string{
+				"DKIM_KEY_PATH": "/etc/dkim/news.pem",
+				"DKIM_DOMAIN":   "example.com",
+				"DKIM_SELECTOR": "news",
+				"DKIM_HEADERS":  "From, To,Subject",
+			},
+			expectedOpts: notifier.DKIMOptions{
+				Domain:   "example.com",
+				Selector: "news",
+				KeyPath:  "/etc/dkim/news.pem",
+				Headers:  []string{"From", "To", "Subject"},
+			},
+			expectedEnabled: true,
+		},
+		{
+			name: "Missing domain",
+			envVars: map[string]string{
+				"DKIM_KEY_PATH": "/etc/dkim/news.pem",
+				"DKIM_SELECTOR": "news",
+			},
+			expectPanic:  true,
+			panicMessage: "dkim domain is empty",
+		},
+		{
+			name: "Missing selector",
+			envVars: map[string]string{
+				"DKIM_KEY_PATH": "/etc/dkim/news.pem",
+				"DKIM_DOMAIN":   "example.com",
+			},
+			expectPanic:  true,
+			panicMessage: "dkim selector is empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.envVars {
+				require.NoError(t, os.Setenv(key, value))
+			}
+
+			defer func() {
+				for key := range tt.envVars {
+					require.NoError(t, os.Unsetenv(key))
+				}
+			}()
+
+			if tt.expectPanic {
+				defer func() {
+					if r := recover(); r != nil {
+						assert.Equal(t, tt.panicMessage, r)
+					} else {
+						t.Errorf("Expected panic with message: %s", tt.panicMessage)
+					}
+				}()
+			}
+
+			options, enabled := getDKIMOptions()
+
+			assert.Equal(t, tt.expectedOpts, options)
+			assert.Equal(t, tt.expectedEnabled, enabled)
+		})
+	}
+}
+
+func TestGetRedisOptions(t *testing.T) {
+	tests := []struct {
+		name         string
+		envVars      map[string]string
+		expectedOpts *redis.Options
+		expectPanic  bool
+		panicMessage string
+	}{
+		{
+			name: "Redis address is empty",
+			envVars: map[string]string{
+				"REDIS_ADDRESS": "",
+			},
+			expectPanic:  true,
+			panicMessage: "redis address is empty",
+		},
+		{
+			name: "OK",
+			envVars: map[string]string{
+				"REDIS_ADDRESS":  "address",
+				"REDIS_PASSWORD": "pass",
+			},
+			expectPanic:  false,
+			panicMessage: "",
+			expectedOpts: &redis.Options{
+				Addr:     "address",
+				Password: "pass",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.envVars {
+				require.NoError(t, os.Setenv(key, value))
+			}
+
+			defer func() {
+				for key := range tt.envVars {
+					require.NoError(t, os.Unsetenv(key))
+				}
+			}()
+
+			if tt.expectPanic {
+				defer func() {
+					if r := recover(); r != nil {
+						assert.Equal(t, tt.panicMessage, r)
+					} else {
+						t.Errorf("Expected panic with message: %s", tt.panicMessage)
+					}
+				}()
+			}
+
+			assert.Equal(t, tt.expectedOpts, getRedisOptions())
+		})
+	}
+}
+
+func TestGetRecordsRetention(t *testing.T) {
+	tests := []struct {
+		name         string
+		envVars      map[string]string
+		expected     time.Duration
+		expectPanic  bool
+		panicMessage string
+	}{
+		{
+			name:     "Default retention",
+			envVars:  map[string]string{},
+			expected: delivery.DefaultRetention,
+		},
+		{
+			name: "Custom retention",
+			envVars: map[string]string{
+				"NOTIFICATION_RETENTION": "24h",
+			},
+			expected: 24 * time.Hour,
+		},
+		{
+			name: "Retention is not a duration",
+			envVars: map[string]string{
+				"NOTIFICATION_RETENTION": "one day",
+			},
+			expectPanic:  true,
+			panicMessage: "notification retention must be a positive duration",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.envVars {
+				require.NoError(t, os.Setenv(key, value))
+			}
+
+			defer func() {
+				for key := range tt.envVars {
+					require.NoError(t, os.Unsetenv(key))
+				}
+			}()
+
+			if tt.expectPanic {
+				defer func() {
+					if r := recover(); r != nil {
+						assert.Equal(t, tt.panicMessage, r)
+					} else {
+						t.Errorf("Expected panic with message: %s", tt.panicMessage)
+					}
+				}()
+			}
+
+			assert.Equal(t, tt.expected, getRecordsRetention())
+		})
+	}
+}
+
+func TestGetLimiterConfigs(t *testing.T) {
+	tests := []struct {
+		name             string
+		envVars          map[string]string
+		expectedPolicies map[string]ratelimiter.OnLimitPolicy
+		expectedDigest   bool
+		expectPanic      bool
+		panicMessage     string
+	}{
+		{
+			name:    "Default policies",
+			envVars: map[string]string{},
+			expectedPolicies: map[string]ratelimiter.OnLimitPolicy{
+				ratelimiter.StatusType: ratelimiter.OnLimitDefer,
+			},
+		},
+		{
+			name: "Policies",
+			envVars: map[string]string{
+				"LIMIT_POLICIES": "News=digest, Marketing = drop_silently,Status=reject",
+			},
+			expectedPolicies: map[string]ratelimiter.OnLimitPolicy{
+				ratelimiter.NewsType:      ratelimiter.OnLimitDigest,
+				ratelimiter.MarketingType: ratelimiter.OnLimitDropSilently,
+				ratelimiter.StatusType:    ratelimiter.OnLimitReject,
+			},
+			expectedDigest: true,
+		},
+		{
+			name: "Missing policy",
+			envVars: map[string]string{
+				"LIMIT_POLICIES": "News",
+			},
+			expectPanic:  true,
+			panicMessage: "limit policy News must be a message type and a policy separated by =",
+		},
+		{
+			name: "Unknown message type",
+			envVars: map[string]string{
+				"LIMIT_POLICIES": "News=digest,Other=defer",
+			},
+			expectPanic:  true,
+			panicMessage: "limit policy message type Other is not a valid message type",
+		},
+		{
+			name: "Unknown policy",
+			envVars: map[string]string{
+				"LIMIT_POLICIES": "News=retry",
+			},
+			expectPanic:  true,
+			panicMessage: "limit policy retry is not valid for message type News",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.envVars {
+				require.NoError(t, os.Setenv(key, value))
+			}
+
+			defer func() {
+				for key := range tt.envVars {
+					require.NoError(t, os.Unsetenv(key))
+				}
+			}()
+
+			if tt.expectPanic {
+				defer func() {
+					if r := recover(); r != nil {
+						assert.Equal(t, tt.panicMessage, r)
+					} else {
+						t.Errorf("Expected panic with message: %s", tt.panicMessage)
+					}
+				}()
+			}
+
+			configs := getLimiterConfigs()
+
+			policies := make(map[string]ratelimiter.OnLimitPolicy)
+			for msgType, config := range configs {
+				assert.Equal(t, ratelimiter.DefaultConfigs[msgType].Max, config.Max)
+
+				if config.OnLimit != "" {
+					policies[msgType] = config.OnLimit
+				}
+			}
+
+			assert.Equal(t, tt.expectedPolicies, policies)
+			assert.Equal(t, tt.expectedDigest, digestEnabled(configs))
+			assert.Empty(t, ratelimiter.DefaultConfigs[ratelimiter.NewsType].OnLimit)
+		})
+	}
+}
+
+func TestSetTransactionalTypes(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         string
+		expectedTypes map[string]bool
+		expectPanic   bool
+		panicMessage  string
+	}{
+		{
+			name:          "Default transactional types",
+			value:         "",
+			expectedTypes: map[string]bool{ratelimiter.StatusType: true},
+		},
+		{
+			name:          "Transactional types",
+			value:         "News, Status",
+			expectedTypes: map[string]bool{ratelimiter.NewsType: true, ratelimiter.StatusType: true},
+		},
+		{
+			name:          "No transactional types",
+			value:         "none",
+			expectedTypes: map[string]bool{},
+		},
+		{
+			name:         "Unknown transactional type",
+			value:        "Other",
+			expectPanic:  true,
+			panicMessage: "transactional type Other is not a valid message type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, os.Setenv("TRANSACTIONAL_TYPES", tt.value))
+
+			defer func() {
+				require.NoError(t, os.Unsetenv("TRANSACTIONAL_TYPES"))
+			}()
+
+			if tt.expectPanic {
+				defer func() {
+					if r := recover(); r != nil {
+						assert.Equal(t, tt.panicMessage, r)
+					} else {
+						t.Errorf("Expected panic with message: %s", tt.panicMessage)
+					}
+				}()
+			}
+
+			configs := getLimiterConfigs()
+
+			transactional := make(map[string]bool)
+			for msgType, config := range configs {
+				if config.Transactional {
+					transactional[msgType] = true
+				}
+			}
+
+			assert.Equal(t, tt.expectedTypes, transactional)
+			assert.True(t, ratelimiter.DefaultConfigs[ratelimiter.StatusType].Transactional)
+		})
+	}
+}
+
+func TestGetUnsubscribeOptions(t *testing.T) {
+	tests := []struct {
+		name            string
+		envVars         map[string]string
+		expectedOpts    unsubscribe.Options
+		expectedEnabled bool
+		expectPanic     bool
+		panicMessage    string
+	}{
+		{
+			name:    "Disabled",
+			envVars: map[string]string{},
+		},
+		{
+			name: "Enabled",
+			envVars: map[string]string{
+				"UNSUBSCRIBE_SECRET":   "secret",
+				"UNSUBSCRIBE_BASE_URL": "https://api.example.com",
+			},
+			expectedOpts:    unsubscribe.Options{BaseURL: "https://api.example.com", Secret: "secret"},
+			expectedEnabled: true,
+		},
+		{
+			name: "Missing base url",
+			envVars: map[string]string{
+				"UNSUBSCRIBE_SECRET": "secret",
+			},
+			expectPanic:  true,
+			panicMessage: "unsubscribe base url is empty",
+		},
+		{
+			name: "Invalid base url",
+			envVars: map[string]string{
+				"UNSUBSCRIBE_SECRET":   "secret",
+				"UNSUBSCRIBE_BASE_URL": "api.example.com",
+			},
+			expectPanic:  true,
+			panicMessage: "unsubscribe base url is not valid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.envVars {
+				require.NoError(t, os.Setenv(key, value))
+			}
+
+			defer func() {
+				for key := range tt.envVars {
+					require.NoError(t, os.Unsetenv(key))
+				}
+			}()
+
+			if tt.expectPanic {
+				defer func() {
+					if r := recover(); r != nil {
+						assert.Equal(t, tt.panicMessage, r)
+					} else {
+						t.Errorf("Expected panic with message: %s", tt.panicMessage)
+					}
+				}()
+			}
+
+			options, enabled := getUnsubscribeOptions()
+
+			assert.Equal(t, tt.expectedOpts, options)
+			assert.Equal(t, tt.expectedEnabled, enabled)
+		})
+	}
+}
+
+func TestGetChannels(t *testing.T) {
+	tests := []struct {
+		name          string
+		envVars       map[string]string
+		expectedNames []string
+		expectPanic   bool
+		panicMessage  string
+	}{
+		{
+			name:          "Only email",
+			envVars:       map[string]string{},
+			expectedNames: []string{"email"},
+		},
+		{
+			name: "Webhooks",
+			envVars: map[string]string{
+				"WEBHOOK_URL":       "https://hooks.example.com/notifications",
+				"SLACK_WEBHOOK_URL": "https://hooks.slack.com/services/T000/B000/XXXX",
+			},
+			expectedNames: []string{"email", "slack", "webhook"},
+		},
+		{
+			name: "Invalid webhook url",
+			envVars: map[string]string{
+				"WEBHOOK_URL": "hooks.example.com",
+			},
+			expectPanic:  true,
+			panicMessage: "webhook url is not valid",
+		},
+		{
+			name: "Invalid slack webhook url",
+			envVars: map[string]string{
+				"SLACK_WEBHOOK_URL": "hooks.slack.com",
+			},
+			expectPanic:  true,
+			panicMessage: "slack webhook url is not valid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.envVars {
+				require.NoError(t, os.Setenv(key, value))
+			}
+
+			defer func() {
+				for key := range tt.envVars {
+					require.NoError(t, os.Unsetenv(key))
+				}
+			}()
+
+			if tt.expectPanic {
+				defer func() {
+					if r := recover(); r != nil {
+						assert.Equal(t, tt.panicMessage, r)
+					} else {
+						t.Errorf("Expected panic with message: %s", tt.panicMessage)
+					}
+				}()
+			}
+
+			assert.Equal(t, tt.expectedNames, getChannels().Names())
+		})
+	}
+}
@@ -0,0 +1,117 @@
+// Package app builds the notification service out of the environment variables, the same way for the API
+// and for the commands sending notifications through it.
+package app
+
+import (
+	"user_news_api/delivery"
+	"user_news_api/digest"
+	"user_news_api/handler"
+	"user_news_api/metrics"
+	"user_news_api/notifier"
+	"user_news_api/preferences"
+	"user_news_api/queue"
+	"user_news_api/ratelimiter"
+	"user_news_api/schedule"
+	"user_news_api/services"
+	"user_news_api/suppression"
+	"user_news_api/unsubscribe"
+
+	"github.com/redis/go-redis/extra/redisotel/v9"
+	"github.com/redis/go-redis/v9"
+)
+
+// Service is the notification service along with the dependencies it is built on, which the API serves as well.
+type Service struct {
+	Notifier     services.UserNotifierService
+	Redis        *redis.Client
+	Mail         notifier.FailoverClient
+	MailServers  []notifier.Options
+	Mailbox      handler.Mailbox // Mailbox reads the mails kept by the development sink, it is nil without one
+	Limiter      ratelimiter.LimiterPool
+	Stream       queue.Stream
+	Schedules    schedule.Store
+	Suppressions suppression.Store
+	Preferences  preferences.Store
+
+	Unsubscribe        unsubscribe.Signer
+	UnsubscribeEnabled bool
+
+	// Flushes schedules the digests when DigestsEnabled, whoever runs the scheduler of the service sends them.
+	Flushes        schedule.Store
+	DigestsEnabled bool
+}
+
+// NewService builds the service out of the environment variables, panicking when any of them is not valid.
+// The mails are sent through the providers of NOTIFIER_* and NOTIFIER_PROVIDERS, or the sink of NOTIFIER_SINK,
+// signed when DKIM_* are set, and the rate limits follow LIMIT_POLICIES and TRANSACTIONAL_TYPES.
+func NewService(registry *metrics.Registry) (Service, error) {
+	var dkimSigner *notifier.DKIMSigner
+	if dkimOptions, dkimEnabled := getDKIMOptions(); dkimEnabled {
+		signer, err := notifier.NewDKIMSigner(dkimOptions)
+		if err != nil {
+			return Service{}, err
+		}
+
+		dkimSigner = &signer
+	}
+
+	mailServers, mailbox, err := getMailServers()
+	if err != nil {
+		return Service{}, err
+	}
+
+	var providers []notifier.Provider
+	for _, options := range mailServers {
+		options.DKIM = dkimSigner
+		options.Metrics = registry
+		providers = append(providers, notifier.NewProvider(options, notifier.DefaultRetryOptions))
+	}
+
+	redisClient := redis.NewClient(getRedisOptions())
+	redisClient.AddHook(registry.RedisHook())
+
+	if err := redisotel.InstrumentTracing(redisClient); err != nil {
+		return Service{}, err
+	}
+
+	limiterConfigs := getLimiterConfigs()
+
+	s := Service{
+		Redis:        redisClient,
+		Mail:         notifier.NewFailoverClient(providers, notifier.DefaultFailoverOptions),
+		MailServers:  mailServers,
+		Mailbox:      mailbox,
+		Limiter:      ratelimiter.NewLimiterPool(redisClient, limiterConfigs).WithMetrics(registry),
+		Stream:       queue.NewStream(redisClient, queue.DefaultStreamOptions),
+		Schedules:    schedule.NewStore(redisClient, schedule.DefaultOptions),
+		Suppressions: suppression.NewStore(redisClient, suppression.DefaultOptions),
+		Preferences:  preferences.NewStore(redisClient, preferences.DefaultOptions),
+	}
+
+	records := delivery.NewStore(redisClient, getRecordsRetention())
+
+	s.Notifier = services.NewUserNotifier(s.Limiter, s.Mail, s.Stream, records, s.Schedules).
+		WithSuppressions(s.Suppressions).
+		WithPreferences(s.Preferences).
+		WithChannels(getChannels()).
+		WithMetrics(registry)
+
+	unsubscribeOptions, unsubscribeEnabled := getUnsubscribeOptions()
+	s.Unsubscribe = unsubscribe.NewSigner(unsubscribeOptions)
+	s.UnsubscribeEnabled = unsubscribeEnabled
+
+	if unsubscribeEnabled {
+		s.Notifier = s.Notifier.WithUnsubscribe(s.Unsubscribe)
+	}
+
+	if digestEnabled(limiterConfigs) {
+		flushOptions := schedule.DefaultOptions
+		flushOptions.Key = "digest-flushes"
+
+		s.Flushes = schedule.NewStore(redisClient, flushOptions)
+		s.DigestsEnabled = true
+		s.Notifier = s.Notifier.WithDigest(digest.NewBuffer(redisClient, digest.DefaultOptions), s.Flushes)
+	}
+
+	return s, nil
+}
@@ -0,0 +1,86 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"user_news_api/notifier"
+)
+
+var (
+	ErrChannelNotValid = errors.New("channel not valid")
+
+	// ErrRejected is a notifier.ErrPermanent when the channel refused the notification, so it is not retried.
+	ErrRejected = fmt.Errorf("%w: notification rejected by the channel", notifier.ErrPermanent)
+	// ErrUnavailable is a notifier.ErrTransient when the channel could take the notification later.
+	ErrUnavailable = fmt.Errorf("%w: channel unavailable", notifier.ErrTransient)
+)
+
+// Email is the default channel, it is sent by the notifier because mails need more than a Message.
+const Email = "email"
+
+// ByEmail tells if the channel reaches the users by their email address, which is the only address the notifications
+// carry. SMS needs the phone number of the user instead, so the notifications cannot be sent through it yet.
+func ByEmail(name string) bool {
+	return name != SMS
+}
+
+// Message is a notification as every channel understands it.
+type Message struct {
+	To          string // To identifies the user in the channel, e.g. its email address or phone number
+	MessageType string
+	Subject     string
+	Text        string // Text is the plain body of the notification
+}
+
+// Channel delivers notifications through a medium other than email.
+// The errors wrap the notifier ones, so they are handled as the mail errors are: ErrRejected is not retried.
+type Channel interface {
+	Name() string
+	Send(context.Context, Message) error
+}
+
+func NewRegistry(channels ...Channel) Registry {
+	registry := Registry{channels: make(map[string]Channel, len(channels))}
+
+	for _, channel := range channels {
+		registry.channels[channel.Name()] = channel
+	}
+
+	return registry
+}
+
+// Registry keeps the enabled channels by name, email is always valid.
+type Registry struct {
+	channels map[string]Channel
+}
+
+// Valid tells if notifications can be sent through the channel.
+func (r Registry) Valid(name string) bool {
+	_, ok := r.channels[name]
+
+	return ok || name == Email
+}
+
+// Get returns the channel with the given name, email is not included because it is sent by the notifier.
+func (r Registry) Get(name string) (Channel, error) {
+	channel, ok := r.channels[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrChannelNotValid, name)
+	}
+
+	return channel, nil
+}
+
+// Names returns the valid channels in alphabetical order.
+func (r Registry) Names() []string {
+	names := []string{Email}
+	for name := range r.channels {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
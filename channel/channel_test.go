@@ -0,0 +1,62 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"user_news_api/notifier"
+	"user_news_api/smstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	sms := NewSMS(&smstest.Provider{})
+	registry := NewRegistry(NewWebhook("http://localhost"), sms)
+
+	assert.True(t, registry.Valid(Email))
+	assert.True(t, registry.Valid(Webhook))
+	assert.False(t, registry.Valid(Slack))
+	assert.Equal(t, []string{Email, SMS, Webhook}, registry.Names())
+
+	channel, err := registry.Get(SMS)
+	require.NoError(t, err)
+	assert.Equal(t, sms, channel)
+
+	_, err = registry.Get(Email)
+	assert.Equal(t, fmt.Errorf("%w: %s", ErrChannelNotValid, Email), err)
+}
+
+func TestSMSChannelSend(t *testing.T) {
+	provider := &smstest.Provider{}
+	sms := NewSMS(provider)
+
+	msg := Message{To: "+5491100000000", MessageType: "Status", Subject: "Notification", Text: "Status notification"}
+
+	require.NoError(t, sms.Send(context.Background(), msg))
+	assert.Equal(t, []smstest.Message{{To: "+5491100000000", Text: "Status notification"}}, provider.Sent())
+
+	provider.Err = errors.New("provider error")
+
+	assert.Equal(t, fmt.Errorf("sms provider error due to: %w", provider.Err), sms.Send(context.Background(), msg))
+	assert.Len(t, provider.Sent(), 1)
+}
+
+func TestSMSChannelSendNotPhoneNumber(t *testing.T) {
+	provider := &smstest.Provider{}
+
+	err := NewSMS(provider).Send(context.Background(), Message{To: "user@example.com", MessageType: "Status"})
+
+	assert.ErrorIs(t, err, ErrRejected)
+	assert.ErrorIs(t, err, notifier.ErrPermanent)
+	assert.Empty(t, provider.Sent())
+}
+
+func TestByEmail(t *testing.T) {
+	assert.True(t, ByEmail(Email))
+	assert.True(t, ByEmail(Webhook))
+	assert.True(t, ByEmail(Slack))
+	assert.False(t, ByEmail(SMS))
+}
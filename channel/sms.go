@@ -0,0 +1,43 @@
+package channel
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+const SMS = "sms"
+
+// phoneNumber matches the phone numbers in E.164 format, e.g. +5491100000000.
+var phoneNumber = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// SMSProvider is the gateway sending the text messages, to is the phone number of the user.
+type SMSProvider interface {
+	SendSMS(ctx context.Context, to string, text string) error
+}
+
+func NewSMS(provider SMSProvider) SMSChannel {
+	return SMSChannel{provider: provider}
+}
+
+// SMSChannel sends the text of the notifications through an SMS provider. The users are reached by their phone
+// number, so the messages addressed by anything else are rejected without calling the provider.
+type SMSChannel struct {
+	provider SMSProvider
+}
+
+func (s SMSChannel) Name() string {
+	return SMS
+}
+
+func (s SMSChannel) Send(ctx context.Context, msg Message) error {
+	if !phoneNumber.MatchString(msg.To) {
+		return fmt.Errorf("%w: sms recipient is not a phone number", ErrRejected)
+	}
+
+	if err := s.provider.SendSMS(ctx, msg.To, msg.Text); err != nil {
+		return fmt.Errorf("sms provider error due to: %w", err)
+	}
+
+	return nil
+}
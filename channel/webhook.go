@@ -0,0 +1,125 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+	"user_news_api/notifier"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+const (
+	Webhook = "webhook"
+	Slack   = "slack"
+)
+
+// DefaultTimeout bounds every request to a webhook.
+const DefaultTimeout = 10 * time.Second
+
+// NewWebhook returns the generic webhook channel, which posts every notification as JSON to url.
+func NewWebhook(url string) WebhookChannel {
+	return WebhookChannel{
+		name:   Webhook,
+		url:    url,
+		client: &http.Client{Timeout: DefaultTimeout},
+		body:   webhookBody,
+	}
+}
+
+// NewSlack returns the channel posting the notifications to a Slack-compatible incoming webhook.
+// Incoming webhooks post to a fixed chat channel, so the user is mentioned in the text.
+func NewSlack(url string) WebhookChannel {
+	return WebhookChannel{
+		name:   Slack,
+		url:    url,
+		client: &http.Client{Timeout: DefaultTimeout},
+		body:   slackBody,
+	}
+}
+
+// WebhookChannel posts the notifications to an HTTP endpoint, body builds the JSON payload.
+type WebhookChannel struct {
+	name   string
+	url    string
+	client *http.Client
+	body   func(Message) interface{}
+}
+
+// WebhookPayload is the body posted by the generic webhook channel.
+type WebhookPayload struct {
+	To          string `json:"to"`
+	MessageType string `json:"message_type"`
+	Subject     string `json:"subject"`
+	Text        string `json:"text"`
+}
+
+type slackPayload struct {
+	Text string `json:"text"`
+}
+
+func webhookBody(msg Message) interface{} {
+	return WebhookPayload{
+		To:          msg.To,
+		MessageType: msg.MessageType,
+		Subject:     msg.Subject,
+		Text:        msg.Text,
+	}
+}
+
+func slackBody(msg Message) interface{} {
+	return slackPayload{Text: fmt.Sprintf("*%s* for %s\n%s", msg.Subject, msg.To, msg.Text)}
+}
+
+func (w WebhookChannel) Name() string {
+	return w.name
+}
+
+// Send posts the notification. Rate limited and server errors are transient, other client errors are permanent.
+func (w WebhookChannel) Send(ctx context.Context, msg Message) error {
+	payload, err := json.Marshal(w.body(msg))
+	if err != nil {
+		return fmt.Errorf("error marshalling %s payload due to: %w", w.name, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("error creating %s request due to: %w", w.name, err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	// the receiver continues the trace of the notification when it is traced too
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		var netErr net.Error
+		if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+			return fmt.Errorf("%w: posting to %s timed out: %w", notifier.ErrTimeout, w.name, err)
+		}
+
+		return fmt.Errorf("unexpected error posting to %s due to: %w", w.name, err)
+	}
+
+	defer res.Body.Close()
+
+	// the body is drained so the connection can be reused
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	switch {
+	case res.StatusCode >= 200 && res.StatusCode < 300:
+		return nil
+	case res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500:
+		return fmt.Errorf("%w: %s replied %d", ErrUnavailable, w.name, res.StatusCode)
+	default:
+		return fmt.Errorf("%w: %s replied %d", ErrRejected, w.name, res.StatusCode)
+	}
+}
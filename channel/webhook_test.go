@@ -0,0 +1,136 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"user_news_api/notifier"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var testMessage = Message{
+	To:          "user@example.com",
+	MessageType: "Status",
+	Subject:     "Notification",
+	Text:        "Status notification",
+}
+
+func TestWebhookChannelSend(t *testing.T) {
+	tests := []struct {
+		name          string
+		channel       func(url string) WebhookChannel
+		status        int
+		expectedBody  string
+		expectedError error
+	}{
+		{
+			name:    "generic webhook",
+			channel: NewWebhook,
+			status:  http.StatusOK,
+			expectedBody: `{"to":"user@example.com","message_type":"Status","subject":"Notification",` +
+				`"text":"Status notification"}`,
+		},
+		{
+			name:         "slack incoming webhook",
+			channel:      NewSlack,
+			status:       http.StatusOK,
+			expectedBody: `{"text":"*Notification* for user@example.com\nStatus notification"}`,
+		},
+		{
+			name:          "rate limited",
+			channel:       NewWebhook,
+			status:        http.StatusTooManyRequests,
+			expectedError: ErrUnavailable,
+		},
+		{
+			name:          "server error",
+			channel:       NewSlack,
+			status:        http.StatusBadGateway,
+			expectedError: ErrUnavailable,
+		},
+		{
+			name:          "client error",
+			channel:       NewSlack,
+			status:        http.StatusNotFound,
+			expectedError: ErrRejected,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body string
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+				payload, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+
+				body = string(payload)
+
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			err := tt.channel(server.URL).Send(context.Background(), testMessage)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expectedBody, body)
+		})
+	}
+}
+
+func TestWebhookChannelSendTimeout(t *testing.T) {
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := NewWebhook(server.URL).Send(ctx, testMessage)
+
+	assert.ErrorIs(t, err, notifier.ErrTimeout)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestWebhookChannelSendTraceparent(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), spanContext)
+
+	var traceparent string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer server.Close()
+
+	require.NoError(t, NewWebhook(server.URL).Send(ctx, testMessage))
+	assert.Equal(t, "00-"+spanContext.TraceID().String()+"-"+spanContext.SpanID().String()+"-01", traceparent)
+}
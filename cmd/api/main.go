@@ -1,86 +1,409 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"user_news_api/apikey"
+	"user_news_api/app"
 	"user_news_api/handler"
+	"user_news_api/health"
+	"user_news_api/idempotency"
+	"user_news_api/jwtauth"
+	"user_news_api/logging"
+	"user_news_api/metrics"
 	"user_news_api/notifier"
+	"user_news_api/queue"
 	"user_news_api/ratelimiter"
+	"user_news_api/schedule"
 	"user_news_api/services"
+	"user_news_api/tracing"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/redis/go-redis/v9"
 )
 
+// defaultServiceName names the traces of the API when OTEL_SERVICE_NAME is not set.
+const defaultServiceName = "user-news-api"
+
+// defaultJWTLeeway tolerates the clock skew with the identity provider.
+const defaultJWTLeeway = 30 * time.Second
+
+// defaultJWTRateLimit is the rate limit of every subject of the bearer tokens when JWT_RATE_LIMIT is not set.
+var defaultJWTRateLimit = apikey.RateLimit{Max: 1000, Period: time.Hour}
+
+// defaultShutdownTimeout waits as long as the delivery of a queued notification can take.
+const defaultShutdownTimeout = 30 * time.Second
+
 func main() {
-	notifierOptions := getNotifierOptions()
-	userNotifier := notifier.NewClient(notifierOptions)
+	slog.SetDefault(logging.New(getLogOptions()))
+
+	registry := metrics.NewRegistry()
+
+	shutdownTracing := func(context.Context) error { return nil }
+	if tracingOptions, tracingEnabled := getTracingOptions(); tracingEnabled {
+		shutdown, err := tracing.Setup(context.Background(), tracingOptions)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		shutdownTracing = shutdown
+	}
 
-	redisOptions := getRedisOptions()
-	redisClient := redis.NewClient(redisOptions)
-	limiter := ratelimiter.NewLimiterPool(redisClient, ratelimiter.DefaultConfigs)
+	service, err := app.NewService(registry)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	serv := services.NewUserNotifier(limiter, userNotifier)
+	redisClient := service.Redis
+	serv := service.Notifier
+
+	stopDigests := stopFunc(noStop)
+	if service.DigestsEnabled {
+		stopDigests = runInBackground(schedule.NewScheduler(service.Flushes, serv.SendDigest).Run)
+	}
+
+	deliveryOptions := getDeliveryOptions()
+
+	stopWorkers := stopFunc(noStop)
+	if deliveryOptions.Async {
+		stopWorkers = startWorkers(service.Stream, serv, deliveryOptions.Workers)
+		registry.RegisterQueueDepth(service.Stream.Depth)
+	}
+
+	stopScheduler := startScheduler(service.Schedules, serv, deliveryOptions.Async)
 
 	router := chi.NewRouter()
+	router.Use(handler.Tracing())
+	router.Use(handler.RequestID())
+	router.Use(handler.Metrics(registry))
+
+	var bearerAuth, apiKeyAuth func(http.Handler) http.Handler
+
+	clientLimiter := ratelimiter.NewClientLimiter(redisClient)
+
+	if keySetOptions, jwtOptions, jwtEnabled := getJWTOptions(); jwtEnabled {
+		validator := jwtauth.NewValidator(jwtauth.NewKeySet(keySetOptions), jwtOptions)
+		bearerAuth = handler.BearerAuth(validator, clientLimiter, getJWTRateLimit())
+	}
+
+	var apiKeys apikey.Store
+	if getAPIKeyAuth() {
+		apiKeys = apikey.NewStore(redisClient, apikey.DefaultOptions)
+		apiKeyAuth = handler.APIKeyAuth(apiKeys, clientLimiter)
+	}
+
+	authEnabled := bearerAuth != nil || apiKeyAuth != nil
+
+	// the idempotency keys are applied after the authentication, so they are kept apart by client
+	router.Group(func(r chi.Router) {
+		if authEnabled {
+			r.Use(handler.Authenticate(bearerAuth, apiKeyAuth))
+		}
+
+		r.Use(handler.Idempotency(idempotency.NewStore(redisClient, idempotency.DefaultOptions)))
+
+		handler.SetUserController(r, serv, deliveryOptions.Async)
+		handler.SetPreferencesController(r, service.Preferences, service.Limiter)
+	})
+
+	router.Group(func(r chi.Router) {
+		if adminToken := getAdminToken(authEnabled); adminToken != "" {
+			r.Use(handler.AdminAuth(adminToken))
+		}
 
-	handler.SetUserController(router, serv)
+		handler.SetSuppressionController(r, service.Suppressions)
+
+		if apiKeyAuth != nil {
+			handler.SetAPIKeyController(r, apiKeys, service.Limiter)
+		}
+
+		// the mails captured by a sink are only exposed while one is configured
+		if service.Mailbox != nil {
+			handler.SetDevMailController(r, service.Mailbox)
+		}
+	})
+
+	handler.SetMetricsController(router, registry)
+	handler.SetHealthController(router, health.NewReadiness(health.DefaultOptions,
+		readinessChecks(redisClient, service.Mail, service.MailServers)...))
+
+	if service.UnsubscribeEnabled {
+		handler.SetUnsubscribeController(router, service.Unsubscribe, service.Preferences, service.Limiter)
+	}
 
 	server := http.Server{
 		Addr:    ":8080",
 		Handler: router,
 	}
 
-	log.Fatal(server.ListenAndServe())
+	shutdownTimeout := getShutdownTimeout()
+
+	signals, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
+	go func() {
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	<-signals.Done()
+
+	// a second signal kills the process, without waiting for the shutdown
+	stopSignals()
+	slog.Info("shutting down", slog.Duration("timeout", shutdownTimeout))
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// the requests are drained before stopping the schedulers, which feed the workers, and Redis is closed
+	// once nobody uses it. The Prometheus metrics are pulled, so only the spans are buffered and flushed.
+	shutdown(ctx,
+		shutdownStep{name: "http server", stop: server.Shutdown},
+		shutdownStep{name: "scheduler", stop: stopScheduler},
+		shutdownStep{name: "digests", stop: stopDigests},
+		shutdownStep{name: "workers", stop: stopWorkers},
+		shutdownStep{name: "tracing", stop: shutdownTracing},
+		shutdownStep{name: "redis", stop: func(context.Context) error { return redisClient.Close() }},
+	)
+}
+
+// startWorkers consumes the notifications queue in background.
+// Permanent failures are not redelivered, because the mail server will reject them again, and neither are the timeouts
+// once the mail was handed to the mail server, because it could have delivered it, see notifier.Retryable.
+func startWorkers(stream queue.Stream, serv services.UserNotifierService, workers int) stopFunc {
+	if err := stream.Setup(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	workerOptions := queue.DefaultWorkerOptions
+	workerOptions.Workers = workers
+	workerOptions.Name = hostname
+	workerOptions.Retryable = notifier.Retryable
+
+	return runInBackground(queue.NewWorkerPool(stream, serv.Deliver, workerOptions).Run)
+}
+
+// startScheduler moves the due notifications into delivery in background, the same way the API handles new ones.
+func startScheduler(schedules schedule.Store, serv services.UserNotifierService, async bool) stopFunc {
+	handle := serv.SendScheduled
+	if async {
+		handle = serv.EnqueueScheduled
+	}
+
+	return runInBackground(schedule.NewScheduler(schedules, handle).Run)
+}
+
+// getShutdownTimeout reads SHUTDOWN_TIMEOUT, how long the API waits for the requests and the notifications
+// being handled when it is stopped, as a Go duration. By default, it is 30s.
+func getShutdownTimeout() time.Duration {
+	timeoutStr := os.Getenv("SHUTDOWN_TIMEOUT")
+	if timeoutStr == "" {
+		return defaultShutdownTimeout
+	}
+
+	timeout, err := time.ParseDuration(timeoutStr)
+	if err != nil || timeout <= 0 {
+		panic("shutdown timeout must be a positive duration")
+	}
+
+	return timeout
+}
+
+// getAPIKeyAuth reads API_KEY_AUTH, when it is true the notifications require an API key.
+// By default, the notifications do not require any key.
+func getAPIKeyAuth() bool {
+	enabledStr := os.Getenv("API_KEY_AUTH")
+	if enabledStr == "" {
+		return false
+	}
+
+	enabled, err := strconv.ParseBool(enabledStr)
+	if err != nil {
+		panic("api key auth is not valid")
+	}
+
+	return enabled
+}
+
+// getAdminToken reads ADMIN_API_TOKEN, the bearer token of the admin endpoints. It is required when the clients
+// are authenticated, by API keys or bearer tokens. Otherwise, it can be empty and the admin endpoints are open.
+func getAdminToken(authEnabled bool) string {
+	adminToken := os.Getenv("ADMIN_API_TOKEN")
+	if adminToken == "" && authEnabled {
+		panic("admin api token is empty")
+	}
+
+	return adminToken
+}
+
+// getJWTOptions reads JWT_JWKS, the URL or the path of the key set of the identity provider. When it is set the
+// notifications require a bearer token issued by JWT_ISSUER for JWT_AUDIENCE. By default, tokens are not accepted.
+func getJWTOptions() (jwtauth.KeySetOptions, jwtauth.Options, bool) {
+	source := os.Getenv("JWT_JWKS")
+	if source == "" {
+		return jwtauth.KeySetOptions{}, jwtauth.Options{}, false
+	}
+
+	issuer := os.Getenv("JWT_ISSUER")
+	if issuer == "" {
+		panic("jwt issuer is empty")
+	}
+
+	audience := os.Getenv("JWT_AUDIENCE")
+	if audience == "" {
+		panic("jwt audience is empty")
+	}
+
+	keySetOptions := jwtauth.DefaultKeySetOptions
+	keySetOptions.Source = source
+
+	return keySetOptions, jwtauth.Options{Issuer: issuer, Audience: audience, Leeway: defaultJWTLeeway}, true
+}
+
+// getJWTRateLimit reads JWT_RATE_LIMIT, the rate limit of every subject of the bearer tokens as the maximum of
+// notifications and its period separated by a slash (e.g. "1000/1h"). "none" leaves them without a rate limit.
+func getJWTRateLimit() apikey.RateLimit {
+	rateLimitStr := os.Getenv("JWT_RATE_LIMIT")
+	if rateLimitStr == "" {
+		return defaultJWTRateLimit
+	}
+
+	if rateLimitStr == "none" {
+		return apikey.RateLimit{}
+	}
+
+	maxStr, periodStr, ok := strings.Cut(rateLimitStr, "/")
+	if !ok {
+		panic("jwt rate limit must be a max and a period separated by /")
+	}
+
+	max, err := strconv.ParseInt(strings.TrimSpace(maxStr), 10, 64)
+	if err != nil || max <= 0 {
+		panic("jwt rate limit max is not valid")
+	}
+
+	period, err := time.ParseDuration(strings.TrimSpace(periodStr))
+	if err != nil || period <= 0 {
+		panic("jwt rate limit period is not valid")
+	}
+
+	return apikey.RateLimit{Max: max, Period: period}
 }
 
-func getNotifierOptions() notifier.Options {
-	host := os.Getenv("NOTIFIER_HOST")
-	if host == "" {
-		panic("notifier host is empty")
+// getLogOptions reads the minimum level of the logs from LOG_LEVEL: "debug", "info", "warn" or "error".
+// By default, it is info.
+func getLogOptions() logging.Options {
+	levelStr := os.Getenv("LOG_LEVEL")
+	if levelStr == "" {
+		return logging.Options{Level: slog.LevelInfo}
 	}
 
-	portStr := os.Getenv("NOTIFIER_PORT")
-	if portStr == "" {
-		panic("notifier port is empty")
+	level, err := logging.ParseLevel(levelStr)
+	if err != nil {
+		panic("log level is not valid")
 	}
 
-	port, err := strconv.Atoi(portStr)
+	return logging.Options{Level: level}
+}
+
+// readinessChecks checks Redis and the circuits of the mail providers, and connects to every mail server
+// when READINESS_SMTP_CHECK is true. The sinks are not checked, they do not connect to any server.
+func readinessChecks(
+	redisClient *redis.Client, providers health.Providers, providersOptions []notifier.Options,
+) []health.Check {
+	checks := []health.Check{health.Redis(redisClient), health.MailProviders(providers)}
+
+	if !getSMTPCheckEnabled() {
+		return checks
+	}
+
+	for _, options := range providersOptions {
+		if options.Dialer == nil {
+			checks = append(checks, health.SMTP(options.Name, options.Host, options.Port))
+		}
+	}
+
+	return checks
+}
+
+// getSMTPCheckEnabled reads READINESS_SMTP_CHECK, by default the mail servers are not checked by /readyz.
+func getSMTPCheckEnabled() bool {
+	enabledStr := os.Getenv("READINESS_SMTP_CHECK")
+	if enabledStr == "" {
+		return false
+	}
+
+	enabled, err := strconv.ParseBool(enabledStr)
 	if err != nil {
-		panic("notifier port is not a number")
+		panic("readiness smtp check is not valid")
 	}
 
-	sender := os.Getenv("NOTIFIER_SENDER")
-	if sender == "" {
-		panic("notifier sender is empty")
+	return enabled
+}
+
+// getTracingOptions reads the exporter of TRACING_EXPORTER: "otlp" or "stdout". The OTLP exporter is configured
+// by the standard OTEL_EXPORTER_OTLP_* variables. Tracing is disabled when TRACING_EXPORTER is not set.
+func getTracingOptions() (tracing.Options, bool) {
+	exporter := os.Getenv("TRACING_EXPORTER")
+	if exporter == "" {
+		return tracing.Options{}, false
 	}
 
-	password := os.Getenv("NOTIFIER_PASSWORD")
-	if password == "" {
-		panic("notifier password is empty")
+	if exporter != tracing.ExporterOTLP && exporter != tracing.ExporterStdout {
+		panic("tracing exporter is not valid")
 	}
 
-	return notifier.Options{
-		Host:     host,
-		Port:     port,
-		Username: sender,
-		Password: password,
+	serviceName := os.Getenv("OTEL_SERVICE_NAME")
+	if serviceName == "" {
+		serviceName = defaultServiceName
 	}
+
+	return tracing.Options{Exporter: exporter, ServiceName: serviceName}, true
+}
+
+type deliveryOptions struct {
+	Async   bool
+	Workers int
 }
 
-func getRedisOptions() *redis.Options {
-	addr := os.Getenv("REDIS_ADDRESS")
-	if addr == "" {
-		panic("redis address is empty")
+func getDeliveryOptions() deliveryOptions {
+	options := deliveryOptions{
+		Async:   false,
+		Workers: queue.DefaultWorkerOptions.Workers,
 	}
 
-	// password is not validated due to local environment use case.
-	password := os.Getenv("REDIS_PASSWORD")
+	mode := os.Getenv("DELIVERY_MODE")
+	switch mode {
+	case "", "sync":
+	case "async":
+		options.Async = true
+	default:
+		panic("delivery mode must be sync or async")
+	}
+
+	if workersStr := os.Getenv("WORKER_COUNT"); workersStr != "" {
+		workers, err := strconv.Atoi(workersStr)
+		if err != nil || workers < 1 {
+			panic("worker count must be a positive number")
+		}
 
-	return &redis.Options{
-		Addr:     addr,
-		Password: password,
+		options.Workers = workers
 	}
+
+	return options
 }
@@ -1,89 +1,475 @@
 package main
 
 import (
+	"log/slog"
 	"os"
 	"testing"
-	"user_news_api/notifier"
+	"time"
+	"user_news_api/apikey"
+	"user_news_api/jwtauth"
+	"user_news_api/logging"
+	"user_news_api/tracing"
 
-	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-func TestGetNotifierOptions(t *testing.T) {
+func TestGetShutdownTimeout(t *testing.T) {
 	tests := []struct {
-		name         string
-		envVars      map[string]string
-		expectedOpts notifier.Options
-		expectPanic  bool
-		panicMessage string
+		name            string
+		envVars         map[string]string
+		expectedTimeout time.Duration
+		expectPanic     bool
+		panicMessage    string
+	}{
+		{
+			name:            "Default timeout",
+			envVars:         map[string]string{},
+			expectedTimeout: defaultShutdownTimeout,
+		},
+		{
+			name:            "Custom timeout",
+			envVars:         map[string]string{"SHUTDOWN_TIMEOUT": "45s"},
+			expectedTimeout: 45 * time.Second,
+		},
+		{
+			name:         "Not a duration",
+			envVars:      map[string]string{"SHUTDOWN_TIMEOUT": "45"},
+			expectPanic:  true,
+			panicMessage: "shutdown timeout must be a positive duration",
+		},
+		{
+			name:         "Negative timeout",
+			envVars:      map[string]string{"SHUTDOWN_TIMEOUT": "-1s"},
+			expectPanic:  true,
+			panicMessage: "shutdown timeout must be a positive duration",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.envVars {
+				require.NoError(t, os.Setenv(key, value))
+			}
+
+			defer func() {
+				for key := range tt.envVars {
+					require.NoError(t, os.Unsetenv(key))
+				}
+			}()
+
+			if tt.expectPanic {
+				defer func() {
+					if r := recover(); r != nil {
+						assert.Equal(t, tt.panicMessage, r)
+					} else {
+						t.Errorf("Expected panic with message: %s", tt.panicMessage)
+					}
+				}()
+			}
+
+			assert.Equal(t, tt.expectedTimeout, getShutdownTimeout())
+		})
+	}
+}
+
+func TestGetSMTPCheckEnabled(t *testing.T) {
+	tests := []struct {
+		name            string
+		envVars         map[string]string
+		expectedEnabled bool
+		expectPanic     bool
+		panicMessage    string
 	}{
 		{
-			name: "All environment variables set correctly",
+			name:    "Disabled by default",
+			envVars: map[string]string{},
+		},
+		{
+			name:            "Enabled",
+			envVars:         map[string]string{"READINESS_SMTP_CHECK": "true"},
+			expectedEnabled: true,
+		},
+		{
+			name:         "Not a boolean",
+			envVars:      map[string]string{"READINESS_SMTP_CHECK": "sometimes"},
+			expectPanic:  true,
+			panicMessage: "readiness smtp check is not valid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.envVars {
+				require.NoError(t, os.Setenv(key, value))
+			}
+
+			defer func() {
+				for key := range tt.envVars {
+					require.NoError(t, os.Unsetenv(key))
+				}
+			}()
+
+			if tt.expectPanic {
+				defer func() {
+					if r := recover(); r != nil {
+						assert.Equal(t, tt.panicMessage, r)
+					} else {
+						t.Errorf("Expected panic with message: %s", tt.panicMessage)
+					}
+				}()
+			}
+
+			assert.Equal(t, tt.expectedEnabled, getSMTPCheckEnabled())
+		})
+	}
+}
+
+func TestGetAPIKeyAuth(t *testing.T) {
+	tests := []struct {
+		name            string
+		envVars         map[string]string
+		expectedEnabled bool
+		expectPanic     bool
+		panicMessage    string
+	}{
+		{
+			name:    "Disabled by default",
+			envVars: map[string]string{},
+		},
+		{
+			name:    "Disabled",
+			envVars: map[string]string{"API_KEY_AUTH": "false"},
+		},
+		{
+			name:            "Enabled",
+			envVars:         map[string]string{"API_KEY_AUTH": "true"},
+			expectedEnabled: true,
+		},
+		{
+			name:         "Not a boolean",
+			envVars:      map[string]string{"API_KEY_AUTH": "sometimes"},
+			expectPanic:  true,
+			panicMessage: "api key auth is not valid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.envVars {
+				require.NoError(t, os.Setenv(key, value))
+			}
+
+			defer func() {
+				for key := range tt.envVars {
+					require.NoError(t, os.Unsetenv(key))
+				}
+			}()
+
+			if tt.expectPanic {
+				defer func() {
+					if r := recover(); r != nil {
+						assert.Equal(t, tt.panicMessage, r)
+					} else {
+						t.Errorf("Expected panic with message: %s", tt.panicMessage)
+					}
+				}()
+			}
+
+			assert.Equal(t, tt.expectedEnabled, getAPIKeyAuth())
+		})
+	}
+}
+
+func TestGetAdminToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		envVars       map[string]string
+		authEnabled   bool
+		expectedToken string
+		expectPanic   bool
+		panicMessage  string
+	}{
+		{
+			name:    "Open admin endpoints without authentication",
+			envVars: map[string]string{},
+		},
+		{
+			name:          "Admin token without authentication",
+			envVars:       map[string]string{"ADMIN_API_TOKEN": "secret"},
+			expectedToken: "secret",
+		},
+		{
+			name:          "Admin token with authentication",
+			envVars:       map[string]string{"ADMIN_API_TOKEN": "secret"},
+			authEnabled:   true,
+			expectedToken: "secret",
+		},
+		{
+			name:         "Without admin token with authentication",
+			envVars:      map[string]string{},
+			authEnabled:  true,
+			expectPanic:  true,
+			panicMessage: "admin api token is empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.envVars {
+				require.NoError(t, os.Setenv(key, value))
+			}
+
+			defer func() {
+				for key := range tt.envVars {
+					require.NoError(t, os.Unsetenv(key))
+				}
+			}()
+
+			if tt.expectPanic {
+				defer func() {
+					if r := recover(); r != nil {
+						assert.Equal(t, tt.panicMessage, r)
+					} else {
+						t.Errorf("Expected panic with message: %s", tt.panicMessage)
+					}
+				}()
+			}
+
+			assert.Equal(t, tt.expectedToken, getAdminToken(tt.authEnabled))
+		})
+	}
+}
+
+func TestGetJWTOptions(t *testing.T) {
+	tests := []struct {
+		name                  string
+		envVars               map[string]string
+		expectedKeySetOptions jwtauth.KeySetOptions
+		expectedOptions       jwtauth.Options
+		expectedEnabled       bool
+		expectPanic           bool
+		panicMessage          string
+	}{
+		{
+			name:    "Disabled by default",
+			envVars: map[string]string{},
+		},
+		{
+			name: "Enabled",
 			envVars: map[string]string{
-				"NOTIFIER_HOST":     "smtp.example.com",
-				"NOTIFIER_PORT":     "587",
-				"NOTIFIER_SENDER":   "user@example.com",
-				"NOTIFIER_PASSWORD": "password",
+				"JWT_JWKS":     "https://id.example.com/.well-known/jwks.json",
+				"JWT_ISSUER":   "https://id.example.com",
+				"JWT_AUDIENCE": "user-news-api",
 			},
-			expectedOpts: notifier.Options{
-				Host:     "smtp.example.com",
-				Port:     587,
-				Username: "user@example.com",
-				Password: "password",
+			expectedKeySetOptions: jwtauth.KeySetOptions{
+				Source:             "https://id.example.com/.well-known/jwks.json",
+				CacheTTL:           time.Hour,
+				MinRefreshInterval: time.Minute,
+				Timeout:            5 * time.Second,
 			},
-			expectPanic: false,
+			expectedOptions: jwtauth.Options{Issuer: "https://id.example.com", Audience: "user-news-api", Leeway: 30 * time.Second},
+			expectedEnabled: true,
 		},
 		{
-			name: "Notifier host is empty",
-			envVars: map[string]string{
-				"NOTIFIER_PORT":     "587",
-				"NOTIFIER_SENDER":   "user@example.com",
-				"NOTIFIER_PASSWORD": "password",
-			},
+			name:         "Without issuer",
+			envVars:      map[string]string{"JWT_JWKS": "/etc/jwks.json", "JWT_AUDIENCE": "user-news-api"},
 			expectPanic:  true,
-			panicMessage: "notifier host is empty",
+			panicMessage: "jwt issuer is empty",
 		},
 		{
-			name: "Notifier port is empty",
-			envVars: map[string]string{
-				"NOTIFIER_HOST":     "smtp.example.com",
-				"NOTIFIER_SENDER":   "user@example.com",
-				"NOTIFIER_PASSWORD": "password",
-			},
+			name:         "Without audience",
+			envVars:      map[string]string{"JWT_JWKS": "/etc/jwks.json", "JWT_ISSUER": "https://id.example.com"},
 			expectPanic:  true,
-			panicMessage: "notifier port is empty",
+			panicMessage: "jwt audience is empty",
 		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.envVars {
+				require.NoError(t, os.Setenv(key, value))
+			}
+
+			defer func() {
+				for key := range tt.envVars {
+					require.NoError(t, os.Unsetenv(key))
+				}
+			}()
+
+			if tt.expectPanic {
+				defer func() {
+					if r := recover(); r != nil {
+						assert.Equal(t, tt.panicMessage, r)
+					} else {
+						t.Errorf("Expected panic with message: %s", tt.panicMessage)
+					}
+				}()
+			}
+
+			keySetOptions, options, enabled := getJWTOptions()
+			assert.Equal(t, tt.expectedKeySetOptions, keySetOptions)
+			assert.Equal(t, tt.expectedOptions, options)
+			assert.Equal(t, tt.expectedEnabled, enabled)
+		})
+	}
+}
+
+func TestGetJWTRateLimit(t *testing.T) {
+	tests := []struct {
+		name              string
+		envVars           map[string]string
+		expectedRateLimit apikey.RateLimit
+		expectPanic       bool
+		panicMessage      string
+	}{
 		{
-			name: "Notifier port is not a number",
-			envVars: map[string]string{
-				"NOTIFIER_HOST":     "smtp.example.com",
-				"NOTIFIER_PORT":     "not a number",
-				"NOTIFIER_SENDER":   "user@example.com",
-				"NOTIFIER_PASSWORD": "password",
-			},
+			name:              "Default rate limit",
+			envVars:           map[string]string{},
+			expectedRateLimit: apikey.RateLimit{Max: 1000, Period: time.Hour},
+		},
+		{
+			name:              "Custom rate limit",
+			envVars:           map[string]string{"JWT_RATE_LIMIT": "50 / 1m"},
+			expectedRateLimit: apikey.RateLimit{Max: 50, Period: time.Minute},
+		},
+		{
+			name:    "Without rate limit",
+			envVars: map[string]string{"JWT_RATE_LIMIT": "none"},
+		},
+		{
+			name:         "Without period",
+			envVars:      map[string]string{"JWT_RATE_LIMIT": "50"},
 			expectPanic:  true,
-			panicMessage: "notifier port is not a number",
+			panicMessage: "jwt rate limit must be a max and a period separated by /",
 		},
 		{
-			name: "Notifier sender is empty",
-			envVars: map[string]string{
-				"NOTIFIER_HOST":     "smtp.example.com",
-				"NOTIFIER_PORT":     "587",
-				"NOTIFIER_PASSWORD": "password",
-			},
+			name:         "Invalid max",
+			envVars:      map[string]string{"JWT_RATE_LIMIT": "0/1h"},
+			expectPanic:  true,
+			panicMessage: "jwt rate limit max is not valid",
+		},
+		{
+			name:         "Invalid period",
+			envVars:      map[string]string{"JWT_RATE_LIMIT": "50/hour"},
+			expectPanic:  true,
+			panicMessage: "jwt rate limit period is not valid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.envVars {
+				require.NoError(t, os.Setenv(key, value))
+			}
+
+			defer func() {
+				for key := range tt.envVars {
+					require.NoError(t, os.Unsetenv(key))
+				}
+			}()
+
+			if tt.expectPanic {
+				defer func() {
+					if r := recover(); r != nil {
+						assert.Equal(t, tt.panicMessage, r)
+					} else {
+						t.Errorf("Expected panic with message: %s", tt.panicMessage)
+					}
+				}()
+			}
+
+			assert.Equal(t, tt.expectedRateLimit, getJWTRateLimit())
+		})
+	}
+}
+
+func TestGetLogOptions(t *testing.T) {
+	tests := []struct {
+		name         string
+		envVars      map[string]string
+		expectedOpts logging.Options
+		expectPanic  bool
+		panicMessage string
+	}{
+		{
+			name:         "Default level",
+			envVars:      map[string]string{},
+			expectedOpts: logging.Options{Level: slog.LevelInfo},
+		},
+		{
+			name:         "Debug level",
+			envVars:      map[string]string{"LOG_LEVEL": "debug"},
+			expectedOpts: logging.Options{Level: slog.LevelDebug},
+		},
+		{
+			name:         "Unknown level",
+			envVars:      map[string]string{"LOG_LEVEL": "verbose"},
 			expectPanic:  true,
-			panicMessage: "notifier sender is empty",
+			panicMessage: "log level is not valid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.envVars {
+				require.NoError(t, os.Setenv(key, value))
+			}
+
+			defer func() {
+				for key := range tt.envVars {
+					require.NoError(t, os.Unsetenv(key))
+				}
+			}()
+
+			if tt.expectPanic {
+				defer func() {
+					if r := recover(); r != nil {
+						assert.Equal(t, tt.panicMessage, r)
+					} else {
+						t.Errorf("Expected panic with message: %s", tt.panicMessage)
+					}
+				}()
+			}
+
+			assert.Equal(t, tt.expectedOpts, getLogOptions())
+		})
+	}
+}
+
+func TestGetTracingOptions(t *testing.T) {
+	tests := []struct {
+		name            string
+		envVars         map[string]string
+		expectedOpts    tracing.Options
+		expectedEnabled bool
+		expectPanic     bool
+		panicMessage    string
+	}{
+		{
+			name:    "Disabled",
+			envVars: map[string]string{},
+		},
+		{
+			name:            "Stdout exporter with the default service name",
+			envVars:         map[string]string{"TRACING_EXPORTER": "stdout"},
+			expectedOpts:    tracing.Options{Exporter: tracing.ExporterStdout, ServiceName: defaultServiceName},
+			expectedEnabled: true,
 		},
 		{
-			name: "Notifier password is empty",
+			name: "OTLP exporter",
 			envVars: map[string]string{
-				"NOTIFIER_HOST":   "smtp.example.com",
-				"NOTIFIER_PORT":   "587",
-				"NOTIFIER_SENDER": "user@example.com",
+				"TRACING_EXPORTER":  "otlp",
+				"OTEL_SERVICE_NAME": "news",
 			},
+			expectedOpts:    tracing.Options{Exporter: tracing.ExporterOTLP, ServiceName: "news"},
+			expectedEnabled: true,
+		},
+		{
+			name:         "Unknown exporter",
+			envVars:      map[string]string{"TRACING_EXPORTER": "jaeger"},
 			expectPanic:  true,
-			panicMessage: "notifier password is empty",
+			panicMessage: "tracing exporter is not valid",
 		},
 	}
 
@@ -109,39 +495,66 @@ func TestGetNotifierOptions(t *testing.T) {
 				}()
 			}
 
-			assert.Equal(t, tt.expectedOpts, getNotifierOptions())
+			options, enabled := getTracingOptions()
+
+			assert.Equal(t, tt.expectedOpts, options)
+			assert.Equal(t, tt.expectedEnabled, enabled)
 		})
 	}
 }
 
-func TestGetRedisOptions(t *testing.T) {
+func TestGetDeliveryOptions(t *testing.T) {
 	tests := []struct {
 		name         string
 		envVars      map[string]string
-		expectedOpts *redis.Options
+		expectedOpts deliveryOptions
 		expectPanic  bool
 		panicMessage string
 	}{
 		{
-			name: "Redis address is empty",
+			name:         "Defaults",
+			envVars:      map[string]string{},
+			expectedOpts: deliveryOptions{Async: false, Workers: 4},
+		},
+		{
+			name: "Async with workers",
+			envVars: map[string]string{
+				"DELIVERY_MODE": "async",
+				"WORKER_COUNT":  "8",
+			},
+			expectedOpts: deliveryOptions{Async: true, Workers: 8},
+		},
+		{
+			name: "Sync",
 			envVars: map[string]string{
-				"REDIS_ADDRESS": "",
+				"DELIVERY_MODE": "sync",
+			},
+			expectedOpts: deliveryOptions{Async: false, Workers: 4},
+		},
+		{
+			name: "Unknown delivery mode",
+			envVars: map[string]string{
+				"DELIVERY_MODE": "other",
 			},
 			expectPanic:  true,
-			panicMessage: "redis address is empty",
+			panicMessage: "delivery mode must be sync or async",
 		},
 		{
-			name: "OK",
+			name: "Worker count is not a number",
 			envVars: map[string]string{
-				"REDIS_ADDRESS":  "address",
-				"REDIS_PASSWORD": "pass",
+				"DELIVERY_MODE": "async",
+				"WORKER_COUNT":  "many",
 			},
-			expectPanic:  false,
-			panicMessage: "",
-			expectedOpts: &redis.Options{
-				Addr:     "address",
-				Password: "pass",
+			expectPanic:  true,
+			panicMessage: "worker count must be a positive number",
+		},
+		{
+			name: "Worker count is zero",
+			envVars: map[string]string{
+				"WORKER_COUNT": "0",
 			},
+			expectPanic:  true,
+			panicMessage: "worker count must be a positive number",
 		},
 	}
 
@@ -167,7 +580,7 @@ func TestGetRedisOptions(t *testing.T) {
 				}()
 			}
 
-			assert.Equal(t, tt.expectedOpts, getRedisOptions())
+			assert.Equal(t, tt.expectedOpts, getDeliveryOptions())
 		})
 	}
 }
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"user_news_api/logging"
+)
+
+// stopFunc stops a component, waiting until it is done or the context is.
+type stopFunc func(ctx context.Context) error
+
+func noStop(context.Context) error { return nil }
+
+// runInBackground runs f in its own goroutine until the returned function is called.
+func runInBackground(f func(context.Context)) stopFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		f(ctx)
+	}()
+
+	return func(stopCtx context.Context) error {
+		cancel()
+
+		select {
+		case <-done:
+			return nil
+		case <-stopCtx.Done():
+			return stopCtx.Err()
+		}
+	}
+}
+
+type shutdownStep struct {
+	name string
+	stop stopFunc
+}
+
+// shutdown stops the steps one after the other, all of them within the deadline of the context.
+// A failed step does not prevent the next ones from stopping.
+func shutdown(ctx context.Context, steps ...shutdownStep) {
+	for _, step := range steps {
+		if err := step.stop(ctx); err != nil {
+			slog.ErrorContext(ctx, "error shutting down", slog.String("step", step.name), logging.Error(err))
+
+			continue
+		}
+
+		slog.InfoContext(ctx, "shut down", slog.String("step", step.name))
+	}
+}
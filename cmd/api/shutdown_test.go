@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunInBackground(t *testing.T) {
+	tests := []struct {
+		name          string
+		run           func(ctx context.Context, release <-chan struct{})
+		expectedError error
+	}{
+		{
+			name: "Stopped when the context is done",
+			run: func(ctx context.Context, _ <-chan struct{}) {
+				<-ctx.Done()
+			},
+		},
+		{
+			name: "Not stopped within the deadline",
+			run: func(_ context.Context, release <-chan struct{}) {
+				<-release
+			},
+			expectedError: context.DeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := make(chan struct{})
+			defer close(release)
+
+			stop := runInBackground(func(ctx context.Context) {
+				tt.run(ctx, release)
+			})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			assert.ErrorIs(t, stop(ctx), tt.expectedError)
+		})
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	var stopped []string
+
+	step := func(name string, err error) shutdownStep {
+		return shutdownStep{name: name, stop: func(context.Context) error {
+			stopped = append(stopped, name)
+
+			return err
+		}}
+	}
+
+	shutdown(context.Background(),
+		step("http server", nil),
+		step("workers", errors.New("workers error")),
+		step("redis", nil),
+	)
+
+	// a failed step does not prevent the next ones from stopping
+	assert.Equal(t, []string{"http server", "workers", "redis"}, stopped)
+}
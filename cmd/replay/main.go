@@ -0,0 +1,106 @@
+// Command replay sends the notification requests of a JSONL file, one request per line, e.g.
+//
+//	{"user_email": "user@example.com", "message_type": "News"}
+//
+// Requests are sent to a running API, authenticated by -api-key or -bearer when it requires so, or straight through
+// the service with -direct, which is built from the same environment variables as the API. A summary report is written when the replay finishes, its resume_offset
+// continues an interrupted replay through -offset.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	"user_news_api/app"
+	"user_news_api/metrics"
+)
+
+func main() {
+	file := flag.String("file", "requests.jsonl", "JSONL file with the notification requests, - reads the standard input")
+	target := flag.String("target", "http://localhost:8080", "base URL of the API receiving the requests")
+	direct := flag.Bool("direct", false, "send the requests through the service instead of a running API")
+	rate := flag.Float64("rate", 0, "maximum of requests per second, 0 means no limit")
+	concurrency := flag.Int("concurrency", 1, "maximum of requests in flight")
+	offset := flag.Int("offset", 0, "amount of lines skipped from the start of the file")
+	continueOnError := flag.Bool("continue", false, "keep sending requests after a failure")
+	reportPath := flag.String("report", "", "file where the summary report is written, the standard output when empty")
+	apiKey := flag.String("api-key", "", "API key sent in the X-API-Key header of the requests")
+	bearer := flag.String("bearer", "", "bearer token sent in the Authorization header of the requests")
+	flag.Parse()
+
+	input, err := openInput(*file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer input.Close()
+
+	var sender Sender = NewHTTPSender(&http.Client{Timeout: time.Minute}, *target, Credentials{
+		APIKey: *apiKey,
+		Bearer: *bearer,
+	})
+
+	if *direct {
+		// the scheduled notifications and the digests are sent by the schedulers of the API
+		service, err := app.NewService(metrics.NewRegistry())
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		sender = ServiceSender{service: service.Notifier}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	report, err := NewReplayer(sender, Options{
+		Rate:        *rate,
+		Concurrency: *concurrency,
+		Offset:      *offset,
+		StopOnError: !*continueOnError,
+		MaxFailures: 100,
+	}).Run(ctx, input)
+	if err != nil {
+		log.Print(err)
+	}
+
+	if reportErr := writeReport(*reportPath, report); reportErr != nil {
+		log.Fatal(reportErr)
+	}
+
+	if err != nil || report.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func openInput(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+
+	return os.Open(path)
+}
+
+func writeReport(path string, report Report) error {
+	output := os.Stdout
+	if path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		output = file
+	}
+
+	encoder := json.NewEncoder(output)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(report)
+}
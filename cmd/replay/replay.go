@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+	"user_news_api/handler"
+	"user_news_api/services"
+)
+
+// maxReplyBody bounds how much of an API reply is kept in the report.
+const maxReplyBody = 512
+
+// Sender delivers a single notification request. Rate limited requests must return an error wrapping
+// services.ErrLimitExceeded, so they are reported apart from the failures.
+type Sender interface {
+	Send(ctx context.Context, payload handler.NotifyUserRequestPayload) error
+}
+
+// Credentials authenticate the requests sent to the API, they are not sent when empty.
+type Credentials struct {
+	APIKey string // APIKey is sent in the X-API-Key header
+	Bearer string // Bearer is a token of the identity provider, sent in the Authorization header
+}
+
+func NewHTTPSender(client *http.Client, target string, credentials Credentials) HTTPSender {
+	return HTTPSender{
+		client:      client,
+		url:         target + "/notifications",
+		credentials: credentials,
+	}
+}
+
+// HTTPSender sends the requests to a running API.
+type HTTPSender struct {
+	client      *http.Client
+	url         string
+	credentials Credentials
+}
+
+func (s HTTPSender) Send(ctx context.Context, payload handler.NotifyUserRequestPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("error marshalling request due to: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating request due to: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if s.credentials.APIKey != "" {
+		req.Header.Set("X-API-Key", s.credentials.APIKey)
+	}
+
+	if s.credentials.Bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+s.credentials.Bearer)
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error calling api due to: %w", err)
+	}
+	defer res.Body.Close()
+
+	reply, _ := io.ReadAll(io.LimitReader(res.Body, maxReplyBody))
+	reply = bytes.TrimSpace(reply)
+
+	switch {
+	case res.StatusCode >= 200 && res.StatusCode < 300:
+		return nil
+	case res.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("%w: api replied %d: %s", services.ErrLimitExceeded, res.StatusCode, reply)
+	default:
+		return fmt.Errorf("api replied %d: %s", res.StatusCode, reply)
+	}
+}
+
+// Notifier is the behavior of services.UserNotifierService used by ServiceSender.
+type Notifier interface {
+	Notify(ctx context.Context, userMail string, messageType string) (string, error)
+	Schedule(ctx context.Context, userMail string, messageType string, sendAt time.Time) (string, error)
+}
+
+// ServiceSender sends the requests straight through the service, without a running API.
+type ServiceSender struct {
+	service Notifier
+}
+
+// Send schedules the requests with a future send_at, as the API does. The notifications deferred, digested
+// or dropped by the rate limit policies are accepted, as the API answers them with 202 or 200.
+func (s ServiceSender) Send(ctx context.Context, payload handler.NotifyUserRequestPayload) error {
+	if payload.SendAt != nil && payload.SendAt.After(time.Now()) {
+		_, err := s.service.Schedule(ctx, payload.UserEmail, payload.MessageType, *payload.SendAt)
+
+		return err
+	}
+
+	_, err := s.service.Notify(ctx, payload.UserEmail, payload.MessageType)
+
+	var deferred services.DeferredError
+	if errors.As(err, &deferred) || errors.Is(err, services.ErrDigested) || errors.Is(err, services.ErrDropped) {
+		return nil
+	}
+
+	return err
+}
+
+type Options struct {
+	Rate        float64 // Rate is the maximum of requests started per second, there is no limit when it is not positive
+	Concurrency int     // Concurrency is the maximum of requests in flight
+	Offset      int     // Offset is the amount of lines skipped from the start of the input
+	StopOnError bool    // StopOnError stops dispatching requests after the first failure
+	MaxFailures int     // MaxFailures bounds how many failures are listed in the report, all of them are when not positive
+}
+
+// interval is the time between the requests of the Rate, zero when they are not limited. Rates over a request
+// per nanosecond round the interval to zero, so they are not limited either.
+func (o Options) interval() time.Duration {
+	if o.Rate <= 0 {
+		return 0
+	}
+
+	return time.Duration(float64(time.Second) / o.Rate)
+}
+
+// Failure is a request that could not be sent, Line is its zero based line number in the input.
+type Failure struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// Report summarizes a replay. ResumeOffset is the Offset that continues it. When the replay stops on a failure
+// it starts from the failed request, so the requests after it that were already in flight are sent again.
+type Report struct {
+	Sent         int       `json:"sent"`
+	RateLimited  int       `json:"rate_limited"`
+	Failed       int       `json:"failed"`
+	ResumeOffset int       `json:"resume_offset"`
+	Failures     []Failure `json:"failures,omitempty"`
+}
+
+func NewReplayer(sender Sender, options Options) Replayer {
+	return Replayer{
+		sender:  sender,
+		options: options,
+	}
+}
+
+// Replayer sends the notification requests of a JSONL input, one request per line.
+type Replayer struct {
+	sender  Sender
+	options Options
+}
+
+// replay holds the state shared by the requests in flight.
+type replay struct {
+	mu          sync.Mutex
+	report      Report
+	firstFailed int
+	stop        chan struct{}
+	stopOnce    sync.Once
+	maxFailures int
+	stopOnError bool
+}
+
+// Run blocks until every request of the input was sent, the replay is stopped by a failure, or the context is done.
+// Only errors reading the input are returned, the outcome of the requests is in the Report.
+func (rp Replayer) Run(ctx context.Context, input io.Reader) (Report, error) {
+	state := &replay{
+		firstFailed: -1,
+		stop:        make(chan struct{}),
+		maxFailures: rp.options.MaxFailures,
+		stopOnError: rp.options.StopOnError,
+	}
+
+	concurrency := rp.options.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	sem := make(chan struct{}, concurrency)
+
+	var throttle <-chan time.Time
+	if interval := rp.options.interval(); interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		throttle = ticker.C
+	}
+
+	var wg sync.WaitGroup
+
+	next := rp.options.Offset
+	line := -1
+
+	scanner := bufio.NewScanner(input)
+
+dispatch:
+	for scanner.Scan() {
+		line++
+		if line < rp.options.Offset {
+			continue
+		}
+
+		// the slot is taken before handling the line, so a single slot handles the lines in order
+		sem <- struct{}{}
+
+		if state.stopped(ctx) {
+			<-sem
+
+			break
+		}
+
+		next = line + 1
+
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			<-sem
+
+			continue
+		}
+
+		var payload handler.NotifyUserRequestPayload
+		if err := json.Unmarshal(text, &payload); err != nil {
+			<-sem
+			state.record(line, fmt.Errorf("error unmarshalling request due to: %w", err))
+
+			continue
+		}
+
+		if throttle != nil {
+			select {
+			case <-throttle:
+			case <-ctx.Done():
+				<-sem
+				next = line
+
+				break dispatch
+			case <-state.stop:
+				<-sem
+				next = line
+
+				break dispatch
+			}
+		}
+
+		wg.Add(1)
+
+		go func(line int, payload handler.NotifyUserRequestPayload) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			state.record(line, rp.sender.Send(ctx, payload))
+		}(line, payload)
+	}
+
+	wg.Wait()
+
+	report := state.report
+	sort.Slice(report.Failures, func(i, j int) bool { return report.Failures[i].Line < report.Failures[j].Line })
+
+	report.ResumeOffset = next
+	if rp.options.StopOnError && state.firstFailed >= 0 && state.firstFailed < next {
+		report.ResumeOffset = state.firstFailed
+	}
+
+	if err := scanner.Err(); err != nil {
+		return report, fmt.Errorf("error reading requests due to: %w", err)
+	}
+
+	return report, nil
+}
+
+func (r *replay) stopped(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return true
+	case <-r.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+func (r *replay) record(line int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case err == nil:
+		r.report.Sent++
+	case errors.Is(err, services.ErrLimitExceeded):
+		r.report.RateLimited++
+	default:
+		r.report.Failed++
+
+		if r.firstFailed < 0 || line < r.firstFailed {
+			r.firstFailed = line
+		}
+
+		if r.maxFailures <= 0 || len(r.report.Failures) < r.maxFailures {
+			r.report.Failures = append(r.report.Failures, Failure{Line: line, Error: err.Error()})
+		}
+
+		if r.stopOnError {
+			r.stopOnce.Do(func() { close(r.stop) })
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"user_news_api/handler"
+	"user_news_api/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAPI replies like the API does depending on the user of the request.
+func newAPI(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload handler.NotifyUserRequestPayload
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+
+		switch payload.UserEmail {
+		case "limited@example.com":
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+		case "broken@example.com":
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}
+	}))
+
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestReplayerRun(t *testing.T) {
+	input := strings.Join([]string{
+		`{"user_email": "first@example.com", "message_type": "News"}`,
+		`{"user_email": "limited@example.com", "message_type": "News"}`,
+		``,
+		`{"user_email": "broken@example.com", "message_type": "News"}`,
+		`not json`,
+		`{"user_email": "last@example.com", "message_type": "News"}`,
+	}, "\n")
+
+	tests := []struct {
+		name     string
+		options  Options
+		expected Report
+	}{
+		{
+			name:    "continue past errors",
+			options: Options{Concurrency: 3},
+			expected: Report{
+				Sent:         2,
+				RateLimited:  1,
+				Failed:       2,
+				ResumeOffset: 6,
+				Failures: []Failure{
+					{Line: 3, Error: "api replied 500: internal error"},
+					{Line: 4, Error: "error unmarshalling request due to: invalid character 'o' in literal null (expecting 'u')"},
+				},
+			},
+		},
+		{
+			name:    "stop on the first error",
+			options: Options{Concurrency: 1, StopOnError: true},
+			expected: Report{
+				Sent:         1,
+				RateLimited:  1,
+				Failed:       1,
+				ResumeOffset: 3,
+				Failures:     []Failure{{Line: 3, Error: "api replied 500: internal error"}},
+			},
+		},
+		{
+			name:    "resume from offset",
+			options: Options{Concurrency: 1, Offset: 5, StopOnError: true, Rate: 100},
+			expected: Report{
+				Sent:         1,
+				ResumeOffset: 6,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newAPI(t)
+
+			replayer := NewReplayer(NewHTTPSender(server.Client(), server.URL, Credentials{}), test.options)
+
+			report, err := replayer.Run(context.Background(), strings.NewReader(input))
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, report)
+		})
+	}
+}
+
+func TestHTTPSenderSendCredentials(t *testing.T) {
+	tests := []struct {
+		name                  string
+		credentials           Credentials
+		expectedAPIKey        string
+		expectedAuthorization string
+	}{
+		{name: "without credentials"},
+		{name: "api key", credentials: Credentials{APIKey: "some-key"}, expectedAPIKey: "some-key"},
+		{name: "bearer token", credentials: Credentials{Bearer: "some-token"}, expectedAuthorization: "Bearer some-token"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, test.expectedAPIKey, r.Header.Get("X-API-Key"))
+				assert.Equal(t, test.expectedAuthorization, r.Header.Get("Authorization"))
+			}))
+			defer server.Close()
+
+			sender := NewHTTPSender(server.Client(), server.URL, test.credentials)
+
+			assert.NoError(t, sender.Send(context.Background(), handler.NotifyUserRequestPayload{
+				UserEmail:   "user@example.com",
+				MessageType: "News",
+			}))
+		})
+	}
+}
+
+// fakeNotifier answers the notifications sent right away with notifyErr, and schedules the others.
+type fakeNotifier struct {
+	notifyErr error
+	scheduled []time.Time
+}
+
+func (f *fakeNotifier) Notify(context.Context, string, string) (string, error) {
+	return "some-id", f.notifyErr
+}
+
+func (f *fakeNotifier) Schedule(_ context.Context, _ string, _ string, sendAt time.Time) (string, error) {
+	f.scheduled = append(f.scheduled, sendAt)
+
+	return "some-id", nil
+}
+
+func TestServiceSenderSend(t *testing.T) {
+	sendAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	input := strings.Join([]string{
+		`{"user_email": "user@example.com", "message_type": "News"}`,
+		`{"user_email": "user@example.com", "message_type": "News", "send_at": "` + sendAt.Format(time.RFC3339) + `"}`,
+	}, "\n")
+
+	service := &fakeNotifier{notifyErr: errors.Join(services.ErrLimitExceeded, errors.New("rate limit reached"))}
+
+	report, err := NewReplayer(ServiceSender{service: service}, Options{}).Run(context.Background(), strings.NewReader(input))
+
+	require.NoError(t, err)
+	assert.Equal(t, Report{Sent: 1, RateLimited: 1, ResumeOffset: 2}, report)
+	assert.Equal(t, []time.Time{sendAt}, service.scheduled)
+}
+
+func TestServiceSenderSendPolicies(t *testing.T) {
+	tests := []struct {
+		name      string
+		notifyErr error
+		expected  Report
+	}{
+		{
+			name:      "deferred",
+			notifyErr: services.DeferredError{SendAt: time.Now().Add(time.Hour)},
+			expected:  Report{Sent: 1, ResumeOffset: 1},
+		},
+		{
+			name:      "digested",
+			notifyErr: fmt.Errorf("%w for user user@example.com and message type News", services.ErrDigested),
+			expected:  Report{Sent: 1, ResumeOffset: 1},
+		},
+		{
+			name:      "dropped",
+			notifyErr: fmt.Errorf("%w for user user@example.com and message type News", services.ErrDropped),
+			expected:  Report{Sent: 1, ResumeOffset: 1},
+		},
+		{
+			name:      "failed",
+			notifyErr: errors.New("notifier error"),
+			expected:  Report{Failed: 1, ResumeOffset: 0, Failures: []Failure{{Line: 0, Error: "notifier error"}}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sender := ServiceSender{service: &fakeNotifier{notifyErr: test.notifyErr}}
+			input := `{"user_email": "user@example.com", "message_type": "News"}`
+
+			report, err := NewReplayer(sender, Options{StopOnError: true}).Run(context.Background(), strings.NewReader(input))
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, report)
+		})
+	}
+}
+
+func TestOptionsInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		rate     float64
+		expected time.Duration
+	}{
+		{name: "not limited", rate: 0, expected: 0},
+		{name: "negative rate", rate: -1, expected: 0},
+		{name: "limited", rate: 20, expected: 50 * time.Millisecond},
+		{name: "above a request per nanosecond", rate: 2e9, expected: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, Options{Rate: test.rate}.interval())
+		})
+	}
+}
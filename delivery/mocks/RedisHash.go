@@ -0,0 +1,102 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	redis "github.com/redis/go-redis/v9"
+
+	time "time"
+)
+
+// RedisHash is an autogenerated mock type for the RedisHash type
+type RedisHash struct {
+	mock.Mock
+}
+
+// Eval provides a mock function with given fields: ctx, script, keys, args
+func (_m *RedisHash) Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd {
+	var _ca []interface{}
+	_ca = append(_ca, ctx, script, keys)
+	_ca = append(_ca, args...)
+	ret := _m.Called(_ca...)
+
+	var r0 *redis.Cmd
+	if rf, ok := ret.Get(0).(func(context.Context, string, []string, ...interface{}) *redis.Cmd); ok {
+		r0 = rf(ctx, script, keys, args...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.Cmd)
+		}
+	}
+
+	return r0
+}
+
+// Expire provides a mock function with given fields: ctx, key, expiration
+func (_m *RedisHash) Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd {
+	ret := _m.Called(ctx, key, expiration)
+
+	var r0 *redis.BoolCmd
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Duration) *redis.BoolCmd); ok {
+		r0 = rf(ctx, key, expiration)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.BoolCmd)
+		}
+	}
+
+	return r0
+}
+
+// HGetAll provides a mock function with given fields: ctx, key
+func (_m *RedisHash) HGetAll(ctx context.Context, key string) *redis.MapStringStringCmd {
+	ret := _m.Called(ctx, key)
+
+	var r0 *redis.MapStringStringCmd
+	if rf, ok := ret.Get(0).(func(context.Context, string) *redis.MapStringStringCmd); ok {
+		r0 = rf(ctx, key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.MapStringStringCmd)
+		}
+	}
+
+	return r0
+}
+
+// HSet provides a mock function with given fields: ctx, key, values
+func (_m *RedisHash) HSet(ctx context.Context, key string, values ...interface{}) *redis.IntCmd {
+	var _ca []interface{}
+	_ca = append(_ca, ctx, key)
+	_ca = append(_ca, values...)
+	ret := _m.Called(_ca...)
+
+	var r0 *redis.IntCmd
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...interface{}) *redis.IntCmd); ok {
+		r0 = rf(ctx, key, values...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.IntCmd)
+		}
+	}
+
+	return r0
+}
+
+// NewRedisHash creates a new instance of RedisHash. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewRedisHash(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *RedisHash {
+	mock := &RedisHash{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,314 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	StateQueued      State = "queued"
+	StateSending     State = "sending"
+	StateSent        State = "sent"
+	StateFailed      State = "failed"
+	StateRateLimited State = "rate_limited"
+	StateScheduled   State = "scheduled"
+	StateCanceled    State = "canceled"
+	StateBuffered    State = "buffered"
+	StateDropped     State = "dropped"
+)
+
+var (
+	ErrNotFound = errors.New("notification not found")
+)
+
+// DefaultRetention is how long a record is kept after its last update, or after it is due when it is scheduled.
+const DefaultRetention = 72 * time.Hour
+
+// State is the stage of a notification in its delivery.
+type State string
+
+// Record tracks the delivery of a single notification.
+type Record struct {
+	ID           string     `json:"id"`
+	UserEmail    string     `json:"user_email"`
+	MessageType  string     `json:"message_type"`
+	Channel      string     `json:"channel,omitempty"` // Channel is empty for email
+	Client       string     `json:"client,omitempty"`  // Client is who sent the notification, empty when the clients are not authenticated
+	State        State      `json:"state"`
+	Reason       string     `json:"reason,omitempty"`        // Reason explains why the notification failed or was rate limited
+	Attempts     int64      `json:"attempts"`                // Attempts counts the times the notification was handed to the mail server
+	SMTPResponse string     `json:"smtp_response,omitempty"` // SMTPResponse is the reply of the mail server when it rejects the notification
+	Provider     string     `json:"provider,omitempty"`      // Provider is the mail server that sent the notification
+	SendAt       *time.Time `json:"send_at,omitempty"`       // SendAt is when a scheduled notification is due
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func NewStore(db *redis.Client, retention time.Duration) Store {
+	return Store{
+		db:        db,
+		retention: retention,
+		now:       time.Now,
+	}
+}
+
+// RedisHash is an abstraction for redis.Client making it mockeable
+type RedisHash interface {
+	HSet(ctx context.Context, key string, values ...interface{}) *redis.IntCmd
+	HGetAll(ctx context.Context, key string) *redis.MapStringStringCmd
+	Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd
+}
+
+// updateScript writes the fields of an existing record, a record that expired or never existed is not created again.
+// The expiration is only extended, so a scheduled record is kept until it is due however it is updated meanwhile.
+// KEYS[1] is the record, ARGV[1] the expiration in milliseconds, ARGV[2] the field counted up by one (empty for none)
+// and the rest are the fields and their values.
+const updateScript = `
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+
+if ARGV[2] ~= '' then
+	redis.call('HINCRBY', KEYS[1], ARGV[2], 1)
+end
+
+redis.call('HSET', KEYS[1], unpack(ARGV, 3))
+
+if redis.call('PTTL', KEYS[1]) < tonumber(ARGV[1]) then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+
+return 1
+`
+
+// Store keeps the records as Redis hashes, so every update only writes the fields it changes.
+type Store struct {
+	db        RedisHash
+	retention time.Duration // retention is restarted on every update, it starts when a scheduled record is due
+	now       func() time.Time
+}
+
+func (s Store) Create(ctx context.Context, record Record) error {
+	now := s.now().UTC()
+
+	fields := map[string]interface{}{
+		"id":           record.ID,
+		"user_email":   record.UserEmail,
+		"message_type": record.MessageType,
+		"state":        string(record.State),
+		"reason":       record.Reason,
+		"attempts":     record.Attempts,
+		"created_at":   now.Format(time.RFC3339Nano),
+		"updated_at":   now.Format(time.RFC3339Nano),
+	}
+
+	if record.Channel != "" {
+		fields["channel"] = record.Channel
+	}
+
+	if record.Client != "" {
+		fields["client"] = record.Client
+	}
+
+	if record.SendAt != nil {
+		fields["send_at"] = record.SendAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	if err := s.db.HSet(ctx, key(record.ID), fields).Err(); err != nil {
+		return fmt.Errorf("error creating record %s due to: %w", record.ID, err)
+	}
+
+	// the expiration is not validated in case of fail, it is set again in the next update
+	_ = s.db.Expire(ctx, key(record.ID), s.expiration(record.SendAt))
+
+	return nil
+}
+
+// MarkQueued records that a scheduled notification is due and was handed to delivery.
+func (s Store) MarkQueued(ctx context.Context, id string) error {
+	return s.update(ctx, id, map[string]interface{}{
+		"state":      string(StateQueued),
+		"updated_at": s.now().UTC().Format(time.RFC3339Nano),
+	})
+}
+
+// MarkRateLimited records a scheduled notification that reached the rate limit when it was due.
+func (s Store) MarkRateLimited(ctx context.Context, id string, reason string) error {
+	return s.update(ctx, id, map[string]interface{}{
+		"state":      string(StateRateLimited),
+		"reason":     reason,
+		"updated_at": s.now().UTC().Format(time.RFC3339Nano),
+	})
+}
+
+// MarkBuffered records a scheduled notification that reached the rate limit when it was due, and waits for a digest.
+func (s Store) MarkBuffered(ctx context.Context, id string, reason string) error {
+	return s.update(ctx, id, map[string]interface{}{
+		"state":      string(StateBuffered),
+		"reason":     reason,
+		"updated_at": s.now().UTC().Format(time.RFC3339Nano),
+	})
+}
+
+// MarkDeferred records a notification that reached the rate limit when it was due, and it was scheduled again.
+func (s Store) MarkDeferred(ctx context.Context, id string, sendAt time.Time, reason string) error {
+	return s.write(ctx, id, s.expiration(&sendAt), "", map[string]interface{}{
+		"state":      string(StateScheduled),
+		"reason":     reason,
+		"send_at":    sendAt.UTC().Format(time.RFC3339Nano),
+		"updated_at": s.now().UTC().Format(time.RFC3339Nano),
+	})
+}
+
+// MarkDropped records a scheduled notification that reached the rate limit when it was due, and it was dropped.
+func (s Store) MarkDropped(ctx context.Context, id string, reason string) error {
+	return s.update(ctx, id, map[string]interface{}{
+		"state":      string(StateDropped),
+		"reason":     reason,
+		"updated_at": s.now().UTC().Format(time.RFC3339Nano),
+	})
+}
+
+func (s Store) MarkCanceled(ctx context.Context, id string) error {
+	return s.update(ctx, id, map[string]interface{}{
+		"state":      string(StateCanceled),
+		"updated_at": s.now().UTC().Format(time.RFC3339Nano),
+	})
+}
+
+// MarkSending counts a new attempt of sending the notification.
+func (s Store) MarkSending(ctx context.Context, id string) error {
+	return s.write(ctx, id, s.retention, "attempts", map[string]interface{}{
+		"state":      string(StateSending),
+		"updated_at": s.now().UTC().Format(time.RFC3339Nano),
+	})
+}
+
+// MarkSent records the delivered notification and the provider that sent it, empty for other channels than email.
+func (s Store) MarkSent(ctx context.Context, id string, provider string) error {
+	return s.update(ctx, id, map[string]interface{}{
+		"state":         string(StateSent),
+		"reason":        "",
+		"smtp_response": "",
+		"provider":      provider,
+		"updated_at":    s.now().UTC().Format(time.RFC3339Nano),
+	})
+}
+
+func (s Store) MarkFailed(ctx context.Context, id string, reason string, smtpResponse string) error {
+	return s.update(ctx, id, map[string]interface{}{
+		"state":         string(StateFailed),
+		"reason":        reason,
+		"smtp_response": smtpResponse,
+		"updated_at":    s.now().UTC().Format(time.RFC3339Nano),
+	})
+}
+
+func (s Store) Get(ctx context.Context, id string) (Record, error) {
+	fields, err := s.db.HGetAll(ctx, key(id)).Result()
+	if err != nil {
+		return Record{}, fmt.Errorf("error getting record %s due to: %w", id, err)
+	}
+
+	if len(fields) == 0 {
+		return Record{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+
+	// malformed numbers and dates are left as zero values, they are always written by this store
+	attempts, _ := strconv.ParseInt(fields["attempts"], 10, 64)
+	createdAt, _ := time.Parse(time.RFC3339Nano, fields["created_at"])
+	updatedAt, _ := time.Parse(time.RFC3339Nano, fields["updated_at"])
+
+	var sendAt *time.Time
+	if value, ok := fields["send_at"]; ok {
+		if parsed, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			sendAt = &parsed
+		}
+	}
+
+	return Record{
+		ID:           fields["id"],
+		UserEmail:    fields["user_email"],
+		MessageType:  fields["message_type"],
+		Channel:      fields["channel"],
+		Client:       fields["client"],
+		State:        State(fields["state"]),
+		Reason:       fields["reason"],
+		Attempts:     attempts,
+		SMTPResponse: fields["smtp_response"],
+		Provider:     fields["provider"],
+		SendAt:       sendAt,
+		CreatedAt:    createdAt,
+		UpdatedAt:    updatedAt,
+	}, nil
+}
+
+func (s Store) update(ctx context.Context, id string, fields map[string]interface{}) error {
+	return s.write(ctx, id, s.retention, "", fields)
+}
+
+// write updates the fields of an existing record, counting up the counter field when it is not empty, and keeps it
+// at least for the expiration. ErrNotFound is returned when the record expired or never existed.
+func (s Store) write(
+	ctx context.Context, id string, expiration time.Duration, counter string, fields map[string]interface{},
+) error {
+	args := make([]interface{}, 0, 2+2*len(fields))
+	args = append(args, expiration.Milliseconds(), counter)
+
+	// the fields are sorted, so the same update always runs with the same arguments
+	names := make([]string, 0, len(fields))
+	for field := range fields {
+		names = append(names, field)
+	}
+
+	sort.Strings(names)
+
+	for _, field := range names {
+		args = append(args, field, fields[field])
+	}
+
+	updated, err := s.db.Eval(ctx, updateScript, []string{key(id)}, args...).Int64()
+	if err != nil {
+		return fmt.Errorf("error updating record %s due to: %w", id, err)
+	}
+
+	if updated == 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+
+	return nil
+}
+
+// expiration is the retention of a record, which starts when it is due for the scheduled ones.
+func (s Store) expiration(sendAt *time.Time) time.Duration {
+	if sendAt == nil {
+		return s.retention
+	}
+
+	return s.retention + max(sendAt.Sub(s.now()), 0)
+}
+
+func key(id string) string {
+	return fmt.Sprintf("notification-%s", id)
+}
+
+type clientContextKey struct{}
+
+// WithClient returns a copy of the context carrying the authenticated client, which owns the notifications it sends.
+func WithClient(ctx context.Context, client string) context.Context {
+	return context.WithValue(ctx, clientContextKey{}, client)
+}
+
+// Client returns the authenticated client of the context, it is empty when the clients are not authenticated.
+func Client(ctx context.Context) string {
+	client, _ := ctx.Value(clientContextKey{}).(string)
+
+	return client
+}
@@ -0,0 +1,332 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"user_news_api/delivery/mocks"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var testNow = time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+func newTestStore(db RedisHash) Store {
+	return Store{
+		db:        db,
+		retention: time.Hour,
+		now:       func() time.Time { return testNow },
+	}
+}
+
+func TestStoreCreate(t *testing.T) {
+	record := Record{
+		ID:          "some-id",
+		UserEmail:   "user@example.com",
+		MessageType: "News",
+		State:       StateQueued,
+	}
+
+	fields := map[string]interface{}{
+		"id":           "some-id",
+		"user_email":   "user@example.com",
+		"message_type": "News",
+		"state":        "queued",
+		"reason":       "",
+		"attempts":     int64(0),
+		"created_at":   "2024-01-01T10:00:00Z",
+		"updated_at":   "2024-01-01T10:00:00Z",
+	}
+
+	tests := []struct {
+		name          string
+		mockApplier   func(mockRedis *mocks.RedisHash)
+		expectedError error
+	}{
+		{
+			name: "record created",
+			mockApplier: func(mockRedis *mocks.RedisHash) {
+				mockRedis.On("HSet", mock.Anything, "notification-some-id", fields).Return(redis.NewIntResult(8, nil)).Once()
+				mockRedis.On("Expire", mock.Anything, "notification-some-id", time.Hour).Return(redis.NewBoolResult(true, nil)).Once()
+			},
+			expectedError: nil,
+		},
+		{
+			name: "expiration errors are ignored",
+			mockApplier: func(mockRedis *mocks.RedisHash) {
+				mockRedis.On("HSet", mock.Anything, "notification-some-id", fields).Return(redis.NewIntResult(8, nil)).Once()
+				mockRedis.On("Expire", mock.Anything, "notification-some-id", time.Hour).Return(redis.NewBoolResult(false, errors.New("error"))).Once()
+			},
+			expectedError: nil,
+		},
+		{
+			name: "redis error",
+			mockApplier: func(mockRedis *mocks.RedisHash) {
+				mockRedis.On("HSet", mock.Anything, "notification-some-id", fields).Return(redis.NewIntResult(0, errors.New("error"))).Once()
+			},
+			expectedError: fmt.Errorf("error creating record some-id due to: %w", errors.New("error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisHash(t)
+			tt.mockApplier(mockRedis)
+
+			assert.Equal(t, tt.expectedError, newTestStore(mockRedis).Create(context.Background(), record))
+		})
+	}
+}
+
+func TestStoreCreateScheduled(t *testing.T) {
+	sendAt := testNow.Add(100 * time.Hour)
+
+	mockRedis := mocks.NewRedisHash(t)
+	mockRedis.On("HSet", mock.Anything, "notification-some-id", mock.Anything).Return(redis.NewIntResult(9, nil)).Once()
+	// the record is kept until the retention is over after it is due
+	mockRedis.On("Expire", mock.Anything, "notification-some-id", 101*time.Hour).Return(redis.NewBoolResult(true, nil)).Once()
+
+	err := newTestStore(mockRedis).Create(context.Background(), Record{ID: "some-id", State: StateScheduled, SendAt: &sendAt})
+	assert.NoError(t, err)
+}
+
+// onUpdate expects the update of the record some-id with the arguments of the updateScript.
+func onUpdate(mockRedis *mocks.RedisHash, updated int64, err error, args ...interface{}) {
+	mockRedis.On("Eval", append([]interface{}{mock.Anything, updateScript, []string{"notification-some-id"}}, args...)...).
+		Return(redis.NewCmdResult(updated, err)).Once()
+}
+
+func TestStoreMarkSending(t *testing.T) {
+	tests := []struct {
+		name          string
+		mockApplier   func(mockRedis *mocks.RedisHash)
+		expectedError error
+	}{
+		{
+			name: "attempt counted",
+			mockApplier: func(mockRedis *mocks.RedisHash) {
+				onUpdate(mockRedis, 1, nil, int64(3600000), "attempts", "state", "sending", "updated_at", "2024-01-01T10:00:00Z")
+			},
+			expectedError: nil,
+		},
+		{
+			name: "record not found",
+			mockApplier: func(mockRedis *mocks.RedisHash) {
+				onUpdate(mockRedis, 0, nil, int64(3600000), "attempts", "state", "sending", "updated_at", "2024-01-01T10:00:00Z")
+			},
+			expectedError: fmt.Errorf("%w: some-id", ErrNotFound),
+		},
+		{
+			name: "redis error",
+			mockApplier: func(mockRedis *mocks.RedisHash) {
+				onUpdate(mockRedis, 0, errors.New("error"), int64(3600000), "attempts", "state", "sending", "updated_at", "2024-01-01T10:00:00Z")
+			},
+			expectedError: fmt.Errorf("error updating record some-id due to: %w", errors.New("error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisHash(t)
+			tt.mockApplier(mockRedis)
+
+			assert.Equal(t, tt.expectedError, newTestStore(mockRedis).MarkSending(context.Background(), "some-id"))
+		})
+	}
+}
+
+func TestStoreMarkOutcome(t *testing.T) {
+	tests := []struct {
+		name     string
+		mark     func(s Store) error
+		expected []interface{}
+	}{
+		{
+			name: "sent",
+			mark: func(s Store) error {
+				return s.MarkSent(context.Background(), "some-id", "backup")
+			},
+			expected: []interface{}{
+				int64(3600000), "",
+				"provider", "backup",
+				"reason", "",
+				"smtp_response", "",
+				"state", "sent",
+				"updated_at", "2024-01-01T10:00:00Z",
+			},
+		},
+		{
+			name: "failed",
+			mark: func(s Store) error {
+				return s.MarkFailed(context.Background(), "some-id", "rejected", "550 no such user")
+			},
+			expected: []interface{}{
+				int64(3600000), "",
+				"reason", "rejected",
+				"smtp_response", "550 no such user",
+				"state", "failed",
+				"updated_at", "2024-01-01T10:00:00Z",
+			},
+		},
+		{
+			name: "queued",
+			mark: func(s Store) error {
+				return s.MarkQueued(context.Background(), "some-id")
+			},
+			expected: []interface{}{
+				int64(3600000), "",
+				"state", "queued",
+				"updated_at", "2024-01-01T10:00:00Z",
+			},
+		},
+		{
+			name: "rate limited",
+			mark: func(s Store) error {
+				return s.MarkRateLimited(context.Background(), "some-id", "limit exceeded")
+			},
+			expected: []interface{}{
+				int64(3600000), "",
+				"reason", "limit exceeded",
+				"state", "rate_limited",
+				"updated_at", "2024-01-01T10:00:00Z",
+			},
+		},
+		{
+			name: "buffered",
+			mark: func(s Store) error {
+				return s.MarkBuffered(context.Background(), "some-id", "limit exceeded")
+			},
+			expected: []interface{}{
+				int64(3600000), "",
+				"reason", "limit exceeded",
+				"state", "buffered",
+				"updated_at", "2024-01-01T10:00:00Z",
+			},
+		},
+		{
+			name: "deferred",
+			mark: func(s Store) error {
+				return s.MarkDeferred(context.Background(), "some-id", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), "limit exceeded")
+			},
+			expected: []interface{}{
+				int64(54000000), "",
+				"reason", "limit exceeded",
+				"send_at", "2024-01-02T00:00:00Z",
+				"state", "scheduled",
+				"updated_at", "2024-01-01T10:00:00Z",
+			},
+		},
+		{
+			name: "dropped",
+			mark: func(s Store) error {
+				return s.MarkDropped(context.Background(), "some-id", "limit exceeded")
+			},
+			expected: []interface{}{
+				int64(3600000), "",
+				"reason", "limit exceeded",
+				"state", "dropped",
+				"updated_at", "2024-01-01T10:00:00Z",
+			},
+		},
+		{
+			name: "canceled",
+			mark: func(s Store) error {
+				return s.MarkCanceled(context.Background(), "some-id")
+			},
+			expected: []interface{}{
+				int64(3600000), "",
+				"state", "canceled",
+				"updated_at", "2024-01-01T10:00:00Z",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisHash(t)
+			onUpdate(mockRedis, 1, nil, tt.expected...)
+
+			assert.NoError(t, tt.mark(newTestStore(mockRedis)))
+		})
+	}
+}
+
+func TestStoreGet(t *testing.T) {
+	sendAt := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		mockApplier   func(mockRedis *mocks.RedisHash)
+		expected      Record
+		expectedError error
+	}{
+		{
+			name: "existing record",
+			mockApplier: func(mockRedis *mocks.RedisHash) {
+				mockRedis.On("HGetAll", mock.Anything, "notification-some-id").Return(redis.NewMapStringStringResult(map[string]string{
+					"id":            "some-id",
+					"user_email":    "user@example.com",
+					"message_type":  "News",
+					"channel":       "webhook",
+					"client":        "api-key:abc",
+					"state":         "failed",
+					"reason":        "rejected",
+					"attempts":      "2",
+					"smtp_response": "550 no such user",
+					"provider":      "primary",
+					"send_at":       "2024-01-01T09:00:00Z",
+					"created_at":    "2024-01-01T10:00:00Z",
+					"updated_at":    "2024-01-01T10:01:00Z",
+				}, nil)).Once()
+			},
+			expected: Record{
+				ID:           "some-id",
+				UserEmail:    "user@example.com",
+				MessageType:  "News",
+				Channel:      "webhook",
+				Client:       "api-key:abc",
+				State:        StateFailed,
+				Reason:       "rejected",
+				Attempts:     2,
+				SMTPResponse: "550 no such user",
+				Provider:     "primary",
+				SendAt:       &sendAt,
+				CreatedAt:    time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+				UpdatedAt:    time.Date(2024, 1, 1, 10, 1, 0, 0, time.UTC),
+			},
+			expectedError: nil,
+		},
+		{
+			name: "missing record",
+			mockApplier: func(mockRedis *mocks.RedisHash) {
+				mockRedis.On("HGetAll", mock.Anything, "notification-some-id").Return(redis.NewMapStringStringResult(map[string]string{}, nil)).Once()
+			},
+			expected:      Record{},
+			expectedError: fmt.Errorf("%w: %s", ErrNotFound, "some-id"),
+		},
+		{
+			name: "redis error",
+			mockApplier: func(mockRedis *mocks.RedisHash) {
+				mockRedis.On("HGetAll", mock.Anything, "notification-some-id").Return(redis.NewMapStringStringResult(nil, errors.New("error"))).Once()
+			},
+			expected:      Record{},
+			expectedError: fmt.Errorf("error getting record some-id due to: %w", errors.New("error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisHash(t)
+			tt.mockApplier(mockRedis)
+
+			record, err := newTestStore(mockRedis).Get(context.Background(), "some-id")
+
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expected, record)
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package digest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"user_news_api/logging"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultOptions are used by the API for every message type with the digest policy.
+var DefaultOptions = Options{
+	Prefix:    "digest",
+	TTL:       48 * time.Hour,
+	DrainSize: 100,
+}
+
+type Options struct {
+	Prefix    string        // Prefix of the Redis lists, there is one list per user and message type
+	TTL       time.Duration // TTL drops the buffered entries that were never drained, it is restarted on every new entry
+	DrainSize int64         // DrainSize is the maximum of entries popped at once while draining
+}
+
+// Entry is a notification waiting for the next digest.
+type Entry struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewBuffer(db *redis.Client, options Options) Buffer {
+	return Buffer{
+		db:      db,
+		options: options,
+	}
+}
+
+// RedisList is an abstraction for redis.Client making it mockeable
+type RedisList interface {
+	RPush(ctx context.Context, key string, values ...interface{}) *redis.IntCmd
+	LPush(ctx context.Context, key string, values ...interface{}) *redis.IntCmd
+	LPopCount(ctx context.Context, key string, count int) *redis.StringSliceCmd
+	Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd
+}
+
+// Buffer keeps the entries of every user and message type in a Redis list, the oldest first.
+type Buffer struct {
+	db      RedisList
+	options Options
+}
+
+func (b Buffer) Add(ctx context.Context, user string, messageType string, entry Entry) error {
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("error marshalling digest entry due to: %w", err)
+	}
+
+	key := b.key(user, messageType)
+	if err = b.db.RPush(ctx, key, payload).Err(); err != nil {
+		return fmt.Errorf("error buffering digest entry %s due to: %w", entry.ID, err)
+	}
+
+	// the expiration is not validated in case of fail, it is set again with the next entry
+	_ = b.db.Expire(ctx, key, b.options.TTL)
+
+	return nil
+}
+
+// Drain pops all the entries of the user and message type. Popping is atomic,
+// so an entry is never returned twice even when several digests are sent at the same time.
+// When popping fails, the entries popped until then are returned along with the error.
+func (b Buffer) Drain(ctx context.Context, user string, messageType string) ([]Entry, error) {
+	key := b.key(user, messageType)
+
+	var entries []Entry
+	for {
+		payloads, err := b.db.LPopCount(ctx, key, int(b.options.DrainSize)).Result()
+		if errors.Is(err, redis.Nil) {
+			return entries, nil
+		}
+
+		if err != nil {
+			return entries, fmt.Errorf("error draining digest entries due to: %w", err)
+		}
+
+		for _, payload := range payloads {
+			var entry Entry
+			if err = json.Unmarshal([]byte(payload), &entry); err != nil {
+				slog.WarnContext(ctx, "discarding malformed digest entry", logging.Error(err))
+
+				continue
+			}
+
+			entries = append(entries, entry)
+		}
+
+		if int64(len(payloads)) < b.options.DrainSize {
+			return entries, nil
+		}
+	}
+}
+
+// Restore puts back drained entries in front of the buffer, so they are sent with the next digest in the same order,
+// before the entries added since they were drained.
+func (b Buffer) Restore(ctx context.Context, user string, messageType string, entries []Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	// LPUSH inserts every value at the head, so they are pushed from the newest to the oldest
+	payloads := make([]interface{}, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		payload, err := json.Marshal(entries[i])
+		if err != nil {
+			return fmt.Errorf("error marshalling digest entry due to: %w", err)
+		}
+
+		payloads = append(payloads, payload)
+	}
+
+	key := b.key(user, messageType)
+	if err := b.db.LPush(ctx, key, payloads...).Err(); err != nil {
+		return fmt.Errorf("error restoring digest entries due to: %w", err)
+	}
+
+	// the expiration is not validated in case of fail, it is set again with the next entry
+	_ = b.db.Expire(ctx, key, b.options.TTL)
+
+	return nil
+}
+
+// FlushID identifies the pending digest of the user and message type.
+func FlushID(user string, messageType string) string {
+	return fmt.Sprintf("digest-%s-%s", messageType, user)
+}
+
+func (b Buffer) key(user string, messageType string) string {
+	return fmt.Sprintf("%s-%s-%s", b.options.Prefix, messageType, user)
+}
@@ -0,0 +1,172 @@
+package digest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"user_news_api/digest/mocks"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var testOptions = Options{
+	Prefix:    "digest",
+	TTL:       time.Hour,
+	DrainSize: 2,
+}
+
+func TestBufferAdd(t *testing.T) {
+	entry := Entry{ID: "some-id", CreatedAt: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)}
+	payload := []byte(`{"id":"some-id","created_at":"2024-01-01T10:00:00Z"}`)
+
+	tests := []struct {
+		name          string
+		mockApplier   func(mockRedis *mocks.RedisList)
+		expectedError error
+	}{
+		{
+			name: "entry buffered",
+			mockApplier: func(mockRedis *mocks.RedisList) {
+				mockRedis.On("RPush", mock.Anything, "digest-News-user@example.com", payload).Return(redis.NewIntResult(1, nil)).Once()
+				mockRedis.On("Expire", mock.Anything, "digest-News-user@example.com", time.Hour).Return(redis.NewBoolResult(true, nil)).Once()
+			},
+			expectedError: nil,
+		},
+		{
+			name: "redis error",
+			mockApplier: func(mockRedis *mocks.RedisList) {
+				mockRedis.On("RPush", mock.Anything, "digest-News-user@example.com", payload).
+					Return(redis.NewIntResult(0, errors.New("error"))).Once()
+			},
+			expectedError: fmt.Errorf("error buffering digest entry some-id due to: %w", errors.New("error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisList(t)
+			tt.mockApplier(mockRedis)
+
+			b := Buffer{db: mockRedis, options: testOptions}
+
+			assert.Equal(t, tt.expectedError, b.Add(context.Background(), "user@example.com", "News", entry))
+		})
+	}
+}
+
+func TestBufferDrain(t *testing.T) {
+	first := Entry{ID: "first-id", CreatedAt: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)}
+	second := Entry{ID: "second-id", CreatedAt: time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)}
+	third := Entry{ID: "third-id", CreatedAt: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	firstPayload := `{"id":"first-id","created_at":"2024-01-01T10:00:00Z"}`
+	secondPayload := `{"id":"second-id","created_at":"2024-01-01T11:00:00Z"}`
+	thirdPayload := `{"id":"third-id","created_at":"2024-01-01T12:00:00Z"}`
+
+	tests := []struct {
+		name          string
+		mockApplier   func(mockRedis *mocks.RedisList)
+		expected      []Entry
+		expectedError error
+	}{
+		{
+			name: "entries popped until the list is empty",
+			mockApplier: func(mockRedis *mocks.RedisList) {
+				mockRedis.On("LPopCount", mock.Anything, "digest-News-user@example.com", 2).
+					Return(redis.NewStringSliceResult([]string{firstPayload, "{"}, nil)).Once()
+				mockRedis.On("LPopCount", mock.Anything, "digest-News-user@example.com", 2).
+					Return(redis.NewStringSliceResult([]string{secondPayload, thirdPayload}, nil)).Once()
+				mockRedis.On("LPopCount", mock.Anything, "digest-News-user@example.com", 2).
+					Return(redis.NewStringSliceResult(nil, redis.Nil)).Once()
+			},
+			expected:      []Entry{first, second, third},
+			expectedError: nil,
+		},
+		{
+			name: "empty list",
+			mockApplier: func(mockRedis *mocks.RedisList) {
+				mockRedis.On("LPopCount", mock.Anything, "digest-News-user@example.com", 2).
+					Return(redis.NewStringSliceResult(nil, redis.Nil)).Once()
+			},
+			expected:      nil,
+			expectedError: nil,
+		},
+		{
+			name: "redis error keeps the popped entries",
+			mockApplier: func(mockRedis *mocks.RedisList) {
+				mockRedis.On("LPopCount", mock.Anything, "digest-News-user@example.com", 2).
+					Return(redis.NewStringSliceResult([]string{firstPayload, secondPayload}, nil)).Once()
+				mockRedis.On("LPopCount", mock.Anything, "digest-News-user@example.com", 2).
+					Return(redis.NewStringSliceResult(nil, errors.New("error"))).Once()
+			},
+			expected:      []Entry{first, second},
+			expectedError: fmt.Errorf("error draining digest entries due to: %w", errors.New("error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisList(t)
+			tt.mockApplier(mockRedis)
+
+			b := Buffer{db: mockRedis, options: testOptions}
+
+			entries, err := b.Drain(context.Background(), "user@example.com", "News")
+
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expected, entries)
+		})
+	}
+}
+
+func TestBufferRestore(t *testing.T) {
+	first := Entry{ID: "first", CreatedAt: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)}
+	second := Entry{ID: "second", CreatedAt: time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)}
+	firstPayload := []byte(`{"id":"first","created_at":"2024-01-01T10:00:00Z"}`)
+	secondPayload := []byte(`{"id":"second","created_at":"2024-01-01T11:00:00Z"}`)
+
+	tests := []struct {
+		name          string
+		entries       []Entry
+		mockApplier   func(mockRedis *mocks.RedisList)
+		expectedError error
+	}{
+		{
+			name:    "entries restored oldest first",
+			entries: []Entry{first, second},
+			mockApplier: func(mockRedis *mocks.RedisList) {
+				mockRedis.On("LPush", mock.Anything, "digest-News-user@example.com", secondPayload, firstPayload).
+					Return(redis.NewIntResult(2, nil)).Once()
+				mockRedis.On("Expire", mock.Anything, "digest-News-user@example.com", testOptions.TTL).
+					Return(redis.NewBoolResult(true, nil)).Once()
+			},
+		},
+		{
+			name:        "nothing to restore",
+			mockApplier: func(mockRedis *mocks.RedisList) {},
+		},
+		{
+			name:    "error pushing",
+			entries: []Entry{first},
+			mockApplier: func(mockRedis *mocks.RedisList) {
+				mockRedis.On("LPush", mock.Anything, "digest-News-user@example.com", firstPayload).
+					Return(redis.NewIntResult(0, errors.New("error"))).Once()
+			},
+			expectedError: fmt.Errorf("error restoring digest entries due to: %w", errors.New("error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisList(t)
+			tt.mockApplier(mockRedis)
+
+			b := Buffer{db: mockRedis, options: testOptions}
+
+			assert.Equal(t, tt.expectedError, b.Restore(context.Background(), "user@example.com", "News", tt.entries))
+		})
+	}
+}
@@ -0,0 +1,102 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	redis "github.com/redis/go-redis/v9"
+
+	time "time"
+)
+
+// RedisList is an autogenerated mock type for the RedisList type
+type RedisList struct {
+	mock.Mock
+}
+
+// Expire provides a mock function with given fields: ctx, key, expiration
+func (_m *RedisList) Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd {
+	ret := _m.Called(ctx, key, expiration)
+
+	var r0 *redis.BoolCmd
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Duration) *redis.BoolCmd); ok {
+		r0 = rf(ctx, key, expiration)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.BoolCmd)
+		}
+	}
+
+	return r0
+}
+
+// LPopCount provides a mock function with given fields: ctx, key, count
+func (_m *RedisList) LPopCount(ctx context.Context, key string, count int) *redis.StringSliceCmd {
+	ret := _m.Called(ctx, key, count)
+
+	var r0 *redis.StringSliceCmd
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) *redis.StringSliceCmd); ok {
+		r0 = rf(ctx, key, count)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.StringSliceCmd)
+		}
+	}
+
+	return r0
+}
+
+// LPush provides a mock function with given fields: ctx, key, values
+func (_m *RedisList) LPush(ctx context.Context, key string, values ...interface{}) *redis.IntCmd {
+	var _ca []interface{}
+	_ca = append(_ca, ctx, key)
+	_ca = append(_ca, values...)
+	ret := _m.Called(_ca...)
+
+	var r0 *redis.IntCmd
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...interface{}) *redis.IntCmd); ok {
+		r0 = rf(ctx, key, values...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.IntCmd)
+		}
+	}
+
+	return r0
+}
+
+// RPush provides a mock function with given fields: ctx, key, values
+func (_m *RedisList) RPush(ctx context.Context, key string, values ...interface{}) *redis.IntCmd {
+	var _ca []interface{}
+	_ca = append(_ca, ctx, key)
+	_ca = append(_ca, values...)
+	ret := _m.Called(_ca...)
+
+	var r0 *redis.IntCmd
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...interface{}) *redis.IntCmd); ok {
+		r0 = rf(ctx, key, values...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.IntCmd)
+		}
+	}
+
+	return r0
+}
+
+// NewRedisList creates a new instance of RedisList. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewRedisList(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *RedisList {
+	mock := &RedisList{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
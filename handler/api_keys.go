@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"user_news_api/apikey"
+	"user_news_api/logging"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator"
+)
+
+// APIKeyStore is an abstraction for apikey.Store making it mockeable
+type APIKeyStore interface {
+	Issue(context.Context, apikey.Key) (apikey.Key, string, error)
+	Revoke(context.Context, string) error
+	List(context.Context) ([]apikey.Key, error)
+}
+
+// SetAPIKeyController registers the admin routes issuing and revoking the API keys.
+func SetAPIKeyController(router chi.Router, store APIKeyStore, messageTypes MessageTypes) {
+	controller := &APIKeyController{store: store, messageTypes: messageTypes}
+
+	router.Get("/admin/api-keys", controller.handleList)
+	router.Post("/admin/api-keys", controller.handleIssue)
+	router.Delete("/admin/api-keys/{id}", controller.handleRevoke)
+}
+
+type APIKeyController struct {
+	store        APIKeyStore
+	messageTypes MessageTypes
+}
+
+type IssueAPIKeyRequestPayload struct {
+	Name         string           `json:"name" validate:"required"`
+	MessageTypes []string         `json:"message_types"` // MessageTypes are the ones allowed, every one when it is empty
+	RateLimit    apikey.RateLimit `json:"rate_limit"`    // RateLimit is not set by default
+}
+
+// IssueAPIKeyResponse has the token of the client, it is the only time it is answered.
+type IssueAPIKeyResponse struct {
+	apikey.Key
+	Token string `json:"token"`
+}
+
+type APIKeysResponse struct {
+	Keys []apikey.Key `json:"keys"`
+}
+
+func (kc *APIKeyController) handleList(w http.ResponseWriter, r *http.Request) {
+	keys, err := kc.store.List(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error listing api keys", logging.Error(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(APIKeysResponse{Keys: keys})
+}
+
+func (kc *APIKeyController) handleIssue(w http.ResponseWriter, r *http.Request) {
+	var payload IssueAPIKeyRequestPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, fmt.Sprintf("error marshalling request body due to: %s", err.Error()), http.StatusBadRequest)
+
+		return
+	}
+
+	if err := validator.New().Struct(payload); err != nil {
+		http.Error(w, fmt.Sprintf("request validation fails due to: %s", err.Error()), http.StatusBadRequest)
+
+		return
+	}
+
+	for _, messageType := range payload.MessageTypes {
+		if !kc.messageTypes.Valid(messageType) {
+			http.Error(w, fmt.Sprintf("message type %s not valid", messageType), http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	if payload.RateLimit.Max < 0 || (payload.RateLimit.Max > 0 && payload.RateLimit.Period <= 0) {
+		http.Error(w, "rate limit must have a positive max and period", http.StatusBadRequest)
+
+		return
+	}
+
+	key, token, err := kc.store.Issue(r.Context(), apikey.Key{
+		Name:         payload.Name,
+		MessageTypes: payload.MessageTypes,
+		RateLimit:    payload.RateLimit,
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error issuing api key", logging.Error(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(IssueAPIKeyResponse{Key: key, Token: token})
+}
+
+func (kc *APIKeyController) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	if err := kc.store.Revoke(r.Context(), chi.URLParam(r, "id")); err != nil {
+		if errors.Is(err, apikey.ErrNotFound) {
+			http.Error(w, apikey.ErrNotFound.Error(), http.StatusNotFound)
+
+			return
+		}
+
+		slog.ErrorContext(r.Context(), "error revoking api key", logging.Error(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"user_news_api/apikey"
+	"user_news_api/handler/mocks"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeys(t *testing.T) {
+	key := apikey.Key{
+		ID:           "abc",
+		Name:         "newsletter",
+		MessageTypes: []string{"News"},
+		RateLimit:    apikey.RateLimit{Max: 100, Period: time.Hour},
+		CreatedAt:    time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+	}
+	keyJSON := `{"id":"abc","name":"newsletter","message_types":["News"],"rate_limit":{"max":100,"period":"1h0m0s"},` +
+		`"created_at":"2024-01-01T09:00:00Z"`
+
+	tests := []struct {
+		name           string
+		method         string
+		target         string
+		body           string
+		setupMocks     func(store *mocks.APIKeyStore, messageTypes *mocks.MessageTypes)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:   "List api keys",
+			method: http.MethodGet,
+			target: "/admin/api-keys",
+			setupMocks: func(store *mocks.APIKeyStore, messageTypes *mocks.MessageTypes) {
+				store.On("List", mock.Anything).Return([]apikey.Key{key}, nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"keys":[` + keyJSON + `}]}`,
+		},
+		{
+			name:   "List error",
+			method: http.MethodGet,
+			target: "/admin/api-keys",
+			setupMocks: func(store *mocks.APIKeyStore, messageTypes *mocks.MessageTypes) {
+				store.On("List", mock.Anything).Return(nil, errors.New("error")).Once()
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   "internal error",
+		},
+		{
+			name:   "Issue api key",
+			method: http.MethodPost,
+			target: "/admin/api-keys",
+			body:   `{"name":"newsletter","message_types":["News"],"rate_limit":{"max":100,"period":"1h"}}`,
+			setupMocks: func(store *mocks.APIKeyStore, messageTypes *mocks.MessageTypes) {
+				messageTypes.On("Valid", "News").Return(true).Once()
+				store.On("Issue", mock.Anything, apikey.Key{
+					Name:         "newsletter",
+					MessageTypes: []string{"News"},
+					RateLimit:    apikey.RateLimit{Max: 100, Period: time.Hour},
+				}).Return(key, "abc.secret", nil).Once()
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   keyJSON + `,"token":"abc.secret"}`,
+		},
+		{
+			name:   "Issue api key without limits",
+			method: http.MethodPost,
+			target: "/admin/api-keys",
+			body:   `{"name":"backoffice"}`,
+			setupMocks: func(store *mocks.APIKeyStore, messageTypes *mocks.MessageTypes) {
+				store.On("Issue", mock.Anything, apikey.Key{Name: "backoffice"}).
+					Return(apikey.Key{ID: "def", Name: "backoffice"}, "def.secret", nil).Once()
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `"token":"def.secret"`,
+		},
+		{
+			name:           "Issue without name",
+			method:         http.MethodPost,
+			target:         "/admin/api-keys",
+			body:           `{"message_types":["News"]}`,
+			setupMocks:     func(store *mocks.APIKeyStore, messageTypes *mocks.MessageTypes) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "request validation fails due to",
+		},
+		{
+			name:   "Issue with unknown message type",
+			method: http.MethodPost,
+			target: "/admin/api-keys",
+			body:   `{"name":"newsletter","message_types":["Other"]}`,
+			setupMocks: func(store *mocks.APIKeyStore, messageTypes *mocks.MessageTypes) {
+				messageTypes.On("Valid", "Other").Return(false).Once()
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "message type Other not valid",
+		},
+		{
+			name:           "Issue with rate limit without period",
+			method:         http.MethodPost,
+			target:         "/admin/api-keys",
+			body:           `{"name":"newsletter","rate_limit":{"max":100}}`,
+			setupMocks:     func(store *mocks.APIKeyStore, messageTypes *mocks.MessageTypes) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "rate limit must have a positive max and period",
+		},
+		{
+			name:   "Issue error",
+			method: http.MethodPost,
+			target: "/admin/api-keys",
+			body:   `{"name":"newsletter"}`,
+			setupMocks: func(store *mocks.APIKeyStore, messageTypes *mocks.MessageTypes) {
+				store.On("Issue", mock.Anything, mock.Anything).Return(apikey.Key{}, "", errors.New("error")).Once()
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   "internal error",
+		},
+		{
+			name:   "Revoke api key",
+			method: http.MethodDelete,
+			target: "/admin/api-keys/abc",
+			setupMocks: func(store *mocks.APIKeyStore, messageTypes *mocks.MessageTypes) {
+				store.On("Revoke", mock.Anything, "abc").Return(nil).Once()
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:   "Revoke unknown api key",
+			method: http.MethodDelete,
+			target: "/admin/api-keys/abc",
+			setupMocks: func(store *mocks.APIKeyStore, messageTypes *mocks.MessageTypes) {
+				store.On("Revoke", mock.Anything, "abc").Return(fmt.Errorf("%w: abc", apikey.ErrNotFound)).Once()
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "api key not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := mocks.NewAPIKeyStore(t)
+			mockMessageTypes := mocks.NewMessageTypes(t)
+
+			tt.setupMocks(mockStore, mockMessageTypes)
+
+			router := chi.NewRouter()
+			SetAPIKeyController(router, mockStore, mockMessageTypes)
+
+			req := httptest.NewRequest(tt.method, tt.target, bytes.NewBufferString(tt.body))
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			assert.Contains(t, string(body), tt.expectedBody)
+		})
+	}
+}
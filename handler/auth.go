@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	"user_news_api/apikey"
+	"user_news_api/delivery"
+	"user_news_api/jwtauth"
+	"user_news_api/logging"
+)
+
+const apiKeyHeader = "X-API-Key"
+
+var (
+	errMessageTypeNotAllowed = errors.New("message type not allowed for the client")
+	errClientLimitExceeded   = errors.New("client rate limit exceeded")
+)
+
+// APIKeyAuthenticator is an abstraction for apikey.Store making it mockeable
+type APIKeyAuthenticator interface {
+	Authenticate(context.Context, string) (apikey.Key, error)
+}
+
+// TokenValidator is an abstraction for jwtauth.Validator making it mockeable
+type TokenValidator interface {
+	Validate(context.Context, string) (jwtauth.Claims, error)
+}
+
+// ClientLimiter is an abstraction for ratelimiter.ClientLimiter making it mockeable
+type ClientLimiter interface {
+	Reached(ctx context.Context, client string, notifications int64, max int64, period time.Duration) (bool, error)
+}
+
+// quota counts the notifications of a request against the rate limit of its client, see chargeClient.
+type quota func(ctx context.Context, notifications int64) (bool, error)
+
+type quotaContextKey struct{}
+
+func withQuota(ctx context.Context, q quota) context.Context {
+	return context.WithValue(ctx, quotaContextKey{}, q)
+}
+
+// chargeClient counts the notifications against the rate limit of the client, and answers the request when it fails
+// or the limit is reached. It returns whether the request can go on, which it always can without a rate limit.
+func chargeClient(w http.ResponseWriter, r *http.Request, notifications int64) bool {
+	charge, ok := r.Context().Value(quotaContextKey{}).(quota)
+	if !ok {
+		return true
+	}
+
+	reached, err := charge(r.Context(), notifications)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error checking client rate limit", logging.Error(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+
+		return false
+	}
+
+	if reached {
+		http.Error(w, errClientLimitExceeded.Error(), http.StatusTooManyRequests)
+
+		return false
+	}
+
+	return true
+}
+
+// APIKeyAuth requires the X-API-Key header. The key is kept in the request context, so the handlers can check
+// the message types it allows and count the notifications against its rate limit, and the notifications are owned
+// by the client, see delivery.Client.
+func APIKeyAuth(keys APIKeyAuthenticator, limiter ClientLimiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := r.Header.Get(apiKeyHeader)
+			if token == "" {
+				http.Error(w, "api key required", http.StatusUnauthorized)
+
+				return
+			}
+
+			key, err := keys.Authenticate(r.Context(), token)
+			if err != nil {
+				if errors.Is(err, apikey.ErrInvalid) {
+					http.Error(w, apikey.ErrInvalid.Error(), http.StatusUnauthorized)
+
+					return
+				}
+
+				slog.ErrorContext(r.Context(), "error authenticating api key", logging.Error(err))
+				http.Error(w, "internal error", http.StatusInternalServerError)
+
+				return
+			}
+
+			ctx := apikey.WithKey(r.Context(), key)
+			client := clientID(ctx)
+
+			next.ServeHTTP(w, r.WithContext(limitClient(ctx, limiter, client, key.RateLimit)))
+		})
+	}
+}
+
+// BearerAuth requires a token of the identity provider as a bearer token of the Authorization header.
+// The claims are kept in the request context, so the handlers can check the message types granted by its scopes.
+// Every subject has the same rate limit, counted apart for each one of them.
+func BearerAuth(tokens TokenValidator, limiter ClientLimiter, limit apikey.RateLimit) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "bearer token required", http.StatusUnauthorized)
+
+				return
+			}
+
+			claims, err := tokens.Validate(r.Context(), token)
+			if err != nil {
+				if errors.Is(err, jwtauth.ErrTokenNotValid) {
+					slog.InfoContext(r.Context(), "bearer token rejected", logging.Error(err))
+					w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+					http.Error(w, jwtauth.ErrTokenNotValid.Error(), http.StatusUnauthorized)
+
+					return
+				}
+
+				slog.ErrorContext(r.Context(), "error validating bearer token", logging.Error(err))
+				http.Error(w, "internal error", http.StatusInternalServerError)
+
+				return
+			}
+
+			ctx := jwtauth.WithClaims(r.Context(), claims)
+
+			next.ServeHTTP(w, r.WithContext(limitClient(ctx, limiter, clientID(ctx), limit)))
+		})
+	}
+}
+
+// Authenticate accepts both bearer tokens and API keys: the requests with the Authorization header are
+// authenticated by bearer and the other ones by apiKey. Either of them can be nil when it is not enabled.
+func Authenticate(bearer, apiKey func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		var bearerNext, apiKeyNext http.Handler
+		if bearer != nil {
+			bearerNext = bearer(next)
+		}
+
+		if apiKey != nil {
+			apiKeyNext = apiKey(next)
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if apiKeyNext == nil || (bearerNext != nil && r.Header.Get("Authorization") != "") {
+				bearerNext.ServeHTTP(w, r)
+
+				return
+			}
+
+			apiKeyNext.ServeHTTP(w, r)
+		})
+	}
+}
+
+// limitClient makes the client own the notifications of the request, see delivery.Client,
+// and count them against its rate limit when it has one.
+func limitClient(ctx context.Context, limiter ClientLimiter, client string, limit apikey.RateLimit) context.Context {
+	if limit.Max > 0 {
+		ctx = withQuota(ctx, func(ctx context.Context, notifications int64) (bool, error) {
+			return limiter.Reached(ctx, client, notifications, limit.Max, limit.Period)
+		})
+	}
+
+	return delivery.WithClient(ctx, client)
+}
+
+// clientID identifies the authenticated client of the request by its API key or the subject of its token,
+// it is empty when the requests are not authenticated.
+func clientID(ctx context.Context) string {
+	if key, ok := apikey.FromContext(ctx); ok {
+		return "api-key:" + key.ID
+	}
+
+	if claims, ok := jwtauth.FromContext(ctx); ok {
+		return "subject:" + claims.Subject
+	}
+
+	return ""
+}
+
+// messageTypeAllowed tells if the client of the request can send the message type, by its API key or the scopes
+// of its token. Every client can when the requests are not authenticated.
+func messageTypeAllowed(ctx context.Context, messageType string) bool {
+	if key, ok := apikey.FromContext(ctx); ok && !key.Allows(messageType) {
+		return false
+	}
+
+	if claims, ok := jwtauth.FromContext(ctx); ok && !claims.Allows(messageType) {
+		return false
+	}
+
+	return true
+}
+
+// AdminAuth requires the admin token as a bearer token of the Authorization header.
+func AdminAuth(token string) func(http.Handler) http.Handler {
+	// the hashes have the same length, so the comparison does not leak the length of the token
+	expected := sha256.Sum256([]byte(token))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			given := sha256.Sum256([]byte(bearer))
+
+			if !ok || subtle.ConstantTimeCompare(given[:], expected[:]) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+				http.Error(w, "admin token required", http.StatusUnauthorized)
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
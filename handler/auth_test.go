@@ -0,0 +1,297 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"user_news_api/apikey"
+	"user_news_api/delivery"
+	"user_news_api/handler/mocks"
+	"user_news_api/jwtauth"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAPIKeyAuth(t *testing.T) {
+	limited := apikey.Key{ID: "abc", Name: "newsletter", RateLimit: apikey.RateLimit{Max: 10, Period: time.Minute}}
+	unlimited := apikey.Key{ID: "def", Name: "backoffice"}
+
+	tests := []struct {
+		name           string
+		token          string
+		setupMocks     func(keys *mocks.APIKeyAuthenticator, limiter *mocks.ClientLimiter)
+		expectedStatus int
+		expectedKey    *apikey.Key
+	}{
+		{
+			name:           "Without api key",
+			setupMocks:     func(keys *mocks.APIKeyAuthenticator, limiter *mocks.ClientLimiter) {},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:  "Invalid api key",
+			token: "abc.wrong",
+			setupMocks: func(keys *mocks.APIKeyAuthenticator, limiter *mocks.ClientLimiter) {
+				keys.On("Authenticate", mock.Anything, "abc.wrong").Return(apikey.Key{}, apikey.ErrInvalid).Once()
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:  "Error authenticating",
+			token: "abc.secret",
+			setupMocks: func(keys *mocks.APIKeyAuthenticator, limiter *mocks.ClientLimiter) {
+				keys.On("Authenticate", mock.Anything, "abc.secret").Return(apikey.Key{}, errors.New("error")).Once()
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:  "Within the rate limit",
+			token: "abc.secret",
+			setupMocks: func(keys *mocks.APIKeyAuthenticator, limiter *mocks.ClientLimiter) {
+				keys.On("Authenticate", mock.Anything, "abc.secret").Return(limited, nil).Once()
+				limiter.On("Reached", mock.Anything, "api-key:abc", int64(3), int64(10), time.Minute).Return(false, nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedKey:    &limited,
+		},
+		{
+			name:  "Rate limit reached",
+			token: "abc.secret",
+			setupMocks: func(keys *mocks.APIKeyAuthenticator, limiter *mocks.ClientLimiter) {
+				keys.On("Authenticate", mock.Anything, "abc.secret").Return(limited, nil).Once()
+				limiter.On("Reached", mock.Anything, "api-key:abc", int64(3), int64(10), time.Minute).Return(true, nil).Once()
+			},
+			expectedStatus: http.StatusTooManyRequests,
+			expectedKey:    &limited,
+		},
+		{
+			name:  "Error checking the rate limit",
+			token: "abc.secret",
+			setupMocks: func(keys *mocks.APIKeyAuthenticator, limiter *mocks.ClientLimiter) {
+				keys.On("Authenticate", mock.Anything, "abc.secret").Return(limited, nil).Once()
+				limiter.On("Reached", mock.Anything, "api-key:abc", int64(3), int64(10), time.Minute).Return(false, errors.New("error")).Once()
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedKey:    &limited,
+		},
+		{
+			name:  "Without rate limit",
+			token: "def.secret",
+			setupMocks: func(keys *mocks.APIKeyAuthenticator, limiter *mocks.ClientLimiter) {
+				keys.On("Authenticate", mock.Anything, "def.secret").Return(unlimited, nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedKey:    &unlimited,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockKeys := mocks.NewAPIKeyAuthenticator(t)
+			mockLimiter := mocks.NewClientLimiter(t)
+
+			tt.setupMocks(mockKeys, mockLimiter)
+
+			var key *apikey.Key
+			var client string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if k, ok := apikey.FromContext(r.Context()); ok {
+					key = &k
+				}
+
+				client = delivery.Client(r.Context())
+
+				// the handlers charge the rate limit with the notifications of the request
+				chargeClient(w, r, 3)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/notifications", nil)
+			if tt.token != "" {
+				req.Header.Set(apiKeyHeader, tt.token)
+			}
+
+			rec := httptest.NewRecorder()
+
+			APIKeyAuth(mockKeys, mockLimiter)(next).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Equal(t, tt.expectedKey, key)
+
+			if tt.expectedKey != nil {
+				assert.Equal(t, "api-key:"+tt.expectedKey.ID, client)
+			}
+		})
+	}
+}
+
+func TestAdminAuth(t *testing.T) {
+	tests := []struct {
+		name           string
+		authorization  string
+		expectedStatus int
+	}{
+		{name: "Valid token", authorization: "Bearer secret", expectedStatus: http.StatusOK},
+		{name: "Wrong token", authorization: "Bearer other", expectedStatus: http.StatusUnauthorized},
+		{name: "Without bearer", authorization: "secret", expectedStatus: http.StatusUnauthorized},
+		{name: "Without authorization", expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/api-keys", nil)
+			req.Header.Set("Authorization", tt.authorization)
+
+			rec := httptest.NewRecorder()
+
+			AdminAuth("secret")(next).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
+
+func TestBearerAuth(t *testing.T) {
+	claims := jwtauth.Claims{Subject: "newsletter-service", Scopes: []string{"notifications:send:news"}}
+
+	tests := []struct {
+		name           string
+		authorization  string
+		setupMocks     func(tokens *mocks.TokenValidator, limiter *mocks.ClientLimiter)
+		expectedStatus int
+		expectedClaims *jwtauth.Claims
+	}{
+		{
+			name:           "Without token",
+			setupMocks:     func(tokens *mocks.TokenValidator, limiter *mocks.ClientLimiter) {},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Not a bearer token",
+			authorization:  "Basic dXNlcjpwYXNz",
+			setupMocks:     func(tokens *mocks.TokenValidator, limiter *mocks.ClientLimiter) {},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:          "Valid token",
+			authorization: "Bearer token",
+			setupMocks: func(tokens *mocks.TokenValidator, limiter *mocks.ClientLimiter) {
+				tokens.On("Validate", mock.Anything, "token").Return(claims, nil).Once()
+				limiter.On("Reached", mock.Anything, "subject:newsletter-service", int64(1), int64(100), time.Hour).
+					Return(false, nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedClaims: &claims,
+		},
+		{
+			name:          "Rate limit of the subject reached",
+			authorization: "Bearer token",
+			setupMocks: func(tokens *mocks.TokenValidator, limiter *mocks.ClientLimiter) {
+				tokens.On("Validate", mock.Anything, "token").Return(claims, nil).Once()
+				limiter.On("Reached", mock.Anything, "subject:newsletter-service", int64(1), int64(100), time.Hour).
+					Return(true, nil).Once()
+			},
+			expectedStatus: http.StatusTooManyRequests,
+			expectedClaims: &claims,
+		},
+		{
+			name:          "Invalid token",
+			authorization: "Bearer token",
+			setupMocks: func(tokens *mocks.TokenValidator, limiter *mocks.ClientLimiter) {
+				tokens.On("Validate", mock.Anything, "token").
+					Return(jwtauth.Claims{}, fmt.Errorf("%w: token is expired", jwtauth.ErrTokenNotValid)).Once()
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:          "Error validating",
+			authorization: "Bearer token",
+			setupMocks: func(tokens *mocks.TokenValidator, limiter *mocks.ClientLimiter) {
+				tokens.On("Validate", mock.Anything, "token").Return(jwtauth.Claims{}, errors.New("error fetching key set")).Once()
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockTokens := mocks.NewTokenValidator(t)
+			mockLimiter := mocks.NewClientLimiter(t)
+
+			tt.setupMocks(mockTokens, mockLimiter)
+
+			var got *jwtauth.Claims
+			var client string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if c, ok := jwtauth.FromContext(r.Context()); ok {
+					got = &c
+				}
+
+				client = delivery.Client(r.Context())
+
+				chargeClient(w, r, 1)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/notifications", nil)
+			req.Header.Set("Authorization", tt.authorization)
+
+			rec := httptest.NewRecorder()
+
+			BearerAuth(mockTokens, mockLimiter, apikey.RateLimit{Max: 100, Period: time.Hour})(next).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Equal(t, tt.expectedClaims, got)
+
+			if tt.expectedClaims != nil {
+				assert.Equal(t, "subject:"+tt.expectedClaims.Subject, client)
+			}
+
+			if tt.expectedStatus == http.StatusUnauthorized {
+				assert.Contains(t, rec.Header().Get("WWW-Authenticate"), "Bearer")
+			}
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	middleware := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Auth", name)
+			})
+		}
+	}
+
+	tests := []struct {
+		name          string
+		bearer        func(http.Handler) http.Handler
+		apiKey        func(http.Handler) http.Handler
+		authorization string
+		expected      string
+	}{
+		{name: "Bearer token", bearer: middleware("bearer"), apiKey: middleware("api-key"), authorization: "Bearer token", expected: "bearer"},
+		{name: "API key", bearer: middleware("bearer"), apiKey: middleware("api-key"), expected: "api-key"},
+		{name: "Only bearer tokens enabled", bearer: middleware("bearer"), expected: "bearer"},
+		{name: "Only API keys enabled", apiKey: middleware("api-key"), authorization: "Bearer token", expected: "api-key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/notifications", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			rec := httptest.NewRecorder()
+
+			Authenticate(tt.bearer, tt.apiKey)(http.NotFoundHandler()).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expected, rec.Header().Get("X-Auth"))
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+	"user_news_api/services"
+
+	"github.com/go-playground/validator"
+)
+
+const (
+	// maxBatchSize keeps a whole batch within the time of a single request.
+	maxBatchSize = 1000
+	// maxBatchBodySize bounds the memory taken by a batch, it is well above maxBatchSize items of a regular size.
+	maxBatchBodySize = 1 << 20
+
+	ndjsonContentType = "application/x-ndjson"
+)
+
+var (
+	errBatchTooLarge  = fmt.Errorf("batch must not exceed %d items", maxBatchSize)
+	errBatchBodySize  = fmt.Errorf("batch must not exceed %d bytes", maxBatchBodySize)
+	errBatchNotArray  = errors.New("batch must be a JSON array")
+	errBatchScheduled = errors.New("send_at is not supported in batches")
+	errBatchChannels  = errors.New("channels are not supported in batches")
+
+	errChannelsScheduled = errors.New("send_at is not supported with channels")
+)
+
+// NotifyBatchItemResult is the outcome of a single item, Index is its position in the request.
+// Channel is only set for the notifications sent through several channels, Index is then the position of the channel.
+type NotifyBatchItemResult struct {
+	Index   int        `json:"index"`
+	Channel string     `json:"channel,omitempty"`
+	ID      string     `json:"id,omitempty"`
+	Status  int        `json:"status"`
+	Reason  string     `json:"reason,omitempty"`
+	SendAt  *time.Time `json:"send_at,omitempty"`
+}
+
+type NotifyBatchResponse struct {
+	Results []NotifyBatchItemResult `json:"results"`
+}
+
+// batchItem is a decoded item of the request, err is set when it cannot be notified.
+type batchItem struct {
+	payload NotifyUserRequestPayload
+	err     error
+}
+
+// handleNotifyBatch accepts a JSON array of notifications, or one notification per line with the NDJSON content type.
+// Invalid items are reported in their result without affecting the others.
+func (uc *UserController) handleNotifyBatch(w http.ResponseWriter, r *http.Request) {
+	items, err := decodeBatch(w, r)
+	if err != nil {
+		if errors.Is(err, errBatchTooLarge) || errors.Is(err, errBatchBodySize) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+
+			return
+		}
+
+		http.Error(w, fmt.Sprintf("error marshalling request body due to: %s", err.Error()), http.StatusBadRequest)
+
+		return
+	}
+
+	validate := validator.New()
+
+	results := make([]NotifyBatchItemResult, len(items))
+
+	var valid []services.BatchItem
+	var positions []int
+
+	for i, item := range items {
+		results[i].Index = i
+
+		if item.err == nil {
+			if err = validate.Struct(item.payload); err != nil {
+				item.err = fmt.Errorf("request validation fails due to: %s", err.Error())
+			} else if item.payload.SendAt != nil {
+				item.err = errBatchScheduled
+			} else if len(item.payload.Channels) > 0 {
+				item.err = errBatchChannels
+			}
+		}
+
+		if item.err != nil {
+			results[i].Status = http.StatusBadRequest
+			results[i].Reason = item.err.Error()
+
+			continue
+		}
+
+		if !messageTypeAllowed(r.Context(), item.payload.MessageType) {
+			results[i].Status = http.StatusForbidden
+			results[i].Reason = errMessageTypeNotAllowed.Error()
+
+			continue
+		}
+
+		valid = append(valid, services.BatchItem{
+			UserEmail:   item.payload.UserEmail,
+			MessageType: item.payload.MessageType,
+		})
+		positions = append(positions, i)
+	}
+
+	// every notification of the batch counts against the rate limit of the client
+	if len(valid) > 0 && !chargeClient(w, r, int64(len(valid))) {
+		return
+	}
+
+	if len(valid) > 0 {
+		var batchResults []services.BatchResult
+		if uc.async {
+			batchResults = uc.service.EnqueueBatch(r.Context(), valid)
+		} else {
+			batchResults = uc.service.NotifyBatch(r.Context(), valid)
+		}
+
+		for j, result := range batchResults {
+			results[positions[j]] = batchItemResult(r.Context(), positions[j], result, uc.async)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(NotifyBatchResponse{Results: results})
+}
+
+func batchItemResult(ctx context.Context, index int, result services.BatchResult, async bool) NotifyBatchItemResult {
+	itemResult := NotifyBatchItemResult{
+		Index:  index,
+		ID:     result.ID,
+		Status: http.StatusOK,
+	}
+
+	if async {
+		itemResult.Status = http.StatusAccepted
+	}
+
+	var deferred services.DeferredError
+
+	switch {
+	case errors.As(result.Err, &deferred):
+		itemResult.Status, itemResult.Reason, itemResult.SendAt = http.StatusAccepted, deferred.Error(), &deferred.SendAt
+	case errors.Is(result.Err, services.ErrDigested):
+		itemResult.Status, itemResult.Reason = http.StatusAccepted, services.ErrDigested.Error()
+	case errors.Is(result.Err, services.ErrDropped):
+		// dropped notifications are answered as if they were sent
+	case result.Err != nil:
+		itemResult.Status, itemResult.Reason = notifyErrorResponse(ctx, result.Err)
+	}
+
+	return itemResult
+}
+
+// decodeBatch reads the items of the request, no more than maxBatchSize of them. The body is bounded
+// by maxBatchBodySize, and the JSON array is decoded item by item, so a batch too large is not kept in memory.
+func decodeBatch(w http.ResponseWriter, r *http.Request) ([]batchItem, error) {
+	body := http.MaxBytesReader(w, r.Body, maxBatchBodySize)
+
+	var items []batchItem
+	var err error
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == ndjsonContentType {
+		items, err = decodeNDJSON(body)
+	} else {
+		items, err = decodeJSONArray(body)
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return nil, errBatchBodySize
+	}
+
+	return items, err
+}
+
+// decodeJSONArray stops at the first item over maxBatchSize. A malformed item fails the whole batch.
+func decodeJSONArray(body io.Reader) ([]batchItem, error) {
+	decoder := json.NewDecoder(body)
+
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, errBatchNotArray
+	}
+
+	var items []batchItem
+
+	for decoder.More() {
+		if len(items) == maxBatchSize {
+			return nil, errBatchTooLarge
+		}
+
+		var item batchItem
+		if err = decoder.Decode(&item.payload); err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	// the closing bracket, so a truncated array is not taken as a whole batch
+	if _, err = decoder.Token(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// decodeNDJSON decodes every line on its own, so a malformed line only fails its own item. Blank lines are skipped.
+func decodeNDJSON(body io.Reader) ([]batchItem, error) {
+	var items []batchItem
+
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		if len(items) == maxBatchSize {
+			return nil, errBatchTooLarge
+		}
+
+		var item batchItem
+		if err := json.Unmarshal(line, &item.payload); err != nil {
+			item.err = fmt.Errorf("error marshalling item due to: %s", err.Error())
+		}
+
+		items = append(items, item)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"user_news_api/apikey"
+	"user_news_api/handler/mocks"
+	"user_news_api/ratelimiter"
+	"user_news_api/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleNotifyBatch(t *testing.T) {
+	tests := []struct {
+		name           string
+		async          bool
+		contentType    string
+		body           string
+		key            *apikey.Key
+		quota          quota
+		setupMocks     func(service *mocks.UserNotifier)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:        "JSON array",
+			contentType: "application/json",
+			body: `[{"user_email":"a@example.com","message_type":"News"},` +
+				`{"user_email":"invalid","message_type":"News"},` +
+				`{"user_email":"b@example.com","message_type":"Status"},` +
+				`{"user_email":"c@example.com","message_type":"Other"}]`,
+			setupMocks: func(service *mocks.UserNotifier) {
+				service.On("NotifyBatch", mock.Anything, []services.BatchItem{
+					{UserEmail: "a@example.com", MessageType: "News"},
+					{UserEmail: "b@example.com", MessageType: "Status"},
+					{UserEmail: "c@example.com", MessageType: "Other"},
+				}).Return([]services.BatchResult{
+					{ID: "id-a"},
+					{ID: "id-b", Err: fmt.Errorf("%w: rate limit reached", services.ErrLimitExceeded)},
+					{Err: fmt.Errorf("limiter error: %w", ratelimiter.ErrMessageTypeNotValid)},
+				}).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"results":[` +
+				`{"index":0,"id":"id-a","status":200},` +
+				`{"index":1,"status":400,"reason":"request validation fails due to: Key: 'NotifyUserRequestPayload.UserEmail' Error:Field validation for 'UserEmail' failed on the 'email' tag"},` +
+				`{"index":2,"id":"id-b","status":429,"reason":"too many requests"},` +
+				`{"index":3,"status":400,"reason":"message type not valid"}]}`,
+		},
+		{
+			name:        "NDJSON stream in async mode",
+			async:       true,
+			contentType: "application/x-ndjson",
+			body: "{\"user_email\":\"a@example.com\",\"message_type\":\"News\"}\n" +
+				"\n" +
+				"{not json}\n" +
+				"{\"user_email\":\"b@example.com\",\"message_type\":\"Status\"}\n" +
+				"{\"user_email\":\"c@example.com\",\"message_type\":\"Status\"}\n" +
+				"{\"user_email\":\"d@example.com\",\"message_type\":\"Status\"}\n",
+			setupMocks: func(service *mocks.UserNotifier) {
+				service.On("EnqueueBatch", mock.Anything, []services.BatchItem{
+					{UserEmail: "a@example.com", MessageType: "News"},
+					{UserEmail: "b@example.com", MessageType: "Status"},
+					{UserEmail: "c@example.com", MessageType: "Status"},
+					{UserEmail: "d@example.com", MessageType: "Status"},
+				}).Return([]services.BatchResult{
+					{ID: "id-a"},
+					{ID: "id-b", Err: fmt.Errorf("%w for user", services.ErrDigested)},
+					{ID: "id-c", Err: services.DeferredError{SendAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}},
+					{ID: "id-d", Err: fmt.Errorf("%w for user", services.ErrDropped)},
+				}).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"results":[` +
+				`{"index":0,"id":"id-a","status":202},` +
+				`{"index":1,"status":400,"reason":"error marshalling item due to: invalid character 'n' looking for beginning of object key string"},` +
+				`{"index":2,"id":"id-b","status":202,"reason":"buffered for the next digest"},` +
+				`{"index":3,"id":"id-c","status":202,"reason":"deferred until 2024-01-02T00:00:00Z","send_at":"2024-01-02T00:00:00Z"},` +
+				`{"index":4,"id":"id-d","status":202}]}`,
+		},
+		{
+			name:           "Only invalid items",
+			contentType:    "application/json",
+			body:           `[{"user_email":"a@example.com"}]`,
+			setupMocks:     func(service *mocks.UserNotifier) {},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"status":400`,
+		},
+		{
+			name:           "Channels are not supported",
+			contentType:    "application/json",
+			body:           `[{"user_email":"a@example.com","message_type":"News","channels":["slack"]}]`,
+			setupMocks:     func(service *mocks.UserNotifier) {},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"results":[{"index":0,"status":400,"reason":"channels are not supported in batches"}]}`,
+		},
+		{
+			name:        "Message types not allowed for the api key",
+			contentType: "application/json",
+			body: `[{"user_email":"a@example.com","message_type":"News"},` +
+				`{"user_email":"b@example.com","message_type":"Marketing"}]`,
+			key: &apikey.Key{ID: "abc", MessageTypes: []string{"News"}},
+			setupMocks: func(service *mocks.UserNotifier) {
+				service.On("NotifyBatch", mock.Anything, []services.BatchItem{
+					{UserEmail: "a@example.com", MessageType: "News"},
+				}).Return([]services.BatchResult{{ID: "id-a"}}).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"results":[{"index":0,"id":"id-a","status":200},` +
+				`{"index":1,"status":403,"reason":"message type not allowed for the client"}]}`,
+		},
+		{
+			name:        "Rate limit of the client reached by the valid items",
+			contentType: "application/json",
+			body: `[{"user_email":"a@example.com","message_type":"News"},` +
+				`{"user_email":"invalid","message_type":"News"},` +
+				`{"user_email":"b@example.com","message_type":"News"}]`,
+			quota: func(_ context.Context, notifications int64) (bool, error) {
+				return notifications > 1, nil
+			},
+			setupMocks:     func(service *mocks.UserNotifier) {},
+			expectedStatus: http.StatusTooManyRequests,
+			expectedBody:   "client rate limit exceeded",
+		},
+		{
+			name:           "Malformed JSON array",
+			contentType:    "application/json",
+			body:           `{"user_email":"a@example.com"}`,
+			setupMocks:     func(service *mocks.UserNotifier) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "error marshalling request body due to",
+		},
+		{
+			name:           "Too many items",
+			contentType:    "application/x-ndjson",
+			body:           strings.Repeat("{}\n", maxBatchSize+1),
+			setupMocks:     func(service *mocks.UserNotifier) {},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedBody:   "batch must not exceed 1000 items",
+		},
+		{
+			name:           "Too many items in the JSON array",
+			contentType:    "application/json",
+			body:           "[" + strings.Repeat("{},", maxBatchSize) + "{}]",
+			setupMocks:     func(service *mocks.UserNotifier) {},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedBody:   "batch must not exceed 1000 items",
+		},
+		{
+			name:           "Body too large",
+			contentType:    "application/json",
+			body:           `[{"user_email":"` + strings.Repeat("a", maxBatchBodySize) + `"}]`,
+			setupMocks:     func(service *mocks.UserNotifier) {},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedBody:   "batch must not exceed 1048576 bytes",
+		},
+		{
+			name:           "Truncated JSON array",
+			contentType:    "application/json",
+			body:           `[{"user_email":"a@example.com","message_type":"News"}`,
+			setupMocks:     func(service *mocks.UserNotifier) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "error marshalling request body due to",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := mocks.NewUserNotifier(t)
+
+			tt.setupMocks(mockService)
+
+			controller := &UserController{service: mockService, async: tt.async}
+
+			req := httptest.NewRequest(http.MethodPost, "/notifications/batch", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.key != nil {
+				req = req.WithContext(apikey.WithKey(req.Context(), *tt.key))
+			}
+
+			if tt.quota != nil {
+				req = req.WithContext(withQuota(req.Context(), tt.quota))
+			}
+
+			rec := httptest.NewRecorder()
+
+			controller.handleNotifyBatch(rec, req)
+
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			assert.Contains(t, string(body), tt.expectedBody)
+		})
+	}
+}
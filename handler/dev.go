@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"user_news_api/logging"
+	"user_news_api/notifier"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Mailbox is an abstraction for the notifier sinks capturing the mails making it mockeable
+type Mailbox interface {
+	List() ([]notifier.CapturedMessage, error)
+	Get(string) (notifier.CapturedMessage, error)
+}
+
+// SetDevMailController registers the routes reading the mails captured by a sink, they are only meant for development.
+func SetDevMailController(router chi.Router, mailbox Mailbox) {
+	controller := &DevMailController{mailbox: mailbox}
+
+	router.Get("/dev/messages", controller.handleList)
+	router.Get("/dev/messages/{id}", controller.handleGet)
+}
+
+type DevMailController struct {
+	mailbox Mailbox
+}
+
+type CapturedMessagesResponse struct {
+	Messages []notifier.CapturedMessage `json:"messages"`
+}
+
+func (dc *DevMailController) handleList(w http.ResponseWriter, r *http.Request) {
+	messages, err := dc.mailbox.List()
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error listing captured messages", logging.Error(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(CapturedMessagesResponse{Messages: messages})
+}
+
+// handleGet answers the message as it would have been sent.
+func (dc *DevMailController) handleGet(w http.ResponseWriter, r *http.Request) {
+	message, err := dc.mailbox.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, notifier.ErrMessageNotFound) {
+			http.Error(w, "message not found", http.StatusNotFound)
+
+			return
+		}
+
+		slog.ErrorContext(r.Context(), "error reading captured message", logging.Error(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "message/rfc822")
+	_, _ = w.Write(message.Raw)
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"user_news_api/handler/mocks"
+	"user_news_api/notifier"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDevMail(t *testing.T) {
+	message := notifier.CapturedMessage{
+		ID:      "20240101T090000.000000000-1",
+		From:    "sender@example.com",
+		To:      "user@example.com",
+		Subject: "Notification",
+		Date:    time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+		Raw:     []byte("Subject: Notification\r\n\r\nnews"),
+	}
+
+	tests := []struct {
+		name           string
+		target         string
+		setupMocks     func(mailbox *mocks.Mailbox)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:   "List captured messages",
+			target: "/dev/messages",
+			setupMocks: func(mailbox *mocks.Mailbox) {
+				mailbox.On("List").Return([]notifier.CapturedMessage{message}, nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"messages":[{"id":"20240101T090000.000000000-1","from":"sender@example.com",` +
+				`"to":"user@example.com","subject":"Notification","date":"2024-01-01T09:00:00Z"}]}`,
+		},
+		{
+			name:   "List error",
+			target: "/dev/messages",
+			setupMocks: func(mailbox *mocks.Mailbox) {
+				mailbox.On("List").Return(nil, errors.New("error")).Once()
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   "internal error",
+		},
+		{
+			name:   "Read captured message",
+			target: "/dev/messages/20240101T090000.000000000-1",
+			setupMocks: func(mailbox *mocks.Mailbox) {
+				mailbox.On("Get", message.ID).Return(message, nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "Subject: Notification\r\n\r\nnews",
+		},
+		{
+			name:   "Message not found",
+			target: "/dev/messages/unknown",
+			setupMocks: func(mailbox *mocks.Mailbox) {
+				mailbox.On("Get", "unknown").Return(notifier.CapturedMessage{}, notifier.ErrMessageNotFound).Once()
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "message not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockMailbox := mocks.NewMailbox(t)
+
+			tt.setupMocks(mockMailbox)
+
+			router := chi.NewRouter()
+			SetDevMailController(router, mockMailbox)
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			assert.Contains(t, string(body), tt.expectedBody)
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"user_news_api/health"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Readiness is an abstraction for health.Readiness making it mockeable
+type Readiness interface {
+	Check(context.Context) health.Report
+}
+
+// SetHealthController registers /healthz, answering while the process is alive, and /readyz,
+// answering 503 when a critical dependency is down. Both are meant for the probes of the orchestrators.
+func SetHealthController(router chi.Router, readiness Readiness) {
+	controller := &HealthController{readiness: readiness}
+
+	router.Get("/healthz", controller.handleLiveness)
+	router.Get("/readyz", controller.handleReadiness)
+}
+
+type HealthController struct {
+	readiness Readiness
+}
+
+type LivenessResponse struct {
+	Status string `json:"status"`
+}
+
+// handleLiveness does not check any dependency, restarting the API would not fix them.
+func (hc *HealthController) handleLiveness(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(LivenessResponse{Status: health.StatusUp})
+}
+
+// handleReadiness answers the breakdown of every dependency. A degraded API is still ready.
+func (hc *HealthController) handleReadiness(w http.ResponseWriter, r *http.Request) {
+	report := hc.readiness.Check(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	if report.Status == health.StatusDown {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	_ = json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"user_news_api/handler/mocks"
+	"user_news_api/health"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthController(t *testing.T) {
+	checkedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		target         string
+		setupMocks     func(readiness *mocks.Readiness)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Liveness",
+			target:         "/healthz",
+			setupMocks:     func(readiness *mocks.Readiness) {},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"up"}`,
+		},
+		{
+			name:   "Ready",
+			target: "/readyz",
+			setupMocks: func(readiness *mocks.Readiness) {
+				readiness.On("Check", mock.Anything).Return(health.Report{
+					Status:    health.StatusUp,
+					CheckedAt: checkedAt,
+					Checks:    []health.CheckResult{{Name: "redis", Status: health.StatusUp, Critical: true, LatencyMs: 1.5}},
+				}).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"status":"up","checked_at":"2024-01-01T00:00:00Z","checks":[` +
+				`{"name":"redis","status":"up","critical":true,"latency_ms":1.5}]}`,
+		},
+		{
+			name:   "Degraded is still ready",
+			target: "/readyz",
+			setupMocks: func(readiness *mocks.Readiness) {
+				readiness.On("Check", mock.Anything).Return(health.Report{
+					Status:    health.StatusDegraded,
+					CheckedAt: checkedAt,
+					Checks: []health.CheckResult{
+						{Name: "redis", Status: health.StatusUp, Critical: true, LatencyMs: 1.5},
+						{Name: "smtp:primary", Status: health.StatusDown, LatencyMs: 2000, Error: "i/o timeout"},
+					},
+				}).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"status":"degraded","checked_at":"2024-01-01T00:00:00Z","checks":[` +
+				`{"name":"redis","status":"up","critical":true,"latency_ms":1.5},` +
+				`{"name":"smtp:primary","status":"down","critical":false,"latency_ms":2000,"error":"i/o timeout"}]}`,
+		},
+		{
+			name:   "Not ready",
+			target: "/readyz",
+			setupMocks: func(readiness *mocks.Readiness) {
+				readiness.On("Check", mock.Anything).Return(health.Report{
+					Status:    health.StatusDown,
+					CheckedAt: checkedAt,
+					Checks: []health.CheckResult{
+						{Name: "redis", Status: health.StatusDown, Critical: true, LatencyMs: 3, Error: "connection refused"},
+					},
+				}).Once()
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody: `{"status":"down","checked_at":"2024-01-01T00:00:00Z","checks":[` +
+				`{"name":"redis","status":"down","critical":true,"latency_ms":3,"error":"connection refused"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockReadiness := mocks.NewReadiness(t)
+			tt.setupMocks(mockReadiness)
+
+			router := chi.NewRouter()
+			SetHealthController(router, mockReadiness)
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			res := rec.Result()
+			defer res.Body.Close()
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+			assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+			assert.JSONEq(t, tt.expectedBody, string(body))
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"user_news_api/idempotency"
+	"user_news_api/logging"
+)
+
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+// IdempotencyStore is an abstraction for idempotency.Store making it mockeable
+type IdempotencyStore interface {
+	Begin(context.Context, string, string) (*idempotency.Response, error)
+	Complete(context.Context, string, string, idempotency.Response) error
+	Release(context.Context, string) error
+}
+
+// Idempotency replays the stored response of POST requests repeated with the same Idempotency-Key header.
+// Requests without the header are not affected. Server errors are not stored, so the request can be retried,
+// unless the mail could have been delivered, see markMaybeSent.
+// It must be applied after the authentication: the keys of every client are kept apart, so a response
+// is only replayed to the client that made the request.
+func Idempotency(store IdempotencyStore) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(idempotencyKeyHeader)
+			if r.Method != http.MethodPost || key == "" {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			if len(key) > maxIdempotencyKeyLength {
+				http.Error(w, fmt.Sprintf("idempotency key must not exceed %d characters", maxIdempotencyKeyLength), http.StatusBadRequest)
+
+				return
+			}
+
+			if client := clientID(r.Context()); client != "" {
+				key = client + ":" + key
+			}
+
+			// the body is kept in memory for the fingerprint, so it is bounded as the largest request, a batch
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBatchBodySize))
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					http.Error(w, fmt.Sprintf("request body must not exceed %d bytes", maxBatchBodySize), http.StatusRequestEntityTooLarge)
+
+					return
+				}
+
+				http.Error(w, fmt.Sprintf("error reading request body due to: %s", err.Error()), http.StatusBadRequest)
+
+				return
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			fingerprint := requestFingerprint(r, body)
+
+			stored, err := store.Begin(r.Context(), key, fingerprint)
+			if err != nil {
+				handleIdempotencyError(r.Context(), w, err)
+
+				return
+			}
+
+			if stored != nil {
+				replay(w, *stored)
+
+				return
+			}
+
+			rec := &responseRecorder{ResponseWriter: w}
+			outcome := &requestOutcome{}
+			next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), outcomeContextKey{}, outcome)))
+
+			// the request is already handled, so the outcome is kept even when the client went away meanwhile
+			ctx := context.WithoutCancel(r.Context())
+
+			// a retry could send a mail twice when it may have been delivered, so only the failures sending nothing are released
+			if rec.statusCode() >= http.StatusInternalServerError && !outcome.maybeSent.Load() {
+				if err = store.Release(ctx, key); err != nil {
+					slog.ErrorContext(ctx, "error releasing idempotency key", logging.Error(err))
+				}
+
+				return
+			}
+
+			err = store.Complete(ctx, key, fingerprint, idempotency.Response{
+				StatusCode: rec.statusCode(),
+				Header:     rec.Header().Clone(),
+				Body:       rec.body.Bytes(),
+			})
+			if err != nil {
+				slog.ErrorContext(ctx, "error storing idempotent response", logging.Error(err))
+			}
+		})
+	}
+}
+
+// requestOutcome is shared by the Idempotency middleware with the handlers through the request context.
+type requestOutcome struct {
+	maybeSent atomic.Bool // maybeSent is set when a failed notification could have been delivered anyway
+}
+
+type outcomeContextKey struct{}
+
+// markMaybeSent tells the Idempotency middleware that the mail of a failed request could have been delivered,
+// e.g. it timed out after the mail server received it, so the key is kept instead of released.
+func markMaybeSent(ctx context.Context) {
+	if outcome, ok := ctx.Value(outcomeContextKey{}).(*requestOutcome); ok {
+		outcome.maybeSent.Store(true)
+	}
+}
+
+func handleIdempotencyError(ctx context.Context, w http.ResponseWriter, err error) {
+	if errors.Is(err, idempotency.ErrPayloadMismatch) {
+		http.Error(w, "idempotency key reused with a different payload", http.StatusUnprocessableEntity)
+
+		return
+	}
+
+	if errors.Is(err, idempotency.ErrInProgress) {
+		http.Error(w, "a request with the same idempotency key is in progress", http.StatusConflict)
+
+		return
+	}
+
+	slog.ErrorContext(ctx, "error checking idempotency key", logging.Error(err))
+	http.Error(w, "internal error", http.StatusInternalServerError)
+}
+
+func replay(w http.ResponseWriter, response idempotency.Response) {
+	for name, values := range response.Header {
+		w.Header()[name] = values
+	}
+
+	w.Header().Set(idempotentReplayedHeader, "true")
+	w.WriteHeader(response.StatusCode)
+	_, _ = w.Write(response.Body)
+}
+
+// requestFingerprint identifies the request, so the same key cannot be used for another endpoint or payload.
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder writes the response while keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	if rr.status == 0 {
+		rr.status = status
+	}
+
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	if rr.status == 0 {
+		rr.status = http.StatusOK
+	}
+
+	rr.body.Write(b)
+
+	return rr.ResponseWriter.Write(b)
+}
+
+func (rr *responseRecorder) statusCode() int {
+	if rr.status == 0 {
+		return http.StatusOK
+	}
+
+	return rr.status
+}
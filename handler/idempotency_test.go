@@ -0,0 +1,281 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"user_news_api/apikey"
+	"user_news_api/handler/mocks"
+	"user_news_api/idempotency"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotency(t *testing.T) {
+	body := `{"user_email":"test@example.com","message_type":"News"}`
+	fingerprint := requestFingerprint(httptest.NewRequest(http.MethodPost, "/notifications", nil), []byte(body))
+
+	tests := []struct {
+		name           string
+		method         string
+		key            string
+		apiKey         *apikey.Key
+		clientGone     bool
+		maybeSent      bool
+		nextStatus     int
+		setupMocks     func(store *mocks.IdempotencyStore)
+		expectedCalls  int
+		expectedStatus int
+		expectedBody   string
+		expectedReplay bool
+	}{
+		{
+			name:           "Request without key",
+			method:         http.MethodPost,
+			key:            "",
+			nextStatus:     http.StatusAccepted,
+			setupMocks:     func(store *mocks.IdempotencyStore) {},
+			expectedCalls:  1,
+			expectedStatus: http.StatusAccepted,
+			expectedBody:   "next",
+		},
+		{
+			name:           "Not a POST request",
+			method:         http.MethodGet,
+			key:            "key",
+			nextStatus:     http.StatusOK,
+			setupMocks:     func(store *mocks.IdempotencyStore) {},
+			expectedCalls:  1,
+			expectedStatus: http.StatusOK,
+			expectedBody:   "next",
+		},
+		{
+			name:       "First request is stored",
+			method:     http.MethodPost,
+			key:        "key",
+			nextStatus: http.StatusAccepted,
+			setupMocks: func(store *mocks.IdempotencyStore) {
+				store.On("Begin", mock.Anything, "key", fingerprint).Return(nil, nil).Once()
+				store.On("Complete", mock.Anything, "key", fingerprint, mock.MatchedBy(func(r idempotency.Response) bool {
+					return r.StatusCode == http.StatusAccepted && string(r.Body) == "next" && r.Header.Get("X-Test") == "value"
+				})).Return(nil).Once()
+			},
+			expectedCalls:  1,
+			expectedStatus: http.StatusAccepted,
+			expectedBody:   "next",
+		},
+		{
+			name:       "Keys of authenticated clients are kept apart",
+			method:     http.MethodPost,
+			key:        "key",
+			apiKey:     &apikey.Key{ID: "abc"},
+			nextStatus: http.StatusAccepted,
+			setupMocks: func(store *mocks.IdempotencyStore) {
+				store.On("Begin", mock.Anything, "api-key:abc:key", fingerprint).Return(nil, nil).Once()
+				store.On("Complete", mock.Anything, "api-key:abc:key", fingerprint, mock.Anything).Return(nil).Once()
+			},
+			expectedCalls:  1,
+			expectedStatus: http.StatusAccepted,
+			expectedBody:   "next",
+		},
+		{
+			name:       "Server errors are released",
+			method:     http.MethodPost,
+			key:        "key",
+			nextStatus: http.StatusInternalServerError,
+			setupMocks: func(store *mocks.IdempotencyStore) {
+				store.On("Begin", mock.Anything, "key", fingerprint).Return(nil, nil).Once()
+				store.On("Release", mock.Anything, "key").Return(nil).Once()
+			},
+			expectedCalls:  1,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   "next",
+		},
+		{
+			name:       "Timeouts after the mail was handed over are stored",
+			method:     http.MethodPost,
+			key:        "key",
+			maybeSent:  true,
+			nextStatus: http.StatusGatewayTimeout,
+			setupMocks: func(store *mocks.IdempotencyStore) {
+				store.On("Begin", mock.Anything, "key", fingerprint).Return(nil, nil).Once()
+				store.On("Complete", mock.Anything, "key", fingerprint, mock.MatchedBy(func(r idempotency.Response) bool {
+					return r.StatusCode == http.StatusGatewayTimeout
+				})).Return(nil).Once()
+			},
+			expectedCalls:  1,
+			expectedStatus: http.StatusGatewayTimeout,
+			expectedBody:   "next",
+		},
+		{
+			name:       "Response is stored when the client is gone",
+			method:     http.MethodPost,
+			key:        "key",
+			clientGone: true,
+			nextStatus: http.StatusAccepted,
+			setupMocks: func(store *mocks.IdempotencyStore) {
+				store.On("Begin", mock.Anything, "key", fingerprint).Return(nil, nil).Once()
+				store.On("Complete", mock.MatchedBy(notCanceled), "key", fingerprint, mock.Anything).Return(nil).Once()
+			},
+			expectedCalls:  1,
+			expectedStatus: http.StatusAccepted,
+			expectedBody:   "next",
+		},
+		{
+			name:       "Key is released when the client is gone",
+			method:     http.MethodPost,
+			key:        "key",
+			clientGone: true,
+			nextStatus: http.StatusInternalServerError,
+			setupMocks: func(store *mocks.IdempotencyStore) {
+				store.On("Begin", mock.Anything, "key", fingerprint).Return(nil, nil).Once()
+				store.On("Release", mock.MatchedBy(notCanceled), "key").Return(nil).Once()
+			},
+			expectedCalls:  1,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   "next",
+		},
+		{
+			name:   "Repeated request is replayed",
+			method: http.MethodPost,
+			key:    "key",
+			setupMocks: func(store *mocks.IdempotencyStore) {
+				store.On("Begin", mock.Anything, "key", fingerprint).Return(&idempotency.Response{
+					StatusCode: http.StatusAccepted,
+					Header:     http.Header{"X-Test": {"value"}},
+					Body:       []byte("stored"),
+				}, nil).Once()
+			},
+			expectedCalls:  0,
+			expectedStatus: http.StatusAccepted,
+			expectedBody:   "stored",
+			expectedReplay: true,
+		},
+		{
+			name:   "Key reused with another payload",
+			method: http.MethodPost,
+			key:    "key",
+			setupMocks: func(store *mocks.IdempotencyStore) {
+				store.On("Begin", mock.Anything, "key", fingerprint).Return(nil, idempotency.ErrPayloadMismatch).Once()
+			},
+			expectedCalls:  0,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "idempotency key reused with a different payload",
+		},
+		{
+			name:   "Concurrent request",
+			method: http.MethodPost,
+			key:    "key",
+			setupMocks: func(store *mocks.IdempotencyStore) {
+				store.On("Begin", mock.Anything, "key", fingerprint).Return(nil, idempotency.ErrInProgress).Once()
+			},
+			expectedCalls:  0,
+			expectedStatus: http.StatusConflict,
+			expectedBody:   "a request with the same idempotency key is in progress",
+		},
+		{
+			name:   "Store error",
+			method: http.MethodPost,
+			key:    "key",
+			setupMocks: func(store *mocks.IdempotencyStore) {
+				store.On("Begin", mock.Anything, "key", fingerprint).Return(nil, errors.New("error")).Once()
+			},
+			expectedCalls:  0,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   "internal error",
+		},
+		{
+			name:           "Key too long",
+			method:         http.MethodPost,
+			key:            strings.Repeat("k", 256),
+			setupMocks:     func(store *mocks.IdempotencyStore) {},
+			expectedCalls:  0,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "idempotency key must not exceed 255 characters",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := mocks.NewIdempotencyStore(t)
+			tt.setupMocks(mockStore)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			calls := 0
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+
+				if tt.clientGone {
+					cancel()
+				}
+
+				if tt.maybeSent {
+					markMaybeSent(r.Context())
+				}
+
+				received, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				assert.Equal(t, body, string(received))
+
+				w.Header().Set("X-Test", "value")
+				w.WriteHeader(tt.nextStatus)
+				_, _ = w.Write([]byte("next"))
+			})
+
+			req := httptest.NewRequest(tt.method, "/notifications", strings.NewReader(body)).WithContext(ctx)
+			if tt.key != "" {
+				req.Header.Set(idempotencyKeyHeader, tt.key)
+			}
+
+			if tt.apiKey != nil {
+				req = req.WithContext(apikey.WithKey(req.Context(), *tt.apiKey))
+			}
+
+			rec := httptest.NewRecorder()
+
+			Idempotency(mockStore)(next).ServeHTTP(rec, req)
+
+			res := rec.Result()
+			defer res.Body.Close()
+
+			received, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expectedCalls, calls)
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+			assert.Contains(t, string(received), tt.expectedBody)
+			assert.Equal(t, tt.expectedReplay, res.Header.Get(idempotentReplayedHeader) == "true")
+		})
+	}
+}
+
+func notCanceled(ctx context.Context) bool {
+	return ctx.Err() == nil
+}
+
+func TestIdempotencyBodyTooLarge(t *testing.T) {
+	// the store is not expected to be called, so the mock fails when it is
+	mockStore := mocks.NewIdempotencyStore(t)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the request is not expected to be handled")
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/notifications/batch", strings.NewReader(strings.Repeat("a", maxBatchBodySize+1)))
+	req.Header.Set(idempotencyKeyHeader, "key")
+
+	rec := httptest.NewRecorder()
+
+	Idempotency(mockStore)(next).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Contains(t, rec.Body.String(), "request body must not exceed 1048576 bytes")
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"user_news_api/handler/mocks"
+	"user_news_api/logging"
+	"user_news_api/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleNotifyUserLog(t *testing.T) {
+	tests := []struct {
+		name            string
+		notifyErr       error
+		expectedLevel   string
+		expectedOutcome string
+	}{
+		{
+			name:            "Sent",
+			expectedLevel:   "INFO",
+			expectedOutcome: outcomeSent,
+		},
+		{
+			name:            "Digested",
+			notifyErr:       services.ErrDigested,
+			expectedLevel:   "INFO",
+			expectedOutcome: outcomeAccepted,
+		},
+		{
+			name:            "Rate limited",
+			notifyErr:       services.ErrLimitExceeded,
+			expectedLevel:   "INFO",
+			expectedOutcome: outcomeRejected,
+		},
+		{
+			name:            "Failed",
+			notifyErr:       errors.New("notifier error for user test@example.com"),
+			expectedLevel:   "ERROR",
+			expectedOutcome: outcomeFailed,
+		},
+	}
+
+	defer slog.SetDefault(slog.Default())
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			slog.SetDefault(logging.New(logging.Options{Writer: &out}))
+
+			mockService := mocks.NewUserNotifier(t)
+			mockService.On("Check", mock.Anything, "test@example.com", "News", "").Return(nil).Once()
+			mockService.On("Notify", mock.Anything, "test@example.com", "News").Return("id-1", tt.notifyErr).Once()
+
+			handler := RequestID()(http.HandlerFunc((&UserController{service: mockService}).handleNotifyUser))
+
+			req := httptest.NewRequest(http.MethodPost, "/notifications",
+				bytes.NewBufferString(`{"user_email":"test@example.com","message_type":"News"}`))
+			req.Header.Set(requestIDHeader, "request-1")
+
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.NotContains(t, out.String(), "test@example.com")
+
+			// the last log is the one of the request, the failures are logged before it
+			var last map[string]any
+
+			decoder := json.NewDecoder(&out)
+			for decoder.More() {
+				last = nil
+				require.NoError(t, decoder.Decode(&last))
+				assert.Equal(t, "request-1", last[logging.KeyRequestID])
+			}
+
+			require.NotNil(t, last)
+			assert.Equal(t, "notification request handled", last["msg"])
+			assert.Equal(t, tt.expectedLevel, last["level"])
+			assert.Equal(t, tt.expectedOutcome, last[logging.KeyOutcome])
+			assert.Equal(t, "News", last[logging.KeyMessageType])
+			assert.Equal(t, "t***@example.com", last[logging.KeyUser])
+			assert.Equal(t, "id-1", last[logging.KeyNotificationID])
+			assert.Contains(t, last, logging.KeyLatency)
+		})
+	}
+}
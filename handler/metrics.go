@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// unmatchedRoute labels the requests not matching any route, so unknown paths do not create a series each.
+const unmatchedRoute = "unmatched"
+
+// RequestMetrics is an abstraction for metrics.Registry making it mockeable
+type RequestMetrics interface {
+	ObserveRequest(route string, method string, status int, duration time.Duration)
+	Handler() http.Handler
+}
+
+// SetMetricsController registers the route Prometheus scrapes.
+func SetMetricsController(router chi.Router, metrics RequestMetrics) {
+	router.Method(http.MethodGet, "/metrics", metrics.Handler())
+}
+
+// Metrics observes every request by the route pattern it matched, its method and its status code.
+// It must be used by the root router, which knows the whole pattern once the request is handled.
+func Metrics(metrics RequestMetrics) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			next.ServeHTTP(ww, r)
+
+			route := unmatchedRoute
+			if routeCtx := chi.RouteContext(r.Context()); routeCtx != nil && routeCtx.RoutePattern() != "" {
+				route = routeCtx.RoutePattern()
+			}
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			metrics.ObserveRequest(route, r.Method, status, time.Since(start))
+		})
+	}
+}
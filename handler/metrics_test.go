@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"user_news_api/handler/mocks"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		target        string
+		expectedRoute string
+		expectedCode  int
+	}{
+		{
+			name:          "Route with parameters",
+			method:        http.MethodGet,
+			target:        "/notifications/abc",
+			expectedRoute: "/notifications/{id}",
+			expectedCode:  http.StatusAccepted,
+		},
+		{
+			name:          "Route writing the body only",
+			method:        http.MethodPost,
+			target:        "/notifications",
+			expectedRoute: "/notifications",
+			expectedCode:  http.StatusOK,
+		},
+		{
+			name:          "Unknown route",
+			method:        http.MethodGet,
+			target:        "/unknown/abc",
+			expectedRoute: unmatchedRoute,
+			expectedCode:  http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockMetrics := mocks.NewRequestMetrics(t)
+			mockMetrics.On("ObserveRequest", tt.expectedRoute, tt.method, tt.expectedCode, mock.Anything).Once()
+
+			router := chi.NewRouter()
+			router.Use(Metrics(mockMetrics))
+			router.Get("/notifications/{id}", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+			})
+			router.Post("/notifications", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("ok"))
+			})
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+		})
+	}
+}
+
+func TestSetMetricsController(t *testing.T) {
+	mockMetrics := mocks.NewRequestMetrics(t)
+	mockMetrics.On("Handler").Return(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("user_news_api_http_requests_total 1"))
+	})).Once()
+
+	router := chi.NewRouter()
+	SetMetricsController(router, mockMetrics)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body, err := io.ReadAll(rec.Result().Body)
+	require.NoError(t, err)
+	assert.Equal(t, "user_news_api_http_requests_total 1", string(body))
+}
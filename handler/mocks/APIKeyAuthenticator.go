@@ -0,0 +1,53 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	apikey "user_news_api/apikey"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// APIKeyAuthenticator is an autogenerated mock type for the APIKeyAuthenticator type
+type APIKeyAuthenticator struct {
+	mock.Mock
+}
+
+// Authenticate provides a mock function with given fields: _a0, _a1
+func (_m *APIKeyAuthenticator) Authenticate(_a0 context.Context, _a1 string) (apikey.Key, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 apikey.Key
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (apikey.Key, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) apikey.Key); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(apikey.Key)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewAPIKeyAuthenticator creates a new instance of APIKeyAuthenticator. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewAPIKeyAuthenticator(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *APIKeyAuthenticator {
+	mock := &APIKeyAuthenticator{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,100 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	apikey "user_news_api/apikey"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// APIKeyStore is an autogenerated mock type for the APIKeyStore type
+type APIKeyStore struct {
+	mock.Mock
+}
+
+// Issue provides a mock function with given fields: _a0, _a1
+func (_m *APIKeyStore) Issue(_a0 context.Context, _a1 apikey.Key) (apikey.Key, string, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 apikey.Key
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, apikey.Key) (apikey.Key, string, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, apikey.Key) apikey.Key); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(apikey.Key)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, apikey.Key) string); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, apikey.Key) error); ok {
+		r2 = rf(_a0, _a1)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// List provides a mock function with given fields: _a0
+func (_m *APIKeyStore) List(_a0 context.Context) ([]apikey.Key, error) {
+	ret := _m.Called(_a0)
+
+	var r0 []apikey.Key
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]apikey.Key, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []apikey.Key); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]apikey.Key)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Revoke provides a mock function with given fields: _a0, _a1
+func (_m *APIKeyStore) Revoke(_a0 context.Context, _a1 string) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewAPIKeyStore creates a new instance of APIKeyStore. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewAPIKeyStore(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *APIKeyStore {
+	mock := &APIKeyStore{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
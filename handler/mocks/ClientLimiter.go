@@ -0,0 +1,54 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// ClientLimiter is an autogenerated mock type for the ClientLimiter type
+type ClientLimiter struct {
+	mock.Mock
+}
+
+// Reached provides a mock function with given fields: ctx, client, notifications, max, period
+func (_m *ClientLimiter) Reached(ctx context.Context, client string, notifications int64, max int64, period time.Duration) (bool, error) {
+	ret := _m.Called(ctx, client, notifications, max, period)
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64, int64, time.Duration) (bool, error)); ok {
+		return rf(ctx, client, notifications, max, period)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64, int64, time.Duration) bool); ok {
+		r0 = rf(ctx, client, notifications, max, period)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int64, int64, time.Duration) error); ok {
+		r1 = rf(ctx, client, notifications, max, period)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewClientLimiter creates a new instance of ClientLimiter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewClientLimiter(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *ClientLimiter {
+	mock := &ClientLimiter{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,84 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	idempotency "user_news_api/idempotency"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// IdempotencyStore is an autogenerated mock type for the IdempotencyStore type
+type IdempotencyStore struct {
+	mock.Mock
+}
+
+// Begin provides a mock function with given fields: _a0, _a1, _a2
+func (_m *IdempotencyStore) Begin(_a0 context.Context, _a1 string, _a2 string) (*idempotency.Response, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 *idempotency.Response
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*idempotency.Response, error)); ok {
+		return rf(_a0, _a1, _a2)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *idempotency.Response); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*idempotency.Response)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Complete provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *IdempotencyStore) Complete(_a0 context.Context, _a1 string, _a2 string, _a3 idempotency.Response) error {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, idempotency.Response) error); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Release provides a mock function with given fields: _a0, _a1
+func (_m *IdempotencyStore) Release(_a0 context.Context, _a1 string) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewIdempotencyStore creates a new instance of IdempotencyStore. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewIdempotencyStore(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *IdempotencyStore {
+	mock := &IdempotencyStore{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
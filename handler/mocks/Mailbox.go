@@ -0,0 +1,78 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	notifier "user_news_api/notifier"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Mailbox is an autogenerated mock type for the Mailbox type
+type Mailbox struct {
+	mock.Mock
+}
+
+// Get provides a mock function with given fields: _a0
+func (_m *Mailbox) Get(_a0 string) (notifier.CapturedMessage, error) {
+	ret := _m.Called(_a0)
+
+	var r0 notifier.CapturedMessage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (notifier.CapturedMessage, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(string) notifier.CapturedMessage); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(notifier.CapturedMessage)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// List provides a mock function with given fields:
+func (_m *Mailbox) List() ([]notifier.CapturedMessage, error) {
+	ret := _m.Called()
+
+	var r0 []notifier.CapturedMessage
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]notifier.CapturedMessage, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []notifier.CapturedMessage); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]notifier.CapturedMessage)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewMailbox creates a new instance of Mailbox. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMailbox(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Mailbox {
+	mock := &Mailbox{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,52 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import mock "github.com/stretchr/testify/mock"
+
+// MessageTypes is an autogenerated mock type for the MessageTypes type
+type MessageTypes struct {
+	mock.Mock
+}
+
+// Transactional provides a mock function with given fields: _a0
+func (_m *MessageTypes) Transactional(_a0 string) bool {
+	ret := _m.Called(_a0)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// Valid provides a mock function with given fields: _a0
+func (_m *MessageTypes) Valid(_a0 string) bool {
+	ret := _m.Called(_a0)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// NewMessageTypes creates a new instance of MessageTypes. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMessageTypes(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MessageTypes {
+	mock := &MessageTypes{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,108 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	preferences "user_news_api/preferences"
+)
+
+// PreferenceStore is an autogenerated mock type for the PreferenceStore type
+type PreferenceStore struct {
+	mock.Mock
+}
+
+// Delete provides a mock function with given fields: _a0, _a1, _a2
+func (_m *PreferenceStore) Delete(_a0 context.Context, _a1 string, _a2 string) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Get provides a mock function with given fields: _a0, _a1, _a2
+func (_m *PreferenceStore) Get(_a0 context.Context, _a1 string, _a2 string) (preferences.Preference, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 preferences.Preference
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (preferences.Preference, error)); ok {
+		return rf(_a0, _a1, _a2)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) preferences.Preference); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Get(0).(preferences.Preference)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// List provides a mock function with given fields: _a0, _a1
+func (_m *PreferenceStore) List(_a0 context.Context, _a1 string) ([]preferences.Preference, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 []preferences.Preference
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]preferences.Preference, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []preferences.Preference); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]preferences.Preference)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Set provides a mock function with given fields: _a0, _a1, _a2
+func (_m *PreferenceStore) Set(_a0 context.Context, _a1 string, _a2 preferences.Preference) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, preferences.Preference) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewPreferenceStore creates a new instance of PreferenceStore. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewPreferenceStore(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *PreferenceStore {
+	mock := &PreferenceStore{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,44 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	health "user_news_api/health"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Readiness is an autogenerated mock type for the Readiness type
+type Readiness struct {
+	mock.Mock
+}
+
+// Check provides a mock function with given fields: _a0
+func (_m *Readiness) Check(_a0 context.Context) health.Report {
+	ret := _m.Called(_a0)
+
+	var r0 health.Report
+	if rf, ok := ret.Get(0).(func(context.Context) health.Report); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(health.Report)
+	}
+
+	return r0
+}
+
+// NewReadiness creates a new instance of Readiness. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewReadiness(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Readiness {
+	mock := &Readiness{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,50 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	http "net/http"
+	time "time"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// RequestMetrics is an autogenerated mock type for the RequestMetrics type
+type RequestMetrics struct {
+	mock.Mock
+}
+
+// Handler provides a mock function with given fields:
+func (_m *RequestMetrics) Handler() http.Handler {
+	ret := _m.Called()
+
+	var r0 http.Handler
+	if rf, ok := ret.Get(0).(func() http.Handler); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(http.Handler)
+		}
+	}
+
+	return r0
+}
+
+// ObserveRequest provides a mock function with given fields: route, method, status, duration
+func (_m *RequestMetrics) ObserveRequest(route string, method string, status int, duration time.Duration) {
+	_m.Called(route, method, status, duration)
+}
+
+// NewRequestMetrics creates a new instance of RequestMetrics. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewRequestMetrics(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *RequestMetrics {
+	mock := &RequestMetrics{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,84 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	suppression "user_news_api/suppression"
+)
+
+// SuppressionStore is an autogenerated mock type for the SuppressionStore type
+type SuppressionStore struct {
+	mock.Mock
+}
+
+// Add provides a mock function with given fields: _a0, _a1
+func (_m *SuppressionStore) Add(_a0 context.Context, _a1 suppression.Entry) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, suppression.Entry) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// List provides a mock function with given fields: _a0, _a1
+func (_m *SuppressionStore) List(_a0 context.Context, _a1 int64) ([]suppression.Entry, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 []suppression.Entry
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]suppression.Entry, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []suppression.Entry); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]suppression.Entry)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Remove provides a mock function with given fields: _a0, _a1
+func (_m *SuppressionStore) Remove(_a0 context.Context, _a1 string) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewSuppressionStore creates a new instance of SuppressionStore. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewSuppressionStore(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *SuppressionStore {
+	mock := &SuppressionStore{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,54 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	jwtauth "user_news_api/jwtauth"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// TokenValidator is an autogenerated mock type for the TokenValidator type
+type TokenValidator struct {
+	mock.Mock
+}
+
+// Validate provides a mock function with given fields: _a0, _a1
+func (_m *TokenValidator) Validate(_a0 context.Context, _a1 string) (jwtauth.Claims, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 jwtauth.Claims
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (jwtauth.Claims, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) jwtauth.Claims); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(jwtauth.Claims)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewTokenValidator creates a new instance of TokenValidator. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewTokenValidator(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *TokenValidator {
+	mock := &TokenValidator{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,55 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import mock "github.com/stretchr/testify/mock"
+
+// UnsubscribeTokens is an autogenerated mock type for the UnsubscribeTokens type
+type UnsubscribeTokens struct {
+	mock.Mock
+}
+
+// Verify provides a mock function with given fields: _a0
+func (_m *UnsubscribeTokens) Verify(_a0 string) (string, string, error) {
+	ret := _m.Called(_a0)
+
+	var r0 string
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(string) (string, string, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) string); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(string) error); ok {
+		r2 = rf(_a0)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// NewUnsubscribeTokens creates a new instance of UnsubscribeTokens. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewUnsubscribeTokens(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *UnsubscribeTokens {
+	mock := &UnsubscribeTokens{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
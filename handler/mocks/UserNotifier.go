@@ -4,8 +4,15 @@ package mocks
 
 import (
 	context "context"
+	delivery "user_news_api/delivery"
 
 	mock "github.com/stretchr/testify/mock"
+
+	schedule "user_news_api/schedule"
+
+	services "user_news_api/services"
+
+	time "time"
 )
 
 // UserNotifier is an autogenerated mock type for the UserNotifier type
@@ -13,20 +20,188 @@ type UserNotifier struct {
 	mock.Mock
 }
 
+// Cancel provides a mock function with given fields: _a0, _a1
+func (_m *UserNotifier) Cancel(_a0 context.Context, _a1 string) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Check provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *UserNotifier) Check(_a0 context.Context, _a1 string, _a2 string, _a3 string) error {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) error); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Enqueue provides a mock function with given fields: _a0, _a1, _a2
+func (_m *UserNotifier) Enqueue(_a0 context.Context, _a1 string, _a2 string) (string, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (string, error)); ok {
+		return rf(_a0, _a1, _a2)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) string); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// EnqueueBatch provides a mock function with given fields: _a0, _a1
+func (_m *UserNotifier) EnqueueBatch(_a0 context.Context, _a1 []services.BatchItem) []services.BatchResult {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 []services.BatchResult
+	if rf, ok := ret.Get(0).(func(context.Context, []services.BatchItem) []services.BatchResult); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]services.BatchResult)
+		}
+	}
+
+	return r0
+}
+
 // Notify provides a mock function with given fields: _a0, _a1, _a2
-func (_m *UserNotifier) Notify(_a0 context.Context, _a1 string, _a2 string) error {
+func (_m *UserNotifier) Notify(_a0 context.Context, _a1 string, _a2 string) (string, error) {
 	ret := _m.Called(_a0, _a1, _a2)
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (string, error)); ok {
+		return rf(_a0, _a1, _a2)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) string); ok {
 		r0 = rf(_a0, _a1, _a2)
 	} else {
-		r0 = ret.Error(0)
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NotifyBatch provides a mock function with given fields: _a0, _a1
+func (_m *UserNotifier) NotifyBatch(_a0 context.Context, _a1 []services.BatchItem) []services.BatchResult {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 []services.BatchResult
+	if rf, ok := ret.Get(0).(func(context.Context, []services.BatchItem) []services.BatchResult); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]services.BatchResult)
+		}
 	}
 
 	return r0
 }
 
+// Schedule provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *UserNotifier) Schedule(_a0 context.Context, _a1 string, _a2 string, _a3 time.Time) (string, error) {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) (string, error)); ok {
+		return rf(_a0, _a1, _a2, _a3)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) string); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, time.Time) error); ok {
+		r1 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Scheduled provides a mock function with given fields: _a0, _a1
+func (_m *UserNotifier) Scheduled(_a0 context.Context, _a1 int64) ([]schedule.Item, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 []schedule.Item
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]schedule.Item, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []schedule.Item); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]schedule.Item)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Status provides a mock function with given fields: _a0, _a1
+func (_m *UserNotifier) Status(_a0 context.Context, _a1 string) (delivery.Record, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 delivery.Record
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (delivery.Record, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) delivery.Record); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(delivery.Record)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewUserNotifier creates a new instance of UserNotifier. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewUserNotifier(t interface {
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+	"user_news_api/logging"
+	"user_news_api/preferences"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator"
+)
+
+// PreferenceStore is an abstraction for preferences.Store making it mockeable
+type PreferenceStore interface {
+	Set(context.Context, string, preferences.Preference) error
+	Get(context.Context, string, string) (preferences.Preference, error)
+	List(context.Context, string) ([]preferences.Preference, error)
+	Delete(context.Context, string, string) error
+}
+
+// MessageTypes is an abstraction for ratelimiter.LimiterPool making it mockeable
+type MessageTypes interface {
+	Valid(string) bool
+	Transactional(string) bool
+}
+
+// SetPreferencesController registers the routes of the user preferences.
+func SetPreferencesController(router chi.Router, store PreferenceStore, messageTypes MessageTypes) {
+	controller := &PreferencesController{store: store, messageTypes: messageTypes}
+
+	router.Get("/users/{email}/preferences", controller.handleList)
+	router.Get("/users/{email}/preferences/{type}", controller.handleGet)
+	router.Put("/users/{email}/preferences/{type}", controller.handleSet)
+	router.Delete("/users/{email}/preferences/{type}", controller.handleDelete)
+}
+
+type PreferencesController struct {
+	store        PreferenceStore
+	messageTypes MessageTypes
+}
+
+type PreferenceRequestPayload struct {
+	OptedIn *bool `json:"opted_in" validate:"required"`
+}
+
+type PreferencesResponse struct {
+	Preferences []preferences.Preference `json:"preferences"`
+}
+
+func (pc *PreferencesController) handleList(w http.ResponseWriter, r *http.Request) {
+	list, err := pc.store.List(r.Context(), chi.URLParam(r, "email"))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error listing preferences", logging.Error(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(PreferencesResponse{Preferences: list})
+}
+
+func (pc *PreferencesController) handleGet(w http.ResponseWriter, r *http.Request) {
+	preference, err := pc.store.Get(r.Context(), chi.URLParam(r, "email"), chi.URLParam(r, "type"))
+	if err != nil {
+		handlePreferenceError(r.Context(), w, err)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(preference)
+}
+
+func (pc *PreferencesController) handleSet(w http.ResponseWriter, r *http.Request) {
+	messageType := chi.URLParam(r, "type")
+	if !pc.messageTypes.Valid(messageType) {
+		http.Error(w, "message type not valid", http.StatusBadRequest)
+
+		return
+	}
+
+	var payload PreferenceRequestPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, fmt.Sprintf("error marshalling request body due to: %s", err.Error()), http.StatusBadRequest)
+
+		return
+	}
+
+	if err := validator.New().Struct(payload); err != nil {
+		http.Error(w, fmt.Sprintf("request validation fails due to: %s", err.Error()), http.StatusBadRequest)
+
+		return
+	}
+
+	if !*payload.OptedIn && pc.messageTypes.Transactional(messageType) {
+		http.Error(w, "transactional message types cannot be opted out of", http.StatusUnprocessableEntity)
+
+		return
+	}
+
+	preference := preferences.Preference{
+		MessageType: messageType,
+		OptedIn:     *payload.OptedIn,
+		UpdatedAt:   time.Now().UTC(),
+	}
+
+	if err := pc.store.Set(r.Context(), chi.URLParam(r, "email"), preference); err != nil {
+		handlePreferenceError(r.Context(), w, err)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(preference)
+}
+
+func (pc *PreferencesController) handleDelete(w http.ResponseWriter, r *http.Request) {
+	if err := pc.store.Delete(r.Context(), chi.URLParam(r, "email"), chi.URLParam(r, "type")); err != nil {
+		handlePreferenceError(r.Context(), w, err)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func handlePreferenceError(ctx context.Context, w http.ResponseWriter, err error) {
+	if errors.Is(err, preferences.ErrNotFound) {
+		http.Error(w, "preference not found", http.StatusNotFound)
+
+		return
+	}
+
+	slog.ErrorContext(ctx, "error handling preference", logging.Error(err))
+	http.Error(w, "internal error", http.StatusInternalServerError)
+}
@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"user_news_api/handler/mocks"
+	"user_news_api/preferences"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreferences(t *testing.T) {
+	preference := preferences.Preference{
+		MessageType: "Marketing",
+		OptedIn:     false,
+		UpdatedAt:   time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+	}
+	optedOut := mock.MatchedBy(func(p preferences.Preference) bool {
+		return p.MessageType == "Marketing" && !p.OptedIn && !p.UpdatedAt.IsZero()
+	})
+
+	tests := []struct {
+		name           string
+		method         string
+		target         string
+		body           string
+		setupMocks     func(store *mocks.PreferenceStore, messageTypes *mocks.MessageTypes)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:   "List preferences",
+			method: http.MethodGet,
+			target: "/users/user@example.com/preferences",
+			setupMocks: func(store *mocks.PreferenceStore, messageTypes *mocks.MessageTypes) {
+				store.On("List", mock.Anything, "user@example.com").Return([]preferences.Preference{preference}, nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"preferences":[{"message_type":"Marketing","opted_in":false,` +
+				`"updated_at":"2024-01-01T09:00:00Z"}]}`,
+		},
+		{
+			name:   "Get preference",
+			method: http.MethodGet,
+			target: "/users/user@example.com/preferences/Marketing",
+			setupMocks: func(store *mocks.PreferenceStore, messageTypes *mocks.MessageTypes) {
+				store.On("Get", mock.Anything, "user@example.com", "Marketing").Return(preference, nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"message_type":"Marketing","opted_in":false,"updated_at":"2024-01-01T09:00:00Z"}`,
+		},
+		{
+			name:   "Get missing preference",
+			method: http.MethodGet,
+			target: "/users/user@example.com/preferences/News",
+			setupMocks: func(store *mocks.PreferenceStore, messageTypes *mocks.MessageTypes) {
+				store.On("Get", mock.Anything, "user@example.com", "News").
+					Return(preferences.Preference{}, fmt.Errorf("%w: News", preferences.ErrNotFound)).Once()
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "preference not found",
+		},
+		{
+			name:   "Opt out",
+			method: http.MethodPut,
+			target: "/users/user@example.com/preferences/Marketing",
+			body:   `{"opted_in":false}`,
+			setupMocks: func(store *mocks.PreferenceStore, messageTypes *mocks.MessageTypes) {
+				messageTypes.On("Valid", "Marketing").Return(true).Once()
+				messageTypes.On("Transactional", "Marketing").Return(false).Once()
+				store.On("Set", mock.Anything, "user@example.com", optedOut).Return(nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"message_type":"Marketing","opted_in":false,`,
+		},
+		{
+			name:   "Opt out of a transactional message type",
+			method: http.MethodPut,
+			target: "/users/user@example.com/preferences/Status",
+			body:   `{"opted_in":false}`,
+			setupMocks: func(store *mocks.PreferenceStore, messageTypes *mocks.MessageTypes) {
+				messageTypes.On("Valid", "Status").Return(true).Once()
+				messageTypes.On("Transactional", "Status").Return(true).Once()
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "transactional message types cannot be opted out of",
+		},
+		{
+			name:   "Invalid message type",
+			method: http.MethodPut,
+			target: "/users/user@example.com/preferences/Other",
+			body:   `{"opted_in":true}`,
+			setupMocks: func(store *mocks.PreferenceStore, messageTypes *mocks.MessageTypes) {
+				messageTypes.On("Valid", "Other").Return(false).Once()
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "message type not valid",
+		},
+		{
+			name:   "Missing opted_in",
+			method: http.MethodPut,
+			target: "/users/user@example.com/preferences/Marketing",
+			body:   `{}`,
+			setupMocks: func(store *mocks.PreferenceStore, messageTypes *mocks.MessageTypes) {
+				messageTypes.On("Valid", "Marketing").Return(true).Once()
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "request validation fails due to",
+		},
+		{
+			name:   "Set error",
+			method: http.MethodPut,
+			target: "/users/user@example.com/preferences/Marketing",
+			body:   `{"opted_in":false}`,
+			setupMocks: func(store *mocks.PreferenceStore, messageTypes *mocks.MessageTypes) {
+				messageTypes.On("Valid", "Marketing").Return(true).Once()
+				messageTypes.On("Transactional", "Marketing").Return(false).Once()
+				store.On("Set", mock.Anything, "user@example.com", optedOut).Return(errors.New("error")).Once()
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   "internal error",
+		},
+		{
+			name:   "Delete preference",
+			method: http.MethodDelete,
+			target: "/users/user@example.com/preferences/Marketing",
+			setupMocks: func(store *mocks.PreferenceStore, messageTypes *mocks.MessageTypes) {
+				store.On("Delete", mock.Anything, "user@example.com", "Marketing").Return(nil).Once()
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := mocks.NewPreferenceStore(t)
+			mockMessageTypes := mocks.NewMessageTypes(t)
+
+			tt.setupMocks(mockStore, mockMessageTypes)
+
+			router := chi.NewRouter()
+			SetPreferencesController(router, mockStore, mockMessageTypes)
+
+			req := httptest.NewRequest(tt.method, tt.target, bytes.NewBufferString(tt.body))
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			assert.Contains(t, string(body), tt.expectedBody)
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"user_news_api/logging"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// requestIDPattern keeps the IDs of the callers out of the logs when they could break them.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._\-]{1,128}$`)
+
+// RequestID propagates the X-Request-ID header of the request, or generates it when it is missing or not valid.
+// The ID is answered in the same header, and it is added to the logs and the span of the request.
+func RequestID() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(requestIDHeader)
+			if !requestIDPattern.MatchString(id) {
+				id = newRequestID()
+			}
+
+			w.Header().Set(requestIDHeader, id)
+			trace.SpanFromContext(r.Context()).SetAttributes(attribute.String(logging.KeyRequestID, id))
+
+			next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+		})
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+
+	// crypto/rand does not fail on the supported platforms
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"user_news_api/logging"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name        string
+		requestID   string
+		expectedNew bool
+	}{
+		{
+			name:      "Request ID of the caller",
+			requestID: "caller-id.1",
+		},
+		{
+			name:        "Missing request ID",
+			expectedNew: true,
+		},
+		{
+			name:        "Request ID breaking the logs",
+			requestID:   "id\nlevel=ERROR",
+			expectedNew: true,
+		},
+		{
+			name:        "Too long request ID",
+			requestID:   strings.Repeat("a", 129),
+			expectedNew: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var handled string
+
+			handler := RequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				handled = logging.RequestID(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/notifications", nil)
+			if tt.requestID != "" {
+				req.Header.Set(requestIDHeader, tt.requestID)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			answered := w.Header().Get(requestIDHeader)
+			assert.Equal(t, handled, answered)
+
+			if tt.expectedNew {
+				assert.Regexp(t, "^[0-9a-f]{32}$", answered)
+			} else {
+				assert.Equal(t, tt.requestID, answered)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"user_news_api/notifier"
+	"user_news_api/ratelimiter"
+	"user_news_api/services"
+	servicemocks "user_news_api/services/mocks"
+	"user_news_api/smtptest"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNotifyUserSMTP goes through the whole HTTP path, from the request to the SMTP dialogue,
+// with only the Redis stores mocked.
+func TestNotifyUserSMTP(t *testing.T) {
+	tests := []struct {
+		name           string
+		failures       []smtptest.Failure
+		expectedStatus int
+		expectedBody   string
+		sent           bool
+	}{
+		{
+			name:           "Notification sent",
+			expectedStatus: http.StatusOK,
+			sent:           true,
+		},
+		{
+			name:           "Recipient rejected by the mail server",
+			failures:       []smtptest.Failure{{Command: "RCPT", Code: 550, Text: "no such user"}},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "notification rejected by the mail server",
+		},
+		{
+			name:           "Mail server not answering",
+			failures:       []smtptest.Failure{{Command: smtptest.DataEnd, Hang: true}},
+			expectedStatus: http.StatusGatewayTimeout,
+			expectedBody:   "gateway timeout",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := smtptest.NewServer(smtptest.Options{Username: "sender@example.com", Password: "secret", TLS: true})
+			defer server.Close()
+
+			server.Fail(tt.failures...)
+
+			dialer := server.Dialer()
+			dialer.Timeout = 200 * time.Millisecond
+
+			mockLimiter := servicemocks.NewLimiter(t)
+			mockRecords := servicemocks.NewRecords(t)
+
+			mockLimiter.On("Valid", ratelimiter.NewsType).Return(true).Once()
+			mockLimiter.On("Reached", mock.Anything, "user@example.com", ratelimiter.NewsType).Return(false, nil).Once()
+			mockRecords.On("Create", mock.Anything, mock.Anything).Return(nil).Once()
+			mockRecords.On("MarkSending", mock.Anything, mock.Anything).Return(nil).Once()
+
+			if tt.sent {
+				mockRecords.On("MarkSent", mock.Anything, mock.Anything, "primary").Return(nil).Once()
+			} else {
+				mockRecords.On("MarkFailed", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+			}
+
+			provider := notifier.Provider{
+				Name:   "primary",
+				Sender: notifier.NewClient(notifier.Options{Username: "sender@example.com", Dialer: dialer}),
+			}
+
+			serv := services.NewUserNotifier(
+				mockLimiter,
+				notifier.NewFailoverClient([]notifier.Provider{provider}, notifier.DefaultFailoverOptions),
+				servicemocks.NewQueue(t),
+				mockRecords,
+				servicemocks.NewSchedules(t),
+			)
+
+			router := chi.NewRouter()
+			SetUserController(router, serv, false)
+
+			req := httptest.NewRequest(http.MethodPost, "/notifications",
+				bytes.NewBufferString(`{"user_email":"user@example.com","message_type":"News"}`))
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			assert.Contains(t, string(body), tt.expectedBody)
+
+			messages := server.Messages()
+			if !tt.sent {
+				assert.Empty(t, messages)
+
+				return
+			}
+
+			require.Len(t, messages, 1)
+			assert.Equal(t, []string{"user@example.com"}, messages[0].To)
+			assert.True(t, messages[0].TLS)
+			assert.Contains(t, string(messages[0].Data), "Subject: Notification")
+		})
+	}
+}
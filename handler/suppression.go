@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"user_news_api/logging"
+	"user_news_api/suppression"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator"
+)
+
+const (
+	defaultSuppressionsLimit = 100
+	maxSuppressionsLimit     = 1000
+)
+
+// SuppressionStore is an abstraction for suppression.Store making it mockeable
+type SuppressionStore interface {
+	Add(context.Context, suppression.Entry) error
+	Remove(context.Context, string) error
+	List(context.Context, int64) ([]suppression.Entry, error)
+}
+
+// SetSuppressionController registers the admin routes of the suppression list.
+func SetSuppressionController(router chi.Router, store SuppressionStore) {
+	controller := &SuppressionController{store: store}
+
+	router.Get("/admin/suppressions", controller.handleList)
+	router.Post("/admin/suppressions", controller.handleAdd)
+	router.Delete("/admin/suppressions/{email}", controller.handleRemove)
+}
+
+type SuppressionController struct {
+	store SuppressionStore
+}
+
+type SuppressRequestPayload struct {
+	Email  string `json:"email" validate:"required,email"`
+	Reason string `json:"reason" validate:"omitempty,oneof=bounce complaint manual"` // Reason is manual by default
+	Detail string `json:"detail,omitempty"`
+}
+
+type SuppressionsResponse struct {
+	Suppressions []suppression.Entry `json:"suppressions"`
+}
+
+func (sc *SuppressionController) handleList(w http.ResponseWriter, r *http.Request) {
+	limit := int64(defaultSuppressionsLimit)
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.ParseInt(limitStr, 10, 64)
+		if err != nil || parsed < 1 || parsed > maxSuppressionsLimit {
+			http.Error(w, fmt.Sprintf("limit must be a number between 1 and %d", maxSuppressionsLimit), http.StatusBadRequest)
+
+			return
+		}
+
+		limit = parsed
+	}
+
+	entries, err := sc.store.List(r.Context(), limit)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error listing suppressed addresses", logging.Error(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(SuppressionsResponse{Suppressions: entries})
+}
+
+func (sc *SuppressionController) handleAdd(w http.ResponseWriter, r *http.Request) {
+	var payload SuppressRequestPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, fmt.Sprintf("error marshalling request body due to: %s", err.Error()), http.StatusBadRequest)
+
+		return
+	}
+
+	if err := validator.New().Struct(payload); err != nil {
+		http.Error(w, fmt.Sprintf("request validation fails due to: %s", err.Error()), http.StatusBadRequest)
+
+		return
+	}
+
+	if payload.Reason == "" {
+		payload.Reason = suppression.ReasonManual
+	}
+
+	err := sc.store.Add(r.Context(), suppression.Entry{
+		Email:     payload.Email,
+		Reason:    payload.Reason,
+		Detail:    payload.Detail,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error suppressing address", logging.Error(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (sc *SuppressionController) handleRemove(w http.ResponseWriter, r *http.Request) {
+	if err := sc.store.Remove(r.Context(), chi.URLParam(r, "email")); err != nil {
+		if errors.Is(err, suppression.ErrNotFound) {
+			http.Error(w, "address not suppressed", http.StatusNotFound)
+
+			return
+		}
+
+		slog.ErrorContext(r.Context(), "error removing suppressed address", logging.Error(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"user_news_api/handler/mocks"
+	"user_news_api/suppression"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuppressions(t *testing.T) {
+	entry := suppression.Entry{
+		Email:     "user@example.com",
+		Reason:    suppression.ReasonBounce,
+		Detail:    "550 no such user",
+		CreatedAt: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		target         string
+		body           string
+		setupMocks     func(store *mocks.SuppressionStore)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:   "List suppressed addresses",
+			method: http.MethodGet,
+			target: "/admin/suppressions?limit=10",
+			setupMocks: func(store *mocks.SuppressionStore) {
+				store.On("List", mock.Anything, int64(10)).Return([]suppression.Entry{entry}, nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"suppressions":[{"email":"user@example.com","reason":"bounce","detail":"550 no such user",` +
+				`"created_at":"2024-01-01T09:00:00Z"}]}`,
+		},
+		{
+			name:           "List with invalid limit",
+			method:         http.MethodGet,
+			target:         "/admin/suppressions?limit=1001",
+			setupMocks:     func(store *mocks.SuppressionStore) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "limit must be a number between 1 and 1000",
+		},
+		{
+			name:   "List error",
+			method: http.MethodGet,
+			target: "/admin/suppressions",
+			setupMocks: func(store *mocks.SuppressionStore) {
+				store.On("List", mock.Anything, int64(100)).Return(nil, errors.New("error")).Once()
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   "internal error",
+		},
+		{
+			name:   "Suppress address manually",
+			method: http.MethodPost,
+			target: "/admin/suppressions",
+			body:   `{"email":"user@example.com"}`,
+			setupMocks: func(store *mocks.SuppressionStore) {
+				store.On("Add", mock.Anything, mock.MatchedBy(func(e suppression.Entry) bool {
+					return e.Email == "user@example.com" && e.Reason == suppression.ReasonManual && !e.CreatedAt.IsZero()
+				})).Return(nil).Once()
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:   "Suppress address after a complaint",
+			method: http.MethodPost,
+			target: "/admin/suppressions",
+			body:   `{"email":"user@example.com","reason":"complaint","detail":"reported as spam"}`,
+			setupMocks: func(store *mocks.SuppressionStore) {
+				store.On("Add", mock.Anything, mock.MatchedBy(func(e suppression.Entry) bool {
+					return e.Reason == suppression.ReasonComplaint && e.Detail == "reported as spam"
+				})).Return(nil).Once()
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Unknown reason",
+			method:         http.MethodPost,
+			target:         "/admin/suppressions",
+			body:           `{"email":"user@example.com","reason":"other"}`,
+			setupMocks:     func(store *mocks.SuppressionStore) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "request validation fails due to",
+		},
+		{
+			name:           "Invalid email",
+			method:         http.MethodPost,
+			target:         "/admin/suppressions",
+			body:           `{"email":"user"}`,
+			setupMocks:     func(store *mocks.SuppressionStore) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "request validation fails due to",
+		},
+		{
+			name:   "Remove suppressed address",
+			method: http.MethodDelete,
+			target: "/admin/suppressions/user@example.com",
+			setupMocks: func(store *mocks.SuppressionStore) {
+				store.On("Remove", mock.Anything, "user@example.com").Return(nil).Once()
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:   "Remove address not suppressed",
+			method: http.MethodDelete,
+			target: "/admin/suppressions/user@example.com",
+			setupMocks: func(store *mocks.SuppressionStore) {
+				store.On("Remove", mock.Anything, "user@example.com").
+					Return(fmt.Errorf("%w: user@example.com", suppression.ErrNotFound)).Once()
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "address not suppressed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := mocks.NewSuppressionStore(t)
+
+			tt.setupMocks(mockStore)
+
+			router := chi.NewRouter()
+			SetSuppressionController(router, mockStore)
+
+			req := httptest.NewRequest(tt.method, tt.target, bytes.NewBufferString(tt.body))
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			assert.Contains(t, string(body), tt.expectedBody)
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing(t *testing.T) {
+	const (
+		traceID  = "4bf92f3577b34da6a3ce929d0e0e4736"
+		parentID = "00f067aa0ba902b7"
+	)
+
+	tests := []struct {
+		name           string
+		target         string
+		traceparent    string
+		expectedName   string
+		expectedCode   int
+		expectedStatus codes.Code
+	}{
+		{
+			name:           "Route with parameters",
+			target:         "/notifications/abc",
+			expectedName:   "GET /notifications/{id}",
+			expectedCode:   http.StatusOK,
+			expectedStatus: codes.Unset,
+		},
+		{
+			name:           "Trace of the caller",
+			target:         "/notifications/abc",
+			traceparent:    "00-" + traceID + "-" + parentID + "-01",
+			expectedName:   "GET /notifications/{id}",
+			expectedCode:   http.StatusOK,
+			expectedStatus: codes.Unset,
+		},
+		{
+			name:           "Server error",
+			target:         "/failing",
+			expectedName:   "GET /failing",
+			expectedCode:   http.StatusInternalServerError,
+			expectedStatus: codes.Error,
+		},
+		{
+			name:           "Unknown route",
+			target:         "/unknown",
+			expectedName:   "GET " + unmatchedRoute,
+			expectedCode:   http.StatusNotFound,
+			expectedStatus: codes.Unset,
+		},
+	}
+
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+			router := chi.NewRouter()
+			router.Use(Tracing())
+			router.Get("/notifications/{id}", func(w http.ResponseWriter, r *http.Request) {
+				// the span of the request is available to the handlers
+				assert.True(t, trace.SpanFromContext(r.Context()).SpanContext().IsValid())
+			})
+			router.Get("/failing", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.traceparent != "" {
+				req.Header.Set("traceparent", tt.traceparent)
+			}
+
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			ended := recorder.Ended()
+			require.Len(t, ended, 1)
+
+			span := ended[0]
+			assert.Equal(t, tt.expectedName, span.Name())
+			assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+			assert.Equal(t, tt.expectedStatus, span.Status().Code)
+			assert.Contains(t, span.Attributes(), attribute.Int("http.response.status_code", tt.expectedCode))
+
+			if tt.traceparent != "" {
+				assert.Equal(t, traceID, span.SpanContext().TraceID().String())
+				assert.Equal(t, parentID, span.Parent().SpanID().String())
+			} else {
+				assert.False(t, span.Parent().IsValid())
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"context"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"time"
+	"user_news_api/logging"
+	"user_news_api/preferences"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// UnsubscribeTokens is an abstraction for unsubscribe.Signer making it mockeable
+type UnsubscribeTokens interface {
+	Verify(string) (string, string, error)
+}
+
+// SetUnsubscribeController registers the public unsubscribe routes, the links of the List-Unsubscribe header.
+// GET only asks for confirmation, because link scanners open the links of the mails,
+// while POST is the RFC 8058 one-click unsubscribe sent by the mail clients.
+func SetUnsubscribeController(
+	router chi.Router, tokens UnsubscribeTokens, store PreferenceStore, messageTypes MessageTypes,
+) {
+	controller := &UnsubscribeController{tokens: tokens, store: store, messageTypes: messageTypes}
+
+	router.Get("/unsubscribe", controller.handleConfirm)
+	router.Post("/unsubscribe", controller.handleUnsubscribe)
+}
+
+type UnsubscribeController struct {
+	tokens       UnsubscribeTokens
+	store        PreferenceStore
+	messageTypes MessageTypes
+}
+
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html>
+<body>
+{{- if .Confirm}}
+    <form method="post" action="/unsubscribe?token={{.Token}}">
+        <p>Stop receiving {{.MessageType}} notifications at {{.User}}?</p>
+        <button type="submit">Unsubscribe</button>
+    </form>
+{{- else}}
+    <p>{{.User}} will not receive {{.MessageType}} notifications anymore.</p>
+{{- end}}
+</body>
+</html>
+`))
+
+type unsubscribePageData struct {
+	Confirm     bool
+	Token       string
+	User        string
+	MessageType string
+}
+
+func (uc *UnsubscribeController) handleConfirm(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+
+	user, messageType, err := uc.tokens.Verify(token)
+	if err != nil {
+		http.Error(w, "invalid unsubscribe link", http.StatusBadRequest)
+
+		return
+	}
+
+	writeUnsubscribePage(r.Context(), w, unsubscribePageData{Confirm: true, Token: token, User: user, MessageType: messageType})
+}
+
+func (uc *UnsubscribeController) handleUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	// the token is in the query of the List-Unsubscribe link, FormValue reads it from there too
+	user, messageType, err := uc.tokens.Verify(r.FormValue("token"))
+	if err != nil {
+		http.Error(w, "invalid unsubscribe link", http.StatusBadRequest)
+
+		return
+	}
+
+	if !uc.messageTypes.Valid(messageType) || uc.messageTypes.Transactional(messageType) {
+		http.Error(w, "this message type cannot be unsubscribed from", http.StatusUnprocessableEntity)
+
+		return
+	}
+
+	err = uc.store.Set(r.Context(), user, preferences.Preference{
+		MessageType: messageType,
+		OptedIn:     false,
+		UpdatedAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error unsubscribing user", logging.Error(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+
+		return
+	}
+
+	writeUnsubscribePage(r.Context(), w, unsubscribePageData{User: user, MessageType: messageType})
+}
+
+func writeUnsubscribePage(ctx context.Context, w http.ResponseWriter, data unsubscribePageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if err := unsubscribePage.Execute(w, data); err != nil {
+		slog.ErrorContext(ctx, "error rendering unsubscribe page", logging.Error(err))
+	}
+}
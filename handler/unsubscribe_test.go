@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"user_news_api/handler/mocks"
+	"user_news_api/preferences"
+	"user_news_api/unsubscribe"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnsubscribe(t *testing.T) {
+	optedOut := mock.MatchedBy(func(p preferences.Preference) bool {
+		return p.MessageType == "Marketing" && !p.OptedIn && !p.UpdatedAt.IsZero()
+	})
+
+	tests := []struct {
+		name           string
+		method         string
+		target         string
+		contentType    string
+		body           string
+		setupMocks     func(*mocks.UnsubscribeTokens, *mocks.PreferenceStore, *mocks.MessageTypes)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:   "Confirmation page",
+			method: http.MethodGet,
+			target: "/unsubscribe?token=abc.def",
+			setupMocks: func(mt *mocks.UnsubscribeTokens, ms *mocks.PreferenceStore, mm *mocks.MessageTypes) {
+				mt.On("Verify", "abc.def").Return("user@example.com", "Marketing", nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `<form method="post" action="/unsubscribe?token=abc.def">`,
+		},
+		{
+			name:   "Confirmation page with invalid token",
+			method: http.MethodGet,
+			target: "/unsubscribe?token=forged",
+			setupMocks: func(mt *mocks.UnsubscribeTokens, ms *mocks.PreferenceStore, mm *mocks.MessageTypes) {
+				mt.On("Verify", "forged").Return("", "", unsubscribe.ErrInvalidToken).Once()
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "invalid unsubscribe link",
+		},
+		{
+			name:        "One-click unsubscribe",
+			method:      http.MethodPost,
+			target:      "/unsubscribe?token=abc.def",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "List-Unsubscribe=One-Click",
+			setupMocks: func(mt *mocks.UnsubscribeTokens, ms *mocks.PreferenceStore, mm *mocks.MessageTypes) {
+				mt.On("Verify", "abc.def").Return("user@example.com", "Marketing", nil).Once()
+				mm.On("Valid", "Marketing").Return(true).Once()
+				mm.On("Transactional", "Marketing").Return(false).Once()
+				ms.On("Set", mock.Anything, "user@example.com", optedOut).Return(nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "user@example.com will not receive Marketing notifications anymore.",
+		},
+		{
+			name:        "Unsubscribe with invalid token",
+			method:      http.MethodPost,
+			target:      "/unsubscribe",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "token=forged",
+			setupMocks: func(mt *mocks.UnsubscribeTokens, ms *mocks.PreferenceStore, mm *mocks.MessageTypes) {
+				mt.On("Verify", "forged").Return("", "", unsubscribe.ErrInvalidToken).Once()
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "invalid unsubscribe link",
+		},
+		{
+			name:   "Unsubscribe from a transactional message type",
+			method: http.MethodPost,
+			target: "/unsubscribe?token=abc.def",
+			setupMocks: func(mt *mocks.UnsubscribeTokens, ms *mocks.PreferenceStore, mm *mocks.MessageTypes) {
+				mt.On("Verify", "abc.def").Return("user@example.com", "Status", nil).Once()
+				mm.On("Valid", "Status").Return(true).Once()
+				mm.On("Transactional", "Status").Return(true).Once()
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "this message type cannot be unsubscribed from",
+		},
+		{
+			name:   "Preferences error",
+			method: http.MethodPost,
+			target: "/unsubscribe?token=abc.def",
+			setupMocks: func(mt *mocks.UnsubscribeTokens, ms *mocks.PreferenceStore, mm *mocks.MessageTypes) {
+				mt.On("Verify", "abc.def").Return("user@example.com", "Marketing", nil).Once()
+				mm.On("Valid", "Marketing").Return(true).Once()
+				mm.On("Transactional", "Marketing").Return(false).Once()
+				ms.On("Set", mock.Anything, "user@example.com", optedOut).Return(errors.New("error")).Once()
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   "internal error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockTokens := mocks.NewUnsubscribeTokens(t)
+			mockStore := mocks.NewPreferenceStore(t)
+			mockMessageTypes := mocks.NewMessageTypes(t)
+
+			tt.setupMocks(mockTokens, mockStore, mockMessageTypes)
+
+			router := chi.NewRouter()
+			SetUnsubscribeController(router, mockTokens, mockStore, mockMessageTypes)
+
+			req := httptest.NewRequest(tt.method, tt.target, bytes.NewBufferString(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			assert.Contains(t, string(body), tt.expectedBody)
+		})
+	}
+}
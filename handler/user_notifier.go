@@ -5,41 +5,97 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"time"
+	"user_news_api/channel"
+	"user_news_api/delivery"
+	"user_news_api/logging"
+	"user_news_api/notifier"
 	"user_news_api/ratelimiter"
+	"user_news_api/schedule"
 	"user_news_api/services"
+	"user_news_api/tracing"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-playground/validator"
 )
 
 func (uc *UserController) registerRoutes(router chi.Router) {
 	router.Post("/notifications", uc.handleNotifyUser)
+	router.Post("/notifications/batch", uc.handleNotifyBatch)
+	router.Get("/notifications/scheduled", uc.handleListScheduled)
+	router.Delete("/notifications/scheduled/{id}", uc.handleCancelScheduled)
+	router.Get("/notifications/{id}", uc.handleGetNotification)
 }
 
+const (
+	defaultScheduledLimit = 100
+	maxScheduledLimit     = 1000
+)
+
+// notificationIDHeader carries the ID of every recorded notification, so its status can be polled.
+const notificationIDHeader = "X-Notification-ID"
+
 // UserNotifier is an abstraction for services.UserNotifierService making it mockeable
 type UserNotifier interface {
-	Notify(context.Context, string, string) error
+	Notify(context.Context, string, string) (string, error)
+	Enqueue(context.Context, string, string) (string, error)
+	Status(context.Context, string) (delivery.Record, error)
+	NotifyBatch(context.Context, []services.BatchItem) []services.BatchResult
+	EnqueueBatch(context.Context, []services.BatchItem) []services.BatchResult
+	Schedule(context.Context, string, string, time.Time) (string, error)
+	Cancel(context.Context, string) error
+	Scheduled(context.Context, int64) ([]schedule.Item, error)
+	Check(context.Context, string, string, string) error
 }
 
-func SetUserController(router chi.Router, service UserNotifier) {
-	controller := &UserController{service: service}
+// SetUserController registers the notification routes.
+// When async is true the notifications are enqueued and answered with 202 instead of being sent within the request.
+func SetUserController(router chi.Router, service UserNotifier, async bool) {
+	controller := &UserController{service: service, async: async}
 
 	controller.registerRoutes(router)
 }
 
 type UserController struct {
 	service UserNotifier
+	async   bool
 }
 
 type NotifyUserRequestPayload struct {
-	UserEmail   string `json:"user_email" validate:"required,email"`
-	MessageType string `json:"message_type" validate:"required"`
+	UserEmail   string     `json:"user_email" validate:"required,email"`
+	MessageType string     `json:"message_type" validate:"required"`
+	SendAt      *time.Time `json:"send_at,omitempty"`  // SendAt delays the notification, it is sent right away when it is in the past
+	Channels    []string   `json:"channels,omitempty"` // Channels sends the notification through each of them, by default by email
+}
+
+type NotifyUserAcceptedResponse struct {
+	ID     string     `json:"id"`
+	SendAt *time.Time `json:"send_at,omitempty"` // SendAt is when the notification is planned to be sent, if it was delayed
+}
+
+type ScheduledNotificationsResponse struct {
+	Notifications []schedule.Item `json:"notifications"`
 }
 
 func (uc *UserController) handleNotifyUser(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "UserController.handleNotifyUser")
+	defer span.End()
+
+	r = r.WithContext(ctx)
+
 	var payload NotifyUserRequestPayload
+
+	start := time.Now()
+	ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+	defer func() {
+		logNotify(ctx, payload, ww.Header().Get(notificationIDHeader), ww.Status(), time.Since(start))
+	}()
+
+	w = ww
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
 		http.Error(w, fmt.Sprintf("error marshalling request body due to: %s", err.Error()), http.StatusBadRequest)
 
@@ -52,24 +108,335 @@ func (uc *UserController) handleNotifyUser(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if err := uc.service.Notify(r.Context(), payload.UserEmail, payload.MessageType); err != nil {
-		if errors.Is(err, services.ErrLimitExceeded) {
-			http.Error(w, "too many requests", http.StatusTooManyRequests)
+	if !messageTypeAllowed(r.Context(), payload.MessageType) {
+		http.Error(w, errMessageTypeNotAllowed.Error(), http.StatusForbidden)
+
+		return
+	}
+
+	if len(payload.Channels) > 0 {
+		uc.notifyChannels(w, r, payload)
+
+		return
+	}
+
+	// the notification is checked before charging the client, so a rejected one does not use up its rate limit
+	if err := uc.service.Check(r.Context(), payload.UserEmail, payload.MessageType, ""); err != nil {
+		handleNotifyError(r.Context(), w, err)
+
+		return
+	}
+
+	if !chargeClient(w, r, 1) {
+		return
+	}
+
+	if payload.SendAt != nil && payload.SendAt.After(time.Now()) {
+		id, err := uc.service.Schedule(r.Context(), payload.UserEmail, payload.MessageType, *payload.SendAt)
+		setNotificationID(w, id)
+
+		if err != nil {
+			handleNotifyError(r.Context(), w, err)
+
+			return
+		}
+
+		writeAccepted(w, id, payload.SendAt)
+
+		return
+	}
+
+	if uc.async {
+		id, err := uc.service.Enqueue(r.Context(), payload.UserEmail, payload.MessageType)
+		setNotificationID(w, id)
+
+		// dropped notifications are answered as if they were sent
+		if errors.Is(err, services.ErrDropped) {
+			err = nil
+		}
+
+		if accepted, sendAt := notifyAccepted(err); accepted {
+			writeAccepted(w, id, sendAt)
 
 			return
 		}
 
-		if errors.Is(err, ratelimiter.ErrMessageTypeNotValid) {
-			http.Error(w, "message type not valid", http.StatusBadRequest)
+		if err != nil {
+			handleNotifyError(r.Context(), w, err)
 
 			return
 		}
 
-		log.Printf("error notifying user: %s", err.Error())
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		writeAccepted(w, id, nil)
+
+		return
+	}
+
+	id, err := uc.service.Notify(r.Context(), payload.UserEmail, payload.MessageType)
+	setNotificationID(w, id)
+
+	if errors.Is(err, services.ErrDropped) {
+		err = nil
+	}
+
+	if accepted, sendAt := notifyAccepted(err); accepted {
+		writeAccepted(w, id, sendAt)
+
+		return
+	}
+
+	if err != nil {
+		handleNotifyError(r.Context(), w, err)
 
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
+
+// notifyChannels sends the notification through every channel as a batch, so each one gets its own notification ID
+// and rate limit. The response has a result per channel, in the same order.
+func (uc *UserController) notifyChannels(w http.ResponseWriter, r *http.Request, payload NotifyUserRequestPayload) {
+	if payload.SendAt != nil {
+		http.Error(w, errChannelsScheduled.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	results := make([]NotifyBatchItemResult, len(payload.Channels))
+
+	// the channels rejected by the checks are answered without charging the client for them
+	var items []services.BatchItem
+	var positions []int
+
+	for i, channelName := range payload.Channels {
+		if err := uc.service.Check(r.Context(), payload.UserEmail, payload.MessageType, channelName); err != nil {
+			results[i] = batchItemResult(r.Context(), i, services.BatchResult{Err: err}, uc.async)
+			results[i].Channel = channelName
+
+			continue
+		}
+
+		items = append(items, services.BatchItem{
+			UserEmail:   payload.UserEmail,
+			MessageType: payload.MessageType,
+			Channel:     channelName,
+		})
+		positions = append(positions, i)
+	}
+
+	// every channel is a notification of its own for the rate limit of the client
+	if len(items) > 0 && !chargeClient(w, r, int64(len(items))) {
+		return
+	}
+
+	if len(items) > 0 {
+		var batchResults []services.BatchResult
+		if uc.async {
+			batchResults = uc.service.EnqueueBatch(r.Context(), items)
+		} else {
+			batchResults = uc.service.NotifyBatch(r.Context(), items)
+		}
+
+		for j, result := range batchResults {
+			i := positions[j]
+			results[i] = batchItemResult(r.Context(), i, result, uc.async)
+			results[i].Channel = payload.Channels[i]
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(NotifyBatchResponse{Results: results})
+}
+
+func (uc *UserController) handleGetNotification(w http.ResponseWriter, r *http.Request) {
+	record, err := uc.service.Status(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, delivery.ErrNotFound) {
+			http.Error(w, "notification not found", http.StatusNotFound)
+
+			return
+		}
+
+		slog.ErrorContext(r.Context(), "error getting notification", logging.Error(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(record)
+}
+
+func (uc *UserController) handleListScheduled(w http.ResponseWriter, r *http.Request) {
+	limit := int64(defaultScheduledLimit)
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.ParseInt(limitStr, 10, 64)
+		if err != nil || parsed < 1 || parsed > maxScheduledLimit {
+			http.Error(w, fmt.Sprintf("limit must be a number between 1 and %d", maxScheduledLimit), http.StatusBadRequest)
+
+			return
+		}
+
+		limit = parsed
+	}
+
+	items, err := uc.service.Scheduled(r.Context(), limit)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error listing scheduled notifications", logging.Error(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(ScheduledNotificationsResponse{Notifications: items})
+}
+
+func (uc *UserController) handleCancelScheduled(w http.ResponseWriter, r *http.Request) {
+	if err := uc.service.Cancel(r.Context(), chi.URLParam(r, "id")); err != nil {
+		if errors.Is(err, schedule.ErrNotFound) {
+			http.Error(w, "scheduled notification not found", http.StatusNotFound)
+
+			return
+		}
+
+		slog.ErrorContext(r.Context(), "error canceling scheduled notification", logging.Error(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Outcomes of the notification requests, see logNotify.
+const (
+	outcomeSent     = "sent"
+	outcomeAccepted = "accepted"
+	outcomeRejected = "rejected"
+	outcomeFailed   = "failed"
+)
+
+// logNotify logs a notification request once it is answered, the server errors at error level.
+func logNotify(ctx context.Context, payload NotifyUserRequestPayload, id string, status int, latency time.Duration) {
+	level, outcome := notifyOutcome(status)
+
+	slog.Log(ctx, level, "notification request handled",
+		slog.String(logging.KeyMessageType, payload.MessageType),
+		slog.String(logging.KeyUser, logging.MaskEmail(payload.UserEmail)),
+		slog.String(logging.KeyNotificationID, id),
+		slog.String(logging.KeyOutcome, outcome),
+		slog.Int("status", status),
+		slog.Duration(logging.KeyLatency, latency),
+	)
+}
+
+func notifyOutcome(status int) (slog.Level, string) {
+	switch {
+	case status == 0 || status == http.StatusOK:
+		return slog.LevelInfo, outcomeSent
+	case status < http.StatusBadRequest:
+		return slog.LevelInfo, outcomeAccepted
+	case status < http.StatusInternalServerError:
+		return slog.LevelInfo, outcomeRejected
+	}
+
+	return slog.LevelError, outcomeFailed
+}
+
+func writeAccepted(w http.ResponseWriter, id string, sendAt *time.Time) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(NotifyUserAcceptedResponse{ID: id, SendAt: sendAt})
+}
+
+func setNotificationID(w http.ResponseWriter, id string) {
+	if id != "" {
+		w.Header().Set(notificationIDHeader, id)
+	}
+}
+
+// notifyAccepted tells if the notification over the rate limit is sent later because of the policy of its message type,
+// returning when it is planned to be sent if it was deferred.
+func notifyAccepted(err error) (bool, *time.Time) {
+	var deferred services.DeferredError
+	if errors.As(err, &deferred) {
+		return true, &deferred.SendAt
+	}
+
+	return errors.Is(err, services.ErrDigested), nil
+}
+
+// handleNotifyError maps the service errors to HTTP responses.
+func handleNotifyError(ctx context.Context, w http.ResponseWriter, err error) {
+	status, message := notifyErrorResponse(ctx, err)
+	if status == http.StatusServiceUnavailable {
+		// the circuits of the providers are tried again once their open timeout is over
+		w.Header().Set("Retry-After", strconv.Itoa(int(notifier.DefaultFailoverOptions.OpenTimeout.Seconds())))
+	}
+
+	http.Error(w, message, status)
+}
+
+// notifyErrorResponse returns the status code and message answered for a service error.
+func notifyErrorResponse(ctx context.Context, err error) (int, string) {
+	if errors.Is(err, services.ErrLimitExceeded) {
+		return http.StatusTooManyRequests, "too many requests"
+	}
+
+	if errors.Is(err, services.ErrSuppressed) {
+		return http.StatusUnprocessableEntity, "address suppressed"
+	}
+
+	if errors.Is(err, services.ErrOptedOut) {
+		return http.StatusConflict, "user opted out of message type"
+	}
+
+	if errors.Is(err, ratelimiter.ErrMessageTypeNotValid) {
+		return http.StatusBadRequest, "message type not valid"
+	}
+
+	if errors.Is(err, channel.ErrChannelNotValid) {
+		return http.StatusBadRequest, "channel not valid"
+	}
+
+	if errors.Is(err, channel.ErrRejected) {
+		slog.WarnContext(ctx, "channel rejected notifying user", logging.Error(err))
+
+		return http.StatusUnprocessableEntity, "notification rejected by the channel"
+	}
+
+	if errors.Is(err, channel.ErrUnavailable) {
+		slog.WarnContext(ctx, "channel unavailable notifying user", logging.Error(err))
+
+		return http.StatusBadGateway, "channel unavailable"
+	}
+
+	if errors.Is(err, notifier.ErrPermanent) {
+		slog.WarnContext(ctx, "mail rejected notifying user", logging.Error(err))
+
+		return http.StatusUnprocessableEntity, "notification rejected by the mail server"
+	}
+
+	if errors.Is(err, notifier.ErrTimeout) {
+		slog.WarnContext(ctx, "timeout notifying user", logging.Error(err))
+
+		if !errors.Is(err, notifier.ErrConnectTimeout) {
+			markMaybeSent(ctx)
+		}
+
+		return http.StatusGatewayTimeout, "gateway timeout"
+	}
+
+	if errors.Is(err, notifier.ErrNoProvider) {
+		slog.WarnContext(ctx, "no mail provider available notifying user", logging.Error(err))
+
+		return http.StatusServiceUnavailable, "no mail provider available"
+	}
+
+	slog.ErrorContext(ctx, "error notifying user", logging.Error(err))
+
+	return http.StatusInternalServerError, "internal error"
+}
@@ -2,9 +2,11 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -12,18 +14,31 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
+	"user_news_api/apikey"
+	"user_news_api/channel"
+	"user_news_api/delivery"
 	"user_news_api/handler/mocks"
+	"user_news_api/jwtauth"
+	"user_news_api/notifier"
 	"user_news_api/ratelimiter"
+	"user_news_api/schedule"
 	"user_news_api/services"
 )
 
 func TestHandleNotifyUser(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+
 	tests := []struct {
 		name           string
 		payload        NotifyUserRequestPayload
+		key            *apikey.Key
+		claims         *jwtauth.Claims
+		quota          quota
 		setupMocks     func(service *mocks.UserNotifier)
 		expectedStatus int
 		expectedBody   string
+		expectedID     string
 	}{
 		{
 			name: "Valid request",
@@ -32,10 +47,11 @@ func TestHandleNotifyUser(t *testing.T) {
 				MessageType: "welcome",
 			},
 			setupMocks: func(service *mocks.UserNotifier) {
-				service.On("Notify", mock.Anything, "test@example.com", "welcome").Return(nil).Once()
+				service.On("Notify", mock.Anything, "test@example.com", "welcome").Return("some-id", nil).Once()
 			},
 			expectedStatus: http.StatusOK,
 			expectedBody:   "",
+			expectedID:     "some-id",
 		},
 		{
 			name: "Invalid email format",
@@ -65,11 +81,79 @@ func TestHandleNotifyUser(t *testing.T) {
 			},
 			setupMocks: func(service *mocks.UserNotifier) {
 				service.On("Notify", mock.Anything, "test@example.com", "welcome").
-					Return(fmt.Errorf(
+					Return("some-id", fmt.Errorf(
 						"%w: rate limit reached for user", services.ErrLimitExceeded)).Once()
 			},
 			expectedStatus: http.StatusTooManyRequests,
 			expectedBody:   "too many requests",
+			expectedID:     "some-id",
+		},
+		{
+			name: "Buffered for the next digest",
+			payload: NotifyUserRequestPayload{
+				UserEmail:   "test@example.com",
+				MessageType: "welcome",
+			},
+			setupMocks: func(service *mocks.UserNotifier) {
+				service.On("Notify", mock.Anything, "test@example.com", "welcome").
+					Return("some-id", fmt.Errorf("%w for user", services.ErrDigested)).Once()
+			},
+			expectedStatus: http.StatusAccepted,
+			expectedBody:   `{"id":"some-id"}`,
+			expectedID:     "some-id",
+		},
+		{
+			name: "Deferred until the limit resets",
+			payload: NotifyUserRequestPayload{
+				UserEmail:   "test@example.com",
+				MessageType: "welcome",
+			},
+			setupMocks: func(service *mocks.UserNotifier) {
+				service.On("Notify", mock.Anything, "test@example.com", "welcome").
+					Return("some-id", services.DeferredError{SendAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}).Once()
+			},
+			expectedStatus: http.StatusAccepted,
+			expectedBody:   `{"id":"some-id","send_at":"2024-01-02T00:00:00Z"}`,
+			expectedID:     "some-id",
+		},
+		{
+			name: "Dropped silently",
+			payload: NotifyUserRequestPayload{
+				UserEmail:   "test@example.com",
+				MessageType: "welcome",
+			},
+			setupMocks: func(service *mocks.UserNotifier) {
+				service.On("Notify", mock.Anything, "test@example.com", "welcome").
+					Return("some-id", fmt.Errorf("%w for user", services.ErrDropped)).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedID:     "some-id",
+		},
+		{
+			name: "Suppressed address",
+			payload: NotifyUserRequestPayload{
+				UserEmail:   "test@example.com",
+				MessageType: "welcome",
+			},
+			setupMocks: func(service *mocks.UserNotifier) {
+				service.On("Check", mock.Anything, "test@example.com", "welcome", "").
+					Return(fmt.Errorf("%w: user test@example.com suppressed due to bounce", services.ErrSuppressed)).Once()
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "address suppressed",
+		},
+		{
+			name: "Opted out",
+			payload: NotifyUserRequestPayload{
+				UserEmail:   "test@example.com",
+				MessageType: "welcome",
+			},
+			setupMocks: func(service *mocks.UserNotifier) {
+				service.On("Check", mock.Anything, "test@example.com", "welcome", "").
+					Return(fmt.Errorf("%w: user test@example.com opted out of message type welcome", services.ErrOptedOut)).Once()
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   "user opted out of message type",
 		},
 		{
 			name: "Service limit exceeded error",
@@ -79,12 +163,42 @@ func TestHandleNotifyUser(t *testing.T) {
 			},
 			setupMocks: func(service *mocks.UserNotifier) {
 				service.On("Notify", mock.Anything, "test@example.com", "welcome").
-					Return(fmt.Errorf(
+					Return("", fmt.Errorf(
 						"%w: error", ratelimiter.ErrMessageTypeNotValid)).Once()
 			},
 			expectedStatus: http.StatusBadRequest,
 			expectedBody:   "message type not valid",
 		},
+		{
+			name: "Notifier permanent error",
+			payload: NotifyUserRequestPayload{
+				UserEmail:   "test@example.com",
+				MessageType: "welcome",
+			},
+			setupMocks: func(service *mocks.UserNotifier) {
+				service.On("Notify", mock.Anything, "test@example.com", "welcome").
+					Return("some-id", fmt.Errorf(
+						"notifier error for user: %w", notifier.ErrPermanent)).Once()
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "notification rejected by the mail server",
+			expectedID:     "some-id",
+		},
+		{
+			name: "Notifier timeout error",
+			payload: NotifyUserRequestPayload{
+				UserEmail:   "test@example.com",
+				MessageType: "welcome",
+			},
+			setupMocks: func(service *mocks.UserNotifier) {
+				service.On("Notify", mock.Anything, "test@example.com", "welcome").
+					Return("some-id", fmt.Errorf(
+						"notifier error for user: %w", notifier.ErrTimeout)).Once()
+			},
+			expectedStatus: http.StatusGatewayTimeout,
+			expectedBody:   "gateway timeout",
+			expectedID:     "some-id",
+		},
 		{
 			name: "Service internal error",
 			payload: NotifyUserRequestPayload{
@@ -93,8 +207,191 @@ func TestHandleNotifyUser(t *testing.T) {
 			},
 			setupMocks: func(service *mocks.UserNotifier) {
 				service.On("Notify", mock.Anything, "test@example.com", "welcome").
-					Return(errors.New("internal error")).Once()
+					Return("", errors.New("internal error")).Once()
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   "internal error",
+		},
+		{
+			name: "Several channels",
+			payload: NotifyUserRequestPayload{
+				UserEmail:   "test@example.com",
+				MessageType: "welcome",
+				Channels:    []string{"email", "slack", "fax"},
+			},
+			setupMocks: func(service *mocks.UserNotifier) {
+				service.On("NotifyBatch", mock.Anything, []services.BatchItem{
+					{UserEmail: "test@example.com", MessageType: "welcome", Channel: "email"},
+					{UserEmail: "test@example.com", MessageType: "welcome", Channel: "slack"},
+					{UserEmail: "test@example.com", MessageType: "welcome", Channel: "fax"},
+				}).Return([]services.BatchResult{
+					{ID: "id-email"},
+					{ID: "id-slack", Err: fmt.Errorf("%w: rate limit reached", services.ErrLimitExceeded)},
+					{Err: fmt.Errorf("channel error: %w: fax", channel.ErrChannelNotValid)},
+				}).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"results":[` +
+				`{"index":0,"channel":"email","id":"id-email","status":200},` +
+				`{"index":1,"channel":"slack","id":"id-slack","status":429,"reason":"too many requests"},` +
+				`{"index":2,"channel":"fax","status":400,"reason":"channel not valid"}]}`,
+		},
+		{
+			name: "Channel errors",
+			payload: NotifyUserRequestPayload{
+				UserEmail:   "test@example.com",
+				MessageType: "welcome",
+				Channels:    []string{"slack", "webhook"},
+			},
+			setupMocks: func(service *mocks.UserNotifier) {
+				service.On("NotifyBatch", mock.Anything, []services.BatchItem{
+					{UserEmail: "test@example.com", MessageType: "welcome", Channel: "slack"},
+					{UserEmail: "test@example.com", MessageType: "welcome", Channel: "webhook"},
+				}).Return([]services.BatchResult{
+					{ID: "id-slack", Err: fmt.Errorf("%w: slack replied 404", channel.ErrRejected)},
+					{ID: "id-webhook", Err: fmt.Errorf("%w: webhook replied 503", channel.ErrUnavailable)},
+				}).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"results":[` +
+				`{"index":0,"channel":"slack","id":"id-slack","status":422,"reason":"notification rejected by the channel"},` +
+				`{"index":1,"channel":"webhook","id":"id-webhook","status":502,"reason":"channel unavailable"}]}`,
+		},
+		{
+			name: "Several channels cannot be scheduled",
+			payload: NotifyUserRequestPayload{
+				UserEmail:   "test@example.com",
+				MessageType: "welcome",
+				SendAt:      &future,
+				Channels:    []string{"webhook"},
+			},
+			setupMocks:     func(service *mocks.UserNotifier) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "send_at is not supported with channels",
+		},
+		{
+			name: "Message type allowed for the api key",
+			payload: NotifyUserRequestPayload{
+				UserEmail:   "test@example.com",
+				MessageType: "welcome",
+			},
+			key: &apikey.Key{ID: "abc", MessageTypes: []string{"welcome"}},
+			setupMocks: func(service *mocks.UserNotifier) {
+				service.On("Notify", mock.Anything, "test@example.com", "welcome").Return("some-id", nil).Once()
 			},
+			expectedStatus: http.StatusOK,
+			expectedID:     "some-id",
+		},
+		{
+			name: "Message type not allowed for the api key",
+			payload: NotifyUserRequestPayload{
+				UserEmail:   "test@example.com",
+				MessageType: "welcome",
+			},
+			key:            &apikey.Key{ID: "abc", MessageTypes: []string{"news"}},
+			setupMocks:     func(service *mocks.UserNotifier) {},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   "message type not allowed for the client",
+		},
+		{
+			name: "Message type granted by the token scopes",
+			payload: NotifyUserRequestPayload{
+				UserEmail:   "test@example.com",
+				MessageType: "Marketing",
+			},
+			claims: &jwtauth.Claims{Subject: "newsletter-service", Scopes: []string{"notifications:send:marketing"}},
+			setupMocks: func(service *mocks.UserNotifier) {
+				service.On("Notify", mock.Anything, "test@example.com", "Marketing").Return("some-id", nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedID:     "some-id",
+		},
+		{
+			name: "Message type not granted by the token scopes",
+			payload: NotifyUserRequestPayload{
+				UserEmail:   "test@example.com",
+				MessageType: "News",
+			},
+			claims:         &jwtauth.Claims{Subject: "newsletter-service", Scopes: []string{"notifications:send:marketing"}},
+			setupMocks:     func(service *mocks.UserNotifier) {},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   "message type not allowed for the client",
+		},
+		{
+			name: "Rate limit of the client reached",
+			payload: NotifyUserRequestPayload{
+				UserEmail:   "test@example.com",
+				MessageType: "welcome",
+			},
+			quota: func(_ context.Context, notifications int64) (bool, error) {
+				return true, nil
+			},
+			setupMocks:     func(service *mocks.UserNotifier) {},
+			expectedStatus: http.StatusTooManyRequests,
+			expectedBody:   "client rate limit exceeded",
+		},
+		{
+			name: "Every channel counts against the rate limit of the client",
+			payload: NotifyUserRequestPayload{
+				UserEmail:   "test@example.com",
+				MessageType: "welcome",
+				Channels:    []string{"email", "slack"},
+			},
+			quota: func(_ context.Context, notifications int64) (bool, error) {
+				return notifications > 1, nil
+			},
+			setupMocks:     func(service *mocks.UserNotifier) {},
+			expectedStatus: http.StatusTooManyRequests,
+			expectedBody:   "client rate limit exceeded",
+		},
+		{
+			name: "Rejected notifications do not use up the rate limit of the client",
+			payload: NotifyUserRequestPayload{
+				UserEmail:   "test@example.com",
+				MessageType: "welcome",
+			},
+			quota: func(_ context.Context, notifications int64) (bool, error) {
+				return true, nil
+			},
+			setupMocks: func(service *mocks.UserNotifier) {
+				service.On("Check", mock.Anything, "test@example.com", "welcome", "").
+					Return(fmt.Errorf("%w: user test@example.com suppressed due to bounce", services.ErrSuppressed)).Once()
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "address suppressed",
+		},
+		{
+			name: "Channels rejected by the checks are not charged to the client",
+			payload: NotifyUserRequestPayload{
+				UserEmail:   "test@example.com",
+				MessageType: "welcome",
+				Channels:    []string{"email", "fax"},
+			},
+			quota: func(_ context.Context, notifications int64) (bool, error) {
+				return notifications > 1, nil
+			},
+			setupMocks: func(service *mocks.UserNotifier) {
+				service.On("Check", mock.Anything, "test@example.com", "welcome", "fax").
+					Return(fmt.Errorf("channel error: %w: fax", channel.ErrChannelNotValid)).Once()
+				service.On("NotifyBatch", mock.Anything, []services.BatchItem{
+					{UserEmail: "test@example.com", MessageType: "welcome", Channel: "email"},
+				}).Return([]services.BatchResult{{ID: "id-email"}}).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"results":[` +
+				`{"index":0,"channel":"email","id":"id-email","status":200},` +
+				`{"index":1,"channel":"fax","status":400,"reason":"channel not valid"}]}`,
+		},
+		{
+			name: "Error checking the rate limit of the client",
+			payload: NotifyUserRequestPayload{
+				UserEmail:   "test@example.com",
+				MessageType: "welcome",
+			},
+			quota: func(_ context.Context, notifications int64) (bool, error) {
+				return false, errors.New("error")
+			},
+			setupMocks:     func(service *mocks.UserNotifier) {},
 			expectedStatus: http.StatusInternalServerError,
 			expectedBody:   "internal error",
 		},
@@ -105,6 +402,8 @@ func TestHandleNotifyUser(t *testing.T) {
 			mockService := mocks.NewUserNotifier(t)
 
 			tt.setupMocks(mockService)
+			// the checks of the service pass unless the test case fails them
+			mockService.On("Check", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
 
 			controller := &UserController{service: mockService}
 
@@ -112,6 +411,18 @@ func TestHandleNotifyUser(t *testing.T) {
 			require.NoError(t, err)
 
 			req := httptest.NewRequest(http.MethodPost, "/notify", bytes.NewBuffer(body))
+			if tt.key != nil {
+				req = req.WithContext(apikey.WithKey(req.Context(), *tt.key))
+			}
+
+			if tt.claims != nil {
+				req = req.WithContext(jwtauth.WithClaims(req.Context(), *tt.claims))
+			}
+
+			if tt.quota != nil {
+				req = req.WithContext(withQuota(req.Context(), tt.quota))
+			}
+
 			rec := httptest.NewRecorder()
 
 			controller.handleNotifyUser(rec, req)
@@ -120,6 +431,7 @@ func TestHandleNotifyUser(t *testing.T) {
 			defer res.Body.Close()
 
 			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+			assert.Equal(t, tt.expectedID, res.Header.Get(notificationIDHeader))
 
 			if tt.expectedBody != "" {
 				body, err = io.ReadAll(res.Body)
@@ -129,3 +441,365 @@ func TestHandleNotifyUser(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleNotifyUserAsync(t *testing.T) {
+	tests := []struct {
+		name           string
+		payload        NotifyUserRequestPayload
+		setupMocks     func(service *mocks.UserNotifier)
+		expectedStatus int
+		expectedBody   string
+		expectedID     string
+	}{
+		{
+			name: "Valid request",
+			payload: NotifyUserRequestPayload{
+				UserEmail:   "test@example.com",
+				MessageType: "welcome",
+			},
+			setupMocks: func(service *mocks.UserNotifier) {
+				service.On("Enqueue", mock.Anything, "test@example.com", "welcome").Return("some-id", nil).Once()
+			},
+			expectedStatus: http.StatusAccepted,
+			expectedBody:   `{"id":"some-id"}`,
+			expectedID:     "some-id",
+		},
+		{
+			name: "Invalid email format",
+			payload: NotifyUserRequestPayload{
+				UserEmail:   "invalid-email",
+				MessageType: "welcome",
+			},
+			setupMocks:     func(service *mocks.UserNotifier) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "request validation fails due to",
+		},
+		{
+			name: "Service limit exceeded error",
+			payload: NotifyUserRequestPayload{
+				UserEmail:   "test@example.com",
+				MessageType: "welcome",
+			},
+			setupMocks: func(service *mocks.UserNotifier) {
+				service.On("Enqueue", mock.Anything, "test@example.com", "welcome").
+					Return("some-id", fmt.Errorf(
+						"%w: rate limit reached for user", services.ErrLimitExceeded)).Once()
+			},
+			expectedStatus: http.StatusTooManyRequests,
+			expectedBody:   "too many requests",
+			expectedID:     "some-id",
+		},
+		{
+			name: "Buffered for the next digest",
+			payload: NotifyUserRequestPayload{
+				UserEmail:   "test@example.com",
+				MessageType: "welcome",
+			},
+			setupMocks: func(service *mocks.UserNotifier) {
+				service.On("Enqueue", mock.Anything, "test@example.com", "welcome").
+					Return("some-id", fmt.Errorf("%w for user", services.ErrDigested)).Once()
+			},
+			expectedStatus: http.StatusAccepted,
+			expectedBody:   `{"id":"some-id"}`,
+			expectedID:     "some-id",
+		},
+		{
+			name: "Deferred until the limit resets",
+			payload: NotifyUserRequestPayload{
+				UserEmail:   "test@example.com",
+				MessageType: "welcome",
+			},
+			setupMocks: func(service *mocks.UserNotifier) {
+				service.On("Enqueue", mock.Anything, "test@example.com", "welcome").
+					Return("some-id", services.DeferredError{SendAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}).Once()
+			},
+			expectedStatus: http.StatusAccepted,
+			expectedBody:   `{"id":"some-id","send_at":"2024-01-02T00:00:00Z"}`,
+			expectedID:     "some-id",
+		},
+		{
+			name: "Dropped silently",
+			payload: NotifyUserRequestPayload{
+				UserEmail:   "test@example.com",
+				MessageType: "welcome",
+			},
+			setupMocks: func(service *mocks.UserNotifier) {
+				service.On("Enqueue", mock.Anything, "test@example.com", "welcome").
+					Return("some-id", fmt.Errorf("%w for user", services.ErrDropped)).Once()
+			},
+			expectedStatus: http.StatusAccepted,
+			expectedID:     "some-id",
+		},
+		{
+			name: "Service internal error",
+			payload: NotifyUserRequestPayload{
+				UserEmail:   "test@example.com",
+				MessageType: "welcome",
+			},
+			setupMocks: func(service *mocks.UserNotifier) {
+				service.On("Enqueue", mock.Anything, "test@example.com", "welcome").
+					Return("", errors.New("queue error")).Once()
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   "internal error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := mocks.NewUserNotifier(t)
+
+			tt.setupMocks(mockService)
+			// the checks of the service pass unless the test case fails them
+			mockService.On("Check", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			controller := &UserController{service: mockService, async: true}
+
+			body, err := json.Marshal(tt.payload)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/notify", bytes.NewBuffer(body))
+			rec := httptest.NewRecorder()
+
+			controller.handleNotifyUser(rec, req)
+
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+			assert.Equal(t, tt.expectedID, res.Header.Get(notificationIDHeader))
+
+			body, err = io.ReadAll(res.Body)
+			require.NoError(t, err)
+			assert.Contains(t, string(body), tt.expectedBody)
+		})
+	}
+}
+
+func TestHandleGetNotification(t *testing.T) {
+	record := delivery.Record{
+		ID:          "some-id",
+		UserEmail:   "test@example.com",
+		MessageType: "welcome",
+		State:       delivery.StateFailed,
+		Reason:      "rejected",
+		Attempts:    2,
+		CreatedAt:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		UpdatedAt:   time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name           string
+		setupMocks     func(service *mocks.UserNotifier)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "Existing notification",
+			setupMocks: func(service *mocks.UserNotifier) {
+				service.On("Status", mock.Anything, "some-id").Return(record, nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"id":"some-id","user_email":"test@example.com","message_type":"welcome","state":"failed",` +
+				`"reason":"rejected","attempts":2,"created_at":"2024-01-01T00:00:00Z","updated_at":"2024-01-01T00:01:00Z"}`,
+		},
+		{
+			name: "Notification not found",
+			setupMocks: func(service *mocks.UserNotifier) {
+				service.On("Status", mock.Anything, "some-id").
+					Return(delivery.Record{}, fmt.Errorf("%w: some-id", delivery.ErrNotFound)).Once()
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "notification not found",
+		},
+		{
+			name: "Service internal error",
+			setupMocks: func(service *mocks.UserNotifier) {
+				service.On("Status", mock.Anything, "some-id").Return(delivery.Record{}, errors.New("error")).Once()
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   "internal error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := mocks.NewUserNotifier(t)
+
+			tt.setupMocks(mockService)
+			// the checks of the service pass unless the test case fails them
+			mockService.On("Check", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			router := chi.NewRouter()
+			SetUserController(router, mockService, false)
+
+			req := httptest.NewRequest(http.MethodGet, "/notifications/some-id", nil)
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			assert.Contains(t, string(body), tt.expectedBody)
+		})
+	}
+}
+
+func TestScheduledNotifications(t *testing.T) {
+	sendAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	item := schedule.Item{
+		ID:          "some-id",
+		UserEmail:   "test@example.com",
+		MessageType: "welcome",
+		SendAt:      time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		target         string
+		body           string
+		setupMocks     func(service *mocks.UserNotifier)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:   "Notification scheduled",
+			method: http.MethodPost,
+			target: "/notifications",
+			body:   fmt.Sprintf(`{"user_email":"test@example.com","message_type":"welcome","send_at":%q}`, sendAt.Format(time.RFC3339)),
+			setupMocks: func(service *mocks.UserNotifier) {
+				service.On("Schedule", mock.Anything, "test@example.com", "welcome", sendAt).Return("some-id", nil).Once()
+			},
+			expectedStatus: http.StatusAccepted,
+			expectedBody:   fmt.Sprintf(`{"id":"some-id","send_at":%q}`, sendAt.Format(time.RFC3339)),
+		},
+		{
+			name:   "Past send_at is sent right away",
+			method: http.MethodPost,
+			target: "/notifications",
+			body:   `{"user_email":"test@example.com","message_type":"welcome","send_at":"2024-01-01T09:00:00Z"}`,
+			setupMocks: func(service *mocks.UserNotifier) {
+				service.On("Notify", mock.Anything, "test@example.com", "welcome").Return("some-id", nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "Invalid message type",
+			method: http.MethodPost,
+			target: "/notifications",
+			body:   fmt.Sprintf(`{"user_email":"test@example.com","message_type":"welcome","send_at":%q}`, sendAt.Format(time.RFC3339)),
+			setupMocks: func(service *mocks.UserNotifier) {
+				service.On("Check", mock.Anything, "test@example.com", "welcome", "").
+					Return(fmt.Errorf("limiter error: %w", ratelimiter.ErrMessageTypeNotValid)).Once()
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "message type not valid",
+		},
+		{
+			name:   "List scheduled notifications",
+			method: http.MethodGet,
+			target: "/notifications/scheduled?limit=10",
+			setupMocks: func(service *mocks.UserNotifier) {
+				service.On("Scheduled", mock.Anything, int64(10)).Return([]schedule.Item{item}, nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"notifications":[{"id":"some-id","user_email":"test@example.com","message_type":"welcome",` +
+				`"send_at":"2024-01-01T09:00:00Z"}]}`,
+		},
+		{
+			name:           "List with invalid limit",
+			method:         http.MethodGet,
+			target:         "/notifications/scheduled?limit=0",
+			setupMocks:     func(service *mocks.UserNotifier) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "limit must be a number between 1 and 1000",
+		},
+		{
+			name:   "Cancel scheduled notification",
+			method: http.MethodDelete,
+			target: "/notifications/scheduled/some-id",
+			setupMocks: func(service *mocks.UserNotifier) {
+				service.On("Cancel", mock.Anything, "some-id").Return(nil).Once()
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:   "Cancel notification already sent",
+			method: http.MethodDelete,
+			target: "/notifications/scheduled/some-id",
+			setupMocks: func(service *mocks.UserNotifier) {
+				service.On("Cancel", mock.Anything, "some-id").Return(fmt.Errorf("%w: some-id", schedule.ErrNotFound)).Once()
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "scheduled notification not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := mocks.NewUserNotifier(t)
+
+			tt.setupMocks(mockService)
+			// the checks of the service pass unless the test case fails them
+			mockService.On("Check", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			router := chi.NewRouter()
+			SetUserController(router, mockService, false)
+
+			req := httptest.NewRequest(tt.method, tt.target, bytes.NewBufferString(tt.body))
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			assert.Contains(t, string(body), tt.expectedBody)
+		})
+	}
+}
+
+func TestNotifyErrorResponseMaybeSent(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		status    int
+		maybeSent bool
+	}{
+		{name: "timeout sending", err: fmt.Errorf("%w: sending mail timed out", notifier.ErrTimeout), status: http.StatusGatewayTimeout, maybeSent: true},
+		{name: "timeout connecting", err: notifier.ErrConnectTimeout, status: http.StatusGatewayTimeout, maybeSent: false},
+		{name: "internal error", err: errors.New("error"), status: http.StatusInternalServerError, maybeSent: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outcome := &requestOutcome{}
+			ctx := context.WithValue(context.Background(), outcomeContextKey{}, outcome)
+
+			status, _ := notifyErrorResponse(ctx, tt.err)
+
+			assert.Equal(t, tt.status, status)
+			assert.Equal(t, tt.maybeSent, outcome.maybeSent.Load())
+		})
+	}
+}
+
+func TestHandleNotifyErrorNoProvider(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	handleNotifyError(context.Background(), rec, fmt.Errorf("notifier error for user: %w", notifier.ErrNoProvider))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "30", rec.Header().Get("Retry-After"))
+	assert.Contains(t, rec.Body.String(), "no mail provider available")
+}
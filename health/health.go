@@ -0,0 +1,226 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"sync"
+	"time"
+	"user_news_api/notifier"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultOptions give every check a couple of seconds, and reuse the report for a few seconds,
+// so frequent probes do not load the dependencies.
+var DefaultOptions = Options{
+	Timeout:  2 * time.Second,
+	CacheTTL: 5 * time.Second,
+}
+
+type Options struct {
+	Timeout  time.Duration // Timeout bounds every check, the ones not answering in time are down
+	CacheTTL time.Duration // CacheTTL is how long a report is answered before checking the dependencies again
+}
+
+// Statuses of the checks and the reports. A report is degraded when only non critical checks are down.
+const (
+	StatusUp       = "up"
+	StatusDegraded = "degraded"
+	StatusDown     = "down"
+)
+
+// Check is a dependency of the API. The API is not ready when a Critical check fails.
+type Check struct {
+	Name     string
+	Critical bool
+	Run      func(ctx context.Context) error
+}
+
+type Report struct {
+	Status    string        `json:"status"`
+	CheckedAt time.Time     `json:"checked_at"`
+	Checks    []CheckResult `json:"checks"`
+}
+
+type CheckResult struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+func NewReadiness(options Options, checks ...Check) *Readiness {
+	return &Readiness{
+		options: options,
+		checks:  checks,
+		now:     time.Now,
+	}
+}
+
+// Readiness checks the dependencies concurrently, and caches the report.
+type Readiness struct {
+	options Options
+	checks  []Check
+	now     func() time.Time
+
+	mu   sync.Mutex
+	last *Report // last is nil until the first check
+}
+
+// Check returns the cached report, or checks every dependency when it expired. The concurrent calls wait
+// for the same round of checks instead of starting their own.
+func (r *Readiness) Check(ctx context.Context) Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.last != nil && r.now().Before(r.last.CheckedAt.Add(r.options.CacheTTL)) {
+		return *r.last
+	}
+
+	report := Report{
+		Status:    StatusUp,
+		CheckedAt: r.now(),
+		Checks:    make([]CheckResult, len(r.checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, check := range r.checks {
+		wg.Add(1)
+
+		go func(i int, check Check) {
+			defer wg.Done()
+
+			report.Checks[i] = r.run(ctx, check)
+		}(i, check)
+	}
+
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status == StatusUp {
+			continue
+		}
+
+		if result.Critical {
+			report.Status = StatusDown
+
+			break
+		}
+
+		report.Status = StatusDegraded
+	}
+
+	r.last = &report
+
+	return report
+}
+
+// run does not stop when the caller goes away, because its result is cached for the other callers.
+func (r *Readiness) run(ctx context.Context, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.options.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Run(ctx)
+
+	result := CheckResult{
+		Name:      check.Name,
+		Status:    StatusUp,
+		Critical:  check.Critical,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// Pinger is an abstraction for redis.Client making it mockeable
+type Pinger interface {
+	Ping(ctx context.Context) *redis.StatusCmd
+}
+
+// Redis is the critical check of the Redis server, every feature of the API needs it.
+func Redis(db Pinger) Check {
+	return Check{
+		Name:     "redis",
+		Critical: true,
+		Run: func(ctx context.Context) error {
+			if err := db.Ping(ctx).Err(); err != nil {
+				return fmt.Errorf("error pinging redis due to: %w", err)
+			}
+
+			return nil
+		},
+	}
+}
+
+// SMTP connects to the mail server and greets it with EHLO, without authenticating nor sending any mail.
+// It is not critical, the notifications can still be queued or sent through another provider.
+func SMTP(name string, host string, port int) Check {
+	return Check{
+		Name: "smtp:" + name,
+		Run: func(ctx context.Context) error {
+			return smtpHello(ctx, net.JoinHostPort(host, strconv.Itoa(port)), host)
+		},
+	}
+}
+
+func smtpHello(ctx context.Context, addr string, host string) error {
+	var dialer net.Dialer
+
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("error connecting to %s due to: %w", addr, err)
+	}
+	defer conn.Close()
+
+	// net/smtp does not accept a context, the deadline of the connection bounds the whole conversation
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return fmt.Errorf("error greeting %s due to: %w", addr, err)
+	}
+	defer client.Close()
+
+	if err := client.Hello("localhost"); err != nil {
+		return fmt.Errorf("error sending EHLO to %s due to: %w", addr, err)
+	}
+
+	return client.Quit()
+}
+
+// Providers is an abstraction for notifier.FailoverClient making it mockeable
+type Providers interface {
+	Health() []notifier.ProviderHealth
+}
+
+var errProvidersOpen = errors.New("the circuit of every mail provider is open")
+
+// MailProviders fails when no mail provider is tried, because the circuits of all of them are open.
+// It is not critical, the providers are tried again once their circuits are half open.
+func MailProviders(providers Providers) Check {
+	return Check{
+		Name: "mail_providers",
+		Run: func(ctx context.Context) error {
+			for _, provider := range providers.Health() {
+				if provider.Circuit != notifier.CircuitOpen {
+					return nil
+				}
+			}
+
+			return errProvidersOpen
+		},
+	}
+}
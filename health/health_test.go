@@ -0,0 +1,236 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+	"user_news_api/health/mocks"
+	"user_news_api/notifier"
+	"user_news_api/smtptest"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func checkReturning(name string, critical bool, err error) Check {
+	return Check{Name: name, Critical: critical, Run: func(context.Context) error { return err }}
+}
+
+func TestReadinessCheck(t *testing.T) {
+	tests := []struct {
+		name            string
+		checks          []Check
+		expectedStatus  string
+		expectedResults []string
+	}{
+		{
+			name:            "Every check up",
+			checks:          []Check{checkReturning("redis", true, nil), checkReturning("smtp", false, nil)},
+			expectedStatus:  StatusUp,
+			expectedResults: []string{StatusUp, StatusUp},
+		},
+		{
+			name: "Non critical check down",
+			checks: []Check{
+				checkReturning("redis", true, nil),
+				checkReturning("smtp", false, errors.New("connection refused")),
+			},
+			expectedStatus:  StatusDegraded,
+			expectedResults: []string{StatusUp, StatusDown},
+		},
+		{
+			name: "Critical check down",
+			checks: []Check{
+				checkReturning("redis", true, errors.New("connection refused")),
+				checkReturning("smtp", false, errors.New("connection refused")),
+			},
+			expectedStatus:  StatusDown,
+			expectedResults: []string{StatusDown, StatusDown},
+		},
+		{
+			name: "Check exceeding the timeout",
+			checks: []Check{{Name: "redis", Critical: true, Run: func(ctx context.Context) error {
+				<-ctx.Done()
+
+				return ctx.Err()
+			}}},
+			expectedStatus:  StatusDown,
+			expectedResults: []string{StatusDown},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readiness := NewReadiness(Options{Timeout: 10 * time.Millisecond}, tt.checks...)
+
+			report := readiness.Check(context.Background())
+
+			assert.Equal(t, tt.expectedStatus, report.Status)
+			require.Len(t, report.Checks, len(tt.expectedResults))
+
+			for i, expected := range tt.expectedResults {
+				assert.Equal(t, tt.checks[i].Name, report.Checks[i].Name)
+				assert.Equal(t, expected, report.Checks[i].Status)
+				assert.Equal(t, expected == StatusDown, report.Checks[i].Error != "")
+			}
+		})
+	}
+}
+
+func TestReadinessCheckCache(t *testing.T) {
+	runs := 0
+	check := Check{Name: "redis", Run: func(context.Context) error {
+		runs++
+
+		return nil
+	}}
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	readiness := NewReadiness(Options{Timeout: time.Second, CacheTTL: 5 * time.Second}, check)
+	readiness.now = func() time.Time { return now }
+
+	first := readiness.Check(context.Background())
+
+	now = now.Add(4 * time.Second)
+	assert.Equal(t, first, readiness.Check(context.Background()))
+	assert.Equal(t, 1, runs)
+
+	now = now.Add(time.Second)
+	assert.Equal(t, now, readiness.Check(context.Background()).CheckedAt)
+	assert.Equal(t, 2, runs)
+}
+
+func TestReadinessCheckCanceledCaller(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	readiness := NewReadiness(DefaultOptions, Check{Name: "redis", Run: func(ctx context.Context) error {
+		return ctx.Err()
+	}})
+
+	// the report is shared by every caller, so it does not fail because one of them went away
+	assert.Equal(t, StatusUp, readiness.Check(ctx).Status)
+}
+
+func TestRedis(t *testing.T) {
+	tests := []struct {
+		name          string
+		pingErr       error
+		expectedError bool
+	}{
+		{name: "Ping answered"},
+		{name: "Ping failed", pingErr: errors.New("connection refused"), expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pinger := mocks.NewPinger(t)
+			pinger.On("Ping", mock.Anything).Return(redis.NewStatusResult("PONG", tt.pingErr)).Once()
+
+			check := Redis(pinger)
+
+			assert.True(t, check.Critical)
+			assert.Equal(t, tt.expectedError, check.Run(context.Background()) != nil)
+		})
+	}
+}
+
+func TestSMTP(t *testing.T) {
+	server := smtptest.NewServer(smtptest.Options{})
+	defer server.Close()
+
+	// a listener closed right away leaves a port refusing the connections
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	closedPort := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+
+	tests := []struct {
+		name          string
+		host          string
+		port          int
+		failure       *smtptest.Failure
+		expectedError bool
+	}{
+		{
+			name: "Server greeting",
+			host: server.Host(),
+			port: server.Port(),
+		},
+		{
+			name:          "Server busy",
+			host:          server.Host(),
+			port:          server.Port(),
+			failure:       &smtptest.Failure{Command: smtptest.Connect, Code: 421, Text: "busy"},
+			expectedError: true,
+		},
+		{
+			name:          "Server not answering in time",
+			host:          server.Host(),
+			port:          server.Port(),
+			failure:       &smtptest.Failure{Command: "EHLO", Hang: true},
+			expectedError: true,
+		},
+		{
+			name:          "Connection refused",
+			host:          "127.0.0.1",
+			port:          closedPort,
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.failure != nil {
+				server.Fail(*tt.failure)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+
+			check := SMTP("primary", tt.host, tt.port)
+
+			assert.Equal(t, "smtp:primary", check.Name)
+			assert.False(t, check.Critical)
+			assert.Equal(t, tt.expectedError, check.Run(ctx) != nil)
+		})
+	}
+}
+
+func TestMailProviders(t *testing.T) {
+	tests := []struct {
+		name          string
+		circuits      []string
+		expectedError error
+	}{
+		{
+			name:     "Some provider available",
+			circuits: []string{notifier.CircuitOpen, notifier.CircuitHalfOpen},
+		},
+		{
+			name:          "Every circuit open",
+			circuits:      []string{notifier.CircuitOpen, notifier.CircuitOpen},
+			expectedError: errProvidersOpen,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			states := make([]notifier.ProviderHealth, len(tt.circuits))
+			for i, circuit := range tt.circuits {
+				states[i] = notifier.ProviderHealth{Circuit: circuit}
+			}
+
+			providers := mocks.NewProviders(t)
+			providers.On("Health").Return(states).Once()
+
+			assert.Equal(t, tt.expectedError, MailProviders(providers).Run(context.Background()))
+		})
+	}
+}
@@ -0,0 +1,46 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	redis "github.com/redis/go-redis/v9"
+)
+
+// Pinger is an autogenerated mock type for the Pinger type
+type Pinger struct {
+	mock.Mock
+}
+
+// Ping provides a mock function with given fields: ctx
+func (_m *Pinger) Ping(ctx context.Context) *redis.StatusCmd {
+	ret := _m.Called(ctx)
+
+	var r0 *redis.StatusCmd
+	if rf, ok := ret.Get(0).(func(context.Context) *redis.StatusCmd); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.StatusCmd)
+		}
+	}
+
+	return r0
+}
+
+// NewPinger creates a new instance of Pinger. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewPinger(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Pinger {
+	mock := &Pinger{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,44 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	notifier "user_news_api/notifier"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Providers is an autogenerated mock type for the Providers type
+type Providers struct {
+	mock.Mock
+}
+
+// Health provides a mock function with given fields:
+func (_m *Providers) Health() []notifier.ProviderHealth {
+	ret := _m.Called()
+
+	var r0 []notifier.ProviderHealth
+	if rf, ok := ret.Get(0).(func() []notifier.ProviderHealth); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]notifier.ProviderHealth)
+		}
+	}
+
+	return r0
+}
+
+// NewProviders creates a new instance of Providers. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewProviders(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Providers {
+	mock := &Providers{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,133 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrInProgress      = errors.New("request in progress")
+	ErrPayloadMismatch = errors.New("idempotency key reused with a different payload")
+)
+
+// DefaultOptions keep the responses for a day, which covers the retries of the upstream services.
+var DefaultOptions = Options{
+	TTL:     24 * time.Hour,
+	LockTTL: time.Minute,
+}
+
+type Options struct {
+	TTL     time.Duration // TTL is how long a response is replayed
+	LockTTL time.Duration // LockTTL bounds the lock of a request that never completes, e.g. when the process dies
+}
+
+// Response is the stored answer to a request, replayed for its repetitions.
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+}
+
+// entry is the stored value of a key. It is in progress until the Response is set.
+type entry struct {
+	Fingerprint string    `json:"fingerprint"`
+	Response    *Response `json:"response,omitempty"`
+}
+
+func NewStore(db *redis.Client, options Options) Store {
+	return Store{
+		db:      db,
+		options: options,
+	}
+}
+
+// RedisKeyValue is an abstraction for redis.Client making it mockeable
+type RedisKeyValue interface {
+	SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+	Get(ctx context.Context, key string) *redis.StringCmd
+	Del(ctx context.Context, keys ...string) *redis.IntCmd
+}
+
+// Store remembers the responses given to each idempotency key.
+// The fingerprint identifies the request payload, a key can only be used with a single payload.
+type Store struct {
+	db      RedisKeyValue
+	options Options
+}
+
+// Begin locks the key for the request. When it returns a nil Response without error, the caller owns the key
+// and must Complete or Release it. Otherwise, the stored Response must be replayed.
+func (s Store) Begin(ctx context.Context, key string, fingerprint string) (*Response, error) {
+	value, err := json.Marshal(entry{Fingerprint: fingerprint})
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling idempotency entry due to: %w", err)
+	}
+
+	locked, err := s.db.SetNX(ctx, redisKey(key), value, s.options.LockTTL).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error locking idempotency key due to: %w", err)
+	}
+
+	if locked {
+		return nil, nil
+	}
+
+	stored, err := s.db.Get(ctx, redisKey(key)).Result()
+	if errors.Is(err, redis.Nil) {
+		// the lock expired between both commands, the other request is considered still in progress
+		return nil, ErrInProgress
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error getting idempotency key due to: %w", err)
+	}
+
+	var current entry
+	if err = json.Unmarshal([]byte(stored), &current); err != nil {
+		return nil, fmt.Errorf("error unmarshalling idempotency entry due to: %w", err)
+	}
+
+	if current.Fingerprint != fingerprint {
+		return nil, ErrPayloadMismatch
+	}
+
+	if current.Response == nil {
+		return nil, ErrInProgress
+	}
+
+	return current.Response, nil
+}
+
+// Complete stores the response of the request and releases its lock.
+func (s Store) Complete(ctx context.Context, key string, fingerprint string, response Response) error {
+	value, err := json.Marshal(entry{Fingerprint: fingerprint, Response: &response})
+	if err != nil {
+		return fmt.Errorf("error marshalling idempotency entry due to: %w", err)
+	}
+
+	if err = s.db.Set(ctx, redisKey(key), value, s.options.TTL).Err(); err != nil {
+		return fmt.Errorf("error storing idempotency response due to: %w", err)
+	}
+
+	return nil
+}
+
+// Release forgets the key, so the request can be tried again.
+func (s Store) Release(ctx context.Context, key string) error {
+	if err := s.db.Del(ctx, redisKey(key)).Err(); err != nil {
+		return fmt.Errorf("error releasing idempotency key due to: %w", err)
+	}
+
+	return nil
+}
+
+func redisKey(key string) string {
+	return fmt.Sprintf("idempotency-%s", key)
+}
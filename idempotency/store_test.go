@@ -0,0 +1,151 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+	"user_news_api/idempotency/mocks"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var testOptions = Options{
+	TTL:     time.Hour,
+	LockTTL: time.Minute,
+}
+
+func TestStoreBegin(t *testing.T) {
+	response := &Response{
+		StatusCode: http.StatusAccepted,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       []byte(`{"id":"some-id"}`),
+	}
+	// the body of the response is saved in base64
+	completed := `{"fingerprint":"fp","response":{"status_code":202,"header":{"Content-Type":["application/json"]},"body":"eyJpZCI6InNvbWUtaWQifQ=="}}`
+
+	tests := []struct {
+		name          string
+		mockApplier   func(mockRedis *mocks.RedisKeyValue)
+		expected      *Response
+		expectedError error
+	}{
+		{
+			name: "new key is locked",
+			mockApplier: func(mockRedis *mocks.RedisKeyValue) {
+				mockRedis.On("SetNX", mock.Anything, "idempotency-key", []byte(`{"fingerprint":"fp"}`), time.Minute).
+					Return(redis.NewBoolResult(true, nil)).Once()
+			},
+			expected:      nil,
+			expectedError: nil,
+		},
+		{
+			name: "completed key is replayed",
+			mockApplier: func(mockRedis *mocks.RedisKeyValue) {
+				mockRedis.On("SetNX", mock.Anything, "idempotency-key", mock.Anything, time.Minute).Return(redis.NewBoolResult(false, nil)).Once()
+				mockRedis.On("Get", mock.Anything, "idempotency-key").
+					Return(redis.NewStringResult(completed, nil)).Once()
+			},
+			expected:      response,
+			expectedError: nil,
+		},
+		{
+			name: "key in progress",
+			mockApplier: func(mockRedis *mocks.RedisKeyValue) {
+				mockRedis.On("SetNX", mock.Anything, "idempotency-key", mock.Anything, time.Minute).Return(redis.NewBoolResult(false, nil)).Once()
+				mockRedis.On("Get", mock.Anything, "idempotency-key").
+					Return(redis.NewStringResult(`{"fingerprint":"fp"}`, nil)).Once()
+			},
+			expected:      nil,
+			expectedError: ErrInProgress,
+		},
+		{
+			name: "key expired between commands",
+			mockApplier: func(mockRedis *mocks.RedisKeyValue) {
+				mockRedis.On("SetNX", mock.Anything, "idempotency-key", mock.Anything, time.Minute).Return(redis.NewBoolResult(false, nil)).Once()
+				mockRedis.On("Get", mock.Anything, "idempotency-key").Return(redis.NewStringResult("", redis.Nil)).Once()
+			},
+			expected:      nil,
+			expectedError: ErrInProgress,
+		},
+		{
+			name: "key used with another payload",
+			mockApplier: func(mockRedis *mocks.RedisKeyValue) {
+				mockRedis.On("SetNX", mock.Anything, "idempotency-key", mock.Anything, time.Minute).Return(redis.NewBoolResult(false, nil)).Once()
+				mockRedis.On("Get", mock.Anything, "idempotency-key").
+					Return(redis.NewStringResult(`{"fingerprint":"other","response":{"status_code":202,"header":{"Content-Type":["application/json"]},"body":"eyJpZCI6InNvbWUtaWQifQ=="}}`, nil)).Once()
+			},
+			expected:      nil,
+			expectedError: ErrPayloadMismatch,
+		},
+		{
+			name: "redis error",
+			mockApplier: func(mockRedis *mocks.RedisKeyValue) {
+				mockRedis.On("SetNX", mock.Anything, "idempotency-key", mock.Anything, time.Minute).
+					Return(redis.NewBoolResult(false, errors.New("error"))).Once()
+			},
+			expected:      nil,
+			expectedError: fmt.Errorf("error locking idempotency key due to: %w", errors.New("error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisKeyValue(t)
+			tt.mockApplier(mockRedis)
+
+			s := Store{db: mockRedis, options: testOptions}
+
+			result, err := s.Begin(context.Background(), "key", "fp")
+
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestStoreComplete(t *testing.T) {
+	response := Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: []byte{}}
+
+	mockRedis := mocks.NewRedisKeyValue(t)
+	mockRedis.On("Set", mock.Anything, "idempotency-key", []byte(`{"fingerprint":"fp","response":{"status_code":200,"header":{},"body":""}}`), time.Hour).
+		Return(redis.NewStatusResult("OK", nil)).Once()
+
+	s := Store{db: mockRedis, options: testOptions}
+
+	assert.NoError(t, s.Complete(context.Background(), "key", "fp", response))
+}
+
+func TestStoreRelease(t *testing.T) {
+	tests := []struct {
+		name          string
+		result        error
+		expectedError error
+	}{
+		{
+			name:          "key released",
+			result:        nil,
+			expectedError: nil,
+		},
+		{
+			name:          "redis error",
+			result:        errors.New("error"),
+			expectedError: fmt.Errorf("error releasing idempotency key due to: %w", errors.New("error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisKeyValue(t)
+			mockRedis.On("Del", mock.Anything, "idempotency-key").Return(redis.NewIntResult(1, tt.result)).Once()
+
+			s := Store{db: mockRedis, options: testOptions}
+
+			assert.Equal(t, tt.expectedError, s.Release(context.Background(), "key"))
+		})
+	}
+}
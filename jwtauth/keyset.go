@@ -0,0 +1,251 @@
+package jwtauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"user_news_api/logging"
+)
+
+var (
+	ErrKeyNotFound    = errors.New("signing key not found")
+	ErrKeySetNotValid = errors.New("key set not valid")
+)
+
+// DefaultKeySetOptions refresh the keys every hour, and look for a rotated key at most once a minute.
+var DefaultKeySetOptions = KeySetOptions{
+	CacheTTL:           time.Hour,
+	MinRefreshInterval: time.Minute,
+	Timeout:            5 * time.Second,
+}
+
+type KeySetOptions struct {
+	Source             string        // Source is the URL of the JWKS, or the path of a local file
+	CacheTTL           time.Duration // CacheTTL is how long the keys are used before fetching them again
+	MinRefreshInterval time.Duration // MinRefreshInterval bounds the fetches made for tokens signed by unknown keys
+	Timeout            time.Duration // Timeout bounds the fetch of a URL
+}
+
+// jwk is a JSON Web Key, only the fields of the RSA and EC public keys are read.
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func NewKeySet(options KeySetOptions) *KeySet {
+	return &KeySet{
+		options: options,
+		client:  &http.Client{Timeout: options.Timeout},
+		now:     time.Now,
+	}
+}
+
+// KeySet caches the public keys of a JWKS by key ID. The keys are fetched again when they expire, or when a token
+// is signed by an unknown key, so the rotated keys of the identity provider are found without restarting.
+type KeySet struct {
+	options KeySetOptions
+	client  *http.Client
+	now     func() time.Time
+
+	mu          sync.Mutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	refreshedAt time.Time // refreshedAt is the last fetch, even the failed ones
+}
+
+// Key returns the public key of the ID. The ID can be empty when the set has a single key.
+// When a fetch fails the cached keys are still used, so the identity provider being down does not reject every token.
+func (ks *KeySet) Key(ctx context.Context, id string) (crypto.PublicKey, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	now := ks.now()
+
+	key, found := ks.lookup(id)
+
+	// the keys are not refreshed more often than the MinRefreshInterval, not even when the fetches fail
+	stale := !found || now.Sub(ks.fetchedAt) >= ks.options.CacheTTL
+	refresh := ks.keys == nil || (stale && now.Sub(ks.refreshedAt) >= ks.options.MinRefreshInterval)
+
+	if refresh {
+		ks.refreshedAt = now
+
+		keys, err := ks.fetch(ctx)
+		if err != nil {
+			if ks.keys == nil {
+				return nil, err
+			}
+
+			slog.WarnContext(ctx, "error refreshing key set, the cached keys are used", logging.Error(err))
+		} else {
+			ks.keys, ks.fetchedAt = keys, now
+			key, found = ks.lookup(id)
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+	}
+
+	return key, nil
+}
+
+func (ks *KeySet) lookup(id string) (crypto.PublicKey, bool) {
+	if id == "" && len(ks.keys) == 1 {
+		for _, key := range ks.keys {
+			return key, true
+		}
+	}
+
+	key, ok := ks.keys[id]
+
+	return key, ok
+}
+
+func (ks *KeySet) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	data, err := ks.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+
+	if err = json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrKeySetNotValid, err.Error())
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := k.publicKey()
+		if err != nil {
+			// an unsupported key does not prevent using the other ones
+			slog.WarnContext(ctx, "skipping key of the key set", slog.String("kid", k.Kid), logging.Error(err))
+
+			continue
+		}
+
+		keys[k.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: it has no signing keys", ErrKeySetNotValid)
+	}
+
+	return keys, nil
+}
+
+func (ks *KeySet) read(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(ks.options.Source, "http://") && !strings.HasPrefix(ks.options.Source, "https://") {
+		data, err := os.ReadFile(ks.options.Source)
+		if err != nil {
+			return nil, fmt.Errorf("error reading key set due to: %w", err)
+		}
+
+		return data, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ks.options.Source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating key set request due to: %w", err)
+	}
+
+	res, err := ks.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching key set due to: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching key set due to: status %d", res.StatusCode)
+	}
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading key set due to: %w", err)
+	}
+
+	return data, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("curve %s not supported", k.Crv)
+		}
+
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on the curve")
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	return nil, fmt.Errorf("key type %s not supported", k.Kty)
+}
+
+func decodeInt(value string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("key parameter not valid")
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,170 @@
+package jwtauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+func rsaJWK(id string, key *rsa.PublicKey) jwk {
+	return jwk{Kid: id, Kty: "RSA", Use: "sig", N: encodeInt(key.N), E: encodeInt(big.NewInt(int64(key.E)))}
+}
+
+func ecJWK(id string, key *ecdsa.PublicKey) jwk {
+	return jwk{Kid: id, Kty: "EC", Crv: key.Curve.Params().Name, X: encodeInt(key.X), Y: encodeInt(key.Y)}
+}
+
+func testKeySet(t *testing.T, keys ...jwk) []byte {
+	data, err := json.Marshal(map[string][]jwk{"keys": keys})
+	require.NoError(t, err)
+
+	return data
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	return key
+}
+
+func newECKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	return key
+}
+
+func TestKeySetFile(t *testing.T) {
+	rsaKey, ecKey := newRSAKey(t), newECKey(t)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, testKeySet(t,
+		rsaJWK("rsa", &rsaKey.PublicKey),
+		ecJWK("ec", &ecKey.PublicKey),
+		jwk{Kid: "enc", Kty: "RSA", Use: "enc", N: "AQAB", E: "AQAB"},
+		jwk{Kid: "oct", Kty: "oct"},
+	), 0o600))
+
+	ks := NewKeySet(KeySetOptions{Source: path, CacheTTL: time.Hour, MinRefreshInterval: time.Minute})
+
+	key, err := ks.Key(context.Background(), "rsa")
+	require.NoError(t, err)
+	assert.True(t, rsaKey.PublicKey.Equal(key))
+
+	key, err = ks.Key(context.Background(), "ec")
+	require.NoError(t, err)
+	assert.True(t, ecKey.PublicKey.Equal(key))
+
+	_, err = ks.Key(context.Background(), "enc")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	_, err = ks.Key(context.Background(), "oct")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestKeySetSingleKeyWithoutID(t *testing.T) {
+	rsaKey := newRSAKey(t)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, testKeySet(t, rsaJWK("rsa", &rsaKey.PublicKey)), 0o600))
+
+	ks := NewKeySet(KeySetOptions{Source: path, CacheTTL: time.Hour})
+
+	key, err := ks.Key(context.Background(), "")
+	require.NoError(t, err)
+	assert.True(t, rsaKey.PublicKey.Equal(key))
+}
+
+func TestKeySetNotValid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"keys":[]}`), 0o600))
+
+	ks := NewKeySet(KeySetOptions{Source: path})
+
+	_, err := ks.Key(context.Background(), "rsa")
+	assert.ErrorIs(t, err, ErrKeySetNotValid)
+
+	ks = NewKeySet(KeySetOptions{Source: filepath.Join(t.TempDir(), "missing.json")})
+
+	_, err = ks.Key(context.Background(), "rsa")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestKeySetURLRotation(t *testing.T) {
+	oldKey, newKey := newRSAKey(t), newRSAKey(t)
+
+	var body atomic.Value
+	body.Store(testKeySet(t, rsaJWK("old", &oldKey.PublicKey)))
+
+	var fetches, failing atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+
+		if failing.Load() == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		_, _ = w.Write(body.Load().([]byte))
+	}))
+	defer server.Close()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	ks := NewKeySet(KeySetOptions{Source: server.URL, CacheTTL: time.Hour, MinRefreshInterval: time.Minute, Timeout: time.Second})
+	ks.now = func() time.Time { return now }
+
+	_, err := ks.Key(context.Background(), "old")
+	require.NoError(t, err)
+
+	_, err = ks.Key(context.Background(), "old")
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), fetches.Load(), "the keys are cached")
+
+	body.Store(testKeySet(t, rsaJWK("old", &oldKey.PublicKey), rsaJWK("new", &newKey.PublicKey)))
+
+	_, err = ks.Key(context.Background(), "new")
+	assert.ErrorIs(t, err, ErrKeyNotFound, "an unknown key is not fetched again before the MinRefreshInterval")
+	assert.Equal(t, int32(1), fetches.Load())
+
+	now = now.Add(time.Minute)
+
+	key, err := ks.Key(context.Background(), "new")
+	require.NoError(t, err)
+	assert.True(t, newKey.PublicKey.Equal(key), "the rotated key is fetched")
+	assert.Equal(t, int32(2), fetches.Load())
+
+	failing.Store(1)
+	now = now.Add(2 * time.Hour)
+
+	key, err = ks.Key(context.Background(), "old")
+	require.NoError(t, err)
+	assert.True(t, oldKey.PublicKey.Equal(key), "the cached keys are used when the refresh fails")
+	assert.Equal(t, int32(3), fetches.Load())
+
+	_, err = ks.Key(context.Background(), "old")
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), fetches.Load(), "a failed refresh is not retried before the MinRefreshInterval")
+}
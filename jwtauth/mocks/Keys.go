@@ -0,0 +1,55 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	crypto "crypto"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Keys is an autogenerated mock type for the Keys type
+type Keys struct {
+	mock.Mock
+}
+
+// Key provides a mock function with given fields: ctx, id
+func (_m *Keys) Key(ctx context.Context, id string) (crypto.PublicKey, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 crypto.PublicKey
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (crypto.PublicKey, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) crypto.PublicKey); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(crypto.PublicKey)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewKeys creates a new instance of Keys. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewKeys(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Keys {
+	mock := &Keys{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
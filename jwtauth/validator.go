@@ -0,0 +1,154 @@
+package jwtauth
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrTokenNotValid = errors.New("bearer token not valid")
+
+// Scopes granting the notifications, ScopeSendPrefix followed by a message type grants only that type
+// (e.g. "notifications:send:marketing"), and ScopeSendAll grants every one.
+const (
+	ScopeSendAll    = "notifications:send"
+	ScopeSendPrefix = ScopeSendAll + ":"
+)
+
+// signingMethods are the asymmetric algorithms of the keys read from the JWKS,
+// the other ones are rejected so a token cannot choose "none" or an HMAC keyed with a public key.
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+type Options struct {
+	Issuer   string        // Issuer must be the iss claim of the tokens
+	Audience string        // Audience must be within the aud claim of the tokens
+	Leeway   time.Duration // Leeway tolerates the clock skew with the identity provider
+}
+
+// Keys is an abstraction for KeySet making it mockeable
+type Keys interface {
+	Key(ctx context.Context, id string) (crypto.PublicKey, error)
+}
+
+// Claims are the claims of a valid token used by the API.
+type Claims struct {
+	Subject string
+	Scopes  []string
+}
+
+// Allows tells if the scopes grant notifications of the message type, which is matched regardless of its case.
+func (c Claims) Allows(messageType string) bool {
+	for _, scope := range c.Scopes {
+		if scope == ScopeSendAll {
+			return true
+		}
+
+		if allowed, ok := strings.CutPrefix(scope, ScopeSendPrefix); ok && strings.EqualFold(allowed, messageType) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// scopes reads the scope claims, which are a string separated by spaces or an array depending on the provider.
+type scopes []string
+
+func (s *scopes) UnmarshalJSON(data []byte) error {
+	var joined string
+	if err := json.Unmarshal(data, &joined); err == nil {
+		*s = strings.Fields(joined)
+
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return errors.New("scopes must be a string or an array of strings")
+	}
+
+	*s = list
+
+	return nil
+}
+
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	Scope scopes `json:"scope"`
+	Scp   scopes `json:"scp"`
+}
+
+func NewValidator(keys Keys, options Options) Validator {
+	return Validator{
+		keys:    keys,
+		options: options,
+	}
+}
+
+// Validator checks the signature of the tokens against the key set, along with their issuer, audience and expiration.
+type Validator struct {
+	keys    Keys
+	options Options
+}
+
+// Validate returns the claims of the token, or an error wrapping ErrTokenNotValid when it must be rejected.
+// The other errors mean the token could not be checked, e.g. when the key set cannot be fetched.
+func (v Validator) Validate(ctx context.Context, token string) (Claims, error) {
+	var claims tokenClaims
+
+	var keyErr error
+
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		id, _ := t.Header["kid"].(string)
+
+		key, err := v.keys.Key(ctx, id)
+		if err != nil && !errors.Is(err, ErrKeyNotFound) {
+			keyErr = err
+		}
+
+		return key, err
+	},
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithIssuer(v.options.Issuer),
+		jwt.WithAudience(v.options.Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(v.options.Leeway),
+	)
+	if keyErr != nil {
+		return Claims{}, keyErr
+	}
+
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %s", ErrTokenNotValid, err.Error())
+	}
+
+	// the subject identifies the client for the rate limits and the idempotency keys, so it cannot be shared
+	if claims.Subject == "" {
+		return Claims{}, fmt.Errorf("%w: token has no subject", ErrTokenNotValid)
+	}
+
+	return Claims{
+		Subject: claims.Subject,
+		Scopes:  append(claims.Scope, claims.Scp...),
+	}, nil
+}
+
+type claimsContextKey struct{}
+
+// WithClaims returns a copy of the context carrying the claims of the authenticated client.
+func WithClaims(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, claimsContextKey{}, claims)
+}
+
+// FromContext returns the claims of the authenticated client, ok is false when the request has no token.
+func FromContext(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey{}).(Claims)
+
+	return claims, ok
+}
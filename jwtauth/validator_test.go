@@ -0,0 +1,204 @@
+package jwtauth
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"user_news_api/jwtauth/mocks"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var testOptions = Options{
+	Issuer:   "https://id.example.com",
+	Audience: "user-news-api",
+	Leeway:   time.Second,
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key crypto.PrivateKey, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+
+	return signed
+}
+
+func validClaims(extra jwt.MapClaims) jwt.MapClaims {
+	claims := jwt.MapClaims{
+		"iss": "https://id.example.com",
+		"aud": []string{"user-news-api", "other"},
+		"sub": "newsletter-service",
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"iat": time.Now().Unix(),
+	}
+
+	for k, v := range extra {
+		claims[k] = v
+	}
+
+	return claims
+}
+
+func TestValidatorValidate(t *testing.T) {
+	rsaKey, ecKey, otherKey := newRSAKey(t), newECKey(t), newRSAKey(t)
+
+	tests := []struct {
+		name          string
+		token         string
+		mockApplier   func(keys *mocks.Keys)
+		expected      Claims
+		expectedError error
+	}{
+		{
+			name:  "RSA token with scope string",
+			token: sign(t, jwt.SigningMethodRS256, rsaKey, "rsa", validClaims(jwt.MapClaims{"scope": "openid notifications:send:marketing"})),
+			mockApplier: func(keys *mocks.Keys) {
+				keys.On("Key", mock.Anything, "rsa").Return(&rsaKey.PublicKey, nil).Once()
+			},
+			expected: Claims{Subject: "newsletter-service", Scopes: []string{"openid", "notifications:send:marketing"}},
+		},
+		{
+			name:  "EC token with scp array",
+			token: sign(t, jwt.SigningMethodES256, ecKey, "ec", validClaims(jwt.MapClaims{"scp": []string{"notifications:send"}})),
+			mockApplier: func(keys *mocks.Keys) {
+				keys.On("Key", mock.Anything, "ec").Return(&ecKey.PublicKey, nil).Once()
+			},
+			expected: Claims{Subject: "newsletter-service", Scopes: []string{"notifications:send"}},
+		},
+		{
+			name:  "Signed by another key",
+			token: sign(t, jwt.SigningMethodRS256, otherKey, "rsa", validClaims(nil)),
+			mockApplier: func(keys *mocks.Keys) {
+				keys.On("Key", mock.Anything, "rsa").Return(&rsaKey.PublicKey, nil).Once()
+			},
+			expectedError: ErrTokenNotValid,
+		},
+		{
+			name:  "Unknown key",
+			token: sign(t, jwt.SigningMethodRS256, rsaKey, "unknown", validClaims(nil)),
+			mockApplier: func(keys *mocks.Keys) {
+				keys.On("Key", mock.Anything, "unknown").Return(nil, fmt.Errorf("%w: unknown", ErrKeyNotFound)).Once()
+			},
+			expectedError: ErrTokenNotValid,
+		},
+		{
+			name:  "Wrong issuer",
+			token: sign(t, jwt.SigningMethodRS256, rsaKey, "rsa", validClaims(jwt.MapClaims{"iss": "https://other.example.com"})),
+			mockApplier: func(keys *mocks.Keys) {
+				keys.On("Key", mock.Anything, "rsa").Return(&rsaKey.PublicKey, nil).Once()
+			},
+			expectedError: ErrTokenNotValid,
+		},
+		{
+			name:  "Wrong audience",
+			token: sign(t, jwt.SigningMethodRS256, rsaKey, "rsa", validClaims(jwt.MapClaims{"aud": "other"})),
+			mockApplier: func(keys *mocks.Keys) {
+				keys.On("Key", mock.Anything, "rsa").Return(&rsaKey.PublicKey, nil).Once()
+			},
+			expectedError: ErrTokenNotValid,
+		},
+		{
+			name:  "Expired",
+			token: sign(t, jwt.SigningMethodRS256, rsaKey, "rsa", validClaims(jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()})),
+			mockApplier: func(keys *mocks.Keys) {
+				keys.On("Key", mock.Anything, "rsa").Return(&rsaKey.PublicKey, nil).Once()
+			},
+			expectedError: ErrTokenNotValid,
+		},
+		{
+			name: "Without expiration",
+			token: sign(t, jwt.SigningMethodRS256, rsaKey, "rsa", jwt.MapClaims{
+				"iss": "https://id.example.com", "aud": "user-news-api",
+			}),
+			mockApplier: func(keys *mocks.Keys) {
+				keys.On("Key", mock.Anything, "rsa").Return(&rsaKey.PublicKey, nil).Once()
+			},
+			expectedError: ErrTokenNotValid,
+		},
+		{
+			name:  "Without subject",
+			token: sign(t, jwt.SigningMethodRS256, rsaKey, "rsa", validClaims(jwt.MapClaims{"sub": ""})),
+			mockApplier: func(keys *mocks.Keys) {
+				keys.On("Key", mock.Anything, "rsa").Return(&rsaKey.PublicKey, nil).Once()
+			},
+			expectedError: ErrTokenNotValid,
+		},
+		{
+			name:          "HMAC algorithm",
+			token:         sign(t, jwt.SigningMethodHS256, []byte("secret"), "rsa", validClaims(nil)),
+			mockApplier:   func(keys *mocks.Keys) {},
+			expectedError: ErrTokenNotValid,
+		},
+		{
+			name:          "None algorithm",
+			token:         sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "rsa", validClaims(nil)),
+			mockApplier:   func(keys *mocks.Keys) {},
+			expectedError: ErrTokenNotValid,
+		},
+		{
+			name:          "Malformed",
+			token:         "not-a-token",
+			mockApplier:   func(keys *mocks.Keys) {},
+			expectedError: ErrTokenNotValid,
+		},
+		{
+			name:  "Key set unavailable",
+			token: sign(t, jwt.SigningMethodRS256, rsaKey, "rsa", validClaims(nil)),
+			mockApplier: func(keys *mocks.Keys) {
+				keys.On("Key", mock.Anything, "rsa").Return(nil, errors.New("error fetching key set")).Once()
+			},
+			expectedError: errors.New("error fetching key set"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockKeys := mocks.NewKeys(t)
+
+			tt.mockApplier(mockKeys)
+
+			validator := NewValidator(mockKeys, testOptions)
+
+			claims, err := validator.Validate(context.Background(), tt.token)
+
+			if tt.expectedError == nil {
+				require.NoError(t, err)
+			} else if errors.Is(tt.expectedError, ErrTokenNotValid) {
+				assert.ErrorIs(t, err, ErrTokenNotValid)
+			} else {
+				assert.Equal(t, tt.expectedError, err)
+			}
+
+			assert.Equal(t, tt.expected, claims)
+		})
+	}
+}
+
+func TestClaimsAllows(t *testing.T) {
+	tests := []struct {
+		name        string
+		scopes      []string
+		messageType string
+		expected    bool
+	}{
+		{name: "Scope of the message type", scopes: []string{"notifications:send:marketing"}, messageType: "Marketing", expected: true},
+		{name: "Scope of every message type", scopes: []string{"openid", "notifications:send"}, messageType: "News", expected: true},
+		{name: "Scope of another message type", scopes: []string{"notifications:send:marketing"}, messageType: "News"},
+		{name: "Scope with a longer prefix", scopes: []string{"notifications:sender"}, messageType: "News"},
+		{name: "Without scopes", messageType: "News"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Claims{Scopes: tt.scopes}.Allows(tt.messageType))
+		})
+	}
+}
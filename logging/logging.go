@@ -0,0 +1,120 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Fields shared by the logs of every package, so the same value is always found under the same key.
+const (
+	KeyRequestID      = "request_id"
+	KeyMessageType    = "message_type"
+	KeyNotificationID = "notification_id"
+	KeyOutcome        = "outcome"
+	KeyLatency        = "latency" // KeyLatency is written in milliseconds, as every duration
+	KeyUser           = "user"
+	KeyError          = "error"
+)
+
+type Options struct {
+	Level  slog.Level
+	Writer io.Writer // Writer receives the logs, the standard output when it is nil
+}
+
+// New returns a JSON logger adding the request ID of the context to every log.
+// The email addresses are masked wherever they are, including the messages of the errors.
+func New(options Options) *slog.Logger {
+	writer := options.Writer
+	if writer == nil {
+		writer = os.Stdout
+	}
+
+	handler := slog.NewJSONHandler(writer, &slog.HandlerOptions{
+		Level:       options.Level,
+		ReplaceAttr: replaceAttr,
+	})
+
+	return slog.New(contextHandler{handler})
+}
+
+// ParseLevel parses "debug", "info", "warn" or "error".
+func ParseLevel(level string) (slog.Level, error) {
+	var parsed slog.Level
+	err := parsed.UnmarshalText([]byte(level))
+
+	return parsed, err
+}
+
+// Error is the attribute of an error, under KeyError.
+func Error(err error) slog.Attr {
+	return slog.String(KeyError, err.Error())
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of the context carrying the request ID, it is added to the logs of that context.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID of the context, it is empty when there is none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+
+	return id
+}
+
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		record.AddAttrs(slog.String(KeyRequestID, id))
+	}
+
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+// MaskEmail keeps the first character of the local part and the domain, e.g. u***@example.com.
+func MaskEmail(email string) string {
+	if email == "" {
+		return ""
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at < 1 {
+		return "***"
+	}
+
+	return email[:1] + "***" + email[at:]
+}
+
+func replaceAttr(_ []string, attr slog.Attr) slog.Attr {
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		attr.Value = slog.StringValue(emailPattern.ReplaceAllStringFunc(attr.Value.String(), MaskEmail))
+	case slog.KindDuration:
+		attr.Value = slog.Float64Value(float64(attr.Value.Duration().Microseconds()) / 1000)
+	case slog.KindAny:
+		if err, ok := attr.Value.Any().(error); ok {
+			attr.Value = slog.StringValue(emailPattern.ReplaceAllStringFunc(err.Error(), MaskEmail))
+		}
+	}
+
+	return attr
+}
@@ -0,0 +1,148 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		level        slog.Level
+		log          func(ctx context.Context, logger *slog.Logger)
+		expectedLogs []map[string]any
+	}{
+		{
+			name: "Fields with the request ID",
+			ctx:  WithRequestID(context.Background(), "request-1"),
+			log: func(ctx context.Context, logger *slog.Logger) {
+				logger.InfoContext(ctx, "notification request handled",
+					slog.String(KeyMessageType, "News"), slog.Duration(KeyLatency, 1500*time.Microsecond))
+			},
+			expectedLogs: []map[string]any{{
+				"level":        "INFO",
+				"msg":          "notification request handled",
+				KeyRequestID:   "request-1",
+				KeyMessageType: "News",
+				KeyLatency:     1.5,
+			}},
+		},
+		{
+			name: "Emails masked in messages, fields and errors",
+			ctx:  context.Background(),
+			log: func(ctx context.Context, logger *slog.Logger) {
+				logger.ErrorContext(ctx, "error for user@example.com",
+					slog.String(KeyUser, "user@example.com"),
+					Error(errors.New("notifier error for user other.user@example.com: rejected")),
+					slog.Any("cause", errors.New("mail to user@example.com failed")))
+			},
+			expectedLogs: []map[string]any{{
+				"level":  "ERROR",
+				"msg":    "error for u***@example.com",
+				KeyUser:  "u***@example.com",
+				KeyError: "notifier error for user o***@example.com: rejected",
+				"cause":  "mail to u***@example.com failed",
+			}},
+		},
+		{
+			name:  "Logs under the level",
+			ctx:   context.Background(),
+			level: slog.LevelWarn,
+			log: func(ctx context.Context, logger *slog.Logger) {
+				logger.InfoContext(ctx, "not logged")
+				logger.WarnContext(ctx, "logged")
+			},
+			expectedLogs: []map[string]any{{
+				"level": "WARN",
+				"msg":   "logged",
+			}},
+		},
+		{
+			name: "Request ID with the attributes of the logger",
+			ctx:  WithRequestID(context.Background(), "request-2"),
+			log: func(ctx context.Context, logger *slog.Logger) {
+				logger.With(slog.String("component", "worker")).InfoContext(ctx, "handled")
+			},
+			expectedLogs: []map[string]any{{
+				"level":      "INFO",
+				"msg":        "handled",
+				"component":  "worker",
+				KeyRequestID: "request-2",
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+
+			tt.log(tt.ctx, New(Options{Level: tt.level, Writer: &out}))
+
+			var logs []map[string]any
+
+			decoder := json.NewDecoder(&out)
+			for decoder.More() {
+				var log map[string]any
+				require.NoError(t, decoder.Decode(&log))
+
+				delete(log, "time")
+				logs = append(logs, log)
+			}
+
+			assert.Equal(t, tt.expectedLogs, logs)
+		})
+	}
+}
+
+func TestMaskEmail(t *testing.T) {
+	tests := []struct {
+		email    string
+		expected string
+	}{
+		{email: "user@example.com", expected: "u***@example.com"},
+		{email: "u@example.com", expected: "u***@example.com"},
+		{email: "not an email", expected: "***"},
+		{email: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			assert.Equal(t, tt.expected, MaskEmail(tt.email))
+		})
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		level         string
+		expected      slog.Level
+		expectedError bool
+	}{
+		{level: "debug", expected: slog.LevelDebug},
+		{level: "WARN", expected: slog.LevelWarn},
+		{level: "verbose", expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			level, err := ParseLevel(tt.level)
+			if tt.expectedError {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, level)
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
+)
+
+const namespace = "user_news_api"
+
+// Limiter decisions, see Registry.ObserveLimit.
+const (
+	DecisionAllowed = "allowed"
+	DecisionDenied  = "denied"
+)
+
+// NewRegistry returns the Registry with every collector of the API, besides the Go runtime and process ones.
+func NewRegistry() *Registry {
+	r := &Registry{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route, method and status code.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of the HTTP requests by route, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		limiterDecisions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "limiter_decisions_total",
+			Help:      "Rate limiter decisions by message type, either allowed or denied.",
+		}, []string{"message_type", "decision"}),
+		redisDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "redis_command_duration_seconds",
+			Help:      "Latency of the Redis commands, pipelines are observed as a single command.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"command", "error"}),
+		smtpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "smtp_send_duration_seconds",
+			Help:      "Latency of the mails sent by provider and error class, which is ok when they are sent.",
+			Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		}, []string{"provider", "class"}),
+		deliveries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "deliveries_total",
+			Help:      "Delivered notifications by message type, channel and outcome, either sent or failed.",
+		}, []string{"message_type", "channel", "outcome"}),
+	}
+
+	r.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		r.httpRequests,
+		r.httpDuration,
+		r.limiterDecisions,
+		r.redisDuration,
+		r.smtpDuration,
+		r.deliveries,
+	)
+
+	return r
+}
+
+// Registry records the metrics of the API and exposes them to Prometheus, see Handler.
+type Registry struct {
+	registry         *prometheus.Registry
+	httpRequests     *prometheus.CounterVec
+	httpDuration     *prometheus.HistogramVec
+	limiterDecisions *prometheus.CounterVec
+	redisDuration    *prometheus.HistogramVec
+	smtpDuration     *prometheus.HistogramVec
+	deliveries       *prometheus.CounterVec
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (r *Registry) Handler() http.Handler {
+	return promhttp.HandlerFor(r.registry, promhttp.HandlerOpts{Registry: r.registry})
+}
+
+// ObserveRequest records an HTTP request. The route is the pattern matched by the router,
+// so the path parameters do not create a series each.
+func (r *Registry) ObserveRequest(route string, method string, status int, duration time.Duration) {
+	statusStr := strconv.Itoa(status)
+
+	r.httpRequests.WithLabelValues(route, method, statusStr).Inc()
+	r.httpDuration.WithLabelValues(route, method, statusStr).Observe(duration.Seconds())
+}
+
+func (r *Registry) ObserveLimit(messageType string, reached bool) {
+	decision := DecisionAllowed
+	if reached {
+		decision = DecisionDenied
+	}
+
+	r.limiterDecisions.WithLabelValues(messageType, decision).Inc()
+}
+
+func (r *Registry) ObserveSend(provider string, class string, duration time.Duration) {
+	r.smtpDuration.WithLabelValues(provider, class).Observe(duration.Seconds())
+}
+
+func (r *Registry) ObserveDelivery(messageType string, channel string, outcome string) {
+	r.deliveries.WithLabelValues(messageType, channel, outcome).Inc()
+}
+
+// RegisterQueueDepth exposes the amount of notifications waiting in the queue, read by depth on every scrape.
+func (r *Registry) RegisterQueueDepth(depth func(context.Context) (int64, error)) {
+	r.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queue_depth",
+		Help:      "Notifications waiting in the queue, either not read yet or not acknowledged. It is -1 when it cannot be read.",
+	}, func() float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		value, err := depth(ctx)
+		if err != nil {
+			return -1
+		}
+
+		return float64(value)
+	}))
+}
+
+// RedisHook returns the hook observing the latency of every command of the Redis client it is added to.
+func (r *Registry) RedisHook() redis.Hook {
+	return redisHook{duration: r.redisDuration}
+}
+
+type redisHook struct {
+	duration *prometheus.HistogramVec
+}
+
+func (h redisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network string, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (h redisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+
+		h.observe(cmd.Name(), err, time.Since(start))
+
+		return err
+	}
+}
+
+func (h redisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+
+		h.observe("pipeline", err, time.Since(start))
+
+		return err
+	}
+}
+
+// observe does not count redis.Nil as an error, it is the reply of the missing keys.
+func (h redisHook) observe(command string, err error, duration time.Duration) {
+	failed := err != nil && !errors.Is(err, redis.Nil)
+
+	h.duration.WithLabelValues(command, strconv.FormatBool(failed)).Observe(duration.Seconds())
+}
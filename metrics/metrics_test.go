@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+
+	r.ObserveRequest("/notifications/{id}", "GET", 200, 10*time.Millisecond)
+	r.ObserveRequest("/notifications/{id}", "GET", 200, 20*time.Millisecond)
+	r.ObserveLimit("News", false)
+	r.ObserveLimit("News", true)
+	r.ObserveLimit("News", true)
+	r.ObserveSend("primary", "transient", time.Second)
+	r.ObserveDelivery("News", "email", "sent")
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(r.httpRequests.WithLabelValues("/notifications/{id}", "GET", "200")))
+	assert.Equal(t, 1, testutil.CollectAndCount(r.httpDuration))
+	assert.Equal(t, 1.0, testutil.ToFloat64(r.limiterDecisions.WithLabelValues("News", DecisionAllowed)))
+	assert.Equal(t, 2.0, testutil.ToFloat64(r.limiterDecisions.WithLabelValues("News", DecisionDenied)))
+	assert.Equal(t, 1, testutil.CollectAndCount(r.smtpDuration, "user_news_api_smtp_send_duration_seconds"))
+	assert.Equal(t, 1.0, testutil.ToFloat64(r.deliveries.WithLabelValues("News", "email", "sent")))
+}
+
+func TestRegistryQueueDepth(t *testing.T) {
+	tests := []struct {
+		name     string
+		depth    int64
+		err      error
+		expected string
+	}{
+		{name: "depth read", depth: 7, expected: "user_news_api_queue_depth 7"},
+		{name: "depth not read", err: errors.New("error"), expected: "user_news_api_queue_depth -1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry()
+			r.RegisterQueueDepth(func(ctx context.Context) (int64, error) {
+				return tt.depth, tt.err
+			})
+
+			rec := httptest.NewRecorder()
+			r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+			body, err := io.ReadAll(rec.Result().Body)
+			require.NoError(t, err)
+			assert.Contains(t, string(body), tt.expected)
+		})
+	}
+}
+
+func TestRedisHook(t *testing.T) {
+	r := NewRegistry()
+	hook := r.RedisHook()
+
+	process := hook.ProcessHook(func(ctx context.Context, cmd redis.Cmder) error {
+		return cmd.Err()
+	})
+
+	missing := redis.NewStringCmd(context.Background(), "get", "key")
+	missing.SetErr(redis.Nil)
+
+	failed := redis.NewIntCmd(context.Background(), "incr", "key")
+	failed.SetErr(errors.New("error"))
+
+	assert.ErrorIs(t, process(context.Background(), missing), redis.Nil)
+	assert.Error(t, process(context.Background(), failed))
+
+	pipeline := hook.ProcessPipelineHook(func(ctx context.Context, cmds []redis.Cmder) error {
+		return nil
+	})
+	assert.NoError(t, pipeline(context.Background(), []redis.Cmder{missing, failed}))
+
+	// a missing key is not a failed command
+	assert.Equal(t, 3, testutil.CollectAndCount(r.redisDuration))
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	body, err := io.ReadAll(rec.Result().Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `user_news_api_redis_command_duration_seconds_count{command="get",error="false"} 1`)
+	assert.Contains(t, string(body), `user_news_api_redis_command_duration_seconds_count{command="incr",error="true"} 1`)
+	assert.Contains(t, string(body), `user_news_api_redis_command_duration_seconds_count{command="pipeline",error="false"} 1`)
+}
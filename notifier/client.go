@@ -2,29 +2,56 @@ package notifier
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
+	"user_news_api/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gopkg.in/mail.v2"
 )
 
+// Options configure a mail server. Name, Priority and Weight are only used when it is a Provider of a FailoverClient.
 type Options struct {
-	Host     string
-	Port     int
-	Username string
-	Password string
+	Name     string `json:"name"`
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Priority int    `json:"priority"`
+	Weight   int    `json:"weight"`
+
+	DKIM    *DKIMSigner `json:"-"` // DKIM signs the mails when it is set
+	Dialer  Dialer      `json:"-"` // Dialer replaces the mail server, e.g. with a sink in development
+	Metrics Metrics     `json:"-"` // Metrics observes every attempt when it is set
 }
 
 func NewClient(options Options) Client {
-	return Client{
-		sender: options.Username,
-		dialer: mail.NewDialer(
+	dialer := options.Dialer
+	if dialer == nil {
+		dialer = mail.NewDialer(
 			options.Host,
 			options.Port,
 			options.Username,
 			options.Password,
-		),
+		)
+	}
+
+	return Client{
+		name:    options.Name,
+		sender:  options.Username,
+		dkim:    options.DKIM,
+		dialer:  dialer,
+		metrics: options.Metrics,
 	}
 }
 
+// Metrics is an abstraction for metrics.Registry making it mockeable
+type Metrics interface {
+	ObserveSend(provider string, class string, duration time.Duration)
+}
+
 // Dialer is an abstraction for mail.Dialer making it mockeable
 type Dialer interface {
 	DialAndSend(m ...*mail.Message) error
@@ -32,26 +59,157 @@ type Dialer interface {
 
 // Client represents a mail sender, all the messages are sent from the same address.
 type Client struct {
-	sender string
-	dialer Dialer
+	name    string // name is the provider of the metrics
+	sender  string
+	dialer  Dialer
+	dkim    *DKIMSigner // dkim is nil when the mails are not signed
+	metrics Metrics     // metrics is nil when the attempts are not observed
 }
 
 type NotifyToOptions struct {
-	To      string
-	Subject string
-	Body    string // Body must be HTML formatted
+	To             string
+	Subject        string
+	Body           string // Body must be HTML formatted
+	UnsubscribeURL string // UnsubscribeURL adds the RFC 8058 one-click unsubscribe headers, it must be HTTPS in production
 }
 
-func (c Client) NotifyTo(_ context.Context, options NotifyToOptions) error {
+// NotifyTo sends the mail within the deadline of the context.
+// mail.Dialer does not accept a context, so its timeout is bounded by the deadline instead, see boundedDialer,
+// and the connection is given up when the context is done before it is ready, see dialAndSend.
+// The outcome of the SMTP round trip is always the one returned: a mail delivered once the context is done
+// is not reported as failed, otherwise it would be sent again by the retries.
+func (c Client) NotifyTo(ctx context.Context, options NotifyToOptions) (err error) {
+	_, span := tracing.Start(ctx, "Client.NotifyTo", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("provider", c.name)))
+	defer func() {
+		span.SetAttributes(attribute.String("class", ErrorClass(err)))
+		tracing.End(span, err)
+	}()
+
+	if c.metrics != nil {
+		start := time.Now()
+		defer func() {
+			c.metrics.ObserveSend(c.name, ErrorClass(err), time.Since(start))
+		}()
+	}
+
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+
 	msg := mail.NewMessage()
 	msg.SetHeader("From", c.sender)
 	msg.SetHeader("To", options.To)
 	msg.SetHeader("Subject", options.Subject)
 	msg.SetBody("text/html", options.Body)
 
-	if err := c.dialer.DialAndSend(msg); err != nil {
-		return fmt.Errorf("unexpected error sending mail due to: %w", err)
+	if options.UnsubscribeURL != "" {
+		msg.SetHeader("List-Unsubscribe", fmt.Sprintf("<%s>", options.UnsubscribeURL))
+		msg.SetHeader("List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+	}
+
+	if c.dkim != nil {
+		if err := c.dkim.SignMessage(msg); err != nil {
+			return err
+		}
+	}
+
+	if err := dialAndSend(ctx, c.boundedDialer(ctx), msg); err != nil {
+		if errors.Is(err, errDialAbandoned) {
+			return connectContextError(ctx.Err())
+		}
+
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return contextError(ctxErr)
+		}
+
+		return sendError(err)
 	}
 
 	return nil
 }
+
+// boundedDialer limits the timeout of the SMTP dialer to the time left until the deadline of the context.
+// The timeout applies to the connection and to the sending on their own, so a mail server not answering
+// is given up around the deadline. Other dialers, like the sink, are used as they are.
+func (c Client) boundedDialer(ctx context.Context) Dialer {
+	dialer, ok := c.dialer.(*mail.Dialer)
+	if !ok {
+		return c.dialer
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return c.dialer
+	}
+
+	// a timeout of 0 disables it, so the deadline reached meanwhile still leaves a positive one
+	left := max(time.Until(deadline), time.Millisecond)
+
+	bounded := *dialer
+	if bounded.Timeout <= 0 || left < bounded.Timeout {
+		bounded.Timeout = left
+	}
+
+	return &bounded
+}
+
+var errDialAbandoned = errors.New("dial abandoned")
+
+// dialAndSend is mail.Dialer.DialAndSend returning errDialAbandoned as soon as the context is done while connecting,
+// so a client going away is not kept waiting for the dialer timeout by a mail server not answering. The mail was not
+// handed to the mail server yet, so it is not delivered, and the connection is closed once the dial returns.
+// Once connected, the sending is only bounded by the dialer timeout. Other dialers, like the sink, send right away.
+func dialAndSend(ctx context.Context, dialer Dialer, msg *mail.Message) error {
+	smtpDialer, ok := dialer.(*mail.Dialer)
+	if !ok {
+		return dialer.DialAndSend(msg)
+	}
+
+	type dialResult struct {
+		sender mail.SendCloser
+		err    error
+	}
+
+	dialed := make(chan dialResult, 1)
+	go func() {
+		sender, err := smtpDialer.Dial()
+		dialed <- dialResult{sender: sender, err: err}
+	}()
+
+	select {
+	case result := <-dialed:
+		if result.err != nil {
+			return result.err
+		}
+
+		defer result.sender.Close()
+
+		return mail.Send(result.sender, msg)
+	case <-ctx.Done():
+		go func() {
+			if result := <-dialed; result.err == nil {
+				_ = result.sender.Close()
+			}
+		}()
+
+		return errDialAbandoned
+	}
+}
+
+// connectContextError is the contextError of a mail given up before it was handed to the mail server.
+func connectContextError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: connecting exceeded the deadline: %w", ErrConnectTimeout, err)
+	}
+
+	return fmt.Errorf("connecting to the mail server was canceled: %w", err)
+}
+
+func contextError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: sending mail exceeded the deadline: %w", ErrTimeout, err)
+	}
+
+	return fmt.Errorf("sending mail was canceled: %w", err)
+}
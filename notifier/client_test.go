@@ -4,12 +4,31 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/mock"
+	"net"
+	"net/textproto"
 	"testing"
+	"time"
 	"user_news_api/notifier/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gopkg.in/mail.v2"
 )
 
+type timeoutErr struct{}
+
+func (timeoutErr) Error() string   { return "i/o timeout" }
+func (timeoutErr) Timeout() bool   { return true }
+func (timeoutErr) Temporary() bool { return true }
+
+var _ net.Error = timeoutErr{}
+
 func TestClientNotifyTo(t *testing.T) {
 	customErr := errors.New("custom error")
 
@@ -25,6 +44,20 @@ func TestClientNotifyTo(t *testing.T) {
 			},
 			expected: fmt.Errorf("unexpected error sending mail due to: %w", customErr),
 		},
+		{
+			name: "return dialer timeout",
+			mockApplier: func(m *mocks.Dialer) {
+				m.On("DialAndSend", mock.Anything).Return(timeoutErr{}).Once()
+			},
+			expected: fmt.Errorf("%w: connecting to the mail server timed out: %w", ErrConnectTimeout, timeoutErr{}),
+		},
+		{
+			name: "return sending timeout",
+			mockApplier: func(m *mocks.Dialer) {
+				m.On("DialAndSend", mock.Anything).Return(&mail.SendError{Cause: timeoutErr{}}).Once()
+			},
+			expected: fmt.Errorf("%w: sending mail timed out: %w", ErrTimeout, &mail.SendError{Cause: timeoutErr{}}),
+		},
 		{
 			name: "no error",
 			mockApplier: func(m *mocks.Dialer) {
@@ -53,3 +86,230 @@ func TestClientNotifyTo(t *testing.T) {
 		})
 	}
 }
+
+func TestClientNotifyToContext(t *testing.T) {
+	tests := []struct {
+		name        string
+		ctx         func() (context.Context, context.CancelFunc)
+		mockApplier func(m *mocks.Dialer)
+		expectedErr error
+		isTimeout   bool
+	}{
+		{
+			name: "deadline exceeded while sending",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 10*time.Millisecond)
+			},
+			mockApplier: func(m *mocks.Dialer) {
+				m.On("DialAndSend", mock.Anything).Return(timeoutErr{}).After(20 * time.Millisecond).Once()
+			},
+			expectedErr: context.DeadlineExceeded,
+			isTimeout:   true,
+		},
+		{
+			name: "sent right after the deadline",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 10*time.Millisecond)
+			},
+			mockApplier: func(m *mocks.Dialer) {
+				m.On("DialAndSend", mock.Anything).Return(nil).After(20 * time.Millisecond).Once()
+			},
+			expectedErr: nil,
+			isTimeout:   false,
+		},
+		{
+			name: "deadline exceeded before dialing",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+			},
+			mockApplier: func(m *mocks.Dialer) {},
+			expectedErr: context.DeadlineExceeded,
+			isTimeout:   true,
+		},
+		{
+			name: "canceled before dialing",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				return ctx, cancel
+			},
+			mockApplier: func(m *mocks.Dialer) {},
+			expectedErr: context.Canceled,
+			isTimeout:   false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dMock := mocks.NewDialer(t)
+
+			test.mockApplier(dMock)
+
+			c := Client{
+				sender: "sender",
+				dialer: dMock,
+			}
+
+			ctx, cancel := test.ctx()
+			defer cancel()
+
+			err := c.NotifyTo(ctx, NotifyToOptions{
+				To:      "email",
+				Subject: "status",
+				Body:    "message",
+			})
+
+			assert.ErrorIs(t, err, test.expectedErr)
+			assert.Equal(t, test.isTimeout, errors.Is(err, ErrTimeout))
+		})
+	}
+}
+
+func TestClientBoundedDialer(t *testing.T) {
+	tests := []struct {
+		name        string
+		timeout     time.Duration
+		ctxTimeout  time.Duration
+		expectedMax time.Duration
+		expectedMin time.Duration
+	}{
+		{name: "without deadline", timeout: 10 * time.Second, expectedMin: 10 * time.Second, expectedMax: 10 * time.Second},
+		{name: "deadline before the timeout", timeout: 10 * time.Second, ctxTimeout: time.Second, expectedMin: time.Millisecond, expectedMax: time.Second},
+		{name: "deadline after the timeout", timeout: time.Second, ctxTimeout: time.Minute, expectedMin: time.Second, expectedMax: time.Second},
+		{name: "timeout disabled", ctxTimeout: time.Second, expectedMin: time.Millisecond, expectedMax: time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dialer := mail.NewDialer("localhost", 25, "sender", "secret")
+			dialer.Timeout = tt.timeout
+
+			c := NewClient(Options{Dialer: dialer})
+
+			ctx := context.Background()
+			if tt.ctxTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.ctxTimeout)
+				defer cancel()
+			}
+
+			bounded, ok := c.boundedDialer(ctx).(*mail.Dialer)
+			require.True(t, ok)
+
+			assert.GreaterOrEqual(t, bounded.Timeout, tt.expectedMin)
+			assert.LessOrEqual(t, bounded.Timeout, tt.expectedMax)
+			assert.Equal(t, tt.timeout, dialer.Timeout)
+		})
+	}
+}
+
+func TestClientNotifyToUnsubscribeHeaders(t *testing.T) {
+	tests := []struct {
+		name           string
+		unsubscribeURL string
+		expected       map[string][]string
+	}{
+		{
+			name:           "one-click unsubscribe",
+			unsubscribeURL: "https://api.example.com/unsubscribe?token=abc",
+			expected: map[string][]string{
+				"List-Unsubscribe":      {"<https://api.example.com/unsubscribe?token=abc>"},
+				"List-Unsubscribe-Post": {"List-Unsubscribe=One-Click"},
+			},
+		},
+		{
+			name:     "without unsubscribe link",
+			expected: map[string][]string{"List-Unsubscribe": nil, "List-Unsubscribe-Post": nil},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dMock := mocks.NewDialer(t)
+
+			dMock.On("DialAndSend", mock.MatchedBy(func(msg *mail.Message) bool {
+				for header, values := range test.expected {
+					if !assert.Equal(t, values, msg.GetHeader(header)) {
+						return false
+					}
+				}
+
+				return true
+			})).Return(nil).Once()
+
+			c := Client{
+				sender: "sender",
+				dialer: dMock,
+			}
+
+			assert.NoError(t, c.NotifyTo(context.TODO(), NotifyToOptions{
+				To:             "email",
+				Subject:        "Marketing",
+				Body:           "message",
+				UnsubscribeURL: test.unsubscribeURL,
+			}))
+		})
+	}
+}
+
+func TestClientNotifyToMetrics(t *testing.T) {
+	tests := []struct {
+		name          string
+		dialerErr     error
+		expectedClass string
+	}{
+		{name: "sent", expectedClass: ClassOK},
+		{name: "server busy", dialerErr: &textproto.Error{Code: 421}, expectedClass: ClassTransient},
+		{name: "recipient rejected", dialerErr: &textproto.Error{Code: 550}, expectedClass: ClassPermanent},
+		{name: "timed out", dialerErr: timeoutErr{}, expectedClass: ClassTimeout},
+		{name: "unclassified error", dialerErr: errors.New("custom error"), expectedClass: ClassUnknown},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dMock := mocks.NewDialer(t)
+			mMock := mocks.NewMetrics(t)
+
+			dMock.On("DialAndSend", mock.Anything).Return(test.dialerErr).Once()
+			mMock.On("ObserveSend", "primary", test.expectedClass, mock.AnythingOfType("time.Duration")).Once()
+
+			c := NewClient(Options{Name: "primary", Username: "sender", Dialer: dMock, Metrics: mMock})
+
+			_ = c.NotifyTo(context.TODO(), NotifyToOptions{To: "email", Subject: "status", Body: "message"})
+		})
+	}
+}
+
+func TestClientNotifyToTracing(t *testing.T) {
+	tests := []struct {
+		name           string
+		dialerErr      error
+		expectedClass  string
+		expectedStatus codes.Code
+	}{
+		{name: "sent", expectedClass: ClassOK, expectedStatus: codes.Unset},
+		{name: "recipient rejected", dialerErr: &textproto.Error{Code: 550}, expectedClass: ClassPermanent, expectedStatus: codes.Error},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+			dMock := mocks.NewDialer(t)
+			dMock.On("DialAndSend", mock.Anything).Return(test.dialerErr).Once()
+
+			c := NewClient(Options{Name: "primary", Username: "sender", Dialer: dMock})
+
+			_ = c.NotifyTo(context.TODO(), NotifyToOptions{To: "email", Subject: "status", Body: "message"})
+
+			ended := recorder.Ended()
+			assert.Len(t, ended, 1)
+			assert.Equal(t, "Client.NotifyTo", ended[0].Name())
+			assert.Equal(t, test.expectedStatus, ended[0].Status().Code)
+			assert.Contains(t, ended[0].Attributes(), attribute.String("provider", "primary"))
+			assert.Contains(t, ended[0].Attributes(), attribute.String("class", test.expectedClass))
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package notifier
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/mail.v2"
+)
+
+var (
+	ErrDKIMKeyNotValid = errors.New("dkim private key not valid")
+)
+
+// DefaultDKIMHeaders are the header fields signed when no other ones are chosen.
+// The unsubscribe headers are signed because the mailbox providers only trust them when they are.
+var DefaultDKIMHeaders = []string{
+	"From", "To", "Subject", "Date", "MIME-Version", "Content-Type", "List-Unsubscribe", "List-Unsubscribe-Post",
+}
+
+type DKIMOptions struct {
+	Domain   string   // Domain is the signing domain, it must match the domain of the sender for DMARC
+	Selector string   // Selector locates the public key in DNS, at <selector>._domainkey.<domain>
+	KeyPath  string   // KeyPath is the PEM file with the RSA or Ed25519 private key
+	Headers  []string // Headers are the signed header fields, DefaultDKIMHeaders when empty. From is always signed
+}
+
+// NewDKIMSigner loads the private key of the options, either PKCS #8 or PKCS #1 for RSA keys.
+func NewDKIMSigner(options DKIMOptions) (DKIMSigner, error) {
+	pemBytes, err := os.ReadFile(options.KeyPath)
+	if err != nil {
+		return DKIMSigner{}, fmt.Errorf("error reading dkim private key due to: %w", err)
+	}
+
+	key, err := parseDKIMKey(pemBytes)
+	if err != nil {
+		return DKIMSigner{}, err
+	}
+
+	headers := options.Headers
+	if len(headers) == 0 {
+		headers = DefaultDKIMHeaders
+	}
+
+	if !containsHeader(headers, "From") {
+		headers = append([]string{"From"}, headers...)
+	}
+
+	return DKIMSigner{
+		domain:   options.Domain,
+		selector: options.Selector,
+		headers:  headers,
+		key:      key,
+		now:      time.Now,
+	}, nil
+}
+
+// DKIMSigner adds a DKIM-Signature (RFC 6376) to the mails, with relaxed/relaxed canonicalization.
+// RSA keys sign with rsa-sha256 and Ed25519 keys with ed25519-sha256 (RFC 8463).
+type DKIMSigner struct {
+	domain   string
+	selector string
+	headers  []string
+	key      crypto.Signer
+	now      func() time.Time
+}
+
+// SignMessage renders the message and sets its DKIM-Signature header. The Date header is fixed before,
+// otherwise it would be set again when the message is sent, breaking the signature.
+func (s DKIMSigner) SignMessage(msg *mail.Message) error {
+	if len(msg.GetHeader("Date")) == 0 {
+		msg.SetDateHeader("Date", s.now())
+	}
+
+	raw, err := render(msg)
+	if err != nil {
+		return err
+	}
+
+	signature, err := s.Sign(raw)
+	if err != nil {
+		return err
+	}
+
+	msg.SetHeader("DKIM-Signature", signature)
+
+	return nil
+}
+
+// Sign returns the value of the DKIM-Signature header of the raw message.
+func (s DKIMSigner) Sign(raw []byte) (string, error) {
+	headers, body := splitMessage(raw)
+
+	bodyHash := sha256.Sum256(relaxedBody(body))
+
+	algorithm := "rsa-sha256"
+	if _, ok := s.key.(ed25519.PrivateKey); ok {
+		algorithm = "ed25519-sha256"
+	}
+
+	signature := fmt.Sprintf("v=1; a=%s; c=relaxed/relaxed; d=%s; s=%s; t=%d; h=%s; bh=%s; b=",
+		algorithm, s.domain, s.selector, s.now().Unix(),
+		strings.ToLower(strings.Join(s.headers, ":")), base64.StdEncoding.EncodeToString(bodyHash[:]))
+
+	var data bytes.Buffer
+	for _, header := range selectHeaders(headers, s.headers) {
+		data.WriteString(relaxedHeader(header))
+		data.WriteString("\r\n")
+	}
+
+	// the signature header is signed last, with an empty b= tag and without the trailing CRLF
+	data.WriteString(relaxedHeader("DKIM-Signature: " + signature))
+
+	digest := sha256.Sum256(data.Bytes())
+
+	var signed []byte
+	var err error
+
+	switch key := s.key.(type) {
+	case ed25519.PrivateKey:
+		signed = ed25519.Sign(key, digest[:])
+	default:
+		signed, err = key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("error signing mail due to: %w", err)
+	}
+
+	return signature + base64.StdEncoding.EncodeToString(signed), nil
+}
+
+func parseDKIMKey(pemBytes []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no PEM block found", ErrDKIMKeyNotValid)
+	}
+
+	if block.Type == "RSA PRIVATE KEY" {
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrDKIMKeyNotValid, err)
+		}
+
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDKIMKeyNotValid, err)
+	}
+
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		return key, nil
+	case ed25519.PrivateKey:
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("%w: only RSA and Ed25519 keys are supported", ErrDKIMKeyNotValid)
+}
+
+// splitMessage returns the header fields of the raw message, unfolded lines kept together, and its body.
+func splitMessage(raw []byte) ([]string, []byte) {
+	head, body, found := bytes.Cut(raw, []byte("\r\n\r\n"))
+	if !found {
+		return nil, nil
+	}
+
+	var headers []string
+	for _, line := range strings.Split(string(head), "\r\n") {
+		if len(headers) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			headers[len(headers)-1] += "\r\n" + line
+
+			continue
+		}
+
+		headers = append(headers, line)
+	}
+
+	return headers, body
+}
+
+// selectHeaders picks the header fields in the order of names. When a field appears several times,
+// the last one is picked first, as verifiers do. Missing fields are left out.
+func selectHeaders(headers []string, names []string) []string {
+	used := make(map[int]bool)
+
+	var selected []string
+	for _, name := range names {
+		for i := len(headers) - 1; i >= 0; i-- {
+			field, _, _ := strings.Cut(headers[i], ":")
+			if !used[i] && strings.EqualFold(strings.TrimSpace(field), name) {
+				used[i] = true
+				selected = append(selected, headers[i])
+
+				break
+			}
+		}
+	}
+
+	return selected
+}
+
+// relaxedHeader canonicalizes a header field: lowercase name, unfolded value and single spaces (RFC 6376, 3.4.2).
+func relaxedHeader(header string) string {
+	name, value, _ := strings.Cut(header, ":")
+
+	value = strings.ReplaceAll(value, "\r\n", "")
+
+	return strings.ToLower(strings.TrimSpace(name)) + ":" + strings.Join(fieldsWSP(value), " ")
+}
+
+// relaxedBody canonicalizes the body: single spaces, no trailing spaces nor empty lines at the end (RFC 6376, 3.4.4).
+func relaxedBody(body []byte) []byte {
+	lines := strings.Split(string(body), "\r\n")
+
+	for i, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		lines[i] = strings.Join(fieldsWSP(line), " ")
+
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			lines[i] = " " + lines[i]
+		}
+	}
+
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	if len(lines) == 0 {
+		return nil
+	}
+
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
+
+// fieldsWSP splits s around the spaces and tabs, the only whitespace of RFC 6376.
+func fieldsWSP(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == '\t' })
+}
+
+func containsHeader(headers []string, name string) bool {
+	for _, header := range headers {
+		if strings.EqualFold(header, name) {
+			return true
+		}
+	}
+
+	return false
+}
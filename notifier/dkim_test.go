@@ -0,0 +1,242 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"user_news_api/notifier/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/mail.v2"
+)
+
+func TestClientNotifyToDKIM(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	rsaPKCS8, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	require.NoError(t, err)
+
+	edPKCS8, err := x509.MarshalPKCS8PrivateKey(edKey)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		block     *pem.Block
+		publicKey crypto.PublicKey
+		algorithm string
+	}{
+		{
+			name:      "RSA PKCS #1 key",
+			block:     &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)},
+			publicKey: &rsaKey.PublicKey,
+			algorithm: "rsa-sha256",
+		},
+		{
+			name:      "RSA PKCS #8 key",
+			block:     &pem.Block{Type: "PRIVATE KEY", Bytes: rsaPKCS8},
+			publicKey: &rsaKey.PublicKey,
+			algorithm: "rsa-sha256",
+		},
+		{
+			name:      "Ed25519 key",
+			block:     &pem.Block{Type: "PRIVATE KEY", Bytes: edPKCS8},
+			publicKey: edPublic,
+			algorithm: "ed25519-sha256",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := NewDKIMSigner(DKIMOptions{
+				Domain:   "example.com",
+				Selector: "news",
+				KeyPath:  writeKey(t, tt.block),
+			})
+			require.NoError(t, err)
+
+			var raw bytes.Buffer
+
+			dMock := mocks.NewDialer(t)
+			dMock.On("DialAndSend", mock.Anything).Run(func(args mock.Arguments) {
+				_, err := args.Get(0).(*mail.Message).WriteTo(&raw)
+				require.NoError(t, err)
+			}).Return(nil).Once()
+
+			c := Client{sender: "sender@example.com", dialer: dMock, dkim: &signer}
+
+			err = c.NotifyTo(context.Background(), NotifyToOptions{
+				To:             "user@example.com",
+				Subject:        "Notification",
+				Body:           "<p>Your  news   are here</p>\n\n",
+				UnsubscribeURL: "https://api.example.com/unsubscribe?token=abc",
+			})
+			require.NoError(t, err)
+
+			tags := verifyDKIM(t, raw.Bytes(), tt.publicKey)
+
+			assert.Equal(t, tt.algorithm, tags["a"])
+			assert.Equal(t, "relaxed/relaxed", tags["c"])
+			assert.Equal(t, "example.com", tags["d"])
+			assert.Equal(t, "news", tags["s"])
+			assert.Equal(t, "from:to:subject:date:mime-version:content-type:list-unsubscribe:list-unsubscribe-post", tags["h"])
+
+			// the signature breaks when a signed header is changed on the way
+			tampered := bytes.Replace(raw.Bytes(), []byte("Subject: Notification"), []byte("Subject: Offer"), 1)
+			assert.Error(t, verify(tampered, tt.publicKey))
+		})
+	}
+}
+
+func TestNewDKIMSigner(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	edPKCS8, err := x509.MarshalPKCS8PrivateKey(edKey)
+	require.NoError(t, err)
+
+	signer, err := NewDKIMSigner(DKIMOptions{
+		Domain:   "example.com",
+		Selector: "news",
+		KeyPath:  writeKey(t, &pem.Block{Type: "PRIVATE KEY", Bytes: edPKCS8}),
+		Headers:  []string{"Subject", "To"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"From", "Subject", "To"}, signer.headers)
+	assert.Equal(t, edKey, signer.key)
+
+	_, err = NewDKIMSigner(DKIMOptions{KeyPath: writeKey(t, &pem.Block{Type: "PRIVATE KEY", Bytes: []byte("bad")})})
+	assert.ErrorIs(t, err, ErrDKIMKeyNotValid)
+
+	_, err = NewDKIMSigner(DKIMOptions{KeyPath: filepath.Join(t.TempDir(), "missing.pem")})
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestRelaxedCanonicalization(t *testing.T) {
+	assert.Equal(t, "subject:Your news", relaxedHeader("SUBJECT :  Your\r\n\t news  "))
+	assert.Equal(t, []byte(" a b\r\n\r\nc\r\n"), relaxedBody([]byte("  a \t b \r\n\r\nc\r\n\r\n\r\n")))
+	assert.Nil(t, relaxedBody([]byte("\r\n\r\n")))
+}
+
+func writeKey(t *testing.T, block *pem.Block) string {
+	path := filepath.Join(t.TempDir(), "dkim.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(block), 0o600))
+
+	return path
+}
+
+// verifyDKIM checks the signature of the raw message as a receiving server does, returning its tags.
+func verifyDKIM(t *testing.T, raw []byte, publicKey crypto.PublicKey) map[string]string {
+	require.NoError(t, verify(raw, publicKey))
+
+	tags, _ := signatureTags(raw)
+
+	return tags
+}
+
+var wsp = regexp.MustCompile(`[ \t]+`)
+
+func verify(raw []byte, publicKey crypto.PublicKey) error {
+	tags, signatureHeader := signatureTags(raw)
+	if tags == nil {
+		return errors.New("no DKIM-Signature header")
+	}
+
+	head, body, _ := strings.Cut(string(raw), "\r\n\r\n")
+
+	// body: collapse the whitespace, strip it at the end of the lines and drop the trailing empty lines
+	lines := strings.Split(body, "\r\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(wsp.ReplaceAllString(lines[i], " "), " ")
+	}
+	canonBody := strings.TrimRight(strings.Join(lines, "\r\n"), "\r\n")
+	if canonBody != "" {
+		canonBody += "\r\n"
+	}
+
+	bodyHash := sha256.Sum256([]byte(canonBody))
+	if base64.StdEncoding.EncodeToString(bodyHash[:]) != tags["bh"] {
+		return errors.New("body hash does not match")
+	}
+
+	// headers: unfold them, and pick the ones of h= from the bottom up
+	fields := strings.Split(regexp.MustCompile(`\r\n[ \t]`).ReplaceAllString(head, " "), "\r\n")
+	canon := func(field string) string {
+		name, value, _ := strings.Cut(field, ":")
+		return strings.ToLower(strings.TrimSpace(name)) + ":" + strings.TrimSpace(wsp.ReplaceAllString(value, " "))
+	}
+
+	var data strings.Builder
+	used := map[int]bool{}
+	for _, name := range strings.Split(tags["h"], ":") {
+		for i := len(fields) - 1; i >= 0; i-- {
+			if !used[i] && strings.HasPrefix(canon(fields[i]), name+":") {
+				used[i] = true
+				data.WriteString(canon(fields[i]) + "\r\n")
+
+				break
+			}
+		}
+	}
+
+	unsigned := regexp.MustCompile(`b=[A-Za-z0-9+/=\s]*$`).ReplaceAllString(canon(signatureHeader), "b=")
+	data.WriteString(unsigned)
+
+	digest := sha256.Sum256([]byte(data.String()))
+
+	signature, err := base64.StdEncoding.DecodeString(wsp.ReplaceAllString(tags["b"], ""))
+	if err != nil {
+		return err
+	}
+
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature)
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, digest[:], signature) {
+			return errors.New("ed25519 signature does not match")
+		}
+	}
+
+	return nil
+}
+
+func signatureTags(raw []byte) (map[string]string, string) {
+	head, _, _ := strings.Cut(string(raw), "\r\n\r\n")
+
+	for _, field := range strings.Split(regexp.MustCompile(`\r\n[ \t]`).ReplaceAllString(head, " "), "\r\n") {
+		name, value, _ := strings.Cut(field, ":")
+		if !strings.EqualFold(name, "DKIM-Signature") {
+			continue
+		}
+
+		tags := map[string]string{}
+		for _, tag := range strings.Split(value, ";") {
+			key, tagValue, _ := strings.Cut(strings.TrimSpace(tag), "=")
+			tags[key] = strings.TrimSpace(tagValue)
+		}
+
+		return tags, field
+	}
+
+	return nil, ""
+}
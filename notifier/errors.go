@@ -0,0 +1,136 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+
+	"gopkg.in/mail.v2"
+)
+
+var (
+	ErrTimeout   = errors.New("notifier timeout")
+	ErrTransient = errors.New("transient notifier error")
+	ErrPermanent = errors.New("permanent notifier error")
+
+	// ErrConnectTimeout is an ErrTimeout before the mail was handed to the mail server, so it was never delivered.
+	ErrConnectTimeout = fmt.Errorf("%w connecting", ErrTimeout)
+
+	// ErrNoProvider is an ErrTransient when the circuits of every provider are open, so no provider was tried.
+	ErrNoProvider = fmt.Errorf("%w: no mail provider available", ErrTransient)
+)
+
+// Error classes, see ErrorClass.
+const (
+	ClassOK        = "ok"
+	ClassTimeout   = "timeout"
+	ClassTransient = "transient"
+	ClassPermanent = "permanent"
+	ClassCanceled  = "canceled"
+	ClassUnknown   = "unknown"
+)
+
+// ErrorClass returns the class of an error of the notifier, ClassOK when there is none.
+func ErrorClass(err error) string {
+	switch {
+	case err == nil:
+		return ClassOK
+	case errors.Is(err, ErrTimeout):
+		return ClassTimeout
+	case errors.Is(err, ErrTransient):
+		return ClassTransient
+	case errors.Is(err, ErrPermanent):
+		return ClassPermanent
+	case errors.Is(err, context.Canceled):
+		return ClassCanceled
+	}
+
+	return ClassUnknown
+}
+
+// Retryable tells if sending the mail again can succeed without delivering it twice. Permanent errors are rejected
+// again, and after a timeout sending the mail the mail server could have delivered it. Timeouts connecting are retried.
+func Retryable(err error) bool {
+	if errors.Is(err, ErrTimeout) {
+		return errors.Is(err, ErrConnectTimeout)
+	}
+
+	return !errors.Is(err, ErrPermanent)
+}
+
+// transientCodes are SMTP replies meaning the server could accept the message later.
+var transientCodes = map[int]bool{
+	421: true, // service not available, closing transmission channel
+	450: true, // mailbox unavailable, e.g. busy or greylisted
+	451: true, // local error in processing
+}
+
+// permanentCodes are SMTP replies meaning the message will never be accepted for the recipient.
+var permanentCodes = map[int]bool{
+	550: true, // mailbox unavailable, e.g. not found or rejected for policy reasons
+	553: true, // mailbox name not allowed
+}
+
+// SMTPResponse returns the reply of the mail server carried by the error, it is empty when there is none.
+func SMTPResponse(err error) string {
+	if protoErr, ok := reply(err); ok {
+		return fmt.Sprintf("%03d %s", protoErr.Code, protoErr.Msg)
+	}
+
+	return ""
+}
+
+// replyCode extracts the SMTP reply code from the errors returned by mail.Dialer.
+// mail.SendError does not implement Unwrap, so its cause is inspected explicitly.
+func replyCode(err error) (int, bool) {
+	if protoErr, ok := reply(err); ok {
+		return protoErr.Code, true
+	}
+
+	return 0, false
+}
+
+func reply(err error) (*textproto.Error, bool) {
+	var protoErr *textproto.Error
+	if errors.As(sendCause(err), &protoErr) {
+		return protoErr, true
+	}
+
+	return nil, false
+}
+
+// sendCause returns the cause of mail.SendError, the error itself otherwise.
+func sendCause(err error) error {
+	var sendErr *mail.SendError
+	if errors.As(err, &sendErr) {
+		return sendErr.Cause
+	}
+
+	return err
+}
+
+func sendError(err error) error {
+	// mail.Dialer reports its own dial and read/write timeouts as net.Error, within mail.SendError once sending.
+	var netErr net.Error
+	if errors.As(sendCause(err), &netErr) && netErr.Timeout() {
+		var sendErr *mail.SendError
+		if !errors.As(err, &sendErr) {
+			return fmt.Errorf("%w: connecting to the mail server timed out: %w", ErrConnectTimeout, err)
+		}
+
+		return fmt.Errorf("%w: sending mail timed out: %w", ErrTimeout, err)
+	}
+
+	if code, ok := replyCode(err); ok {
+		switch {
+		case transientCodes[code]:
+			return fmt.Errorf("%w: smtp server replied %d: %w", ErrTransient, code, err)
+		case permanentCodes[code]:
+			return fmt.Errorf("%w: smtp server replied %d: %w", ErrPermanent, code, err)
+		}
+	}
+
+	return fmt.Errorf("unexpected error sending mail due to: %w", err)
+}
@@ -0,0 +1,121 @@
+package notifier
+
+import (
+	"errors"
+	"fmt"
+	"net/textproto"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mail.v2"
+)
+
+func TestSendError(t *testing.T) {
+	customErr := errors.New("custom error")
+	greylisted := &textproto.Error{Code: 451, Msg: "greylisted, try again later"}
+	unknownUser := &textproto.Error{Code: 550, Msg: "no such user"}
+	authFailed := &textproto.Error{Code: 535, Msg: "authentication failed"}
+
+	tests := []struct {
+		name     string
+		err      error
+		expected error
+		class    error
+	}{
+		{
+			name:     "unclassified error",
+			err:      customErr,
+			expected: fmt.Errorf("unexpected error sending mail due to: %w", customErr),
+		},
+		{
+			name:     "transient reply",
+			err:      greylisted,
+			expected: fmt.Errorf("%w: smtp server replied %d: %w", ErrTransient, 451, greylisted),
+			class:    ErrTransient,
+		},
+		{
+			name:     "permanent reply inside a send error",
+			err:      &mail.SendError{Cause: unknownUser},
+			expected: fmt.Errorf("%w: smtp server replied %d: %w", ErrPermanent, 550, &mail.SendError{Cause: unknownUser}),
+			class:    ErrPermanent,
+		},
+		{
+			name:     "reply without class",
+			err:      authFailed,
+			expected: fmt.Errorf("unexpected error sending mail due to: %w", authFailed),
+		},
+		{
+			name:     "network timeout connecting",
+			err:      timeoutErr{},
+			expected: fmt.Errorf("%w: connecting to the mail server timed out: %w", ErrConnectTimeout, timeoutErr{}),
+			class:    ErrTimeout,
+		},
+		{
+			name:     "network timeout inside a send error",
+			err:      &mail.SendError{Cause: timeoutErr{}},
+			expected: fmt.Errorf("%w: sending mail timed out: %w", ErrTimeout, &mail.SendError{Cause: timeoutErr{}}),
+			class:    ErrTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := sendError(tt.err)
+
+			assert.Equal(t, tt.expected, err)
+
+			for _, class := range []error{ErrTimeout, ErrTransient, ErrPermanent} {
+				assert.Equal(t, class == tt.class, errors.Is(err, class), class.Error())
+			}
+
+			assert.Equal(t, tt.err == timeoutErr{}, errors.Is(err, ErrConnectTimeout))
+		})
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "unclassified error", err: errors.New("custom error"), expected: true},
+		{name: "transient error", err: fmt.Errorf("%w: smtp server replied 451", ErrTransient), expected: true},
+		{name: "permanent error", err: fmt.Errorf("%w: smtp server replied 550", ErrPermanent), expected: false},
+		{name: "timeout connecting", err: sendError(timeoutErr{}), expected: true},
+		{name: "timeout sending", err: sendError(&mail.SendError{Cause: timeoutErr{}}), expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Retryable(tt.err))
+		})
+	}
+}
+
+func TestSMTPResponse(t *testing.T) {
+	unknownUser := &textproto.Error{Code: 550, Msg: "no such user"}
+
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			name:     "reply wrapped by the client",
+			err:      sendError(&mail.SendError{Cause: unknownUser}),
+			expected: "550 no such user",
+		},
+		{
+			name:     "error without reply",
+			err:      sendError(errors.New("custom error")),
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, SMTPResponse(tt.err))
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultFailoverOptions open the circuit of a provider after a few failures in a row,
+// and try it again after half a minute.
+var DefaultFailoverOptions = FailoverOptions{
+	FailureThreshold: 3,
+	OpenTimeout:      30 * time.Second,
+}
+
+type FailoverOptions struct {
+	FailureThreshold int           // FailureThreshold is the amount of failures in a row opening the circuit of a provider
+	OpenTimeout      time.Duration // OpenTimeout is how long a provider is skipped before trying it again
+}
+
+// Circuit states of a provider, see FailoverClient.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// Provider is a mail server the FailoverClient can send through. Providers with a lower Priority are tried first,
+// and the ones with the same Priority share the mails according to their Weight.
+type Provider struct {
+	Name     string
+	Priority int
+	Weight   int // Weight is taken as 1 when it is not positive
+	Sender   Sender
+}
+
+// ProviderHealth is the state of a provider as tracked by the FailoverClient.
+type ProviderHealth struct {
+	Name                string `json:"name"`
+	Circuit             string `json:"circuit"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	Sent                int64  `json:"sent"`
+	Failed              int64  `json:"failed"`
+}
+
+// NewProvider returns the Provider sending through the mail server of the options, retrying its transient failures.
+func NewProvider(options Options, retryOptions RetryOptions) Provider {
+	return Provider{
+		Name:     options.Name,
+		Priority: options.Priority,
+		Weight:   options.Weight,
+		Sender:   NewRetryClient(NewClient(options), retryOptions),
+	}
+}
+
+func NewFailoverClient(providers []Provider, options FailoverOptions) FailoverClient {
+	client := FailoverClient{
+		options: options,
+		random:  rand.Intn,
+		now:     time.Now,
+	}
+
+	for _, p := range providers {
+		if p.Weight < 1 {
+			p.Weight = 1
+		}
+
+		client.providers = append(client.providers, &provider{Provider: p})
+	}
+
+	// the stable sort keeps the configured order within the same priority, it is the order of Health
+	sort.SliceStable(client.providers, func(i, j int) bool {
+		return client.providers[i].Priority < client.providers[j].Priority
+	})
+
+	return client
+}
+
+// FailoverClient sends every mail through the first healthy provider, moving to the next one when it fails.
+// Each provider has a circuit breaker: after FailureThreshold failures in a row it is skipped for OpenTimeout,
+// and then a single mail tries it again. Permanent errors are replies about the recipient, so they are returned
+// right away without counting against the provider. So are the timeouts once the mail was handed to the provider,
+// because it could still deliver it; only the timeouts connecting to it count as failures and fail over.
+type FailoverClient struct {
+	providers []*provider
+	options   FailoverOptions
+	random    func(int) int
+	now       func() time.Time
+}
+
+// provider tracks the health of a Provider, it is shared by the copies of the FailoverClient.
+type provider struct {
+	Provider
+
+	mu       sync.Mutex
+	failures int       // failures counts the failures in a row
+	openedAt time.Time // openedAt is when the circuit was opened the last time
+	trial    bool      // trial is set while a half open circuit is being tried
+	sent     int64
+	failed   int64
+}
+
+func (fc FailoverClient) NotifyTo(ctx context.Context, options NotifyToOptions) error {
+	_, err := fc.Send(ctx, options)
+
+	return err
+}
+
+// Send sends the mail and returns the name of the provider that sent it.
+func (fc FailoverClient) Send(ctx context.Context, options NotifyToOptions) (string, error) {
+	var lastErr error
+
+	for _, p := range fc.order() {
+		if !p.allow(fc.now(), fc.options) {
+			continue
+		}
+
+		err := p.Sender.NotifyTo(ctx, options)
+		switch {
+		case err == nil:
+			p.success()
+
+			return p.Name, nil
+		case errors.Is(err, ErrPermanent):
+			p.rejected()
+
+			return "", err
+		case ctx.Err() != nil:
+			// the caller gave up, which says nothing about the provider
+			p.release()
+
+			return "", err
+		case errors.Is(err, ErrTimeout) && !errors.Is(err, ErrConnectTimeout):
+			// the mail server may have delivered the mail anyway, sending it through another provider could duplicate it
+			p.release()
+
+			return "", err
+		}
+
+		p.failure(fc.now(), fc.options)
+		lastErr = fmt.Errorf("provider %s failed: %w", p.Name, err)
+	}
+
+	if lastErr == nil {
+		return "", ErrNoProvider
+	}
+
+	return "", lastErr
+}
+
+// Health returns the state of every provider, in the order they are tried.
+func (fc FailoverClient) Health() []ProviderHealth {
+	health := make([]ProviderHealth, len(fc.providers))
+	for i, p := range fc.providers {
+		health[i] = p.health(fc.now(), fc.options)
+	}
+
+	return health
+}
+
+// order returns the providers by priority, shuffling the ones with the same priority according to their weights.
+func (fc FailoverClient) order() []*provider {
+	ordered := make([]*provider, 0, len(fc.providers))
+
+	for start := 0; start < len(fc.providers); {
+		end := start
+		for end < len(fc.providers) && fc.providers[end].Priority == fc.providers[start].Priority {
+			end++
+		}
+
+		group := append([]*provider(nil), fc.providers[start:end]...)
+		for len(group) > 0 {
+			total := 0
+			for _, p := range group {
+				total += p.Weight
+			}
+
+			pick := fc.random(total)
+			for i, p := range group {
+				if pick < p.Weight {
+					ordered = append(ordered, p)
+					group = append(group[:i], group[i+1:]...)
+
+					break
+				}
+
+				pick -= p.Weight
+			}
+		}
+
+		start = end
+	}
+
+	return ordered
+}
+
+// allow tells if a mail can be sent through the provider, taking the trial of a half open circuit.
+func (p *provider) allow(now time.Time, options FailoverOptions) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch p.circuit(now, options) {
+	case CircuitClosed:
+		return true
+	case CircuitHalfOpen:
+		if p.trial {
+			return false
+		}
+
+		p.trial = true
+
+		return true
+	}
+
+	return false
+}
+
+func (p *provider) success() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.failures = 0
+	p.trial = false
+	p.sent++
+}
+
+// rejected closes the circuit after the provider rejected the mail, which is not counted as sent.
+func (p *provider) rejected() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.failures = 0
+	p.trial = false
+}
+
+func (p *provider) failure(now time.Time, options FailoverOptions) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.failures++
+	p.failed++
+	p.trial = false
+
+	if p.failures >= options.FailureThreshold {
+		p.openedAt = now
+	}
+}
+
+// release gives back the trial of a half open circuit when the outcome of the attempt is unknown.
+func (p *provider) release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.trial = false
+}
+
+func (p *provider) health(now time.Time, options FailoverOptions) ProviderHealth {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return ProviderHealth{
+		Name:                p.Name,
+		Circuit:             p.circuit(now, options),
+		ConsecutiveFailures: p.failures,
+		Sent:                p.sent,
+		Failed:              p.failed,
+	}
+}
+
+// circuit must be called holding the lock.
+func (p *provider) circuit(now time.Time, options FailoverOptions) string {
+	if p.failures < options.FailureThreshold {
+		return CircuitClosed
+	}
+
+	if now.Before(p.openedAt.Add(options.OpenTimeout)) {
+		return CircuitOpen
+	}
+
+	return CircuitHalfOpen
+}
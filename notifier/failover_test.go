@@ -0,0 +1,230 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"testing"
+	"time"
+	"user_news_api/notifier/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/mail.v2"
+)
+
+func TestFailoverClientSend(t *testing.T) {
+	options := NotifyToOptions{To: "user@example.com", Subject: "Notification", Body: "message"}
+	busy := &textproto.Error{Code: 421, Msg: "service not available"}
+	unknownUser := &textproto.Error{Code: 550, Msg: "no such user"}
+
+	tests := []struct {
+		name             string
+		mockApplier      func(primary *mocks.Dialer, backup *mocks.Dialer)
+		expectedProvider string
+		expectedError    error
+	}{
+		{
+			name: "sent by the primary provider",
+			mockApplier: func(primary *mocks.Dialer, backup *mocks.Dialer) {
+				primary.On("DialAndSend", mock.Anything).Return(nil).Once()
+			},
+			expectedProvider: "primary",
+		},
+		{
+			name: "failover to the backup provider",
+			mockApplier: func(primary *mocks.Dialer, backup *mocks.Dialer) {
+				primary.On("DialAndSend", mock.Anything).Return(busy).Once()
+				backup.On("DialAndSend", mock.Anything).Return(nil).Once()
+			},
+			expectedProvider: "backup",
+		},
+		{
+			name: "permanent errors do not fail over",
+			mockApplier: func(primary *mocks.Dialer, backup *mocks.Dialer) {
+				primary.On("DialAndSend", mock.Anything).Return(unknownUser).Once()
+			},
+			expectedError: sendError(unknownUser),
+		},
+		{
+			name: "timeouts connecting fail over",
+			mockApplier: func(primary *mocks.Dialer, backup *mocks.Dialer) {
+				primary.On("DialAndSend", mock.Anything).Return(timeoutErr{}).Once()
+				backup.On("DialAndSend", mock.Anything).Return(nil).Once()
+			},
+			expectedProvider: "backup",
+		},
+		{
+			name: "timeouts sending do not fail over",
+			mockApplier: func(primary *mocks.Dialer, backup *mocks.Dialer) {
+				primary.On("DialAndSend", mock.Anything).Return(&mail.SendError{Cause: timeoutErr{}}).Once()
+			},
+			expectedError: sendError(&mail.SendError{Cause: timeoutErr{}}),
+		},
+		{
+			name: "every provider fails",
+			mockApplier: func(primary *mocks.Dialer, backup *mocks.Dialer) {
+				primary.On("DialAndSend", mock.Anything).Return(busy).Once()
+				backup.On("DialAndSend", mock.Anything).Return(errors.New("connection refused")).Once()
+			},
+			expectedError: fmt.Errorf("provider backup failed: %w", sendError(errors.New("connection refused"))),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := mocks.NewDialer(t)
+			backup := mocks.NewDialer(t)
+
+			tt.mockApplier(primary, backup)
+
+			fc := NewFailoverClient([]Provider{
+				{Name: "backup", Priority: 1, Sender: Client{sender: "sender", dialer: backup}},
+				{Name: "primary", Priority: 0, Sender: Client{sender: "sender", dialer: primary}},
+			}, DefaultFailoverOptions)
+
+			provider, err := fc.Send(context.Background(), options)
+
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expectedProvider, provider)
+		})
+	}
+}
+
+func TestFailoverClientCircuitBreaker(t *testing.T) {
+	options := NotifyToOptions{To: "user@example.com"}
+	busy := &textproto.Error{Code: 421, Msg: "service not available"}
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	primary := mocks.NewDialer(t)
+	backup := mocks.NewDialer(t)
+
+	fc := NewFailoverClient([]Provider{
+		{Name: "primary", Sender: Client{sender: "sender", dialer: primary}},
+		{Name: "backup", Priority: 1, Sender: Client{sender: "sender", dialer: backup}},
+	}, FailoverOptions{FailureThreshold: 2, OpenTimeout: time.Minute})
+	fc.now = func() time.Time { return now }
+
+	// two failures in a row open the circuit of the primary provider
+	primary.On("DialAndSend", mock.Anything).Return(busy).Twice()
+	backup.On("DialAndSend", mock.Anything).Return(nil).Times(3)
+
+	for i := 0; i < 3; i++ {
+		provider, err := fc.Send(context.Background(), options)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "backup", provider)
+	}
+
+	assert.Equal(t, []ProviderHealth{
+		{Name: "primary", Circuit: CircuitOpen, ConsecutiveFailures: 2, Failed: 2},
+		{Name: "backup", Circuit: CircuitClosed, Sent: 3},
+	}, fc.Health())
+
+	// once the timeout is over, a single mail tries the primary provider again and closes its circuit
+	now = now.Add(time.Minute)
+	primary.On("DialAndSend", mock.Anything).Return(nil).Once()
+
+	provider, err := fc.Send(context.Background(), options)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "primary", provider)
+	assert.Equal(t, CircuitClosed, fc.Health()[0].Circuit)
+}
+
+func TestFailoverClientTimeouts(t *testing.T) {
+	options := NotifyToOptions{To: "user@example.com"}
+
+	primary := mocks.NewDialer(t)
+
+	fc := NewFailoverClient([]Provider{
+		{Name: "primary", Sender: Client{sender: "sender", dialer: primary}},
+	}, FailoverOptions{FailureThreshold: 1, OpenTimeout: time.Minute})
+
+	// the mail could still be delivered, so the provider is not blamed for it
+	primary.On("DialAndSend", mock.Anything).Return(&mail.SendError{Cause: timeoutErr{}}).Once()
+
+	_, err := fc.Send(context.Background(), options)
+
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.Equal(t, ProviderHealth{Name: "primary", Circuit: CircuitClosed}, fc.Health()[0])
+
+	// the mail server could not be reached
+	primary.On("DialAndSend", mock.Anything).Return(timeoutErr{}).Once()
+
+	_, err = fc.Send(context.Background(), options)
+
+	assert.ErrorIs(t, err, ErrConnectTimeout)
+	assert.Equal(t, ProviderHealth{Name: "primary", Circuit: CircuitOpen, ConsecutiveFailures: 1, Failed: 1}, fc.Health()[0])
+}
+
+func TestFailoverClientPermanentErrors(t *testing.T) {
+	primary := mocks.NewDialer(t)
+
+	fc := NewFailoverClient([]Provider{
+		{Name: "primary", Sender: Client{sender: "sender", dialer: primary}},
+	}, FailoverOptions{FailureThreshold: 2, OpenTimeout: time.Minute})
+	fc.providers[0].failures = 1
+
+	// the provider answered, so its failures are reset, but the rejected mail is not counted as sent
+	primary.On("DialAndSend", mock.Anything).Return(&textproto.Error{Code: 550, Msg: "no such user"}).Once()
+
+	_, err := fc.Send(context.Background(), NotifyToOptions{To: "user@example.com"})
+
+	assert.ErrorIs(t, err, ErrPermanent)
+	assert.Equal(t, ProviderHealth{Name: "primary", Circuit: CircuitClosed}, fc.Health()[0])
+}
+
+func TestFailoverClientNoProviderAvailable(t *testing.T) {
+	fc := NewFailoverClient([]Provider{
+		{Name: "primary", Sender: Client{dialer: mocks.NewDialer(t)}},
+	}, DefaultFailoverOptions)
+	fc.providers[0].failures = DefaultFailoverOptions.FailureThreshold
+	fc.providers[0].openedAt = time.Now()
+
+	_, err := fc.Send(context.Background(), NotifyToOptions{})
+
+	assert.ErrorIs(t, err, ErrNoProvider)
+	assert.ErrorIs(t, err, ErrTransient)
+}
+
+func TestFailoverClientOrder(t *testing.T) {
+	first := Provider{Name: "first", Weight: 3}
+	second := Provider{Name: "second", Weight: 1}
+	backup := Provider{Name: "backup", Priority: 1}
+
+	fc := NewFailoverClient([]Provider{backup, first, second}, DefaultFailoverOptions)
+
+	tests := []struct {
+		name     string
+		pick     int
+		expected []string
+	}{
+		{name: "weight of the first provider", pick: 2, expected: []string{"first", "second", "backup"}},
+		{name: "weight of the second provider", pick: 3, expected: []string{"second", "first", "backup"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			picks := []int{tt.pick}
+			fc.random = func(n int) int {
+				if len(picks) == 0 {
+					return 0
+				}
+
+				pick := picks[0]
+				picks = picks[1:]
+
+				return pick
+			}
+
+			var names []string
+			for _, p := range fc.order() {
+				names = append(names, p.Name)
+			}
+
+			assert.Equal(t, tt.expected, names)
+		})
+	}
+}
@@ -0,0 +1,33 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// Metrics is an autogenerated mock type for the Metrics type
+type Metrics struct {
+	mock.Mock
+}
+
+// ObserveSend provides a mock function with given fields: provider, class, duration
+func (_m *Metrics) ObserveSend(provider string, class string, duration time.Duration) {
+	_m.Called(provider, class, duration)
+}
+
+// NewMetrics creates a new instance of Metrics. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMetrics(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Metrics {
+	mock := &Metrics{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,97 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// DefaultRetryOptions keeps the whole retry budget within a few seconds, so it fits in a regular HTTP request.
+var DefaultRetryOptions = RetryOptions{
+	MaxAttempts: 3,
+	BaseDelay:   200 * time.Millisecond,
+	MaxDelay:    2 * time.Second,
+}
+
+type RetryOptions struct {
+	MaxAttempts int           // MaxAttempts includes the first attempt
+	BaseDelay   time.Duration // BaseDelay is doubled after every failed attempt
+	MaxDelay    time.Duration // MaxDelay caps the delay between two attempts
+}
+
+// Sender is the behavior shared by Client and RetryClient, so the retries can decorate any of them
+type Sender interface {
+	NotifyTo(context.Context, NotifyToOptions) error
+}
+
+func NewRetryClient(sender Sender, options RetryOptions) RetryClient {
+	return RetryClient{
+		sender:  sender,
+		options: options,
+		jitter:  fullJitter,
+	}
+}
+
+// RetryClient decorates a Sender retrying the transient failures with jittered exponential backoff.
+// The retries never outlive the context: when the next attempt would start after the deadline, it gives up.
+type RetryClient struct {
+	sender  Sender
+	options RetryOptions
+	jitter  func(time.Duration) time.Duration
+}
+
+func (rc RetryClient) NotifyTo(ctx context.Context, options NotifyToOptions) error {
+	var err error
+
+	for attempt := 1; ; attempt++ {
+		err = rc.sender.NotifyTo(ctx, options)
+		if err == nil || !errors.Is(err, ErrTransient) {
+			return err
+		}
+
+		if attempt >= rc.options.MaxAttempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+
+		delay := rc.jitter(rc.backoff(attempt))
+
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return fmt.Errorf("giving up after %d attempts, no time left before the deadline: %w", attempt, err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, contextError(ctx.Err()))
+		}
+	}
+}
+
+// backoff returns the upper bound of the delay after the given failed attempt.
+func (rc RetryClient) backoff(attempt int) time.Duration {
+	delay := rc.options.BaseDelay
+	for i := 1; i < attempt && delay < rc.options.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > rc.options.MaxDelay {
+		return rc.options.MaxDelay
+	}
+
+	return delay
+}
+
+// fullJitter picks a random delay between zero and the given upper bound,
+// so clients failing at the same time do not retry at the same time.
+func fullJitter(delay time.Duration) time.Duration {
+	if delay <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
@@ -0,0 +1,163 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"testing"
+	"time"
+	"user_news_api/notifier/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRetryClientNotifyTo(t *testing.T) {
+	busy := &textproto.Error{Code: 421, Msg: "service not available"}
+	unknownUser := &textproto.Error{Code: 550, Msg: "no such user"}
+	customErr := errors.New("custom error")
+
+	transientErr := sendError(busy)
+
+	options := NotifyToOptions{
+		To:      "email",
+		Subject: "status",
+		Body:    "message",
+	}
+
+	tests := []struct {
+		name        string
+		ctx         func() (context.Context, context.CancelFunc)
+		mockApplier func(m *mocks.Dialer)
+		expected    error
+	}{
+		{
+			name: "success at first attempt",
+			mockApplier: func(m *mocks.Dialer) {
+				m.On("DialAndSend", mock.Anything).Return(nil).Once()
+			},
+			expected: nil,
+		},
+		{
+			name: "success after transient errors",
+			mockApplier: func(m *mocks.Dialer) {
+				m.On("DialAndSend", mock.Anything).Return(busy).Twice()
+				m.On("DialAndSend", mock.Anything).Return(nil).Once()
+			},
+			expected: nil,
+		},
+		{
+			name: "permanent error is not retried",
+			mockApplier: func(m *mocks.Dialer) {
+				m.On("DialAndSend", mock.Anything).Return(unknownUser).Once()
+			},
+			expected: sendError(unknownUser),
+		},
+		{
+			name: "unclassified error is not retried",
+			mockApplier: func(m *mocks.Dialer) {
+				m.On("DialAndSend", mock.Anything).Return(customErr).Once()
+			},
+			expected: sendError(customErr),
+		},
+		{
+			name: "transient errors exhaust the attempts",
+			mockApplier: func(m *mocks.Dialer) {
+				m.On("DialAndSend", mock.Anything).Return(busy).Times(3)
+			},
+			expected: fmt.Errorf("giving up after %d attempts: %w", 3, transientErr),
+		},
+		{
+			name: "deadline shorter than the next delay",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), time.Millisecond)
+			},
+			mockApplier: func(m *mocks.Dialer) {
+				m.On("DialAndSend", mock.Anything).Return(busy).Once()
+			},
+			expected: fmt.Errorf("giving up after %d attempts, no time left before the deadline: %w", 1, transientErr),
+		},
+		{
+			name: "context canceled while waiting",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(10*time.Millisecond, cancel)
+
+				return ctx, cancel
+			},
+			mockApplier: func(m *mocks.Dialer) {
+				m.On("DialAndSend", mock.Anything).Return(busy).Once()
+			},
+			expected: fmt.Errorf("giving up after %d attempts: %w", 1, contextError(context.Canceled)),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dMock := mocks.NewDialer(t)
+
+			tt.mockApplier(dMock)
+
+			ctx, cancel := context.Background(), context.CancelFunc(func() {})
+			if tt.ctx != nil {
+				ctx, cancel = tt.ctx()
+			}
+			defer cancel()
+
+			rc := RetryClient{
+				sender: Client{
+					sender: "sender",
+					dialer: dMock,
+				},
+				options: RetryOptions{
+					MaxAttempts: 3,
+					BaseDelay:   time.Second,
+					MaxDelay:    time.Second,
+				},
+				jitter: func(delay time.Duration) time.Duration {
+					// the delay is only honored when the context is canceled by the test
+					if tt.ctx != nil {
+						return delay
+					}
+
+					return 0
+				},
+			}
+
+			assert.Equal(t, tt.expected, rc.NotifyTo(ctx, options))
+		})
+	}
+}
+
+func TestRetryClientBackoff(t *testing.T) {
+	rc := RetryClient{
+		options: RetryOptions{
+			BaseDelay: 100 * time.Millisecond,
+			MaxDelay:  time.Second,
+		},
+	}
+
+	expected := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+
+	for i, delay := range expected {
+		assert.Equal(t, delay, rc.backoff(i+1))
+	}
+}
+
+func TestFullJitter(t *testing.T) {
+	assert.Equal(t, time.Duration(0), fullJitter(0))
+
+	for i := 0; i < 100; i++ {
+		delay := fullJitter(time.Second)
+		assert.GreaterOrEqual(t, delay, time.Duration(0))
+		assert.LessOrEqual(t, delay, time.Second)
+	}
+}
@@ -0,0 +1,235 @@
+package notifier
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	netmail "net/mail"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"user_news_api/logging"
+
+	"gopkg.in/mail.v2"
+)
+
+var (
+	ErrMessageNotFound = errors.New("message not found")
+)
+
+// Sinks are Dialers keeping the mails in development instead of sending them, see Options.Dialer.
+const (
+	SinkLog    = "log"    // SinkLog logs the mails, see LogDialer
+	SinkFile   = "file"   // SinkFile writes the mails as .eml files, see FileDialer
+	SinkMemory = "memory" // SinkMemory keeps the mails, see MemoryDialer
+)
+
+// CapturedMessage is a mail kept by a sink.
+type CapturedMessage struct {
+	ID      string    `json:"id"`
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+	Subject string    `json:"subject"`
+	Date    time.Time `json:"date"`
+	Raw     []byte    `json:"-"` // Raw is the message as it would have been sent
+}
+
+func NewLogDialer(logger *slog.Logger) LogDialer {
+	return LogDialer{logger: logger}
+}
+
+// LogDialer logs the mails instead of sending them. Only their summary is logged, with the recipient masked
+// as in every log, so their content is kept by the file and memory sinks alone.
+type LogDialer struct {
+	logger *slog.Logger
+}
+
+func (d LogDialer) DialAndSend(msgs ...*mail.Message) error {
+	for _, msg := range msgs {
+		raw, err := render(msg)
+		if err != nil {
+			return err
+		}
+
+		captured := parseCaptured("", raw)
+		d.logger.Info("mail kept by the log sink",
+			slog.String(logging.KeyUser, logging.MaskEmail(captured.To)),
+			slog.String("subject", captured.Subject),
+			slog.Int("size", len(raw)))
+	}
+
+	return nil
+}
+
+// NewFileDialer returns the FileDialer writing into dir, creating it when it does not exist.
+func NewFileDialer(dir string) (*FileDialer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating sink directory due to: %w", err)
+	}
+
+	return &FileDialer{dir: dir, now: time.Now}, nil
+}
+
+// FileDialer writes every mail into its own .eml file instead of sending it, they can be opened by any mail client.
+// The file names are the message ids, which sort as the messages were captured.
+type FileDialer struct {
+	dir string
+	seq atomic.Int64
+	now func() time.Time
+}
+
+func (d *FileDialer) DialAndSend(msgs ...*mail.Message) error {
+	for _, msg := range msgs {
+		raw, err := render(msg)
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(d.dir, newMessageID(d.now(), d.seq.Add(1))+".eml")
+		if err := os.WriteFile(path, raw, 0o644); err != nil {
+			return fmt.Errorf("error writing mail to the file sink due to: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// List returns the captured messages, the oldest first.
+func (d *FileDialer) List() ([]CapturedMessage, error) {
+	paths, err := filepath.Glob(filepath.Join(d.dir, "*.eml"))
+	if err != nil {
+		return nil, fmt.Errorf("error listing the file sink due to: %w", err)
+	}
+
+	sort.Strings(paths)
+
+	messages := make([]CapturedMessage, 0, len(paths))
+	for _, path := range paths {
+		message, err := d.Get(strings.TrimSuffix(filepath.Base(path), ".eml"))
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
+
+func (d *FileDialer) Get(id string) (CapturedMessage, error) {
+	// the id becomes a path, so it must look like the ones of the sink
+	if !messageIDPattern.MatchString(id) {
+		return CapturedMessage{}, ErrMessageNotFound
+	}
+
+	raw, err := os.ReadFile(filepath.Join(d.dir, id+".eml"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return CapturedMessage{}, ErrMessageNotFound
+		}
+
+		return CapturedMessage{}, fmt.Errorf("error reading mail from the file sink due to: %w", err)
+	}
+
+	return parseCaptured(id, raw), nil
+}
+
+func NewMemoryDialer() *MemoryDialer {
+	return &MemoryDialer{now: time.Now}
+}
+
+// MemoryDialer keeps the mails instead of sending them, for the tests and local runs.
+// Its messages are lost when the process ends.
+type MemoryDialer struct {
+	mu       sync.Mutex
+	messages []CapturedMessage
+	now      func() time.Time
+}
+
+func (d *MemoryDialer) DialAndSend(msgs ...*mail.Message) error {
+	for _, msg := range msgs {
+		raw, err := render(msg)
+		if err != nil {
+			return err
+		}
+
+		d.mu.Lock()
+		id := newMessageID(d.now(), int64(len(d.messages)+1))
+		d.messages = append(d.messages, parseCaptured(id, raw))
+		d.mu.Unlock()
+	}
+
+	return nil
+}
+
+// List returns the captured messages, the oldest first.
+func (d *MemoryDialer) List() ([]CapturedMessage, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]CapturedMessage{}, d.messages...), nil
+}
+
+func (d *MemoryDialer) Get(id string) (CapturedMessage, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, message := range d.messages {
+		if message.ID == id {
+			return message, nil
+		}
+	}
+
+	return CapturedMessage{}, ErrMessageNotFound
+}
+
+// messageIDPattern matches the ids of newMessageID.
+var messageIDPattern = regexp.MustCompile(`^[0-9]{8}T[0-9]{6}\.[0-9]{9}-[0-9]+$`)
+
+func newMessageID(now time.Time, seq int64) string {
+	return fmt.Sprintf("%s-%d", now.UTC().Format("20060102T150405.000000000"), seq)
+}
+
+func render(msg *mail.Message) ([]byte, error) {
+	var raw bytes.Buffer
+	if _, err := msg.WriteTo(&raw); err != nil {
+		return nil, fmt.Errorf("error rendering mail due to: %w", err)
+	}
+
+	return raw.Bytes(), nil
+}
+
+// parseCaptured reads the summary of the raw message. The headers are written by the Client,
+// so a header that cannot be read is left empty instead of failing.
+func parseCaptured(id string, raw []byte) CapturedMessage {
+	message := CapturedMessage{ID: id, Raw: raw}
+
+	parsed, err := netmail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return message
+	}
+
+	decoder := new(mime.WordDecoder)
+	decode := func(name string) string {
+		value := parsed.Header.Get(name)
+		if decoded, err := decoder.DecodeHeader(value); err == nil {
+			return decoded
+		}
+
+		return value
+	}
+
+	message.From = decode("From")
+	message.To = decode("To")
+	message.Subject = decode("Subject")
+	message.Date, _ = parsed.Header.Date()
+
+	return message
+}
@@ -0,0 +1,94 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSinks(t *testing.T) {
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	fileDialer, err := NewFileDialer(filepath.Join(t.TempDir(), "mails"))
+	require.NoError(t, err)
+	fileDialer.now = func() time.Time { return now }
+
+	memoryDialer := NewMemoryDialer()
+	memoryDialer.now = func() time.Time { return now }
+
+	tests := []struct {
+		name   string
+		dialer interface {
+			Dialer
+			List() ([]CapturedMessage, error)
+			Get(string) (CapturedMessage, error)
+		}
+	}{
+		{name: "file sink", dialer: fileDialer},
+		{name: "memory sink", dialer: memoryDialer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient(Options{Username: "sender@example.com", Dialer: tt.dialer})
+
+			for _, subject := range []string{"First", "Notificación"} {
+				require.NoError(t, c.NotifyTo(context.Background(), NotifyToOptions{
+					To:      "user@example.com",
+					Subject: subject,
+					Body:    "<p>news</p>",
+				}))
+			}
+
+			messages, err := tt.dialer.List()
+			require.NoError(t, err)
+			require.Len(t, messages, 2)
+
+			assert.Equal(t, "20240101T090000.000000000-1", messages[0].ID)
+			assert.Equal(t, "sender@example.com", messages[0].From)
+			assert.Equal(t, "user@example.com", messages[0].To)
+			assert.Equal(t, "First", messages[0].Subject)
+			assert.False(t, messages[0].Date.IsZero())
+			assert.Equal(t, "Notificación", messages[1].Subject)
+
+			message, err := tt.dialer.Get(messages[1].ID)
+			require.NoError(t, err)
+			assert.Equal(t, messages[1], message)
+			assert.Contains(t, string(message.Raw), "<p>news</p>")
+
+			_, err = tt.dialer.Get("20240101T090000.000000000-3")
+			assert.ErrorIs(t, err, ErrMessageNotFound)
+		})
+	}
+}
+
+func TestFileDialerGetNotValidID(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "secret.eml"), []byte("Subject: secret\r\n\r\n"), 0o600))
+
+	d, err := NewFileDialer(filepath.Join(dir, "mails"))
+	require.NoError(t, err)
+
+	_, err = d.Get("../secret")
+	assert.ErrorIs(t, err, ErrMessageNotFound)
+}
+
+func TestLogDialer(t *testing.T) {
+	var out bytes.Buffer
+
+	c := NewClient(Options{Username: "sender@example.com", Dialer: NewLogDialer(slog.New(slog.NewTextHandler(&out, nil)))})
+
+	require.NoError(t, c.NotifyTo(context.Background(), NotifyToOptions{To: "user@example.com", Subject: "Notification"}))
+
+	assert.Contains(t, out.String(), "mail kept by the log sink")
+	assert.Contains(t, out.String(), "user=u***@example.com")
+	assert.Contains(t, out.String(), "subject=Notification")
+	assert.NotContains(t, out.String(), "user@example.com")
+}
@@ -0,0 +1,158 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	netmail "net/mail"
+	"testing"
+	"time"
+	"user_news_api/smtptest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClientNotifyToSMTP sends the mails through a real SMTP dialogue, checking the MIME output and the errors
+// of the replies the mocked Dialer cannot produce.
+func TestClientNotifyToSMTP(t *testing.T) {
+	options := NotifyToOptions{
+		To:             "user@example.com",
+		Subject:        "Notificación",
+		Body:           "<p>Your news are here</p>",
+		UnsubscribeURL: "https://api.example.com/unsubscribe?token=abc",
+	}
+
+	tests := []struct {
+		name          string
+		serverOptions smtptest.Options
+		failures      []smtptest.Failure
+		expectedErr   error
+		sent          bool
+	}{
+		{
+			name:          "sent with AUTH over STARTTLS",
+			serverOptions: smtptest.Options{Username: "sender@example.com", Password: "secret", TLS: true},
+			sent:          true,
+		},
+		{
+			name:          "sent without TLS",
+			serverOptions: smtptest.Options{Username: "sender@example.com", Password: "secret"},
+			sent:          true,
+		},
+		{
+			name:        "server busy",
+			failures:    []smtptest.Failure{{Command: "MAIL", Code: 421, Text: "service not available"}},
+			expectedErr: ErrTransient,
+		},
+		{
+			name:        "recipient rejected",
+			failures:    []smtptest.Failure{{Command: "RCPT", Code: 550, Text: "no such user"}},
+			expectedErr: ErrPermanent,
+		},
+		{
+			name:        "server not answering",
+			failures:    []smtptest.Failure{{Command: smtptest.DataEnd, Hang: true}},
+			expectedErr: ErrTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := smtptest.NewServer(tt.serverOptions)
+			defer server.Close()
+
+			server.Fail(tt.failures...)
+
+			dialer := server.Dialer()
+			dialer.Timeout = 200 * time.Millisecond
+
+			c := NewClient(Options{Username: "sender@example.com", Dialer: dialer})
+
+			err := c.NotifyTo(context.Background(), options)
+			assert.ErrorIs(t, err, tt.expectedErr)
+
+			messages := server.Messages()
+			if !tt.sent {
+				assert.Empty(t, messages)
+
+				return
+			}
+
+			require.Len(t, messages, 1)
+			assert.Equal(t, "sender@example.com", messages[0].From)
+			assert.Equal(t, []string{"user@example.com"}, messages[0].To)
+			assert.Equal(t, tt.serverOptions.Username, messages[0].Username)
+			assert.Equal(t, tt.serverOptions.TLS, messages[0].TLS)
+
+			msg, err := netmail.ReadMessage(bytes.NewReader(messages[0].Data))
+			require.NoError(t, err)
+
+			subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+			require.NoError(t, err)
+
+			assert.Equal(t, "Notificación", subject)
+			assert.Equal(t, "sender@example.com", msg.Header.Get("From"))
+			assert.Equal(t, "user@example.com", msg.Header.Get("To"))
+			assert.Equal(t, "1.0", msg.Header.Get("Mime-Version"))
+			assert.Equal(t, "text/html; charset=UTF-8", msg.Header.Get("Content-Type"))
+			assert.Equal(t, "<https://api.example.com/unsubscribe?token=abc>", msg.Header.Get("List-Unsubscribe"))
+			assert.Equal(t, "List-Unsubscribe=One-Click", msg.Header.Get("List-Unsubscribe-Post"))
+
+			body, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+			require.NoError(t, err)
+			assert.Equal(t, options.Body+"\r\n", string(body))
+		})
+	}
+}
+
+// TestClientNotifyToSMTPDeadline checks the mail server not answering is given up around the deadline
+// of the context, even with a longer timeout of the dialer.
+func TestClientNotifyToSMTPDeadline(t *testing.T) {
+	server := smtptest.NewServer(smtptest.Options{})
+	defer server.Close()
+
+	server.Fail(smtptest.Failure{Command: smtptest.DataEnd, Hang: true})
+
+	dialer := server.Dialer()
+	dialer.Timeout = 10 * time.Second
+
+	c := NewClient(Options{Username: "sender@example.com", Dialer: dialer})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := c.NotifyTo(ctx, NotifyToOptions{To: "user@example.com", Subject: "status", Body: "message"})
+
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.Empty(t, server.Messages())
+}
+
+// TestClientNotifyToSMTPCanceled checks a client going away gives up a mail server not answering the connection,
+// even without a deadline, and the mail is not delivered.
+func TestClientNotifyToSMTPCanceled(t *testing.T) {
+	server := smtptest.NewServer(smtptest.Options{})
+	defer server.Close()
+
+	server.Fail(smtptest.Failure{Command: smtptest.Connect, Hang: true})
+
+	dialer := server.Dialer()
+	dialer.Timeout = 10 * time.Second
+
+	c := NewClient(Options{Username: "sender@example.com", Dialer: dialer})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := c.NotifyTo(ctx, NotifyToOptions{To: "user@example.com", Subject: "status", Body: "message"})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.True(t, Retryable(err))
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.Empty(t, server.Messages())
+}
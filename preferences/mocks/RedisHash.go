@@ -0,0 +1,104 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	redis "github.com/redis/go-redis/v9"
+)
+
+// RedisHash is an autogenerated mock type for the RedisHash type
+type RedisHash struct {
+	mock.Mock
+}
+
+// HDel provides a mock function with given fields: ctx, key, fields
+func (_m *RedisHash) HDel(ctx context.Context, key string, fields ...string) *redis.IntCmd {
+	_va := make([]interface{}, len(fields))
+	for _i := range fields {
+		_va[_i] = fields[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, key)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *redis.IntCmd
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...string) *redis.IntCmd); ok {
+		r0 = rf(ctx, key, fields...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.IntCmd)
+		}
+	}
+
+	return r0
+}
+
+// HGet provides a mock function with given fields: ctx, key, field
+func (_m *RedisHash) HGet(ctx context.Context, key string, field string) *redis.StringCmd {
+	ret := _m.Called(ctx, key, field)
+
+	var r0 *redis.StringCmd
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *redis.StringCmd); ok {
+		r0 = rf(ctx, key, field)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.StringCmd)
+		}
+	}
+
+	return r0
+}
+
+// HGetAll provides a mock function with given fields: ctx, key
+func (_m *RedisHash) HGetAll(ctx context.Context, key string) *redis.MapStringStringCmd {
+	ret := _m.Called(ctx, key)
+
+	var r0 *redis.MapStringStringCmd
+	if rf, ok := ret.Get(0).(func(context.Context, string) *redis.MapStringStringCmd); ok {
+		r0 = rf(ctx, key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.MapStringStringCmd)
+		}
+	}
+
+	return r0
+}
+
+// HSet provides a mock function with given fields: ctx, key, values
+func (_m *RedisHash) HSet(ctx context.Context, key string, values ...interface{}) *redis.IntCmd {
+	var _ca []interface{}
+	_ca = append(_ca, ctx, key)
+	_ca = append(_ca, values...)
+	ret := _m.Called(_ca...)
+
+	var r0 *redis.IntCmd
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...interface{}) *redis.IntCmd); ok {
+		r0 = rf(ctx, key, values...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.IntCmd)
+		}
+	}
+
+	return r0
+}
+
+// NewRedisHash creates a new instance of RedisHash. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewRedisHash(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *RedisHash {
+	mock := &RedisHash{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,149 @@
+package preferences
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+	"user_news_api/logging"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrNotFound = errors.New("preference not found")
+)
+
+// DefaultOptions are used by the API for the preferences of every user.
+var DefaultOptions = Options{
+	Prefix: "preferences",
+}
+
+type Options struct {
+	Prefix string // Prefix of the Redis hashes, there is one hash per user with a field per message type
+}
+
+// Preference is the choice of a user about a message type. Users without a preference are opted in.
+type Preference struct {
+	MessageType string    `json:"message_type"`
+	OptedIn     bool      `json:"opted_in"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func NewStore(db *redis.Client, options Options) Store {
+	return Store{
+		db:      db,
+		options: options,
+	}
+}
+
+// RedisHash is an abstraction for redis.Client making it mockeable
+type RedisHash interface {
+	HSet(ctx context.Context, key string, values ...interface{}) *redis.IntCmd
+	HGet(ctx context.Context, key, field string) *redis.StringCmd
+	HGetAll(ctx context.Context, key string) *redis.MapStringStringCmd
+	HDel(ctx context.Context, key string, fields ...string) *redis.IntCmd
+}
+
+// Store keeps the preferences of every user in a Redis hash. Users are compared in lower case.
+type Store struct {
+	db      RedisHash
+	options Options
+}
+
+// Set saves the preference of the user, replacing the previous one of the same message type.
+func (s Store) Set(ctx context.Context, user string, preference Preference) error {
+	payload, err := json.Marshal(preference)
+	if err != nil {
+		return fmt.Errorf("error marshalling preference due to: %w", err)
+	}
+
+	if err = s.db.HSet(ctx, s.key(user), preference.MessageType, payload).Err(); err != nil {
+		return fmt.Errorf("error saving preference %s of user %s due to: %w", preference.MessageType, user, err)
+	}
+
+	return nil
+}
+
+// Get returns the preference of the user, ErrNotFound is returned when the user has none for the message type.
+func (s Store) Get(ctx context.Context, user string, messageType string) (Preference, error) {
+	payload, err := s.db.HGet(ctx, s.key(user), messageType).Result()
+	if errors.Is(err, redis.Nil) {
+		return Preference{}, fmt.Errorf("%w: %s of user %s", ErrNotFound, messageType, user)
+	}
+
+	if err != nil {
+		return Preference{}, fmt.Errorf("error getting preference %s of user %s due to: %w", messageType, user, err)
+	}
+
+	var preference Preference
+	if err = json.Unmarshal([]byte(payload), &preference); err != nil {
+		return Preference{}, fmt.Errorf("error unmarshalling preference %s of user %s due to: %w", messageType, user, err)
+	}
+
+	return preference, nil
+}
+
+// OptedOut tells if the user opted out of the message type.
+func (s Store) OptedOut(ctx context.Context, user string, messageType string) (bool, error) {
+	preference, err := s.Get(ctx, user, messageType)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return !preference.OptedIn, nil
+}
+
+// List returns the preferences of the user ordered by message type, the malformed ones are skipped.
+func (s Store) List(ctx context.Context, user string) ([]Preference, error) {
+	values, err := s.db.HGetAll(ctx, s.key(user)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error listing preferences of user %s due to: %w", user, err)
+	}
+
+	preferences := make([]Preference, 0, len(values))
+	for messageType, payload := range values {
+		var preference Preference
+		if err = json.Unmarshal([]byte(payload), &preference); err != nil {
+			slog.WarnContext(ctx, "discarding malformed preference", slog.String(logging.KeyMessageType, messageType),
+				slog.String(logging.KeyUser, logging.MaskEmail(user)), logging.Error(err))
+
+			continue
+		}
+
+		preferences = append(preferences, preference)
+	}
+
+	sort.Slice(preferences, func(i, j int) bool {
+		return preferences[i].MessageType < preferences[j].MessageType
+	})
+
+	return preferences, nil
+}
+
+// Delete forgets the preference, so the user is opted in again.
+// ErrNotFound is returned when the user has no preference for the message type.
+func (s Store) Delete(ctx context.Context, user string, messageType string) error {
+	deleted, err := s.db.HDel(ctx, s.key(user), messageType).Result()
+	if err != nil {
+		return fmt.Errorf("error deleting preference %s of user %s due to: %w", messageType, user, err)
+	}
+
+	if deleted == 0 {
+		return fmt.Errorf("%w: %s of user %s", ErrNotFound, messageType, user)
+	}
+
+	return nil
+}
+
+func (s Store) key(user string) string {
+	return fmt.Sprintf("%s-%s", s.options.Prefix, strings.ToLower(strings.TrimSpace(user)))
+}
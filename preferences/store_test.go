@@ -0,0 +1,208 @@
+package preferences
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"user_news_api/preferences/mocks"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var testOptions = Options{Prefix: "prefs"}
+
+var testPreference = Preference{
+	MessageType: "Marketing",
+	OptedIn:     false,
+	UpdatedAt:   time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+}
+
+// testPreferencePayload is testPreference as it is saved
+const testPreferencePayload = `{"message_type":"Marketing","opted_in":false,"updated_at":"2024-01-01T09:00:00Z"}`
+
+func TestStoreSet(t *testing.T) {
+	tests := []struct {
+		name          string
+		mockApplier   func(mockRedis *mocks.RedisHash)
+		expectedError error
+	}{
+		{
+			name: "preference saved",
+			mockApplier: func(mockRedis *mocks.RedisHash) {
+				mockRedis.On("HSet", mock.Anything, "prefs-user@example.com", "Marketing", []byte(testPreferencePayload)).
+					Return(redis.NewIntResult(1, nil)).Once()
+			},
+			expectedError: nil,
+		},
+		{
+			name: "error saving preference",
+			mockApplier: func(mockRedis *mocks.RedisHash) {
+				mockRedis.On("HSet", mock.Anything, "prefs-user@example.com", "Marketing", []byte(testPreferencePayload)).
+					Return(redis.NewIntResult(0, errors.New("error"))).Once()
+			},
+			expectedError: fmt.Errorf(
+				"error saving preference Marketing of user User@example.com due to: %w", errors.New("error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisHash(t)
+			tt.mockApplier(mockRedis)
+
+			store := Store{db: mockRedis, options: testOptions}
+
+			assert.Equal(t, tt.expectedError, store.Set(context.Background(), "User@example.com", testPreference))
+		})
+	}
+}
+
+func TestStoreOptedOut(t *testing.T) {
+	tests := []struct {
+		name          string
+		mockApplier   func(mockRedis *mocks.RedisHash)
+		expected      bool
+		expectedError error
+	}{
+		{
+			name: "opted out",
+			mockApplier: func(mockRedis *mocks.RedisHash) {
+				mockRedis.On("HGet", mock.Anything, "prefs-user@example.com", "Marketing").
+					Return(redis.NewStringResult(testPreferencePayload, nil)).Once()
+			},
+			expected: true,
+		},
+		{
+			name: "opted in",
+			mockApplier: func(mockRedis *mocks.RedisHash) {
+				mockRedis.On("HGet", mock.Anything, "prefs-user@example.com", "Marketing").
+					Return(redis.NewStringResult(`{"message_type":"Marketing","opted_in":true,"updated_at":"0001-01-01T00:00:00Z"}`, nil)).Once()
+			},
+			expected: false,
+		},
+		{
+			name: "without preference",
+			mockApplier: func(mockRedis *mocks.RedisHash) {
+				mockRedis.On("HGet", mock.Anything, "prefs-user@example.com", "Marketing").
+					Return(redis.NewStringResult("", redis.Nil)).Once()
+			},
+			expected: false,
+		},
+		{
+			name: "error getting preference",
+			mockApplier: func(mockRedis *mocks.RedisHash) {
+				mockRedis.On("HGet", mock.Anything, "prefs-user@example.com", "Marketing").
+					Return(redis.NewStringResult("", errors.New("error"))).Once()
+			},
+			expectedError: fmt.Errorf(
+				"error getting preference Marketing of user user@example.com due to: %w", errors.New("error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisHash(t)
+			tt.mockApplier(mockRedis)
+
+			store := Store{db: mockRedis, options: testOptions}
+
+			optedOut, err := store.OptedOut(context.Background(), "user@example.com", "Marketing")
+
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expected, optedOut)
+		})
+	}
+}
+
+func TestStoreList(t *testing.T) {
+	news := Preference{MessageType: "News", OptedIn: true, UpdatedAt: testPreference.UpdatedAt}
+
+	tests := []struct {
+		name                string
+		mockApplier         func(mockRedis *mocks.RedisHash)
+		expectedPreferences []Preference
+		expectedError       error
+	}{
+		{
+			name: "ordered by message type, skipping the malformed ones",
+			mockApplier: func(mockRedis *mocks.RedisHash) {
+				mockRedis.On("HGetAll", mock.Anything, "prefs-user@example.com").
+					Return(redis.NewMapStringStringResult(map[string]string{
+						"News":      `{"message_type":"News","opted_in":true,"updated_at":"2024-01-01T09:00:00Z"}`,
+						"Status":    "{",
+						"Marketing": testPreferencePayload,
+					}, nil)).Once()
+			},
+			expectedPreferences: []Preference{testPreference, news},
+		},
+		{
+			name: "without preferences",
+			mockApplier: func(mockRedis *mocks.RedisHash) {
+				mockRedis.On("HGetAll", mock.Anything, "prefs-user@example.com").
+					Return(redis.NewMapStringStringResult(map[string]string{}, nil)).Once()
+			},
+			expectedPreferences: []Preference{},
+		},
+		{
+			name: "error listing preferences",
+			mockApplier: func(mockRedis *mocks.RedisHash) {
+				mockRedis.On("HGetAll", mock.Anything, "prefs-user@example.com").
+					Return(redis.NewMapStringStringResult(nil, errors.New("error"))).Once()
+			},
+			expectedError: fmt.Errorf("error listing preferences of user user@example.com due to: %w", errors.New("error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisHash(t)
+			tt.mockApplier(mockRedis)
+
+			store := Store{db: mockRedis, options: testOptions}
+
+			preferences, err := store.List(context.Background(), "user@example.com")
+
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expectedPreferences, preferences)
+		})
+	}
+}
+
+func TestStoreDelete(t *testing.T) {
+	tests := []struct {
+		name          string
+		mockApplier   func(mockRedis *mocks.RedisHash)
+		expectedError error
+	}{
+		{
+			name: "preference deleted",
+			mockApplier: func(mockRedis *mocks.RedisHash) {
+				mockRedis.On("HDel", mock.Anything, "prefs-user@example.com", "Marketing").
+					Return(redis.NewIntResult(1, nil)).Once()
+			},
+		},
+		{
+			name: "without preference",
+			mockApplier: func(mockRedis *mocks.RedisHash) {
+				mockRedis.On("HDel", mock.Anything, "prefs-user@example.com", "Marketing").
+					Return(redis.NewIntResult(0, nil)).Once()
+			},
+			expectedError: fmt.Errorf("%w: Marketing of user user@example.com", ErrNotFound),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisHash(t)
+			tt.mockApplier(mockRedis)
+
+			store := Store{db: mockRedis, options: testOptions}
+
+			assert.Equal(t, tt.expectedError, store.Delete(context.Background(), "user@example.com", "Marketing"))
+		})
+	}
+}
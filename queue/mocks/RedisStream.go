@@ -0,0 +1,172 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	redis "github.com/redis/go-redis/v9"
+)
+
+// RedisStream is an autogenerated mock type for the RedisStream type
+type RedisStream struct {
+	mock.Mock
+}
+
+// XAck provides a mock function with given fields: ctx, stream, group, ids
+func (_m *RedisStream) XAck(ctx context.Context, stream string, group string, ids ...string) *redis.IntCmd {
+	_va := make([]interface{}, len(ids))
+	for _i := range ids {
+		_va[_i] = ids[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, stream, group)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *redis.IntCmd
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, ...string) *redis.IntCmd); ok {
+		r0 = rf(ctx, stream, group, ids...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.IntCmd)
+		}
+	}
+
+	return r0
+}
+
+// XAdd provides a mock function with given fields: ctx, a
+func (_m *RedisStream) XAdd(ctx context.Context, a *redis.XAddArgs) *redis.StringCmd {
+	ret := _m.Called(ctx, a)
+
+	var r0 *redis.StringCmd
+	if rf, ok := ret.Get(0).(func(context.Context, *redis.XAddArgs) *redis.StringCmd); ok {
+		r0 = rf(ctx, a)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.StringCmd)
+		}
+	}
+
+	return r0
+}
+
+// XAutoClaim provides a mock function with given fields: ctx, a
+func (_m *RedisStream) XAutoClaim(ctx context.Context, a *redis.XAutoClaimArgs) *redis.XAutoClaimCmd {
+	ret := _m.Called(ctx, a)
+
+	var r0 *redis.XAutoClaimCmd
+	if rf, ok := ret.Get(0).(func(context.Context, *redis.XAutoClaimArgs) *redis.XAutoClaimCmd); ok {
+		r0 = rf(ctx, a)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.XAutoClaimCmd)
+		}
+	}
+
+	return r0
+}
+
+// XDel provides a mock function with given fields: ctx, stream, ids
+func (_m *RedisStream) XDel(ctx context.Context, stream string, ids ...string) *redis.IntCmd {
+	_va := make([]interface{}, len(ids))
+	for _i := range ids {
+		_va[_i] = ids[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, stream)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *redis.IntCmd
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...string) *redis.IntCmd); ok {
+		r0 = rf(ctx, stream, ids...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.IntCmd)
+		}
+	}
+
+	return r0
+}
+
+// XGroupCreateMkStream provides a mock function with given fields: ctx, stream, group, start
+func (_m *RedisStream) XGroupCreateMkStream(ctx context.Context, stream string, group string, start string) *redis.StatusCmd {
+	ret := _m.Called(ctx, stream, group, start)
+
+	var r0 *redis.StatusCmd
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) *redis.StatusCmd); ok {
+		r0 = rf(ctx, stream, group, start)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.StatusCmd)
+		}
+	}
+
+	return r0
+}
+
+// XInfoGroups provides a mock function with given fields: ctx, key
+func (_m *RedisStream) XInfoGroups(ctx context.Context, key string) *redis.XInfoGroupsCmd {
+	ret := _m.Called(ctx, key)
+
+	var r0 *redis.XInfoGroupsCmd
+	if rf, ok := ret.Get(0).(func(context.Context, string) *redis.XInfoGroupsCmd); ok {
+		r0 = rf(ctx, key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.XInfoGroupsCmd)
+		}
+	}
+
+	return r0
+}
+
+// XPendingExt provides a mock function with given fields: ctx, a
+func (_m *RedisStream) XPendingExt(ctx context.Context, a *redis.XPendingExtArgs) *redis.XPendingExtCmd {
+	ret := _m.Called(ctx, a)
+
+	var r0 *redis.XPendingExtCmd
+	if rf, ok := ret.Get(0).(func(context.Context, *redis.XPendingExtArgs) *redis.XPendingExtCmd); ok {
+		r0 = rf(ctx, a)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.XPendingExtCmd)
+		}
+	}
+
+	return r0
+}
+
+// XReadGroup provides a mock function with given fields: ctx, a
+func (_m *RedisStream) XReadGroup(ctx context.Context, a *redis.XReadGroupArgs) *redis.XStreamSliceCmd {
+	ret := _m.Called(ctx, a)
+
+	var r0 *redis.XStreamSliceCmd
+	if rf, ok := ret.Get(0).(func(context.Context, *redis.XReadGroupArgs) *redis.XStreamSliceCmd); ok {
+		r0 = rf(ctx, a)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.XStreamSliceCmd)
+		}
+	}
+
+	return r0
+}
+
+// NewRedisStream creates a new instance of RedisStream. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewRedisStream(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *RedisStream {
+	mock := &RedisStream{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,264 @@
+package queue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"user_news_api/logging"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const payloadField = "payload"
+
+// DefaultStreamOptions set how the notifications are queued and redelivered.
+var DefaultStreamOptions = StreamOptions{
+	Stream:        "notifications",
+	Group:         "notifiers",
+	BatchSize:     10,
+	BlockTimeout:  5 * time.Second,
+	MinIdle:       time.Minute,
+	MaxDeliveries: 5,
+}
+
+type StreamOptions struct {
+	Stream        string        // Stream is the Redis key of the stream
+	Group         string        // Group is the consumer group shared by all the workers
+	BatchSize     int64         // BatchSize is the maximum of messages read at once by a worker
+	BlockTimeout  time.Duration // BlockTimeout is how long a worker waits for new messages
+	MinIdle       time.Duration // MinIdle is how long a message stays unacknowledged before being redelivered
+	MaxDeliveries int64         // MaxDeliveries is how many times a message is delivered before discarding it
+}
+
+// Message is a notification waiting to be delivered.
+type Message struct {
+	ID          string    `json:"id"`
+	UserEmail   string    `json:"user_email"`
+	MessageType string    `json:"message_type"`
+	Channel     string    `json:"channel,omitempty"` // Channel is empty for email
+	EnqueuedAt  time.Time `json:"enqueued_at"`
+}
+
+// entry is a Message read from the stream, it keeps the stream ID for acknowledging it.
+type entry struct {
+	streamID string
+	message  Message
+}
+
+// NewID returns a random identifier for a Message.
+func NewID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating message id due to: %w", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func NewStream(db *redis.Client, options StreamOptions) Stream {
+	return Stream{
+		db:      db,
+		options: options,
+	}
+}
+
+// RedisStream is an abstraction for redis.Client making it mockeable
+type RedisStream interface {
+	XAdd(ctx context.Context, a *redis.XAddArgs) *redis.StringCmd
+	XGroupCreateMkStream(ctx context.Context, stream, group, start string) *redis.StatusCmd
+	XReadGroup(ctx context.Context, a *redis.XReadGroupArgs) *redis.XStreamSliceCmd
+	XAck(ctx context.Context, stream, group string, ids ...string) *redis.IntCmd
+	XDel(ctx context.Context, stream string, ids ...string) *redis.IntCmd
+	XAutoClaim(ctx context.Context, a *redis.XAutoClaimArgs) *redis.XAutoClaimCmd
+	XPendingExt(ctx context.Context, a *redis.XPendingExtArgs) *redis.XPendingExtCmd
+	XInfoGroups(ctx context.Context, key string) *redis.XInfoGroupsCmd
+}
+
+// Stream is a durable queue backed by a Redis stream and a consumer group.
+// Messages are acknowledged only after being handled, so the ones lost by a crashed worker are redelivered.
+// The acknowledged messages are deleted, so the stream only keeps the ones not handled yet.
+type Stream struct {
+	db      RedisStream
+	options StreamOptions
+}
+
+// Setup creates the stream and its consumer group when they do not exist.
+func (s Stream) Setup(ctx context.Context) error {
+	err := s.db.XGroupCreateMkStream(ctx, s.options.Stream, s.options.Group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("error creating consumer group due to: %w", err)
+	}
+
+	return nil
+}
+
+// Enqueue adds the message to the stream, the message ID is generated when it is empty.
+func (s Stream) Enqueue(ctx context.Context, msg Message) (string, error) {
+	if msg.ID == "" {
+		id, err := NewID()
+		if err != nil {
+			return "", err
+		}
+
+		msg.ID = id
+	}
+
+	if msg.EnqueuedAt.IsZero() {
+		msg.EnqueuedAt = time.Now().UTC()
+	}
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling message due to: %w", err)
+	}
+
+	err = s.db.XAdd(ctx, &redis.XAddArgs{
+		Stream: s.options.Stream,
+		Values: map[string]interface{}{payloadField: payload},
+	}).Err()
+	if err != nil {
+		return "", fmt.Errorf("error adding message to stream due to: %w", err)
+	}
+
+	return msg.ID, nil
+}
+
+// Depth returns the amount of messages waiting to be handled, either not read by the group yet or not acknowledged.
+func (s Stream) Depth(ctx context.Context) (int64, error) {
+	groups, err := s.db.XInfoGroups(ctx, s.options.Stream).Result()
+	if err != nil {
+		return 0, fmt.Errorf("error reading consumer groups due to: %w", err)
+	}
+
+	for _, group := range groups {
+		if group.Name == s.options.Group {
+			return group.Lag + group.Pending, nil
+		}
+	}
+
+	return 0, nil
+}
+
+// read waits for messages never delivered to any consumer of the group.
+func (s Stream) read(ctx context.Context, consumer string) ([]entry, error) {
+	streams, err := s.db.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    s.options.Group,
+		Consumer: consumer,
+		Streams:  []string{s.options.Stream, ">"},
+		Count:    s.options.BatchSize,
+		Block:    s.options.BlockTimeout,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error reading stream due to: %w", err)
+	}
+
+	var entries []entry
+	for _, stream := range streams {
+		entries = append(entries, s.decode(ctx, stream.Messages)...)
+	}
+
+	return entries, nil
+}
+
+// claim takes over the messages that were not acknowledged in time by other consumers.
+// Messages delivered too many times are acknowledged without being returned.
+func (s Stream) claim(ctx context.Context, consumer string) ([]entry, error) {
+	messages, _, err := s.db.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   s.options.Stream,
+		Group:    s.options.Group,
+		Consumer: consumer,
+		MinIdle:  s.options.MinIdle,
+		Start:    "0-0",
+		Count:    s.options.BatchSize,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error claiming pending messages due to: %w", err)
+	}
+
+	var pending []redis.XMessage
+	for _, msg := range messages {
+		deliveries, err := s.deliveries(ctx, msg.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if deliveries > s.options.MaxDeliveries {
+			// the message is discarded for not blocking the queue forever
+			slog.WarnContext(ctx, "discarding message after too many deliveries",
+				slog.String("stream_id", msg.ID), slog.Int64("deliveries", deliveries))
+			_ = s.ack(ctx, msg.ID)
+
+			continue
+		}
+
+		pending = append(pending, msg)
+	}
+
+	return s.decode(ctx, pending), nil
+}
+
+// deliveries returns how many times the message was delivered, claims included.
+func (s Stream) deliveries(ctx context.Context, streamID string) (int64, error) {
+	pending, err := s.db.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: s.options.Stream,
+		Group:  s.options.Group,
+		Start:  streamID,
+		End:    streamID,
+		Count:  1,
+	}).Result()
+	if err != nil {
+		return 0, fmt.Errorf("error checking pending message due to: %w", err)
+	}
+
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	return pending[0].RetryCount, nil
+}
+
+// ack acknowledges the message and deletes it from the stream. The stream is not trimmed by length or age instead,
+// because that could drop the messages still pending.
+func (s Stream) ack(ctx context.Context, streamID string) error {
+	if err := s.db.XAck(ctx, s.options.Stream, s.options.Group, streamID).Err(); err != nil {
+		return fmt.Errorf("error acknowledging message due to: %w", err)
+	}
+
+	// the message will not be delivered again, so failing to delete it only leaves it behind
+	if err := s.db.XDel(ctx, s.options.Stream, streamID).Err(); err != nil {
+		slog.WarnContext(ctx, "error deleting acknowledged message", slog.String("stream_id", streamID), logging.Error(err))
+	}
+
+	return nil
+}
+
+// decode parses the stream messages. The malformed ones are acknowledged because they will never be handled.
+func (s Stream) decode(ctx context.Context, messages []redis.XMessage) []entry {
+	entries := make([]entry, 0, len(messages))
+
+	for _, msg := range messages {
+		var decoded Message
+
+		payload, _ := msg.Values[payloadField].(string)
+		if err := json.Unmarshal([]byte(payload), &decoded); err != nil {
+			slog.WarnContext(ctx, "discarding malformed message", slog.String("stream_id", msg.ID), logging.Error(err))
+			_ = s.ack(ctx, msg.ID)
+
+			continue
+		}
+
+		entries = append(entries, entry{streamID: msg.ID, message: decoded})
+	}
+
+	return entries
+}
@@ -0,0 +1,392 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"user_news_api/queue/mocks"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var testOptions = StreamOptions{
+	Stream:        "stream",
+	Group:         "group",
+	BatchSize:     10,
+	BlockTimeout:  time.Second,
+	MinIdle:       time.Minute,
+	MaxDeliveries: 3,
+}
+
+func TestStreamSetup(t *testing.T) {
+	tests := []struct {
+		name          string
+		result        error
+		expectedError error
+	}{
+		{
+			name:          "group created",
+			result:        nil,
+			expectedError: nil,
+		},
+		{
+			name:          "group already exists",
+			result:        errors.New("BUSYGROUP Consumer Group name already exists"),
+			expectedError: nil,
+		},
+		{
+			name:          "redis error",
+			result:        errors.New("error"),
+			expectedError: fmt.Errorf("error creating consumer group due to: %w", errors.New("error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisStream(t)
+			mockRedis.On("XGroupCreateMkStream", mock.Anything, "stream", "group", "0").
+				Return(redis.NewStatusResult("OK", tt.result)).Once()
+
+			s := Stream{db: mockRedis, options: testOptions}
+
+			assert.Equal(t, tt.expectedError, s.Setup(context.Background()))
+		})
+	}
+}
+
+func TestStreamEnqueue(t *testing.T) {
+	enqueuedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		message       Message
+		mockApplier   func(mockRedis *mocks.RedisStream)
+		expectedID    string
+		expectedError error
+	}{
+		{
+			name: "message with ID",
+			message: Message{
+				ID:          "some-id",
+				UserEmail:   "user@example.com",
+				MessageType: "News",
+				EnqueuedAt:  enqueuedAt,
+			},
+			mockApplier: func(mockRedis *mocks.RedisStream) {
+				mockRedis.On("XAdd", mock.Anything, mock.MatchedBy(func(args *redis.XAddArgs) bool {
+					var msg Message
+					err := json.Unmarshal(args.Values.(map[string]interface{})[payloadField].([]byte), &msg)
+
+					return err == nil && args.Stream == "stream" && msg.ID == "some-id" && msg.EnqueuedAt.Equal(enqueuedAt)
+				})).Return(redis.NewStringResult("1-0", nil)).Once()
+			},
+			expectedID:    "some-id",
+			expectedError: nil,
+		},
+		{
+			name: "redis error",
+			message: Message{
+				ID:          "some-id",
+				UserEmail:   "user@example.com",
+				MessageType: "News",
+			},
+			mockApplier: func(mockRedis *mocks.RedisStream) {
+				mockRedis.On("XAdd", mock.Anything, mock.Anything).Return(redis.NewStringResult("", errors.New("error"))).Once()
+			},
+			expectedID:    "",
+			expectedError: fmt.Errorf("error adding message to stream due to: %w", errors.New("error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisStream(t)
+			tt.mockApplier(mockRedis)
+
+			s := Stream{db: mockRedis, options: testOptions}
+
+			id, err := s.Enqueue(context.Background(), tt.message)
+
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expectedID, id)
+		})
+	}
+}
+
+func TestStreamEnqueueGeneratesID(t *testing.T) {
+	mockRedis := mocks.NewRedisStream(t)
+	mockRedis.On("XAdd", mock.Anything, mock.Anything).Return(redis.NewStringResult("1-0", nil)).Once()
+
+	s := Stream{db: mockRedis, options: testOptions}
+
+	id, err := s.Enqueue(context.Background(), Message{UserEmail: "user@example.com", MessageType: "News"})
+
+	require.NoError(t, err)
+	assert.Len(t, id, 32)
+}
+
+func TestStreamDepth(t *testing.T) {
+	groups := func(groups []redis.XInfoGroup, err error) *redis.XInfoGroupsCmd {
+		cmd := redis.NewXInfoGroupsCmd(context.Background(), "stream")
+		cmd.SetVal(groups)
+		cmd.SetErr(err)
+
+		return cmd
+	}
+
+	tests := []struct {
+		name          string
+		result        *redis.XInfoGroupsCmd
+		expectedDepth int64
+		expectedError error
+	}{
+		{
+			name: "unread and pending messages",
+			result: groups([]redis.XInfoGroup{
+				{Name: "other", Lag: 100},
+				{Name: "group", Lag: 3, Pending: 2},
+			}, nil),
+			expectedDepth: 5,
+		},
+		{
+			name:          "group not created yet",
+			result:        groups(nil, nil),
+			expectedDepth: 0,
+		},
+		{
+			name:          "redis error",
+			result:        groups(nil, errors.New("error")),
+			expectedError: fmt.Errorf("error reading consumer groups due to: %w", errors.New("error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisStream(t)
+			mockRedis.On("XInfoGroups", mock.Anything, "stream").Return(tt.result).Once()
+
+			s := Stream{db: mockRedis, options: testOptions}
+
+			depth, err := s.Depth(context.Background())
+
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expectedDepth, depth)
+		})
+	}
+}
+
+func TestStreamRead(t *testing.T) {
+	msg := Message{ID: "some-id", UserEmail: "user@example.com", MessageType: "News"}
+	payload := `{"id":"some-id","user_email":"user@example.com","message_type":"News","enqueued_at":"0001-01-01T00:00:00Z"}`
+
+	tests := []struct {
+		name          string
+		mockApplier   func(mockRedis *mocks.RedisStream)
+		expected      []entry
+		expectedError error
+	}{
+		{
+			name: "new messages",
+			mockApplier: func(mockRedis *mocks.RedisStream) {
+				mockRedis.On("XReadGroup", mock.Anything, &redis.XReadGroupArgs{
+					Group:    "group",
+					Consumer: "consumer",
+					Streams:  []string{"stream", ">"},
+					Count:    10,
+					Block:    time.Second,
+				}).Return(redis.NewXStreamSliceCmdResult([]redis.XStream{{
+					Stream: "stream",
+					Messages: []redis.XMessage{
+						{ID: "1-0", Values: map[string]interface{}{payloadField: payload}},
+					},
+				}}, nil)).Once()
+			},
+			expected:      []entry{{streamID: "1-0", message: msg}},
+			expectedError: nil,
+		},
+		{
+			name: "malformed messages are acknowledged",
+			mockApplier: func(mockRedis *mocks.RedisStream) {
+				mockRedis.On("XReadGroup", mock.Anything, mock.Anything).Return(redis.NewXStreamSliceCmdResult([]redis.XStream{{
+					Stream: "stream",
+					Messages: []redis.XMessage{
+						{ID: "1-0", Values: map[string]interface{}{payloadField: "{"}},
+					},
+				}}, nil)).Once()
+				mockRedis.On("XAck", mock.Anything, "stream", "group", "1-0").Return(redis.NewIntResult(1, nil)).Once()
+				mockRedis.On("XDel", mock.Anything, "stream", "1-0").Return(redis.NewIntResult(1, nil)).Once()
+			},
+			expected:      nil,
+			expectedError: nil,
+		},
+		{
+			name: "no messages before the block timeout",
+			mockApplier: func(mockRedis *mocks.RedisStream) {
+				mockRedis.On("XReadGroup", mock.Anything, mock.Anything).Return(redis.NewXStreamSliceCmdResult(nil, redis.Nil)).Once()
+			},
+			expected:      nil,
+			expectedError: nil,
+		},
+		{
+			name: "redis error",
+			mockApplier: func(mockRedis *mocks.RedisStream) {
+				mockRedis.On("XReadGroup", mock.Anything, mock.Anything).Return(redis.NewXStreamSliceCmdResult(nil, errors.New("error"))).Once()
+			},
+			expected:      nil,
+			expectedError: fmt.Errorf("error reading stream due to: %w", errors.New("error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisStream(t)
+			tt.mockApplier(mockRedis)
+
+			s := Stream{db: mockRedis, options: testOptions}
+
+			entries, err := s.read(context.Background(), "consumer")
+
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expected, entries)
+		})
+	}
+}
+
+func TestStreamClaim(t *testing.T) {
+	msg := Message{ID: "some-id", UserEmail: "user@example.com", MessageType: "News"}
+	payload := `{"id":"some-id","user_email":"user@example.com","message_type":"News","enqueued_at":"0001-01-01T00:00:00Z"}`
+
+	claimed := func(ids ...string) *redis.XAutoClaimCmd {
+		cmd := redis.NewXAutoClaimCmd(context.Background())
+
+		var messages []redis.XMessage
+		for _, id := range ids {
+			messages = append(messages, redis.XMessage{ID: id, Values: map[string]interface{}{payloadField: payload}})
+		}
+
+		cmd.SetVal(messages, "0-0")
+
+		return cmd
+	}
+
+	pending := func(retryCount int64) *redis.XPendingExtCmd {
+		cmd := redis.NewXPendingExtCmd(context.Background())
+		cmd.SetVal([]redis.XPendingExt{{RetryCount: retryCount}})
+
+		return cmd
+	}
+
+	tests := []struct {
+		name          string
+		mockApplier   func(mockRedis *mocks.RedisStream)
+		expected      []entry
+		expectedError error
+	}{
+		{
+			name: "pending messages are claimed",
+			mockApplier: func(mockRedis *mocks.RedisStream) {
+				mockRedis.On("XAutoClaim", mock.Anything, &redis.XAutoClaimArgs{
+					Stream:   "stream",
+					Group:    "group",
+					Consumer: "consumer",
+					MinIdle:  time.Minute,
+					Start:    "0-0",
+					Count:    10,
+				}).Return(claimed("1-0")).Once()
+				mockRedis.On("XPendingExt", mock.Anything, mock.Anything).Return(pending(2)).Once()
+			},
+			expected:      []entry{{streamID: "1-0", message: msg}},
+			expectedError: nil,
+		},
+		{
+			name: "messages delivered too many times are discarded",
+			mockApplier: func(mockRedis *mocks.RedisStream) {
+				mockRedis.On("XAutoClaim", mock.Anything, mock.Anything).Return(claimed("1-0", "2-0")).Once()
+				mockRedis.On("XPendingExt", mock.Anything, mock.MatchedBy(func(args *redis.XPendingExtArgs) bool {
+					return args.Start == "1-0"
+				})).Return(pending(4)).Once()
+				mockRedis.On("XPendingExt", mock.Anything, mock.MatchedBy(func(args *redis.XPendingExtArgs) bool {
+					return args.Start == "2-0"
+				})).Return(pending(1)).Once()
+				mockRedis.On("XAck", mock.Anything, "stream", "group", "1-0").Return(redis.NewIntResult(1, nil)).Once()
+				mockRedis.On("XDel", mock.Anything, "stream", "1-0").Return(redis.NewIntResult(1, nil)).Once()
+			},
+			expected:      []entry{{streamID: "2-0", message: msg}},
+			expectedError: nil,
+		},
+		{
+			name: "redis error",
+			mockApplier: func(mockRedis *mocks.RedisStream) {
+				cmd := redis.NewXAutoClaimCmd(context.Background())
+				cmd.SetErr(errors.New("error"))
+
+				mockRedis.On("XAutoClaim", mock.Anything, mock.Anything).Return(cmd).Once()
+			},
+			expected:      nil,
+			expectedError: fmt.Errorf("error claiming pending messages due to: %w", errors.New("error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisStream(t)
+			tt.mockApplier(mockRedis)
+
+			s := Stream{db: mockRedis, options: testOptions}
+
+			entries, err := s.claim(context.Background(), "consumer")
+
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expected, entries)
+		})
+	}
+}
+
+func TestStreamAck(t *testing.T) {
+	tests := []struct {
+		name          string
+		mockApplier   func(mockRedis *mocks.RedisStream)
+		expectedError error
+	}{
+		{
+			name: "acknowledged messages are deleted",
+			mockApplier: func(mockRedis *mocks.RedisStream) {
+				mockRedis.On("XAck", mock.Anything, "stream", "group", "1-0").Return(redis.NewIntResult(1, nil)).Once()
+				mockRedis.On("XDel", mock.Anything, "stream", "1-0").Return(redis.NewIntResult(1, nil)).Once()
+			},
+			expectedError: nil,
+		},
+		{
+			name: "error deleting the acknowledged message",
+			mockApplier: func(mockRedis *mocks.RedisStream) {
+				mockRedis.On("XAck", mock.Anything, "stream", "group", "1-0").Return(redis.NewIntResult(1, nil)).Once()
+				mockRedis.On("XDel", mock.Anything, "stream", "1-0").Return(redis.NewIntResult(0, errors.New("error"))).Once()
+			},
+			expectedError: nil,
+		},
+		{
+			name: "error acknowledging the message",
+			mockApplier: func(mockRedis *mocks.RedisStream) {
+				mockRedis.On("XAck", mock.Anything, "stream", "group", "1-0").Return(redis.NewIntResult(0, errors.New("error"))).Once()
+			},
+			expectedError: fmt.Errorf("error acknowledging message due to: %w", errors.New("error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisStream(t)
+			tt.mockApplier(mockRedis)
+
+			s := Stream{db: mockRedis, options: testOptions}
+
+			assert.Equal(t, tt.expectedError, s.ack(context.Background(), "1-0"))
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+	"user_news_api/logging"
+)
+
+// Handler delivers a Message. When it fails the message is redelivered later, unless the error is not retryable.
+type Handler func(context.Context, Message) error
+
+// DefaultWorkerOptions are used by the API when async delivery is enabled.
+var DefaultWorkerOptions = WorkerOptions{
+	Workers:         4,
+	Name:            "worker",
+	DeliveryTimeout: 30 * time.Second,
+	ErrorBackoff:    time.Second,
+}
+
+type WorkerOptions struct {
+	Workers         int              // Workers is the amount of consumers reading the stream concurrently
+	Name            string           // Name prefixes the consumer names, it must be unique between processes
+	DeliveryTimeout time.Duration    // DeliveryTimeout bounds the handling of every message
+	ErrorBackoff    time.Duration    // ErrorBackoff is how long a worker waits after failing to read the stream
+	Retryable       func(error) bool // Retryable decides if a failed message is redelivered, all of them are when nil
+}
+
+func NewWorkerPool(stream Stream, handler Handler, options WorkerOptions) WorkerPool {
+	return WorkerPool{
+		stream:  stream,
+		handler: handler,
+		options: options,
+	}
+}
+
+// WorkerPool consumes the Stream with several consumers of the same group.
+type WorkerPool struct {
+	stream  Stream
+	handler Handler
+	options WorkerOptions
+}
+
+// Run blocks until the context is done and every worker has returned. The messages being handled are finished,
+// and the ones read but not handled yet are claimed later by other consumers.
+func (wp WorkerPool) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for i := 0; i < wp.options.Workers; i++ {
+		wg.Add(1)
+
+		go func(consumer string) {
+			defer wg.Done()
+
+			wp.work(ctx, consumer)
+		}(fmt.Sprintf("%s-%d", wp.options.Name, i))
+	}
+
+	wg.Wait()
+}
+
+func (wp WorkerPool) work(ctx context.Context, consumer string) {
+	for ctx.Err() == nil {
+		entries, err := wp.next(ctx, consumer)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			slog.ErrorContext(ctx, "worker error", slog.String("consumer", consumer), logging.Error(err))
+
+			select {
+			case <-time.After(wp.options.ErrorBackoff):
+			case <-ctx.Done():
+			}
+
+			continue
+		}
+
+		for _, e := range entries {
+			if ctx.Err() != nil {
+				return
+			}
+
+			wp.process(ctx, e)
+		}
+	}
+}
+
+// next gives priority to the messages abandoned by other consumers over the new ones.
+func (wp WorkerPool) next(ctx context.Context, consumer string) ([]entry, error) {
+	entries, err := wp.stream.claim(ctx, consumer)
+	if err != nil || len(entries) > 0 {
+		return entries, err
+	}
+
+	return wp.stream.read(ctx, consumer)
+}
+
+// process is not interrupted when the pool is stopped, bounded by the DeliveryTimeout instead.
+// Otherwise a mail already sent could not be acknowledged, and it would be sent again.
+func (wp WorkerPool) process(ctx context.Context, e entry) {
+	ctx = context.WithoutCancel(ctx)
+
+	handlerCtx, cancel := context.WithTimeout(ctx, wp.options.DeliveryTimeout)
+	defer cancel()
+
+	if err := wp.handler(handlerCtx, e.message); err != nil {
+		if wp.options.Retryable == nil || wp.options.Retryable(err) {
+			// it is not acknowledged, so it is claimed again after the stream MinIdle
+			slog.WarnContext(ctx, "message failed, it will be redelivered",
+				slog.String(logging.KeyNotificationID, e.message.ID), logging.Error(err))
+
+			return
+		}
+
+		slog.ErrorContext(ctx, "message failed, it will not be redelivered",
+			slog.String(logging.KeyNotificationID, e.message.ID), logging.Error(err))
+	}
+
+	if err := wp.stream.ack(ctx, e.streamID); err != nil {
+		slog.WarnContext(ctx, "message was handled but not acknowledged",
+			slog.String(logging.KeyNotificationID, e.message.ID), logging.Error(err))
+	}
+}
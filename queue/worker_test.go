@@ -0,0 +1,146 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"user_news_api/queue/mocks"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var errPermanent = errors.New("permanent error")
+
+func TestWorkerPoolProcess(t *testing.T) {
+	msg := Message{ID: "some-id", UserEmail: "user@example.com", MessageType: "News"}
+
+	tests := []struct {
+		name        string
+		handlerErr  error
+		mockApplier func(mockRedis *mocks.RedisStream)
+	}{
+		{
+			name:       "handled messages are acknowledged",
+			handlerErr: nil,
+			mockApplier: func(mockRedis *mocks.RedisStream) {
+				mockRedis.On("XAck", mock.Anything, "stream", "group", "1-0").Return(redis.NewIntResult(1, nil)).Once()
+				mockRedis.On("XDel", mock.Anything, "stream", "1-0").Return(redis.NewIntResult(1, nil)).Once()
+			},
+		},
+		{
+			name:        "retryable failures are not acknowledged",
+			handlerErr:  errors.New("transient error"),
+			mockApplier: func(mockRedis *mocks.RedisStream) {},
+		},
+		{
+			name:       "not retryable failures are acknowledged",
+			handlerErr: errPermanent,
+			mockApplier: func(mockRedis *mocks.RedisStream) {
+				mockRedis.On("XAck", mock.Anything, "stream", "group", "1-0").Return(redis.NewIntResult(1, nil)).Once()
+				mockRedis.On("XDel", mock.Anything, "stream", "1-0").Return(redis.NewIntResult(1, nil)).Once()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisStream(t)
+			tt.mockApplier(mockRedis)
+
+			var handled Message
+
+			wp := WorkerPool{
+				stream: Stream{db: mockRedis, options: testOptions},
+				handler: func(ctx context.Context, m Message) error {
+					handled = m
+
+					_, hasDeadline := ctx.Deadline()
+					assert.True(t, hasDeadline)
+
+					return tt.handlerErr
+				},
+				options: WorkerOptions{
+					DeliveryTimeout: time.Second,
+					Retryable: func(err error) bool {
+						return !errors.Is(err, errPermanent)
+					},
+				},
+			}
+
+			wp.process(context.Background(), entry{streamID: "1-0", message: msg})
+
+			assert.Equal(t, msg, handled)
+		})
+	}
+}
+
+func TestWorkerPoolProcessStopped(t *testing.T) {
+	mockRedis := mocks.NewRedisStream(t)
+	mockRedis.On("XAck", mock.MatchedBy(func(ctx context.Context) bool { return ctx.Err() == nil }),
+		"stream", "group", "1-0").Return(redis.NewIntResult(1, nil)).Once()
+	mockRedis.On("XDel", mock.MatchedBy(func(ctx context.Context) bool { return ctx.Err() == nil }),
+		"stream", "1-0").Return(redis.NewIntResult(1, nil)).Once()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	wp := WorkerPool{
+		stream: Stream{db: mockRedis, options: testOptions},
+		handler: func(ctx context.Context, m Message) error {
+			// the pool is stopped while the message is being handled
+			cancel()
+
+			return ctx.Err()
+		},
+		options: WorkerOptions{DeliveryTimeout: time.Second},
+	}
+
+	wp.process(ctx, entry{streamID: "1-0", message: Message{ID: "some-id"}})
+}
+
+func TestWorkerPoolNext(t *testing.T) {
+	claimed := redis.NewXAutoClaimCmd(context.Background())
+	claimed.SetVal(nil, "0-0")
+
+	mockRedis := mocks.NewRedisStream(t)
+	mockRedis.On("XAutoClaim", mock.Anything, mock.Anything).Return(claimed).Once()
+	mockRedis.On("XReadGroup", mock.Anything, mock.Anything).Return(redis.NewXStreamSliceCmdResult(nil, redis.Nil)).Once()
+
+	wp := WorkerPool{stream: Stream{db: mockRedis, options: testOptions}}
+
+	entries, err := wp.next(context.Background(), "consumer")
+
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestWorkerPoolRunStopsWithContext(t *testing.T) {
+	mockRedis := mocks.NewRedisStream(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	claimed := redis.NewXAutoClaimCmd(ctx)
+	claimed.SetVal(nil, "0-0")
+
+	mockRedis.On("XAutoClaim", mock.Anything, mock.Anything).Return(claimed)
+	mockRedis.On("XReadGroup", mock.Anything, mock.Anything).
+		Run(func(mock.Arguments) { cancel() }).
+		Return(redis.NewXStreamSliceCmdResult(nil, redis.Nil))
+
+	wp := NewWorkerPool(Stream{db: mockRedis, options: testOptions}, nil, WorkerOptions{Workers: 2, Name: "test"})
+
+	done := make(chan struct{})
+	go func() {
+		wp.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("workers did not stop after the context was canceled")
+	}
+}
@@ -0,0 +1,34 @@
+package ratelimiter
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// clientKeySuffix keeps the counters of the API clients apart from the ones of the users.
+const clientKeySuffix = "api-client"
+
+func NewClientLimiter(db *redis.Client) ClientLimiter {
+	return ClientLimiter{db: db}
+}
+
+// ClientLimiter counts the notifications of every API client, each one with its own limit.
+type ClientLimiter struct {
+	db RedisCounter
+}
+
+// Reached counts the notifications and tells if the client sent more than max notifications within the period.
+func (cl ClientLimiter) Reached(
+	ctx context.Context, client string, notifications int64, max int64, period time.Duration,
+) (bool, error) {
+	limiter := rateLimiter{
+		db:        cl.db,
+		max:       max,
+		suffixKey: clientKeySuffix,
+		ttl:       period,
+	}
+
+	return limiter.reachedBy(ctx, client, notifications)
+}
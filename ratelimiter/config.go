@@ -12,8 +12,10 @@ const (
 // Depending on the context, they could be migrated to a database for getting dynamism
 var DefaultConfigs = map[string]Config{
 	StatusType: {
-		Max: 2,
-		TTL: time.Minute,
+		Max:           2,
+		TTL:           time.Minute,
+		OnLimit:       OnLimitDefer,
+		Transactional: true,
 	},
 	NewsType: {
 		Max: 1,
@@ -25,7 +27,29 @@ var DefaultConfigs = map[string]Config{
 	},
 }
 
+// OnLimitPolicy decides what happens to the messages over the limit.
+type OnLimitPolicy string
+
+const (
+	OnLimitReject       OnLimitPolicy = "reject"        // the message is rejected, it is the default policy
+	OnLimitDefer        OnLimitPolicy = "defer"         // the message is sent when the TTL ends
+	OnLimitDropSilently OnLimitPolicy = "drop_silently" // the message is dropped, but it is answered as if it was sent
+	OnLimitDigest       OnLimitPolicy = "digest"        // the message is buffered and sent along the others when the TTL ends
+)
+
+// Valid tells if the policy is one of the known ones, the empty policy is valid and means OnLimitReject.
+func (p OnLimitPolicy) Valid() bool {
+	switch p {
+	case "", OnLimitReject, OnLimitDefer, OnLimitDropSilently, OnLimitDigest:
+		return true
+	}
+
+	return false
+}
+
 type Config struct {
-	Max int64
-	TTL time.Duration
+	Max           int64
+	TTL           time.Duration
+	OnLimit       OnLimitPolicy
+	Transactional bool // Transactional message types are sent even to the users that opted out of them
 }
@@ -0,0 +1,29 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import mock "github.com/stretchr/testify/mock"
+
+// Metrics is an autogenerated mock type for the Metrics type
+type Metrics struct {
+	mock.Mock
+}
+
+// ObserveLimit provides a mock function with given fields: messageType, reached
+func (_m *Metrics) ObserveLimit(messageType string, reached bool) {
+	_m.Called(messageType, reached)
+}
+
+// NewMetrics creates a new instance of Metrics. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMetrics(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Metrics {
+	mock := &Metrics{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -65,6 +65,48 @@ func (_m *RedisCounter) Incr(ctx context.Context, key string) *redis.IntCmd {
 	return r0
 }
 
+// IncrBy provides a mock function with given fields: ctx, key, value
+func (_m *RedisCounter) IncrBy(ctx context.Context, key string, value int64) *redis.IntCmd {
+	ret := _m.Called(ctx, key, value)
+
+	var r0 *redis.IntCmd
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) *redis.IntCmd); ok {
+		r0 = rf(ctx, key, value)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.IntCmd)
+		}
+	}
+
+	return r0
+}
+
+// Pipelined provides a mock function with given fields: ctx, fn
+func (_m *RedisCounter) Pipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+	ret := _m.Called(ctx, fn)
+
+	var r0 []redis.Cmder
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, func(redis.Pipeliner) error) ([]redis.Cmder, error)); ok {
+		return rf(ctx, fn)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, func(redis.Pipeliner) error) []redis.Cmder); ok {
+		r0 = rf(ctx, fn)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]redis.Cmder)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, func(redis.Pipeliner) error) error); ok {
+		r1 = rf(ctx, fn)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // TTL provides a mock function with given fields: ctx, key
 func (_m *RedisCounter) TTL(ctx context.Context, key string) *redis.DurationCmd {
 	ret := _m.Called(ctx, key)
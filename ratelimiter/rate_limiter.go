@@ -20,9 +20,11 @@ func newRateLimiter(db *redis.Client, max int64, suffixKey string, ttl time.Dura
 // RedisCounter is an abstraction for redis.Client making it mockeable
 type RedisCounter interface {
 	Incr(ctx context.Context, key string) *redis.IntCmd
+	IncrBy(ctx context.Context, key string, value int64) *redis.IntCmd
 	Decr(ctx context.Context, key string) *redis.IntCmd
 	Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd
 	TTL(ctx context.Context, key string) *redis.DurationCmd
+	Pipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error)
 }
 
 type rateLimiter struct {
@@ -30,10 +32,13 @@ type rateLimiter struct {
 	max       int64        // max is the maximum hits
 	suffixKey string       // suffixKey is used for avoiding collisions between different rateLimiter
 	ttl       time.Duration
+	onLimit   OnLimitPolicy
+
+	transactional bool
 }
 
 func (rl rateLimiter) Reached(ctx context.Context, key string) (bool, error) {
-	key = fmt.Sprintf("%s-%s", key, rl.suffixKey)
+	key = rl.key(key)
 
 	// It increases by one the counter associated with the key.
 	// If the key does not exist, then is created with one value.
@@ -42,6 +47,27 @@ func (rl rateLimiter) Reached(ctx context.Context, key string) (bool, error) {
 		return false, fmt.Errorf("error increasing user counter due to: %w", err)
 	}
 
+	rl.expire(ctx, key)
+
+	return counter > rl.max, nil
+}
+
+// reachedBy counts several hits at once, e.g. the notifications of a batch. As in Reached, the hits are counted
+// even when they reach the limit.
+func (rl rateLimiter) reachedBy(ctx context.Context, key string, hits int64) (bool, error) {
+	key = rl.key(key)
+
+	counter, err := rl.db.IncrBy(ctx, key, hits).Result()
+	if err != nil {
+		return false, fmt.Errorf("error increasing user counter due to: %w", err)
+	}
+
+	rl.expire(ctx, key)
+
+	return counter > rl.max, nil
+}
+
+func (rl rateLimiter) expire(ctx context.Context, key string) {
 	// It checks if the key resource has a TTL set. If it is not, then is created.
 	// Both the TTL and Expire methods are not validated in case of fail, but they are retried in future requests.
 	// This approach avoids to use methods that lock the resource.
@@ -49,6 +75,22 @@ func (rl rateLimiter) Reached(ctx context.Context, key string) (bool, error) {
 	if ttl := rl.db.TTL(ctx, key).Val(); ttl < 0 {
 		_ = rl.db.Expire(ctx, key, rl.ttl)
 	}
+}
 
-	return counter > rl.max, nil
+// resetIn returns how long until the counter of the key starts again, the whole TTL when it is not counting yet.
+func (rl rateLimiter) resetIn(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := rl.db.TTL(ctx, rl.key(key)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("error getting user counter ttl due to: %w", err)
+	}
+
+	if ttl < 0 {
+		return rl.ttl, nil
+	}
+
+	return ttl, nil
+}
+
+func (rl rateLimiter) key(key string) string {
+	return fmt.Sprintf("%s-%s", key, rl.suffixKey)
 }
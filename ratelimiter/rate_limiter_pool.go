@@ -3,7 +3,13 @@ package ratelimiter
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
+	"user_news_api/tracing"
+
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
@@ -12,25 +18,193 @@ var (
 
 func NewLimiterPool(db *redis.Client, configs map[string]Config) LimiterPool {
 	limiterPool := LimiterPool{
+		db:       db,
 		limiters: make(map[string]rateLimiter),
 	}
 
 	for msgType, config := range configs {
-		limiterPool.limiters[msgType] = newRateLimiter(db, config.Max, msgType, config.TTL)
+		limiter := newRateLimiter(db, config.Max, msgType, config.TTL)
+		limiter.onLimit = config.OnLimit
+		limiter.transactional = config.Transactional
+
+		limiterPool.limiters[msgType] = limiter
 	}
 
 	return limiterPool
 }
 
+// Metrics is an abstraction for metrics.Registry making it mockeable
+type Metrics interface {
+	ObserveLimit(messageType string, reached bool)
+}
+
 type LimiterPool struct {
+	db       RedisCounter // db is shared by all the limiters, it is used for evaluating batches at once
 	limiters map[string]rateLimiter
+	metrics  Metrics // metrics is nil when the decisions are not observed
 }
 
-func (lp LimiterPool) Reached(ctx context.Context, user string, msgType string) (bool, error) {
+// WithMetrics observes every decision of Reached and ReachedBatch.
+func (lp LimiterPool) WithMetrics(metrics Metrics) LimiterPool {
+	lp.metrics = metrics
+
+	return lp
+}
+
+// Hit is a message counted by ReachedBatch.
+type Hit struct {
+	User        string
+	MessageType string
+}
+
+// HitResult is the outcome of a single Hit. Err is ErrMessageTypeNotValid for unknown message types.
+type HitResult struct {
+	Reached bool
+	Err     error
+}
+
+// Valid tells if the message type has a limiter, without counting any message.
+func (lp LimiterPool) Valid(msgType string) bool {
+	_, ok := lp.limiters[msgType]
+
+	return ok
+}
+
+// OnLimit returns the policy of the message type for the messages over the limit.
+func (lp LimiterPool) OnLimit(msgType string) OnLimitPolicy {
+	if policy := lp.limiters[msgType].onLimit; policy != "" {
+		return policy
+	}
+
+	return OnLimitReject
+}
+
+// Transactional tells if the message type cannot be opted out of.
+func (lp LimiterPool) Transactional(msgType string) bool {
+	return lp.limiters[msgType].transactional
+}
+
+// ResetAt returns when the user can receive messages of the message type again.
+func (lp LimiterPool) ResetAt(ctx context.Context, user string, msgType string) (time.Time, error) {
+	limiter, ok := lp.limiters[msgType]
+	if !ok {
+		return time.Time{}, ErrMessageTypeNotValid
+	}
+
+	resetIn, err := limiter.resetIn(ctx, user)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Now().UTC().Add(resetIn), nil
+}
+
+// Reached counts the message and tells if the user is over the limit of the message type. Its span is the parent
+// of the Redis commands when the client is instrumented with redisotel.
+func (lp LimiterPool) Reached(ctx context.Context, user string, msgType string) (reached bool, err error) {
+	ctx, span := tracing.Start(ctx, "LimiterPool.Reached", trace.WithAttributes(attribute.String("message_type", msgType)))
+	defer func() {
+		span.SetAttributes(attribute.Bool("reached", reached))
+		tracing.End(span, err)
+	}()
+
 	limiter, ok := lp.limiters[msgType]
 	if !ok {
 		return false, ErrMessageTypeNotValid
 	}
 
-	return limiter.Reached(ctx, user)
+	reached, err = limiter.Reached(ctx, user)
+	if err == nil {
+		lp.observe(msgType, reached)
+	}
+
+	return reached, err
+}
+
+func (lp LimiterPool) observe(msgType string, reached bool) {
+	if lp.metrics != nil {
+		lp.metrics.ObserveLimit(msgType, reached)
+	}
+}
+
+// ReachedBatch counts all the hits with a single pipeline, keeping the same rules as Reached.
+// Hits of the same user and message type are counted one after the other, as separated calls to Reached would do.
+func (lp LimiterPool) ReachedBatch(ctx context.Context, hits []Hit) ([]HitResult, error) {
+	results := make([]HitResult, len(hits))
+	keys := make([]string, len(hits))
+
+	counted := 0
+	for i, hit := range hits {
+		limiter, ok := lp.limiters[hit.MessageType]
+		if !ok {
+			results[i].Err = ErrMessageTypeNotValid
+
+			continue
+		}
+
+		keys[i] = limiter.key(hit.User)
+		counted++
+	}
+
+	if counted == 0 {
+		return results, nil
+	}
+
+	// The pipeline error is the first failed command, so the errors are checked for each command instead.
+	cmds, _ := lp.db.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			if key == "" {
+				continue
+			}
+
+			pipe.Incr(ctx, key)
+			pipe.TTL(ctx, key)
+		}
+
+		return nil
+	})
+	if len(cmds) != 2*counted {
+		return nil, fmt.Errorf("error increasing user counters: %d replies for %d commands", len(cmds), 2*counted)
+	}
+
+	// expire keeps the TTL of every key without it, the same key can appear several times in the batch
+	expire := make(map[string]time.Duration)
+
+	next := 0
+	for i, key := range keys {
+		if key == "" {
+			continue
+		}
+
+		incr, ttl := cmds[next], cmds[next+1]
+		next += 2
+
+		counter, err := incr.(*redis.IntCmd).Result()
+		if err != nil {
+			results[i].Err = fmt.Errorf("error increasing user counter due to: %w", err)
+
+			continue
+		}
+
+		// as in Reached, a key without TTL gets it now, and failing to set it is retried in future requests
+		limiter := lp.limiters[hits[i].MessageType]
+		if ttl.(*redis.DurationCmd).Val() < 0 {
+			expire[key] = limiter.ttl
+		}
+
+		results[i].Reached = counter > limiter.max
+		lp.observe(hits[i].MessageType, results[i].Reached)
+	}
+
+	if len(expire) > 0 {
+		_, _ = lp.db.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for key, ttl := range expire {
+				pipe.Expire(ctx, key, ttl)
+			}
+
+			return nil
+		})
+	}
+
+	return results, nil
 }
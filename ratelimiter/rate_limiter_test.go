@@ -11,6 +11,7 @@ import (
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestReached(t *testing.T) {
@@ -84,6 +85,60 @@ func TestReached(t *testing.T) {
 	}
 }
 
+func TestResetIn(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockApplier    func(mockRedis *mocks.RedisCounter)
+		expectedResult time.Duration
+		expectedError  error
+	}{
+		{
+			name: "Counting",
+			mockApplier: func(mockRedis *mocks.RedisCounter) {
+				mockRedis.On("TTL", mock.Anything, "testKey-suffix").Return(redis.NewDurationResult(10*time.Second, nil)).Once()
+			},
+			expectedResult: 10 * time.Second,
+			expectedError:  nil,
+		},
+		{
+			name: "Not counting yet",
+			mockApplier: func(mockRedis *mocks.RedisCounter) {
+				mockRedis.On("TTL", mock.Anything, "testKey-suffix").Return(redis.NewDurationResult(-2, nil)).Once()
+			},
+			expectedResult: 30 * time.Second,
+			expectedError:  nil,
+		},
+		{
+			name: "Error getting TTL",
+			mockApplier: func(mockRedis *mocks.RedisCounter) {
+				mockRedis.On("TTL", mock.Anything, "testKey-suffix").Return(redis.NewDurationResult(0, errors.New("error"))).Once()
+			},
+			expectedResult: 0,
+			expectedError:  fmt.Errorf("error getting user counter ttl due to: %w", errors.New("error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisCounter(t)
+
+			tt.mockApplier(mockRedis)
+
+			rl := rateLimiter{
+				db:        mockRedis,
+				max:       10,
+				suffixKey: "suffix",
+				ttl:       30 * time.Second,
+			}
+
+			result, err := rl.resetIn(context.Background(), "testKey")
+
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expectedResult, result)
+		})
+	}
+}
+
 func TestLimiterPoolReached(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -158,9 +213,15 @@ func TestLimiterPoolReached(t *testing.T) {
 				tt.limiters[msgType] = limiter
 			}
 
+			// only the decisions are observed, not the errors
+			mockMetrics := mocks.NewMetrics(t)
+			if tt.expectedError == nil {
+				mockMetrics.On("ObserveLimit", "type", tt.expectedResult).Once()
+			}
+
 			lp := LimiterPool{
 				limiters: tt.limiters,
-			}
+			}.WithMetrics(mockMetrics)
 
 			result, err := lp.Reached(context.Background(), "user", "type")
 
@@ -169,3 +230,202 @@ func TestLimiterPoolReached(t *testing.T) {
 		})
 	}
 }
+
+func TestLimiterPoolReachedBatch(t *testing.T) {
+	limiters := map[string]rateLimiter{
+		"type": {
+			suffixKey: "type",
+			max:       2,
+			ttl:       30 * time.Second,
+		},
+	}
+
+	replies := func(values ...interface{}) []redis.Cmder {
+		var cmds []redis.Cmder
+		for _, value := range values {
+			switch v := value.(type) {
+			case int64:
+				cmds = append(cmds, redis.NewIntResult(v, nil))
+			case time.Duration:
+				cmds = append(cmds, redis.NewDurationResult(v, nil))
+			case error:
+				cmds = append(cmds, redis.NewIntResult(0, v))
+			}
+		}
+
+		return cmds
+	}
+
+	tests := []struct {
+		name           string
+		hits           []Hit
+		mockApplier    func(mockRedis *mocks.RedisCounter)
+		expectedResult []HitResult
+		expectedError  error
+	}{
+		{
+			name: "Counters below and above max",
+			hits: []Hit{
+				{User: "user", MessageType: "type"},
+				{User: "other", MessageType: "type"},
+			},
+			mockApplier: func(mockRedis *mocks.RedisCounter) {
+				mockRedis.On("Pipelined", mock.Anything, mock.Anything).
+					Run(func(args mock.Arguments) {
+						// an INCR and a TTL for each hit
+						pipe := redis.NewClient(&redis.Options{}).Pipeline()
+						require.NoError(t, args.Get(1).(func(redis.Pipeliner) error)(pipe))
+						assert.Equal(t, 4, pipe.Len())
+					}).
+					Return(replies(int64(1), 10*time.Second, int64(3), 10*time.Second), nil).Once()
+			},
+			expectedResult: []HitResult{
+				{Reached: false},
+				{Reached: true},
+			},
+			expectedError: nil,
+		},
+		{
+			name: "Invalid message types are not counted",
+			hits: []Hit{
+				{User: "user", MessageType: "other"},
+				{User: "user", MessageType: "type"},
+			},
+			mockApplier: func(mockRedis *mocks.RedisCounter) {
+				mockRedis.On("Pipelined", mock.Anything, mock.Anything).
+					Return(replies(int64(1), 10*time.Second), nil).Once()
+			},
+			expectedResult: []HitResult{
+				{Err: ErrMessageTypeNotValid},
+				{Reached: false},
+			},
+			expectedError: nil,
+		},
+		{
+			name: "Only invalid message types",
+			hits: []Hit{
+				{User: "user", MessageType: "other"},
+			},
+			mockApplier: func(mockRedis *mocks.RedisCounter) {},
+			expectedResult: []HitResult{
+				{Err: ErrMessageTypeNotValid},
+			},
+			expectedError: nil,
+		},
+		{
+			name: "TTL not set, then set",
+			hits: []Hit{
+				{User: "user", MessageType: "type"},
+			},
+			mockApplier: func(mockRedis *mocks.RedisCounter) {
+				mockRedis.On("Pipelined", mock.Anything, mock.Anything).
+					Return(replies(int64(1), time.Duration(-1)), nil).Once()
+				mockRedis.On("Pipelined", mock.Anything, mock.Anything).
+					Return(replies(int64(1)), nil).Once()
+			},
+			expectedResult: []HitResult{
+				{Reached: false},
+			},
+			expectedError: nil,
+		},
+		{
+			name: "Error increasing a counter",
+			hits: []Hit{
+				{User: "user", MessageType: "type"},
+			},
+			mockApplier: func(mockRedis *mocks.RedisCounter) {
+				mockRedis.On("Pipelined", mock.Anything, mock.Anything).
+					Return(replies(errors.New("error"), 10*time.Second), errors.New("error")).Once()
+			},
+			expectedResult: []HitResult{
+				{Err: fmt.Errorf("error increasing user counter due to: %w", errors.New("error"))},
+			},
+			expectedError: nil,
+		},
+		{
+			name: "Pipeline without replies",
+			hits: []Hit{
+				{User: "user", MessageType: "type"},
+			},
+			mockApplier: func(mockRedis *mocks.RedisCounter) {
+				mockRedis.On("Pipelined", mock.Anything, mock.Anything).Return(nil, errors.New("error")).Once()
+			},
+			expectedResult: nil,
+			expectedError:  errors.New("error increasing user counters: 0 replies for 2 commands"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisCounter(t)
+			tt.mockApplier(mockRedis)
+
+			mockMetrics := mocks.NewMetrics(t)
+			for i, hitResult := range tt.expectedResult {
+				if hitResult.Err == nil {
+					mockMetrics.On("ObserveLimit", tt.hits[i].MessageType, hitResult.Reached).Once()
+				}
+			}
+
+			lp := LimiterPool{
+				db:       mockRedis,
+				limiters: limiters,
+			}.WithMetrics(mockMetrics)
+
+			result, err := lp.ReachedBatch(context.Background(), tt.hits)
+
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expectedResult, result)
+		})
+	}
+}
+
+func TestClientLimiterReached(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockApplier    func(mockRedis *mocks.RedisCounter)
+		expectedResult bool
+		expectedError  error
+	}{
+		{
+			name: "Notifications below the max of the client",
+			mockApplier: func(mockRedis *mocks.RedisCounter) {
+				mockRedis.On("IncrBy", mock.Anything, "client-id-api-client", int64(2)).Return(redis.NewIntResult(2, nil)).Once()
+				mockRedis.On("TTL", mock.Anything, "client-id-api-client").Return(redis.NewDurationResult(time.Minute, nil)).Once()
+			},
+			expectedResult: false,
+		},
+		{
+			name: "Notifications above the max of the client",
+			mockApplier: func(mockRedis *mocks.RedisCounter) {
+				mockRedis.On("IncrBy", mock.Anything, "client-id-api-client", int64(2)).Return(redis.NewIntResult(4, nil)).Once()
+				mockRedis.On("TTL", mock.Anything, "client-id-api-client").Return(redis.NewDurationResult(time.Minute, nil)).Once()
+			},
+			expectedResult: true,
+		},
+		{
+			name: "Period of the client set on the first request",
+			mockApplier: func(mockRedis *mocks.RedisCounter) {
+				mockRedis.On("IncrBy", mock.Anything, "client-id-api-client", int64(2)).Return(redis.NewIntResult(2, nil)).Once()
+				mockRedis.On("TTL", mock.Anything, "client-id-api-client").Return(redis.NewDurationResult(-1, nil)).Once()
+				mockRedis.On("Expire", mock.Anything, "client-id-api-client", time.Hour).Return(redis.NewBoolResult(true, nil)).Once()
+			},
+			expectedResult: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisCounter(t)
+
+			tt.mockApplier(mockRedis)
+
+			cl := ClientLimiter{db: mockRedis}
+
+			result, err := cl.Reached(context.Background(), "client-id", 2, 3, time.Hour)
+
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expectedResult, result)
+		})
+	}
+}
@@ -0,0 +1,210 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	redis "github.com/redis/go-redis/v9"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// RedisSortedSet is an autogenerated mock type for the RedisSortedSet type
+type RedisSortedSet struct {
+	mock.Mock
+}
+
+// Eval provides a mock function with given fields: ctx, script, keys, args
+func (_m *RedisSortedSet) Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd {
+	var _ca []interface{}
+	_ca = append(_ca, ctx, script, keys)
+	_ca = append(_ca, args...)
+	ret := _m.Called(_ca...)
+
+	var r0 *redis.Cmd
+	if rf, ok := ret.Get(0).(func(context.Context, string, []string, ...interface{}) *redis.Cmd); ok {
+		r0 = rf(ctx, script, keys, args...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.Cmd)
+		}
+	}
+
+	return r0
+}
+
+// HDel provides a mock function with given fields: ctx, key, fields
+func (_m *RedisSortedSet) HDel(ctx context.Context, key string, fields ...string) *redis.IntCmd {
+	_va := make([]interface{}, len(fields))
+	for _i := range fields {
+		_va[_i] = fields[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, key)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *redis.IntCmd
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...string) *redis.IntCmd); ok {
+		r0 = rf(ctx, key, fields...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.IntCmd)
+		}
+	}
+
+	return r0
+}
+
+// HMGet provides a mock function with given fields: ctx, key, fields
+func (_m *RedisSortedSet) HMGet(ctx context.Context, key string, fields ...string) *redis.SliceCmd {
+	_va := make([]interface{}, len(fields))
+	for _i := range fields {
+		_va[_i] = fields[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, key)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *redis.SliceCmd
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...string) *redis.SliceCmd); ok {
+		r0 = rf(ctx, key, fields...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.SliceCmd)
+		}
+	}
+
+	return r0
+}
+
+// HSet provides a mock function with given fields: ctx, key, values
+func (_m *RedisSortedSet) HSet(ctx context.Context, key string, values ...interface{}) *redis.IntCmd {
+	var _ca []interface{}
+	_ca = append(_ca, ctx, key)
+	_ca = append(_ca, values...)
+	ret := _m.Called(_ca...)
+
+	var r0 *redis.IntCmd
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...interface{}) *redis.IntCmd); ok {
+		r0 = rf(ctx, key, values...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.IntCmd)
+		}
+	}
+
+	return r0
+}
+
+// ZAdd provides a mock function with given fields: ctx, key, members
+func (_m *RedisSortedSet) ZAdd(ctx context.Context, key string, members ...redis.Z) *redis.IntCmd {
+	_va := make([]interface{}, len(members))
+	for _i := range members {
+		_va[_i] = members[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, key)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *redis.IntCmd
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...redis.Z) *redis.IntCmd); ok {
+		r0 = rf(ctx, key, members...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.IntCmd)
+		}
+	}
+
+	return r0
+}
+
+// ZAddNX provides a mock function with given fields: ctx, key, members
+func (_m *RedisSortedSet) ZAddNX(ctx context.Context, key string, members ...redis.Z) *redis.IntCmd {
+	_va := make([]interface{}, len(members))
+	for _i := range members {
+		_va[_i] = members[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, key)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *redis.IntCmd
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...redis.Z) *redis.IntCmd); ok {
+		r0 = rf(ctx, key, members...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.IntCmd)
+		}
+	}
+
+	return r0
+}
+
+// ZRangeByScore provides a mock function with given fields: ctx, key, opt
+func (_m *RedisSortedSet) ZRangeByScore(ctx context.Context, key string, opt *redis.ZRangeBy) *redis.StringSliceCmd {
+	ret := _m.Called(ctx, key, opt)
+
+	var r0 *redis.StringSliceCmd
+	if rf, ok := ret.Get(0).(func(context.Context, string, *redis.ZRangeBy) *redis.StringSliceCmd); ok {
+		r0 = rf(ctx, key, opt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.StringSliceCmd)
+		}
+	}
+
+	return r0
+}
+
+// ZRem provides a mock function with given fields: ctx, key, members
+func (_m *RedisSortedSet) ZRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	var _ca []interface{}
+	_ca = append(_ca, ctx, key)
+	_ca = append(_ca, members...)
+	ret := _m.Called(_ca...)
+
+	var r0 *redis.IntCmd
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...interface{}) *redis.IntCmd); ok {
+		r0 = rf(ctx, key, members...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.IntCmd)
+		}
+	}
+
+	return r0
+}
+
+// ZScore provides a mock function with given fields: ctx, key, member
+func (_m *RedisSortedSet) ZScore(ctx context.Context, key string, member string) *redis.FloatCmd {
+	ret := _m.Called(ctx, key, member)
+
+	var r0 *redis.FloatCmd
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *redis.FloatCmd); ok {
+		r0 = rf(ctx, key, member)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.FloatCmd)
+		}
+	}
+
+	return r0
+}
+
+// NewRedisSortedSet creates a new instance of RedisSortedSet. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewRedisSortedSet(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *RedisSortedSet {
+	mock := &RedisSortedSet{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
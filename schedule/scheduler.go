@@ -0,0 +1,124 @@
+package schedule
+
+import (
+	"context"
+	"log/slog"
+	"time"
+	"user_news_api/logging"
+)
+
+// Handler hands a due item to delivery. When it fails the item is scheduled again after the RetryDelay.
+type Handler func(context.Context, Item) error
+
+func NewScheduler(store Store, handler Handler) Scheduler {
+	return Scheduler{
+		store:   store,
+		handler: handler,
+		now:     time.Now,
+	}
+}
+
+// Scheduler moves the due items of the Store into delivery.
+type Scheduler struct {
+	store   Store
+	handler Handler
+	now     func() time.Time
+}
+
+// Run blocks until the context is done, looking for due items every PollInterval.
+// The item being handled is finished, the other due items are left for the next run.
+func (s Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.store.options.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.poll(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// poll handles the due items, batch after batch, until there are no more due items.
+func (s Scheduler) poll(ctx context.Context) {
+	if err := s.store.recoverLeases(ctx, s.now()); err != nil {
+		slog.ErrorContext(ctx, "scheduler error", logging.Error(err))
+	}
+
+	for ctx.Err() == nil {
+		now := s.now()
+
+		items, err := s.store.due(ctx, now)
+		if err != nil {
+			slog.ErrorContext(ctx, "scheduler error", logging.Error(err))
+
+			return
+		}
+
+		for _, item := range items {
+			if ctx.Err() != nil {
+				return
+			}
+
+			s.handle(ctx, item, now)
+		}
+
+		if int64(len(items)) < s.store.options.BatchSize {
+			return
+		}
+	}
+}
+
+// handle hands the item to delivery, and then it finishes the item or schedules it again after the RetryDelay.
+// The lease taken with the batch is renewed before handling the item, and the handling is bounded by HandleTimeout,
+// so the item is not recovered by another scheduler while it is handled. It is not interrupted when the scheduler
+// is stopped, so the item is not left leased until the lease expires.
+func (s Scheduler) handle(ctx context.Context, item Item, now time.Time) {
+	ctx = context.WithoutCancel(ctx)
+
+	renewed, err := s.store.renew(ctx, item.ID, s.now())
+	if err != nil {
+		// the item keeps its lease, so it is scheduled again when the lease expires
+		slog.ErrorContext(ctx, "scheduler error", slog.String(logging.KeyNotificationID, item.ID), logging.Error(err))
+
+		return
+	}
+
+	if !renewed {
+		slog.WarnContext(ctx, "scheduled item lease was lost, it is handled by another scheduler", slog.String(logging.KeyNotificationID, item.ID))
+
+		return
+	}
+
+	if err = s.runHandler(ctx, item); err != nil {
+		slog.WarnContext(ctx, "scheduled item failed, it will be retried", slog.String(logging.KeyNotificationID, item.ID), logging.Error(err))
+
+		item.SendAt = now.Add(s.store.options.RetryDelay)
+		if err = s.store.Add(ctx, item); err != nil {
+			// the item keeps its lease, so it is scheduled again when the lease expires
+			slog.ErrorContext(ctx, "scheduled item could not be retried",
+				slog.String(logging.KeyNotificationID, item.ID), logging.Error(err))
+
+			return
+		}
+
+		s.store.release(ctx, item.ID)
+
+		return
+	}
+
+	s.store.finish(ctx, item.ID)
+}
+
+func (s Scheduler) runHandler(ctx context.Context, item Item) error {
+	if s.store.options.HandleTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.store.options.HandleTimeout)
+		defer cancel()
+	}
+
+	return s.handler(ctx, item)
+}
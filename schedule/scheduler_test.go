@@ -0,0 +1,164 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"user_news_api/schedule/mocks"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSchedulerPoll(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	dueRange := &redis.ZRangeBy{Min: "-inf", Max: "1704103200000", Count: 2}
+
+	failing := testItem
+	failing.ID = "failing-id"
+
+	mockRedis := mocks.NewRedisSortedSet(t)
+
+	mockRedis.On("ZRangeByScore", mock.Anything, "scheduled-leases", dueRange).
+		Return(redis.NewStringSliceResult([]string{}, nil)).Once()
+
+	// a full batch is followed by another read
+	mockRedis.On("ZRangeByScore", mock.Anything, "scheduled", dueRange).
+		Return(redis.NewStringSliceResult([]string{"some-id", "failing-id"}, nil)).Once()
+	mockRedis.On("ZAddNX", mock.Anything, "scheduled-leases", redis.Z{Score: 1704103500000, Member: "some-id"}).
+		Return(redis.NewIntResult(1, nil)).Once()
+	mockRedis.On("ZAddNX", mock.Anything, "scheduled-leases", redis.Z{Score: 1704103500000, Member: "failing-id"}).
+		Return(redis.NewIntResult(1, nil)).Once()
+	mockRedis.On("ZRem", mock.Anything, "scheduled", "some-id").Return(redis.NewIntResult(1, nil)).Once()
+	mockRedis.On("ZRem", mock.Anything, "scheduled", "failing-id").Return(redis.NewIntResult(1, nil)).Once()
+	mockRedis.On("HMGet", mock.Anything, "scheduled-items", "some-id", "failing-id").
+		Return(redis.NewSliceResult([]interface{}{testItemPayload,
+			`{"id":"failing-id","user_email":"user@example.com","message_type":"News","send_at":"2024-01-01T09:00:00Z"}`}, nil)).Once()
+
+	// the leases are renewed before handling every item
+	mockRedis.On("Eval", mock.Anything, renewScript, []string{"scheduled-leases"}, int64(1704103500000), "some-id").
+		Return(redis.NewCmdResult(int64(1), nil)).Once()
+	mockRedis.On("Eval", mock.Anything, renewScript, []string{"scheduled-leases"}, int64(1704103500000), "failing-id").
+		Return(redis.NewCmdResult(int64(1), nil)).Once()
+
+	// the items are only forgotten once handled, and the failing one is scheduled again
+	mockRedis.On("ZScore", mock.Anything, "scheduled", "some-id").Return(redis.NewFloatResult(0, redis.Nil)).Once()
+	mockRedis.On("HDel", mock.Anything, "scheduled-items", "some-id").Return(redis.NewIntResult(1, nil)).Once()
+	mockRedis.On("ZRem", mock.Anything, "scheduled-leases", "some-id").Return(redis.NewIntResult(1, nil)).Once()
+	mockRedis.On("HSet", mock.Anything, "scheduled-items", "failing-id", []byte(`{"id":"failing-id","user_email":"user@example.com","message_type":"News","send_at":"2024-01-01T10:01:00Z"}`)).
+		Return(redis.NewIntResult(1, nil)).Once()
+	mockRedis.On("ZAdd", mock.Anything, "scheduled", redis.Z{Score: 1704103260000, Member: "failing-id"}).
+		Return(redis.NewIntResult(1, nil)).Once()
+	mockRedis.On("ZRem", mock.Anything, "scheduled-leases", "failing-id").Return(redis.NewIntResult(1, nil)).Once()
+	mockRedis.On("ZRangeByScore", mock.Anything, "scheduled", dueRange).
+		Return(redis.NewStringSliceResult([]string{}, nil)).Once()
+
+	var handled []string
+
+	s := Scheduler{
+		store: Store{db: mockRedis, options: testOptions},
+		handler: func(ctx context.Context, item Item) error {
+			handled = append(handled, item.ID)
+
+			if item.ID == "failing-id" {
+				return errors.New("error")
+			}
+
+			return nil
+		},
+		now: func() time.Time { return now },
+	}
+
+	s.poll(context.Background())
+
+	assert.Equal(t, []string{"some-id", "failing-id"}, handled)
+}
+
+func TestSchedulerHandleNotRetried(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	mockRedis := mocks.NewRedisSortedSet(t)
+
+	// the lease is kept, so the item is scheduled again once it expires
+	mockRedis.On("Eval", mock.Anything, renewScript, []string{"scheduled-leases"}, int64(1704103500000), "some-id").
+		Return(redis.NewCmdResult(int64(1), nil)).Once()
+	mockRedis.On("HSet", mock.Anything, "scheduled-items", "some-id", mock.Anything).
+		Return(redis.NewIntResult(0, errors.New("error"))).Once()
+
+	s := Scheduler{
+		store: Store{db: mockRedis, options: testOptions},
+		handler: func(ctx context.Context, item Item) error {
+			return errors.New("error")
+		},
+		now: func() time.Time { return now },
+	}
+
+	s.handle(context.Background(), testItem, now)
+}
+
+func TestSchedulerHandleLease(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		renewed         *redis.Cmd
+		applyMocks      func(mockRedis *mocks.RedisSortedSet)
+		expectedHandled bool
+	}{
+		{
+			name:    "lease renewed",
+			renewed: redis.NewCmdResult(int64(1), nil),
+			applyMocks: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("ZScore", mock.Anything, "scheduled", "some-id").Return(redis.NewFloatResult(0, redis.Nil)).Once()
+				mockRedis.On("HDel", mock.Anything, "scheduled-items", "some-id").Return(redis.NewIntResult(1, nil)).Once()
+				mockRedis.On("ZRem", mock.Anything, "scheduled-leases", "some-id").Return(redis.NewIntResult(1, nil)).Once()
+			},
+			expectedHandled: true,
+		},
+		{
+			name:            "lease lost to another scheduler",
+			renewed:         redis.NewCmdResult(int64(0), nil),
+			applyMocks:      func(mockRedis *mocks.RedisSortedSet) {},
+			expectedHandled: false,
+		},
+		{
+			name:            "lease not renewed",
+			renewed:         redis.NewCmdResult(nil, errors.New("error")),
+			applyMocks:      func(mockRedis *mocks.RedisSortedSet) {},
+			expectedHandled: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisSortedSet(t)
+
+			mockRedis.On("Eval", mock.Anything, renewScript, []string{"scheduled-leases"}, int64(1704103500000), "some-id").
+				Return(tt.renewed).Once()
+			tt.applyMocks(mockRedis)
+
+			handled := false
+
+			s := Scheduler{
+				store: Store{db: mockRedis, options: testOptions},
+				handler: func(ctx context.Context, item Item) error {
+					handled = true
+
+					// every item is handled well within its lease
+					deadline, ok := ctx.Deadline()
+					assert.True(t, ok)
+					assert.WithinDuration(t, time.Now().Add(testOptions.HandleTimeout), deadline, time.Second)
+
+					return nil
+				},
+				now: func() time.Time { return now },
+			}
+
+			s.handle(context.Background(), testItem, now)
+
+			assert.Equal(t, tt.expectedHandled, handled)
+		})
+	}
+}
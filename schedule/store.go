@@ -0,0 +1,339 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+	"user_news_api/logging"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrNotFound = errors.New("scheduled notification not found")
+)
+
+// DefaultOptions are used by the API for every scheduled notification.
+var DefaultOptions = Options{
+	Key:           "scheduled-notifications",
+	PollInterval:  time.Second,
+	BatchSize:     100,
+	RetryDelay:    time.Minute,
+	LeaseTimeout:  5 * time.Minute,
+	HandleTimeout: time.Minute,
+}
+
+type Options struct {
+	Key           string        // Key is the sorted set of due times, the items are kept in the hash Key-items
+	PollInterval  time.Duration // PollInterval is how often the scheduler looks for due items
+	BatchSize     int64         // BatchSize is the maximum of due items taken at once
+	RetryDelay    time.Duration // RetryDelay postpones the items that could not be handed to delivery
+	LeaseTimeout  time.Duration // LeaseTimeout is how long an item is being handled before it is taken as lost
+	HandleTimeout time.Duration // HandleTimeout bounds the handling of every item, so it ends well within its lease
+}
+
+// Item is a notification waiting for its due time.
+type Item struct {
+	ID          string    `json:"id"`
+	UserEmail   string    `json:"user_email"`
+	MessageType string    `json:"message_type"`
+	Channel     string    `json:"channel,omitempty"` // Channel is empty for email
+	Client      string    `json:"client,omitempty"`  // Client is who scheduled the notification, see delivery.Client
+	SendAt      time.Time `json:"send_at"`
+}
+
+func NewStore(db *redis.Client, options Options) Store {
+	return Store{
+		db:      db,
+		options: options,
+	}
+}
+
+// RedisSortedSet is an abstraction for redis.Client making it mockeable
+type RedisSortedSet interface {
+	ZAdd(ctx context.Context, key string, members ...redis.Z) *redis.IntCmd
+	ZAddNX(ctx context.Context, key string, members ...redis.Z) *redis.IntCmd
+	ZRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	ZScore(ctx context.Context, key string, member string) *redis.FloatCmd
+	ZRangeByScore(ctx context.Context, key string, opt *redis.ZRangeBy) *redis.StringSliceCmd
+	HSet(ctx context.Context, key string, values ...interface{}) *redis.IntCmd
+	HMGet(ctx context.Context, key string, fields ...string) *redis.SliceCmd
+	HDel(ctx context.Context, key string, fields ...string) *redis.IntCmd
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd
+}
+
+// Store keeps the IDs of the items in a sorted set scored by their due time in Unix milliseconds.
+// A due item is leased before it is removed from the sorted set, and it belongs to whoever holds its lease,
+// so several schedulers can share the store. The lease is kept until the item is handled, so the items
+// of a crashed scheduler are scheduled again once their lease expires.
+type Store struct {
+	db      RedisSortedSet
+	options Options
+}
+
+// Add schedules the item, an item with the same ID is replaced.
+func (s Store) Add(ctx context.Context, item Item) error {
+	payload, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("error marshalling scheduled item due to: %w", err)
+	}
+
+	if err = s.db.HSet(ctx, s.itemsKey(), item.ID, payload).Err(); err != nil {
+		return fmt.Errorf("error saving scheduled item %s due to: %w", item.ID, err)
+	}
+
+	err = s.db.ZAdd(ctx, s.options.Key, redis.Z{Score: float64(item.SendAt.UnixMilli()), Member: item.ID}).Err()
+	if err != nil {
+		return fmt.Errorf("error scheduling item %s due to: %w", item.ID, err)
+	}
+
+	return nil
+}
+
+// Cancel removes an item that is not due yet, ErrNotFound is returned when it was already taken or never existed.
+func (s Store) Cancel(ctx context.Context, id string) error {
+	removed, err := s.db.ZRem(ctx, s.options.Key, id).Result()
+	if err != nil {
+		return fmt.Errorf("error canceling scheduled item %s due to: %w", id, err)
+	}
+
+	if removed == 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+
+	s.done(ctx, id)
+
+	return nil
+}
+
+// Get returns a pending item, ErrNotFound is returned when it was already taken or never existed.
+func (s Store) Get(ctx context.Context, id string) (Item, error) {
+	items, err := s.items(ctx, []string{id})
+	if err != nil {
+		return Item{}, err
+	}
+
+	if len(items) == 0 {
+		return Item{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+
+	return items[0], nil
+}
+
+// List returns the pending items ordered by due time, limit bounds the amount of items.
+// When client is not empty, only the items it scheduled are returned.
+func (s Store) List(ctx context.Context, client string, limit int64) ([]Item, error) {
+	items := []Item{}
+
+	// the items are not indexed by client, so they are read page after page until there are enough of them
+	for offset := int64(0); int64(len(items)) < limit; offset += limit {
+		ids, err := s.db.ZRangeByScore(ctx, s.options.Key, &redis.ZRangeBy{
+			Min:    "-inf",
+			Max:    "+inf",
+			Offset: offset,
+			Count:  limit,
+		}).Result()
+		if err != nil {
+			return nil, fmt.Errorf("error listing scheduled items due to: %w", err)
+		}
+
+		page, err := s.items(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range page {
+			if (client == "" || item.Client == client) && int64(len(items)) < limit {
+				items = append(items, item)
+			}
+		}
+
+		if int64(len(ids)) < limit {
+			break
+		}
+	}
+
+	return items, nil
+}
+
+// due takes the items due at the given time, leasing them until they are finished or released.
+func (s Store) due(ctx context.Context, now time.Time) ([]Item, error) {
+	ids, err := s.db.ZRangeByScore(ctx, s.options.Key, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(now.UnixMilli(), 10),
+		Count: s.options.BatchSize,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error getting due items due to: %w", err)
+	}
+
+	expiry := float64(now.Add(s.options.LeaseTimeout).UnixMilli())
+
+	var taken []string
+	for _, id := range ids {
+		leased, err := s.db.ZAddNX(ctx, s.leasesKey(), redis.Z{Score: expiry, Member: id}).Result()
+		if err != nil {
+			return nil, fmt.Errorf("error leasing due item %s due to: %w", id, err)
+		}
+
+		// another scheduler is handling it
+		if leased == 0 {
+			continue
+		}
+
+		// when it fails, the item is scheduled again once the lease expires
+		removed, err := s.db.ZRem(ctx, s.options.Key, id).Result()
+		if err != nil {
+			return nil, fmt.Errorf("error taking due item %s due to: %w", id, err)
+		}
+
+		// another scheduler handled it, or it was canceled, meanwhile
+		if removed == 0 {
+			s.release(ctx, id)
+
+			continue
+		}
+
+		taken = append(taken, id)
+	}
+
+	items, err := s.items(ctx, taken)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool, len(items))
+	for _, item := range items {
+		found[item.ID] = true
+	}
+
+	// the items that cannot be read will never be handled
+	for _, id := range taken {
+		if !found[id] {
+			s.release(ctx, id)
+		}
+	}
+
+	return items, nil
+}
+
+// recoverLeases schedules again the items whose lease expired, the scheduler taking them crashed before handling them.
+func (s Store) recoverLeases(ctx context.Context, now time.Time) error {
+	ids, err := s.db.ZRangeByScore(ctx, s.leasesKey(), &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(now.UnixMilli(), 10),
+		Count: s.options.BatchSize,
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("error getting expired leases due to: %w", err)
+	}
+
+	for _, id := range ids {
+		slog.WarnContext(ctx, "scheduling again an item whose lease expired", slog.String(logging.KeyNotificationID, id))
+
+		err = s.db.ZAdd(ctx, s.options.Key, redis.Z{Score: float64(now.UnixMilli()), Member: id}).Err()
+		if err != nil {
+			return fmt.Errorf("error scheduling again item %s due to: %w", id, err)
+		}
+
+		s.release(ctx, id)
+	}
+
+	return nil
+}
+
+// renewScript extends the lease of ARGV[2] until ARGV[1], unless it expired and another scheduler recovered it.
+const renewScript = `
+if redis.call('ZSCORE', KEYS[1], ARGV[2]) then
+	redis.call('ZADD', KEYS[1], ARGV[1], ARGV[2])
+	return 1
+end
+return 0
+`
+
+// renew extends the lease of a taken item for another LeaseTimeout, it returns false when the lease was lost.
+func (s Store) renew(ctx context.Context, id string, now time.Time) (bool, error) {
+	expiry := now.Add(s.options.LeaseTimeout).UnixMilli()
+
+	renewed, err := s.db.Eval(ctx, renewScript, []string{s.leasesKey()}, expiry, id).Int()
+	if err != nil {
+		return false, fmt.Errorf("error renewing lease of item %s due to: %w", id, err)
+	}
+
+	return renewed == 1, nil
+}
+
+// finish forgets the handled item, unless it was scheduled again while it was handled, and releases its lease.
+func (s Store) finish(ctx context.Context, id string) {
+	err := s.db.ZScore(ctx, s.options.Key, id).Err()
+
+	switch {
+	case errors.Is(err, redis.Nil):
+		s.done(ctx, id)
+	case err != nil:
+		// the item is kept, so it is not lost in case it was scheduled again
+		slog.WarnContext(ctx, "error checking handled scheduled item", slog.String(logging.KeyNotificationID, id), logging.Error(err))
+	}
+
+	s.release(ctx, id)
+}
+
+// release gives up the lease of the item, the error is not returned because the lease expires anyway.
+func (s Store) release(ctx context.Context, id string) {
+	if err := s.db.ZRem(ctx, s.leasesKey(), id).Err(); err != nil {
+		slog.WarnContext(ctx, "error releasing scheduled item", slog.String(logging.KeyNotificationID, id), logging.Error(err))
+	}
+}
+
+// done forgets the item, the error is not returned because the item is not scheduled anymore.
+func (s Store) done(ctx context.Context, id string) {
+	if err := s.db.HDel(ctx, s.itemsKey(), id).Err(); err != nil {
+		slog.WarnContext(ctx, "error deleting scheduled item", slog.String(logging.KeyNotificationID, id), logging.Error(err))
+	}
+}
+
+// items gets the items of the given IDs, the missing and malformed ones are skipped.
+func (s Store) items(ctx context.Context, ids []string) ([]Item, error) {
+	if len(ids) == 0 {
+		return []Item{}, nil
+	}
+
+	values, err := s.db.HMGet(ctx, s.itemsKey(), ids...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error getting scheduled items due to: %w", err)
+	}
+
+	items := make([]Item, 0, len(values))
+	for i, value := range values {
+		payload, ok := value.(string)
+		if !ok {
+			slog.WarnContext(ctx, "scheduled item not found", slog.String(logging.KeyNotificationID, ids[i]))
+
+			continue
+		}
+
+		var item Item
+		if err = json.Unmarshal([]byte(payload), &item); err != nil {
+			slog.WarnContext(ctx, "discarding malformed scheduled item", slog.String(logging.KeyNotificationID, ids[i]), logging.Error(err))
+
+			continue
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+func (s Store) itemsKey() string {
+	return fmt.Sprintf("%s-items", s.options.Key)
+}
+
+// leasesKey is the sorted set of the items being handled, scored by the expiry of their lease in Unix milliseconds.
+func (s Store) leasesKey() string {
+	return fmt.Sprintf("%s-leases", s.options.Key)
+}
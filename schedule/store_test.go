@@ -0,0 +1,400 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"user_news_api/schedule/mocks"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var testOptions = Options{
+	Key:           "scheduled",
+	PollInterval:  time.Second,
+	BatchSize:     2,
+	RetryDelay:    time.Minute,
+	LeaseTimeout:  5 * time.Minute,
+	HandleTimeout: time.Minute,
+}
+
+var testItem = Item{
+	ID:          "some-id",
+	UserEmail:   "user@example.com",
+	MessageType: "News",
+	SendAt:      time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+}
+
+// testItemPayload is testItem as it is saved
+const testItemPayload = `{"id":"some-id","user_email":"user@example.com","message_type":"News","send_at":"2024-01-01T09:00:00Z"}`
+
+func TestStoreAdd(t *testing.T) {
+	tests := []struct {
+		name          string
+		mockApplier   func(mockRedis *mocks.RedisSortedSet)
+		expectedError error
+	}{
+		{
+			name: "item scheduled",
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("HSet", mock.Anything, "scheduled-items", "some-id", []byte(testItemPayload)).
+					Return(redis.NewIntResult(1, nil)).Once()
+				mockRedis.On("ZAdd", mock.Anything, "scheduled", redis.Z{Score: 1704099600000, Member: "some-id"}).
+					Return(redis.NewIntResult(1, nil)).Once()
+			},
+			expectedError: nil,
+		},
+		{
+			name: "error saving item",
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("HSet", mock.Anything, "scheduled-items", "some-id", []byte(testItemPayload)).
+					Return(redis.NewIntResult(0, errors.New("error"))).Once()
+			},
+			expectedError: fmt.Errorf("error saving scheduled item some-id due to: %w", errors.New("error")),
+		},
+		{
+			name: "error scheduling item",
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("HSet", mock.Anything, "scheduled-items", "some-id", []byte(testItemPayload)).
+					Return(redis.NewIntResult(1, nil)).Once()
+				mockRedis.On("ZAdd", mock.Anything, "scheduled", redis.Z{Score: 1704099600000, Member: "some-id"}).
+					Return(redis.NewIntResult(0, errors.New("error"))).Once()
+			},
+			expectedError: fmt.Errorf("error scheduling item some-id due to: %w", errors.New("error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisSortedSet(t)
+			tt.mockApplier(mockRedis)
+
+			s := Store{db: mockRedis, options: testOptions}
+
+			assert.Equal(t, tt.expectedError, s.Add(context.Background(), testItem))
+		})
+	}
+}
+
+func TestStoreCancel(t *testing.T) {
+	tests := []struct {
+		name          string
+		mockApplier   func(mockRedis *mocks.RedisSortedSet)
+		expectedError error
+	}{
+		{
+			name: "item canceled",
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("ZRem", mock.Anything, "scheduled", "some-id").Return(redis.NewIntResult(1, nil)).Once()
+				mockRedis.On("HDel", mock.Anything, "scheduled-items", "some-id").Return(redis.NewIntResult(1, nil)).Once()
+			},
+			expectedError: nil,
+		},
+		{
+			name: "item already taken",
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("ZRem", mock.Anything, "scheduled", "some-id").Return(redis.NewIntResult(0, nil)).Once()
+			},
+			expectedError: fmt.Errorf("%w: %s", ErrNotFound, "some-id"),
+		},
+		{
+			name: "redis error",
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("ZRem", mock.Anything, "scheduled", "some-id").Return(redis.NewIntResult(0, errors.New("error"))).Once()
+			},
+			expectedError: fmt.Errorf("error canceling scheduled item some-id due to: %w", errors.New("error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisSortedSet(t)
+			tt.mockApplier(mockRedis)
+
+			s := Store{db: mockRedis, options: testOptions}
+
+			assert.Equal(t, tt.expectedError, s.Cancel(context.Background(), "some-id"))
+		})
+	}
+}
+
+func TestStoreList(t *testing.T) {
+	tests := []struct {
+		name          string
+		client        string
+		limit         int64
+		mockApplier   func(mockRedis *mocks.RedisSortedSet)
+		expected      []Item
+		expectedError error
+	}{
+		{
+			name:  "missing and malformed items are skipped",
+			limit: 10,
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("ZRangeByScore", mock.Anything, "scheduled", &redis.ZRangeBy{Min: "-inf", Max: "+inf", Count: 10}).
+					Return(redis.NewStringSliceResult([]string{"some-id", "missing-id", "malformed-id"}, nil)).Once()
+				mockRedis.On("HMGet", mock.Anything, "scheduled-items", "some-id", "missing-id", "malformed-id").
+					Return(redis.NewSliceResult([]interface{}{testItemPayload, nil, "{"}, nil)).Once()
+			},
+			expected:      []Item{testItem},
+			expectedError: nil,
+		},
+		{
+			name:  "no items",
+			limit: 10,
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("ZRangeByScore", mock.Anything, "scheduled", &redis.ZRangeBy{Min: "-inf", Max: "+inf", Count: 10}).
+					Return(redis.NewStringSliceResult([]string{}, nil)).Once()
+			},
+			expected:      []Item{},
+			expectedError: nil,
+		},
+		{
+			name:  "redis error",
+			limit: 10,
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("ZRangeByScore", mock.Anything, "scheduled", &redis.ZRangeBy{Min: "-inf", Max: "+inf", Count: 10}).
+					Return(redis.NewStringSliceResult(nil, errors.New("error"))).Once()
+			},
+			expected:      nil,
+			expectedError: fmt.Errorf("error listing scheduled items due to: %w", errors.New("error")),
+		},
+		{
+			name:   "items of the client are read page after page",
+			client: "api-key:abc",
+			limit:  2,
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("ZRangeByScore", mock.Anything, "scheduled", &redis.ZRangeBy{Min: "-inf", Max: "+inf", Count: 2}).
+					Return(redis.NewStringSliceResult([]string{"id-1", "id-2"}, nil)).Once()
+				mockRedis.On("HMGet", mock.Anything, "scheduled-items", "id-1", "id-2").
+					Return(redis.NewSliceResult([]interface{}{`{"id":"id-1","user_email":"user@example.com","message_type":"News","client":"api-key:abc","send_at":"2024-01-01T09:00:00Z"}`, `{"id":"id-2","user_email":"user@example.com","message_type":"News","client":"api-key:def","send_at":"2024-01-01T09:00:00Z"}`}, nil)).Once()
+				mockRedis.On("ZRangeByScore", mock.Anything, "scheduled", &redis.ZRangeBy{Min: "-inf", Max: "+inf", Offset: 2, Count: 2}).
+					Return(redis.NewStringSliceResult([]string{"id-3"}, nil)).Once()
+				mockRedis.On("HMGet", mock.Anything, "scheduled-items", "id-3").
+					Return(redis.NewSliceResult([]interface{}{`{"id":"id-3","user_email":"user@example.com","message_type":"News","client":"api-key:abc","send_at":"2024-01-01T09:00:00Z"}`}, nil)).Once()
+			},
+			expected:      []Item{ownedItem("id-1", "api-key:abc"), ownedItem("id-3", "api-key:abc")},
+			expectedError: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisSortedSet(t)
+			tt.mockApplier(mockRedis)
+
+			s := Store{db: mockRedis, options: testOptions}
+
+			items, err := s.List(context.Background(), tt.client, tt.limit)
+
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expected, items)
+		})
+	}
+}
+
+func ownedItem(id string, client string) Item {
+	item := testItem
+	item.ID, item.Client = id, client
+
+	return item
+}
+
+func TestStoreGet(t *testing.T) {
+	mockRedis := mocks.NewRedisSortedSet(t)
+	mockRedis.On("HMGet", mock.Anything, "scheduled-items", "some-id").
+		Return(redis.NewSliceResult([]interface{}{testItemPayload}, nil)).Once()
+	mockRedis.On("HMGet", mock.Anything, "scheduled-items", "missing-id").
+		Return(redis.NewSliceResult([]interface{}{nil}, nil)).Once()
+
+	s := Store{db: mockRedis, options: testOptions}
+
+	item, err := s.Get(context.Background(), "some-id")
+	assert.NoError(t, err)
+	assert.Equal(t, testItem, item)
+
+	_, err = s.Get(context.Background(), "missing-id")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestStoreDue(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	dueRange := &redis.ZRangeBy{Min: "-inf", Max: "1704103200000", Count: 2}
+	lease := func(id string) redis.Z { return redis.Z{Score: 1704103500000, Member: id} }
+
+	tests := []struct {
+		name          string
+		mockApplier   func(mockRedis *mocks.RedisSortedSet)
+		expected      []Item
+		expectedError error
+	}{
+		{
+			name: "items taken by other schedulers are skipped",
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("ZRangeByScore", mock.Anything, "scheduled", dueRange).
+					Return(redis.NewStringSliceResult([]string{"some-id", "other-id"}, nil)).Once()
+				mockRedis.On("ZAddNX", mock.Anything, "scheduled-leases", lease("some-id")).Return(redis.NewIntResult(1, nil)).Once()
+				mockRedis.On("ZAddNX", mock.Anything, "scheduled-leases", lease("other-id")).Return(redis.NewIntResult(0, nil)).Once()
+				mockRedis.On("ZRem", mock.Anything, "scheduled", "some-id").Return(redis.NewIntResult(1, nil)).Once()
+				mockRedis.On("HMGet", mock.Anything, "scheduled-items", "some-id").
+					Return(redis.NewSliceResult([]interface{}{testItemPayload}, nil)).Once()
+			},
+			expected:      []Item{testItem},
+			expectedError: nil,
+		},
+		{
+			name: "items handled or canceled meanwhile are released",
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("ZRangeByScore", mock.Anything, "scheduled", dueRange).
+					Return(redis.NewStringSliceResult([]string{"other-id"}, nil)).Once()
+				mockRedis.On("ZAddNX", mock.Anything, "scheduled-leases", lease("other-id")).Return(redis.NewIntResult(1, nil)).Once()
+				mockRedis.On("ZRem", mock.Anything, "scheduled", "other-id").Return(redis.NewIntResult(0, nil)).Once()
+				mockRedis.On("ZRem", mock.Anything, "scheduled-leases", "other-id").Return(redis.NewIntResult(1, nil)).Once()
+			},
+			expected:      []Item{},
+			expectedError: nil,
+		},
+		{
+			name: "items not found are released",
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("ZRangeByScore", mock.Anything, "scheduled", dueRange).
+					Return(redis.NewStringSliceResult([]string{"some-id", "missing-id"}, nil)).Once()
+				mockRedis.On("ZAddNX", mock.Anything, "scheduled-leases", lease("some-id")).Return(redis.NewIntResult(1, nil)).Once()
+				mockRedis.On("ZAddNX", mock.Anything, "scheduled-leases", lease("missing-id")).Return(redis.NewIntResult(1, nil)).Once()
+				mockRedis.On("ZRem", mock.Anything, "scheduled", "some-id").Return(redis.NewIntResult(1, nil)).Once()
+				mockRedis.On("ZRem", mock.Anything, "scheduled", "missing-id").Return(redis.NewIntResult(1, nil)).Once()
+				mockRedis.On("HMGet", mock.Anything, "scheduled-items", "some-id", "missing-id").
+					Return(redis.NewSliceResult([]interface{}{testItemPayload, nil}, nil)).Once()
+				mockRedis.On("ZRem", mock.Anything, "scheduled-leases", "missing-id").Return(redis.NewIntResult(1, nil)).Once()
+			},
+			expected:      []Item{testItem},
+			expectedError: nil,
+		},
+		{
+			name: "error leasing item",
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("ZRangeByScore", mock.Anything, "scheduled", dueRange).
+					Return(redis.NewStringSliceResult([]string{"some-id"}, nil)).Once()
+				mockRedis.On("ZAddNX", mock.Anything, "scheduled-leases", lease("some-id")).
+					Return(redis.NewIntResult(0, errors.New("error"))).Once()
+			},
+			expected:      nil,
+			expectedError: fmt.Errorf("error leasing due item some-id due to: %w", errors.New("error")),
+		},
+		{
+			name: "error taking item",
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("ZRangeByScore", mock.Anything, "scheduled", dueRange).
+					Return(redis.NewStringSliceResult([]string{"some-id"}, nil)).Once()
+				mockRedis.On("ZAddNX", mock.Anything, "scheduled-leases", lease("some-id")).Return(redis.NewIntResult(1, nil)).Once()
+				mockRedis.On("ZRem", mock.Anything, "scheduled", "some-id").Return(redis.NewIntResult(0, errors.New("error"))).Once()
+			},
+			expected:      nil,
+			expectedError: fmt.Errorf("error taking due item some-id due to: %w", errors.New("error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisSortedSet(t)
+			tt.mockApplier(mockRedis)
+
+			s := Store{db: mockRedis, options: testOptions}
+
+			items, err := s.due(context.Background(), now)
+
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expected, items)
+		})
+	}
+}
+
+func TestStoreRecoverLeases(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	expiredRange := &redis.ZRangeBy{Min: "-inf", Max: "1704103200000", Count: 2}
+
+	tests := []struct {
+		name          string
+		mockApplier   func(mockRedis *mocks.RedisSortedSet)
+		expectedError error
+	}{
+		{
+			name: "items with an expired lease are scheduled again",
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("ZRangeByScore", mock.Anything, "scheduled-leases", expiredRange).
+					Return(redis.NewStringSliceResult([]string{"some-id"}, nil)).Once()
+				mockRedis.On("ZAdd", mock.Anything, "scheduled", redis.Z{Score: 1704103200000, Member: "some-id"}).
+					Return(redis.NewIntResult(1, nil)).Once()
+				mockRedis.On("ZRem", mock.Anything, "scheduled-leases", "some-id").Return(redis.NewIntResult(1, nil)).Once()
+			},
+			expectedError: nil,
+		},
+		{
+			name: "error scheduling again",
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("ZRangeByScore", mock.Anything, "scheduled-leases", expiredRange).
+					Return(redis.NewStringSliceResult([]string{"some-id"}, nil)).Once()
+				mockRedis.On("ZAdd", mock.Anything, "scheduled", redis.Z{Score: 1704103200000, Member: "some-id"}).
+					Return(redis.NewIntResult(0, errors.New("error"))).Once()
+			},
+			expectedError: fmt.Errorf("error scheduling again item some-id due to: %w", errors.New("error")),
+		},
+		{
+			name: "error getting expired leases",
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("ZRangeByScore", mock.Anything, "scheduled-leases", expiredRange).
+					Return(redis.NewStringSliceResult(nil, errors.New("error"))).Once()
+			},
+			expectedError: fmt.Errorf("error getting expired leases due to: %w", errors.New("error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisSortedSet(t)
+			tt.mockApplier(mockRedis)
+
+			s := Store{db: mockRedis, options: testOptions}
+
+			assert.Equal(t, tt.expectedError, s.recoverLeases(context.Background(), now))
+		})
+	}
+}
+
+func TestStoreFinish(t *testing.T) {
+	tests := []struct {
+		name        string
+		mockApplier func(mockRedis *mocks.RedisSortedSet)
+	}{
+		{
+			name: "handled items are forgotten",
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("ZScore", mock.Anything, "scheduled", "some-id").Return(redis.NewFloatResult(0, redis.Nil)).Once()
+				mockRedis.On("HDel", mock.Anything, "scheduled-items", "some-id").Return(redis.NewIntResult(1, nil)).Once()
+				mockRedis.On("ZRem", mock.Anything, "scheduled-leases", "some-id").Return(redis.NewIntResult(1, nil)).Once()
+			},
+		},
+		{
+			name: "items scheduled again while handled are kept",
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("ZScore", mock.Anything, "scheduled", "some-id").Return(redis.NewFloatResult(1704103260000, nil)).Once()
+				mockRedis.On("ZRem", mock.Anything, "scheduled-leases", "some-id").Return(redis.NewIntResult(1, nil)).Once()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisSortedSet(t)
+			tt.mockApplier(mockRedis)
+
+			s := Store{db: mockRedis, options: testOptions}
+
+			s.finish(context.Background(), "some-id")
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"user_news_api/channel"
+	"user_news_api/ratelimiter"
+)
+
+// Channels is an abstraction for channel.Registry making it mockeable
+type Channels interface {
+	Valid(string) bool
+	Get(string) (channel.Channel, error)
+}
+
+// WithChannels enables delivering notifications through the channels of the registry besides email.
+// Rate limits are counted apart for every channel, so a webhook does not use up the mails of the user.
+func (serv UserNotifierService) WithChannels(channels Channels) UserNotifierService {
+	serv.channels = channels
+
+	return serv
+}
+
+// checkChannel returns channel.ErrChannelNotValid when the notification cannot be sent through the channel.
+func (serv UserNotifierService) checkChannel(userMail string, channelName string) error {
+	if isEmail(channelName) {
+		return nil
+	}
+
+	if !channel.ByEmail(channelName) {
+		return recipientNotValid(userMail, channelName)
+	}
+
+	if serv.channels != nil && serv.channels.Valid(channelName) {
+		return nil
+	}
+
+	return fmt.Errorf("channel error for user %s: %w: %s", userMail, channel.ErrChannelNotValid, channelName)
+}
+
+// sendChannel sends the notification through a channel other than email.
+func (serv UserNotifierService) sendChannel(
+	ctx context.Context, userMail string, messageType string, channelName string,
+) error {
+	if !channel.ByEmail(channelName) {
+		return recipientNotValid(userMail, channelName)
+	}
+
+	if serv.channels == nil {
+		return fmt.Errorf("channel error for user %s: %w: %s", userMail, channel.ErrChannelNotValid, channelName)
+	}
+
+	ch, err := serv.channels.Get(channelName)
+	if err != nil {
+		return fmt.Errorf("channel error for user %s: %w", userMail, err)
+	}
+
+	err = ch.Send(ctx, channel.Message{
+		To:          userMail,
+		MessageType: messageType,
+		Subject:     "Notification",
+		Text:        fmt.Sprintf("%s notification", messageType),
+	})
+	if err != nil {
+		return fmt.Errorf("%s channel error for user %s: %w", channelName, userMail, err)
+	}
+
+	return nil
+}
+
+// recipientNotValid rejects the channels which cannot reach the user by the email address, see channel.ByEmail.
+func recipientNotValid(userMail string, channelName string) error {
+	return fmt.Errorf("channel error for user %s: %w: %s needs the phone number of the user",
+		userMail, channel.ErrChannelNotValid, channelName)
+}
+
+// limitKey is the user the rate limit is counted for. Mails keep the address alone,
+// so their counters are the same as before channels were introduced.
+func limitKey(userMail string, channelName string) string {
+	if isEmail(channelName) {
+		return userMail
+	}
+
+	return channelName + ":" + userMail
+}
+
+// channelPolicy returns the policy applied to a notification over the rate limit of a channel.
+// Digests are mails, so the other channels reject the notifications instead of buffering them.
+func (serv UserNotifierService) channelPolicy(messageType string, channelName string) ratelimiter.OnLimitPolicy {
+	policy := serv.onLimit(messageType)
+	if policy == ratelimiter.OnLimitDigest && !isEmail(channelName) {
+		return ratelimiter.OnLimitReject
+	}
+
+	return policy
+}
+
+// isEmail tells if the channel is email, the default one when no channel is set.
+func isEmail(channelName string) bool {
+	return channelName == "" || channelName == channel.Email
+}
@@ -0,0 +1,281 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"user_news_api/channel"
+	"user_news_api/delivery"
+	"user_news_api/queue"
+	"user_news_api/ratelimiter"
+	"user_news_api/schedule"
+	"user_news_api/services/mocks"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserNotifier_EnqueueBatchChannels(t *testing.T) {
+	ctx := context.Background()
+	userMail := "user@example.com"
+	items := []BatchItem{
+		{UserEmail: userMail, MessageType: ratelimiter.NewsType},
+		{UserEmail: userMail, MessageType: ratelimiter.NewsType, Channel: channel.Webhook},
+		{UserEmail: userMail, MessageType: ratelimiter.NewsType, Channel: "fax"},
+	}
+	hits := []ratelimiter.Hit{
+		{User: userMail, MessageType: ratelimiter.NewsType},
+		{User: "webhook:" + userMail, MessageType: ratelimiter.NewsType},
+	}
+	resetAt := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	limitErr := fmt.Errorf(
+		"%w: rate limit reached for user %s and message type %s", ErrLimitExceeded, userMail, ratelimiter.NewsType)
+
+	mockLimiter := mocks.NewLimiter(t)
+	mockQueue := mocks.NewQueue(t)
+	mockRecords := mocks.NewRecords(t)
+	mockSchedules := mocks.NewSchedules(t)
+	mockChannels := mocks.NewChannels(t)
+
+	mockChannels.On("Valid", channel.Webhook).Return(true).Once()
+	mockChannels.On("Valid", "fax").Return(false).Once()
+	mockLimiter.On("ReachedBatch", ctx, hits).Return([]ratelimiter.HitResult{
+		{Reached: false},
+		{Reached: true},
+	}, nil).Once()
+	mockLimiter.On("OnLimit", ratelimiter.NewsType).Return(ratelimiter.OnLimitDefer).Once()
+	mockLimiter.On("ResetAt", ctx, "webhook:"+userMail, ratelimiter.NewsType).Return(resetAt, nil).Once()
+	mockRecords.On("Create", ctx, delivery.Record{
+		ID:          notificationID,
+		UserEmail:   userMail,
+		MessageType: ratelimiter.NewsType,
+		State:       delivery.StateQueued,
+	}).Return(nil).Once()
+	mockRecords.On("Create", ctx, delivery.Record{
+		ID:          notificationID,
+		UserEmail:   userMail,
+		MessageType: ratelimiter.NewsType,
+		Channel:     channel.Webhook,
+		State:       delivery.StateScheduled,
+		Reason:      limitErr.Error(),
+		SendAt:      &resetAt,
+	}).Return(nil).Once()
+	mockSchedules.On("Add", ctx, schedule.Item{
+		ID:          notificationID,
+		UserEmail:   userMail,
+		MessageType: ratelimiter.NewsType,
+		Channel:     channel.Webhook,
+		SendAt:      resetAt,
+	}).Return(nil).Once()
+	mockQueue.On("Enqueue", ctx, queue.Message{
+		ID:          notificationID,
+		UserEmail:   userMail,
+		MessageType: ratelimiter.NewsType,
+	}).Return(notificationID, nil).Once()
+
+	serv := UserNotifierService{
+		limiter:          mockLimiter,
+		queue:            mockQueue,
+		records:          mockRecords,
+		schedules:        mockSchedules,
+		newID:            fixedID,
+		batchConcurrency: 2,
+	}.WithChannels(mockChannels)
+
+	assert.Equal(t, []BatchResult{
+		{ID: notificationID},
+		{ID: notificationID, Err: DeferredError{SendAt: resetAt}},
+		{Err: fmt.Errorf("channel error for user %s: %w: %s", userMail, channel.ErrChannelNotValid, "fax")},
+	}, serv.EnqueueBatch(ctx, items))
+}
+
+func TestUserNotifier_NotifyBatchChannelsDisabled(t *testing.T) {
+	ctx := context.Background()
+	userMail := "user@example.com"
+
+	serv := UserNotifierService{limiter: mocks.NewLimiter(t)}
+
+	results := serv.NotifyBatch(ctx, []BatchItem{
+		{UserEmail: userMail, MessageType: ratelimiter.NewsType, Channel: channel.Slack},
+	})
+
+	assert.Equal(t, []BatchResult{
+		{Err: fmt.Errorf("channel error for user %s: %w: %s", userMail, channel.ErrChannelNotValid, channel.Slack)},
+	}, results)
+}
+
+// fakeChannel keeps the messages instead of sending them, failing with err when it is set.
+type fakeChannel struct {
+	name string
+	err  error
+	sent []channel.Message
+}
+
+func (f *fakeChannel) Name() string {
+	return f.name
+}
+
+func (f *fakeChannel) Send(_ context.Context, msg channel.Message) error {
+	if f.err != nil {
+		return f.err
+	}
+
+	f.sent = append(f.sent, msg)
+
+	return nil
+}
+
+func TestUserNotifier_DeliverChannel(t *testing.T) {
+	ctx := context.Background()
+	userMail := "user@example.com"
+	messageType := ratelimiter.StatusType
+	rejected := fmt.Errorf("%w: webhook answered 404", channel.ErrRejected)
+
+	tests := []struct {
+		name          string
+		channelErr    error
+		applyMocks    func(*mocks.Records)
+		expectedError error
+	}{
+		{
+			name:       "Success",
+			channelErr: nil,
+			applyMocks: func(mr *mocks.Records) {
+				mr.On("MarkSending", ctx, notificationID).Return(nil).Once()
+				mr.On("MarkSent", ctx, notificationID, "").Return(nil).Once()
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "Permanent errors do not suppress the address",
+			channelErr: rejected,
+			applyMocks: func(mr *mocks.Records) {
+				mr.On("MarkSending", ctx, notificationID).Return(nil).Once()
+				mr.On("MarkFailed", ctx, notificationID,
+					fmt.Sprintf("webhook channel error for user %s: %s", userMail, rejected), "").
+					Return(nil).Once()
+			},
+			expectedError: fmt.Errorf("webhook channel error for user %s: %w", userMail, rejected),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the notifier and the suppression list are not expected to be called, so the mocks fail when they are
+			mockNotifier := mocks.NewNotifier(t)
+			mockRecords := mocks.NewRecords(t)
+			mockChannels := mocks.NewChannels(t)
+			webhook := &fakeChannel{name: channel.Webhook, err: tt.channelErr}
+
+			mockChannels.On("Get", channel.Webhook).Return(webhook, nil).Once()
+			tt.applyMocks(mockRecords)
+
+			serv := UserNotifierService{
+				notifier: mockNotifier,
+				records:  mockRecords,
+			}.WithChannels(mockChannels).WithSuppressions(mocks.NewSuppressions(t))
+
+			err := serv.Deliver(ctx, queue.Message{
+				ID:          notificationID,
+				UserEmail:   userMail,
+				MessageType: messageType,
+				Channel:     channel.Webhook,
+			})
+
+			assert.Equal(t, tt.expectedError, err)
+			if tt.channelErr == nil {
+				assert.Equal(t, []channel.Message{{
+					To:          userMail,
+					MessageType: messageType,
+					Subject:     "Notification",
+					Text:        "Status notification",
+				}}, webhook.sent)
+			}
+		})
+	}
+}
+
+func TestUserNotifier_SMSRejected(t *testing.T) {
+	ctx := context.Background()
+	userMail := "user@example.com"
+	rejected := fmt.Errorf("channel error for user %s: %w: sms needs the phone number of the user",
+		userMail, channel.ErrChannelNotValid)
+
+	t.Run("batch", func(t *testing.T) {
+		// the channel is enabled, but the notifications only carry the email address of the user
+		serv := UserNotifierService{limiter: mocks.NewLimiter(t)}.WithChannels(mocks.NewChannels(t))
+
+		results := serv.NotifyBatch(ctx, []BatchItem{
+			{UserEmail: userMail, MessageType: ratelimiter.StatusType, Channel: channel.SMS},
+		})
+
+		assert.Equal(t, []BatchResult{{Err: rejected}}, results)
+	})
+
+	t.Run("queued", func(t *testing.T) {
+		mockRecords := mocks.NewRecords(t)
+
+		mockRecords.On("MarkSending", ctx, notificationID).Return(nil).Once()
+		mockRecords.On("MarkFailed", ctx, notificationID, rejected.Error(), "").Return(nil).Once()
+
+		serv := UserNotifierService{records: mockRecords}.WithChannels(mocks.NewChannels(t))
+
+		err := serv.Deliver(ctx, queue.Message{
+			ID:          notificationID,
+			UserEmail:   userMail,
+			MessageType: ratelimiter.StatusType,
+			Channel:     channel.SMS,
+		})
+
+		assert.Equal(t, rejected, err)
+	})
+}
+
+func TestUserNotifier_ScheduledChannelDisabled(t *testing.T) {
+	ctx := context.Background()
+	item := schedule.Item{
+		ID:          notificationID,
+		UserEmail:   "user@example.com",
+		MessageType: ratelimiter.NewsType,
+		Channel:     channel.Webhook,
+		SendAt:      time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+	}
+
+	mockRecords := mocks.NewRecords(t)
+	mockChannels := mocks.NewChannels(t)
+
+	mockChannels.On("Valid", channel.Webhook).Return(false).Once()
+	mockRecords.On("MarkFailed", ctx, notificationID,
+		fmt.Sprintf("channel error for user %s: channel not valid: webhook", item.UserEmail), "").
+		Return(nil).Once()
+
+	serv := UserNotifierService{
+		limiter: mocks.NewLimiter(t),
+		records: mockRecords,
+	}.WithChannels(mockChannels)
+
+	assert.NoError(t, serv.SendScheduled(ctx, item))
+}
+
+func TestUserNotifier_ScheduledChannelLimit(t *testing.T) {
+	ctx := context.Background()
+	item := schedule.Item{
+		ID:          notificationID,
+		UserEmail:   "user@example.com",
+		MessageType: ratelimiter.NewsType,
+		Channel:     channel.Webhook,
+		SendAt:      time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+	}
+	limiterErr := errors.New("limiter error")
+
+	mockLimiter := mocks.NewLimiter(t)
+	mockChannels := mocks.NewChannels(t)
+
+	mockChannels.On("Valid", channel.Webhook).Return(true).Once()
+	mockLimiter.On("Reached", ctx, "webhook:user@example.com", ratelimiter.NewsType).Return(false, limiterErr).Once()
+
+	serv := UserNotifierService{limiter: mockLimiter}.WithChannels(mockChannels)
+
+	assert.Equal(t, fmt.Errorf("limiter error for user %s: %w", item.UserEmail, limiterErr), serv.SendScheduled(ctx, item))
+}
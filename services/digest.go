@@ -0,0 +1,189 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"time"
+	"user_news_api/digest"
+	"user_news_api/logging"
+	"user_news_api/notifier"
+	"user_news_api/queue"
+	"user_news_api/schedule"
+)
+
+// Digests is an abstraction for digest.Buffer making it mockeable
+type Digests interface {
+	Add(context.Context, string, string, digest.Entry) error
+	Drain(context.Context, string, string) ([]digest.Entry, error)
+	Restore(context.Context, string, string, []digest.Entry) error
+}
+
+// WithDigest enables the digest policy of the limiter. The notifications over the limit of the message types
+// with that policy are buffered instead of rejected, and sent together through flushes when the limit resets.
+func (serv UserNotifierService) WithDigest(digests Digests, flushes Schedules) UserNotifierService {
+	serv.digests = digests
+	serv.flushes = flushes
+
+	return serv
+}
+
+// SendDigest sends the buffered notifications of a due digest in a single mail. It is not rate limited,
+// because its notifications were already counted when they were buffered. When the mail can be sent again,
+// see notifier.Retryable, the entries go back to the buffer and the error is returned, so the flush is retried.
+func (serv UserNotifierService) SendDigest(ctx context.Context, item schedule.Item) error {
+	entries, drainErr := serv.digests.Drain(ctx, item.UserEmail, item.MessageType)
+	if len(entries) == 0 {
+		return drainErr
+	}
+
+	for _, entry := range entries {
+		if err := serv.records.MarkSending(ctx, entry.ID); err != nil {
+			slog.WarnContext(ctx, "error recording notification as sending", slog.String(logging.KeyNotificationID, entry.ID), logging.Error(err))
+		}
+	}
+
+	provider, err := serv.sendDigest(ctx, item.UserEmail, item.MessageType, entries)
+	serv.suppressBounce(ctx, item.UserEmail, err)
+
+	if err != nil && notifier.Retryable(err) {
+		if restoreErr := serv.restoreDigest(ctx, item, entries, err); restoreErr == nil {
+			return err
+		}
+	}
+
+	for _, entry := range entries {
+		serv.observeDelivery(item.MessageType, "", err)
+
+		if err != nil {
+			serv.markFailed(ctx, entry.ID, err)
+
+			continue
+		}
+
+		if err := serv.records.MarkSent(ctx, entry.ID, provider); err != nil {
+			slog.WarnContext(ctx, "error recording notification as sent", slog.String(logging.KeyNotificationID, entry.ID), logging.Error(err))
+		}
+	}
+
+	// the failed entries are recorded and will not be sent again, while the ones left in the buffer by a drain error
+	// are sent when the flush is retried
+	return drainErr
+}
+
+// restoreDigest puts the entries of a digest that could not be sent back in the buffer, recording them as buffered.
+// When they cannot be restored they are lost, so the error is returned for them to be recorded as failed.
+func (serv UserNotifierService) restoreDigest(
+	ctx context.Context, item schedule.Item, entries []digest.Entry, sendErr error,
+) error {
+	if err := serv.digests.Restore(ctx, item.UserEmail, item.MessageType, entries); err != nil {
+		slog.ErrorContext(ctx, "error restoring digest entries", slog.String(logging.KeyMessageType, item.MessageType),
+			slog.String(logging.KeyUser, logging.MaskEmail(item.UserEmail)), logging.Error(err))
+
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := serv.records.MarkBuffered(ctx, entry.ID, sendErr.Error()); err != nil {
+			slog.WarnContext(ctx, "error recording notification as buffered", slog.String(logging.KeyNotificationID, entry.ID), logging.Error(err))
+		}
+	}
+
+	return nil
+}
+
+// buffer adds the notification to the next digest of the user, which is sent when the rate limit resets.
+func (serv UserNotifierService) buffer(ctx context.Context, msg queue.Message) error {
+	resetAt, err := serv.resetAt(ctx, msg.UserEmail, msg.MessageType, "")
+	if err != nil {
+		return err
+	}
+
+	err = serv.digests.Add(ctx, msg.UserEmail, msg.MessageType, digest.Entry{
+		ID:        msg.ID,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("digest error for user %s: %w", msg.UserEmail, err)
+	}
+
+	// every buffered notification schedules the same digest again, so it is not lost when scheduling fails once
+	err = serv.flushes.Add(ctx, schedule.Item{
+		ID:          digest.FlushID(msg.UserEmail, msg.MessageType),
+		UserEmail:   msg.UserEmail,
+		MessageType: msg.MessageType,
+		SendAt:      resetAt,
+	})
+	if err != nil {
+		return fmt.Errorf("digest error for user %s: %w", msg.UserEmail, err)
+	}
+
+	return nil
+}
+
+func (serv UserNotifierService) sendDigest(
+	ctx context.Context, userMail string, messageType string, entries []digest.Entry,
+) (string, error) {
+	body, err := toDigestHTML(messageType, entries)
+	if err != nil {
+		return "", err
+	}
+
+	provider, err := serv.notifier.Send(ctx, notifier.NotifyToOptions{
+		To:             userMail,
+		Subject:        fmt.Sprintf("Your %s digest", messageType),
+		Body:           body,
+		UnsubscribeURL: serv.unsubscribeURL(userMail, messageType),
+	})
+	if err != nil {
+		return "", fmt.Errorf("notifier error for user %s: %w", userMail, err)
+	}
+
+	return provider, nil
+}
+
+var digestTemplate = template.Must(template.New("digest").Parse(`
+<!DOCTYPE html>
+<html>
+<head>
+    <style>
+        .centered {
+            text-align: center;
+            color: {{.Color}};
+            font-size: 48px;
+        }
+    </style>
+</head>
+<body>
+    <div class="centered">{{.MessageType}} digest</div>
+    <p>{{len .Entries}} notifications arrived while you were over your limit:</p>
+    <ul>
+    {{- range .Entries}}
+        <li>{{$.MessageType}} at {{.CreatedAt.Format "2006-01-02 15:04 MST"}}</li>
+    {{- end}}
+    </ul>
+</body>
+</html>
+`))
+
+// toDigestHTML renders the notifications of a digest, the oldest first.
+func toDigestHTML(messageType string, entries []digest.Entry) (string, error) {
+	var body bytes.Buffer
+
+	err := digestTemplate.Execute(&body, struct {
+		MessageType string
+		Color       template.CSS
+		Entries     []digest.Entry
+	}{
+		MessageType: messageType,
+		Color:       template.CSS(messageColor(messageType)),
+		Entries:     entries,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error rendering digest due to: %w", err)
+	}
+
+	return body.String(), nil
+}
@@ -0,0 +1,241 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"user_news_api/delivery"
+	"user_news_api/digest"
+	"user_news_api/notifier"
+	"user_news_api/ratelimiter"
+	"user_news_api/schedule"
+	"user_news_api/services/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserNotifier_NotifyDigest(t *testing.T) {
+	ctx := context.Background()
+	// Notify passes its span within the context
+	spanCtx := mock.AnythingOfType("*context.valueCtx")
+	userMail := "user@example.com"
+	messageType := ratelimiter.NewsType
+	resetAt := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	limitErr := fmt.Errorf("%w: rate limit reached for user %s and message type %s", ErrLimitExceeded, userMail, messageType)
+	record := delivery.Record{
+		ID:          notificationID,
+		UserEmail:   userMail,
+		MessageType: messageType,
+		State:       delivery.StateBuffered,
+		Reason:      limitErr.Error(),
+	}
+	flush := schedule.Item{
+		ID:          digest.FlushID(userMail, messageType),
+		UserEmail:   userMail,
+		MessageType: messageType,
+		SendAt:      resetAt,
+	}
+	entry := mock.MatchedBy(func(e digest.Entry) bool { return e.ID == notificationID })
+
+	tests := []struct {
+		name          string
+		applyMocks    func(*mocks.Limiter, *mocks.Records, *mocks.Digests, *mocks.Schedules)
+		expectedError error
+	}{
+		{
+			name: "Buffered for the next digest",
+			applyMocks: func(ml *mocks.Limiter, mr *mocks.Records, md *mocks.Digests, mf *mocks.Schedules) {
+				ml.On("Reached", spanCtx, userMail, messageType).Return(true, nil).Once()
+				ml.On("OnLimit", messageType).Return(ratelimiter.OnLimitDigest).Once()
+				mr.On("Create", spanCtx, record).Return(nil).Once()
+				ml.On("ResetAt", spanCtx, userMail, messageType).Return(resetAt, nil).Once()
+				md.On("Add", spanCtx, userMail, messageType, entry).Return(nil).Once()
+				mf.On("Add", spanCtx, flush).Return(nil).Once()
+			},
+			expectedError: fmt.Errorf("%w for user %s and message type %s", ErrDigested, userMail, messageType),
+		},
+		{
+			name: "Message type without digest",
+			applyMocks: func(ml *mocks.Limiter, mr *mocks.Records, md *mocks.Digests, mf *mocks.Schedules) {
+				ml.On("Reached", spanCtx, userMail, messageType).Return(true, nil).Once()
+				ml.On("OnLimit", messageType).Return(ratelimiter.OnLimitReject).Once()
+				mr.On("Create", spanCtx, delivery.Record{
+					ID:          notificationID,
+					UserEmail:   userMail,
+					MessageType: messageType,
+					State:       delivery.StateRateLimited,
+					Reason:      limitErr.Error(),
+				}).Return(nil).Once()
+			},
+			expectedError: limitErr,
+		},
+		{
+			name: "Digest Error",
+			applyMocks: func(ml *mocks.Limiter, mr *mocks.Records, md *mocks.Digests, mf *mocks.Schedules) {
+				ml.On("Reached", spanCtx, userMail, messageType).Return(true, nil).Once()
+				ml.On("OnLimit", messageType).Return(ratelimiter.OnLimitDigest).Once()
+				mr.On("Create", spanCtx, record).Return(nil).Once()
+				ml.On("ResetAt", spanCtx, userMail, messageType).Return(resetAt, nil).Once()
+				md.On("Add", spanCtx, userMail, messageType, entry).Return(errors.New("digest error")).Once()
+				mr.On("MarkFailed", spanCtx, notificationID,
+					fmt.Sprintf("digest error for user %s: digest error", userMail), "").Return(nil).Once()
+			},
+			expectedError: fmt.Errorf("digest error for user %s: %w", userMail, errors.New("digest error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLimiter := mocks.NewLimiter(t)
+			mockRecords := mocks.NewRecords(t)
+			mockDigests := mocks.NewDigests(t)
+			mockFlushes := mocks.NewSchedules(t)
+
+			tt.applyMocks(mockLimiter, mockRecords, mockDigests, mockFlushes)
+
+			serv := UserNotifierService{
+				limiter: mockLimiter,
+				records: mockRecords,
+				newID:   fixedID,
+			}.WithDigest(mockDigests, mockFlushes)
+
+			id, err := serv.Notify(ctx, userMail, messageType)
+
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, notificationID, id)
+		})
+	}
+}
+
+func TestUserNotifier_SendDigest(t *testing.T) {
+	ctx := context.Background()
+	userMail := "user@example.com"
+	messageType := ratelimiter.NewsType
+	item := schedule.Item{
+		ID:          digest.FlushID(userMail, messageType),
+		UserEmail:   userMail,
+		MessageType: messageType,
+	}
+	entries := []digest.Entry{
+		{ID: "first-id", CreatedAt: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)},
+		{ID: "second-id", CreatedAt: time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)},
+	}
+	body, err := toDigestHTML(messageType, entries)
+	require.NoError(t, err)
+
+	options := notifier.NotifyToOptions{
+		To:      userMail,
+		Subject: "Your News digest",
+		Body:    body,
+	}
+
+	tests := []struct {
+		name          string
+		applyMocks    func(*mocks.Notifier, *mocks.Records, *mocks.Digests)
+		expectedError error
+	}{
+		{
+			name: "Success",
+			applyMocks: func(mn *mocks.Notifier, mr *mocks.Records, md *mocks.Digests) {
+				md.On("Drain", ctx, userMail, messageType).Return(entries, nil).Once()
+				mr.On("MarkSending", ctx, "first-id").Return(nil).Once()
+				mr.On("MarkSending", ctx, "second-id").Return(nil).Once()
+				mn.On("Send", ctx, options).Return("primary", nil).Once()
+				mr.On("MarkSent", ctx, "first-id", "primary").Return(nil).Once()
+				mr.On("MarkSent", ctx, "second-id", "primary").Return(nil).Once()
+			},
+			expectedError: nil,
+		},
+		{
+			name: "Nothing buffered",
+			applyMocks: func(mn *mocks.Notifier, mr *mocks.Records, md *mocks.Digests) {
+				md.On("Drain", ctx, userMail, messageType).Return(nil, nil).Once()
+			},
+			expectedError: nil,
+		},
+		{
+			name: "Notifier Error restores the entries",
+			applyMocks: func(mn *mocks.Notifier, mr *mocks.Records, md *mocks.Digests) {
+				md.On("Drain", ctx, userMail, messageType).Return(entries, nil).Once()
+				mr.On("MarkSending", ctx, "first-id").Return(nil).Once()
+				mr.On("MarkSending", ctx, "second-id").Return(nil).Once()
+				mn.On("Send", ctx, options).Return("", errors.New("notifier error")).Once()
+				md.On("Restore", ctx, userMail, messageType, entries).Return(nil).Once()
+				reason := fmt.Sprintf("notifier error for user %s: notifier error", userMail)
+				mr.On("MarkBuffered", ctx, "first-id", reason).Return(nil).Once()
+				mr.On("MarkBuffered", ctx, "second-id", reason).Return(nil).Once()
+			},
+			expectedError: fmt.Errorf("notifier error for user %s: %w", userMail, errors.New("notifier error")),
+		},
+		{
+			name: "Notifier Error is recorded when the entries cannot be restored",
+			applyMocks: func(mn *mocks.Notifier, mr *mocks.Records, md *mocks.Digests) {
+				md.On("Drain", ctx, userMail, messageType).Return(entries, nil).Once()
+				mr.On("MarkSending", ctx, "first-id").Return(nil).Once()
+				mr.On("MarkSending", ctx, "second-id").Return(nil).Once()
+				mn.On("Send", ctx, options).Return("", errors.New("notifier error")).Once()
+				md.On("Restore", ctx, userMail, messageType, entries).Return(errors.New("restore error")).Once()
+				reason := fmt.Sprintf("notifier error for user %s: notifier error", userMail)
+				mr.On("MarkFailed", ctx, "first-id", reason, "").Return(nil).Once()
+				mr.On("MarkFailed", ctx, "second-id", reason, "").Return(nil).Once()
+			},
+			expectedError: nil,
+		},
+		{
+			name: "Timeout after handing the mail over is recorded",
+			applyMocks: func(mn *mocks.Notifier, mr *mocks.Records, md *mocks.Digests) {
+				md.On("Drain", ctx, userMail, messageType).Return(entries, nil).Once()
+				mr.On("MarkSending", ctx, "first-id").Return(nil).Once()
+				mr.On("MarkSending", ctx, "second-id").Return(nil).Once()
+				mn.On("Send", ctx, options).Return("", fmt.Errorf("%w: sending mail timed out", notifier.ErrTimeout)).Once()
+				reason := fmt.Sprintf("notifier error for user %s: notifier timeout: sending mail timed out", userMail)
+				mr.On("MarkFailed", ctx, "first-id", reason, "").Return(nil).Once()
+				mr.On("MarkFailed", ctx, "second-id", reason, "").Return(nil).Once()
+			},
+			expectedError: nil,
+		},
+		{
+			name: "Drain Error is retried",
+			applyMocks: func(mn *mocks.Notifier, mr *mocks.Records, md *mocks.Digests) {
+				md.On("Drain", ctx, userMail, messageType).Return(nil, errors.New("drain error")).Once()
+			},
+			expectedError: errors.New("drain error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockNotifier := mocks.NewNotifier(t)
+			mockRecords := mocks.NewRecords(t)
+			mockDigests := mocks.NewDigests(t)
+
+			tt.applyMocks(mockNotifier, mockRecords, mockDigests)
+
+			serv := UserNotifierService{
+				notifier: mockNotifier,
+				records:  mockRecords,
+				digests:  mockDigests,
+			}
+
+			assert.Equal(t, tt.expectedError, serv.SendDigest(ctx, item))
+		})
+	}
+}
+
+func TestToDigestHTML(t *testing.T) {
+	body, err := toDigestHTML(ratelimiter.NewsType, []digest.Entry{
+		{ID: "first-id", CreatedAt: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)},
+		{ID: "second-id", CreatedAt: time.Date(2024, 1, 1, 11, 30, 0, 0, time.UTC)},
+	})
+
+	require.NoError(t, err)
+	assert.Contains(t, body, "color: green;")
+	assert.Contains(t, body, `<div class="centered">News digest</div>`)
+	assert.Contains(t, body, "<p>2 notifications arrived while you were over your limit:</p>")
+	assert.Contains(t, body, "<li>News at 2024-01-01 10:00 UTC</li>")
+	assert.Contains(t, body, "<li>News at 2024-01-01 11:30 UTC</li>")
+}
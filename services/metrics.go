@@ -0,0 +1,38 @@
+package services
+
+import "user_news_api/channel"
+
+// Delivery outcomes, see Metrics.
+const (
+	OutcomeSent   = "sent"
+	OutcomeFailed = "failed"
+)
+
+// Metrics is an abstraction for metrics.Registry making it mockeable
+type Metrics interface {
+	ObserveDelivery(messageType string, channel string, outcome string)
+}
+
+// WithMetrics observes the outcome of every delivered notification, digests included.
+func (serv UserNotifierService) WithMetrics(metrics Metrics) UserNotifierService {
+	serv.metrics = metrics
+
+	return serv
+}
+
+func (serv UserNotifierService) observeDelivery(messageType string, channelName string, err error) {
+	if serv.metrics == nil {
+		return
+	}
+
+	if isEmail(channelName) {
+		channelName = channel.Email
+	}
+
+	outcome := OutcomeSent
+	if err != nil {
+		outcome = OutcomeFailed
+	}
+
+	serv.metrics.ObserveDelivery(messageType, channelName, outcome)
+}
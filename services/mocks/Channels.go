@@ -0,0 +1,68 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	channel "user_news_api/channel"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Channels is an autogenerated mock type for the Channels type
+type Channels struct {
+	mock.Mock
+}
+
+// Get provides a mock function with given fields: _a0
+func (_m *Channels) Get(_a0 string) (channel.Channel, error) {
+	ret := _m.Called(_a0)
+
+	var r0 channel.Channel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (channel.Channel, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(string) channel.Channel); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(channel.Channel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Valid provides a mock function with given fields: _a0
+func (_m *Channels) Valid(_a0 string) bool {
+	ret := _m.Called(_a0)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// NewChannels creates a new instance of Channels. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewChannels(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Channels {
+	mock := &Channels{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,83 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	digest "user_news_api/digest"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Digests is an autogenerated mock type for the Digests type
+type Digests struct {
+	mock.Mock
+}
+
+// Add provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *Digests) Add(_a0 context.Context, _a1 string, _a2 string, _a3 digest.Entry) error {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, digest.Entry) error); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Drain provides a mock function with given fields: _a0, _a1, _a2
+func (_m *Digests) Drain(_a0 context.Context, _a1 string, _a2 string) ([]digest.Entry, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 []digest.Entry
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) ([]digest.Entry, error)); ok {
+		return rf(_a0, _a1, _a2)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []digest.Entry); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]digest.Entry)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Restore provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *Digests) Restore(_a0 context.Context, _a1 string, _a2 string, _a3 []digest.Entry) error {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, []digest.Entry) error); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewDigests creates a new instance of Digests. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewDigests(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Digests {
+	mock := &Digests{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -4,8 +4,11 @@ package mocks
 
 import (
 	context "context"
+	ratelimiter "user_news_api/ratelimiter"
 
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // Limiter is an autogenerated mock type for the Limiter type
@@ -13,6 +16,20 @@ type Limiter struct {
 	mock.Mock
 }
 
+// OnLimit provides a mock function with given fields: _a0
+func (_m *Limiter) OnLimit(_a0 string) ratelimiter.OnLimitPolicy {
+	ret := _m.Called(_a0)
+
+	var r0 ratelimiter.OnLimitPolicy
+	if rf, ok := ret.Get(0).(func(string) ratelimiter.OnLimitPolicy); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(ratelimiter.OnLimitPolicy)
+	}
+
+	return r0
+}
+
 // Reached provides a mock function with given fields: _a0, _a1, _a2
 func (_m *Limiter) Reached(_a0 context.Context, _a1 string, _a2 string) (bool, error) {
 	ret := _m.Called(_a0, _a1, _a2)
@@ -37,6 +54,84 @@ func (_m *Limiter) Reached(_a0 context.Context, _a1 string, _a2 string) (bool, e
 	return r0, r1
 }
 
+// ReachedBatch provides a mock function with given fields: _a0, _a1
+func (_m *Limiter) ReachedBatch(_a0 context.Context, _a1 []ratelimiter.Hit) ([]ratelimiter.HitResult, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 []ratelimiter.HitResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []ratelimiter.Hit) ([]ratelimiter.HitResult, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []ratelimiter.Hit) []ratelimiter.HitResult); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]ratelimiter.HitResult)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []ratelimiter.Hit) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ResetAt provides a mock function with given fields: _a0, _a1, _a2
+func (_m *Limiter) ResetAt(_a0 context.Context, _a1 string, _a2 string) (time.Time, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 time.Time
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (time.Time, error)); ok {
+		return rf(_a0, _a1, _a2)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) time.Time); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Get(0).(time.Time)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Transactional provides a mock function with given fields: _a0
+func (_m *Limiter) Transactional(_a0 string) bool {
+	ret := _m.Called(_a0)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// Valid provides a mock function with given fields: _a0
+func (_m *Limiter) Valid(_a0 string) bool {
+	ret := _m.Called(_a0)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // NewLimiter creates a new instance of Limiter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewLimiter(t interface {
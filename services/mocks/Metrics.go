@@ -0,0 +1,29 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import mock "github.com/stretchr/testify/mock"
+
+// Metrics is an autogenerated mock type for the Metrics type
+type Metrics struct {
+	mock.Mock
+}
+
+// ObserveDelivery provides a mock function with given fields: messageType, channel, outcome
+func (_m *Metrics) ObserveDelivery(messageType string, channel string, outcome string) {
+	_m.Called(messageType, channel, outcome)
+}
+
+// NewMetrics creates a new instance of Metrics. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMetrics(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Metrics {
+	mock := &Metrics{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
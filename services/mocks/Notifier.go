@@ -14,18 +14,28 @@ type Notifier struct {
 	mock.Mock
 }
 
-// NotifyTo provides a mock function with given fields: _a0, _a1
-func (_m *Notifier) NotifyTo(_a0 context.Context, _a1 notifier.NotifyToOptions) error {
+// Send provides a mock function with given fields: _a0, _a1
+func (_m *Notifier) Send(_a0 context.Context, _a1 notifier.NotifyToOptions) (string, error) {
 	ret := _m.Called(_a0, _a1)
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, notifier.NotifyToOptions) error); ok {
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, notifier.NotifyToOptions) (string, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, notifier.NotifyToOptions) string); ok {
 		r0 = rf(_a0, _a1)
 	} else {
-		r0 = ret.Error(0)
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, notifier.NotifyToOptions) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
 	}
 
-	return r0
+	return r0, r1
 }
 
 // NewNotifier creates a new instance of Notifier. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
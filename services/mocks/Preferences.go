@@ -0,0 +1,52 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Preferences is an autogenerated mock type for the Preferences type
+type Preferences struct {
+	mock.Mock
+}
+
+// OptedOut provides a mock function with given fields: _a0, _a1, _a2
+func (_m *Preferences) OptedOut(_a0 context.Context, _a1 string, _a2 string) (bool, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (bool, error)); ok {
+		return rf(_a0, _a1, _a2)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) bool); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewPreferences creates a new instance of Preferences. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewPreferences(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Preferences {
+	mock := &Preferences{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,107 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	schedule "user_news_api/schedule"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Schedules is an autogenerated mock type for the Schedules type
+type Schedules struct {
+	mock.Mock
+}
+
+// Add provides a mock function with given fields: _a0, _a1
+func (_m *Schedules) Add(_a0 context.Context, _a1 schedule.Item) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, schedule.Item) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Cancel provides a mock function with given fields: _a0, _a1
+func (_m *Schedules) Cancel(_a0 context.Context, _a1 string) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Get provides a mock function with given fields: _a0, _a1
+func (_m *Schedules) Get(_a0 context.Context, _a1 string) (schedule.Item, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 schedule.Item
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (schedule.Item, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) schedule.Item); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(schedule.Item)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// List provides a mock function with given fields: _a0, _a1, _a2
+func (_m *Schedules) List(_a0 context.Context, _a1 string, _a2 int64) ([]schedule.Item, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 []schedule.Item
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) ([]schedule.Item, error)); ok {
+		return rf(_a0, _a1, _a2)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) []schedule.Item); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]schedule.Item)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int64) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewSchedules creates a new instance of Schedules. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewSchedules(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Schedules {
+	mock := &Schedules{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,68 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	suppression "user_news_api/suppression"
+)
+
+// Suppressions is an autogenerated mock type for the Suppressions type
+type Suppressions struct {
+	mock.Mock
+}
+
+// Add provides a mock function with given fields: _a0, _a1
+func (_m *Suppressions) Add(_a0 context.Context, _a1 suppression.Entry) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, suppression.Entry) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Get provides a mock function with given fields: _a0, _a1
+func (_m *Suppressions) Get(_a0 context.Context, _a1 string) (suppression.Entry, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 suppression.Entry
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (suppression.Entry, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) suppression.Entry); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(suppression.Entry)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewSuppressions creates a new instance of Suppressions. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewSuppressions(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Suppressions {
+	mock := &Suppressions{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
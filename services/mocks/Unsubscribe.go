@@ -0,0 +1,38 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import mock "github.com/stretchr/testify/mock"
+
+// Unsubscribe is an autogenerated mock type for the Unsubscribe type
+type Unsubscribe struct {
+	mock.Mock
+}
+
+// URL provides a mock function with given fields: _a0, _a1
+func (_m *Unsubscribe) URL(_a0 string, _a1 string) string {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string, string) string); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// NewUnsubscribe creates a new instance of Unsubscribe. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewUnsubscribe(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Unsubscribe {
+	mock := &Unsubscribe{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+	"user_news_api/delivery"
+	"user_news_api/logging"
+	"user_news_api/queue"
+	"user_news_api/ratelimiter"
+	"user_news_api/schedule"
+)
+
+// DeferredError is returned for a notification over the rate limit that was scheduled for when the limit resets.
+type DeferredError struct {
+	SendAt time.Time
+}
+
+func (e DeferredError) Error() string {
+	return fmt.Sprintf("deferred until %s", e.SendAt.Format(time.RFC3339))
+}
+
+// overLimitStates are the states recorded for the notifications over the rate limit, depending on the policy.
+var overLimitStates = map[ratelimiter.OnLimitPolicy]delivery.State{
+	ratelimiter.OnLimitReject:       delivery.StateRateLimited,
+	ratelimiter.OnLimitDefer:        delivery.StateScheduled,
+	ratelimiter.OnLimitDropSilently: delivery.StateDropped,
+	ratelimiter.OnLimitDigest:       delivery.StateBuffered,
+}
+
+// onLimit returns the policy applied to a notification over the rate limit.
+// The digest policy falls back to rejecting the notification when digests are not enabled.
+func (serv UserNotifierService) onLimit(messageType string) ratelimiter.OnLimitPolicy {
+	policy := serv.limiter.OnLimit(messageType)
+	if _, ok := overLimitStates[policy]; !ok || (policy == ratelimiter.OnLimitDigest && serv.digests == nil) {
+		return ratelimiter.OnLimitReject
+	}
+
+	return policy
+}
+
+// overLimit applies the policy to a new notification over the rate limit, already recorded.
+// The returned error tells the caller what happened to the notification.
+func (serv UserNotifierService) overLimit(
+	ctx context.Context, msg queue.Message, policy ratelimiter.OnLimitPolicy, sendAt time.Time, limitErr error,
+) error {
+	switch policy {
+	case ratelimiter.OnLimitDigest:
+		if err := serv.buffer(ctx, msg); err != nil {
+			serv.markFailed(ctx, msg.ID, err)
+
+			return err
+		}
+
+		return fmt.Errorf("%w for user %s and message type %s", ErrDigested, msg.UserEmail, msg.MessageType)
+	case ratelimiter.OnLimitDefer:
+		err := serv.schedules.Add(ctx, schedule.Item{
+			ID:          msg.ID,
+			UserEmail:   msg.UserEmail,
+			MessageType: msg.MessageType,
+			Channel:     msg.Channel,
+			Client:      delivery.Client(ctx),
+			SendAt:      sendAt,
+		})
+		if err != nil {
+			err = fmt.Errorf("schedule error for user %s: %w", msg.UserEmail, err)
+			serv.markFailed(ctx, msg.ID, err)
+
+			return err
+		}
+
+		return DeferredError{SendAt: sendAt}
+	case ratelimiter.OnLimitDropSilently:
+		return fmt.Errorf("%w for user %s and message type %s", ErrDropped, msg.UserEmail, msg.MessageType)
+	}
+
+	return limitErr
+}
+
+// overLimitDue applies the policy to a scheduled notification that is over the rate limit when it is due.
+// As in scheduled, only the errors worth retrying later are returned.
+func (serv UserNotifierService) overLimitDue(ctx context.Context, item schedule.Item, limitErr error) error {
+	switch serv.channelPolicy(item.MessageType, item.Channel) {
+	case ratelimiter.OnLimitDigest:
+		if err := serv.records.MarkBuffered(ctx, item.ID, limitErr.Error()); err != nil {
+			slog.WarnContext(ctx, "error recording notification as buffered", slog.String(logging.KeyNotificationID, item.ID), logging.Error(err))
+		}
+
+		return serv.buffer(ctx, queue.Message{ID: item.ID, UserEmail: item.UserEmail, MessageType: item.MessageType})
+	case ratelimiter.OnLimitDefer:
+		sendAt, err := serv.resetAt(ctx, item.UserEmail, item.MessageType, item.Channel)
+		if err != nil {
+			return err
+		}
+
+		if err = serv.records.MarkDeferred(ctx, item.ID, sendAt, limitErr.Error()); err != nil {
+			slog.WarnContext(ctx, "error recording notification as deferred", slog.String(logging.KeyNotificationID, item.ID), logging.Error(err))
+		}
+
+		item.SendAt = sendAt
+		if err = serv.schedules.Add(ctx, item); err != nil {
+			return fmt.Errorf("schedule error for user %s: %w", item.UserEmail, err)
+		}
+
+		return nil
+	case ratelimiter.OnLimitDropSilently:
+		if err := serv.records.MarkDropped(ctx, item.ID, limitErr.Error()); err != nil {
+			slog.WarnContext(ctx, "error recording notification as dropped", slog.String(logging.KeyNotificationID, item.ID), logging.Error(err))
+		}
+
+		return nil
+	}
+
+	if err := serv.records.MarkRateLimited(ctx, item.ID, limitErr.Error()); err != nil {
+		slog.WarnContext(ctx, "error recording notification as rate limited", slog.String(logging.KeyNotificationID, item.ID), logging.Error(err))
+	}
+
+	return nil
+}
+
+// resetAt returns when the user can receive notifications of the message type through the channel again.
+func (serv UserNotifierService) resetAt(
+	ctx context.Context, userMail string, messageType string, channelName string,
+) (time.Time, error) {
+	resetAt, err := serv.limiter.ResetAt(ctx, limitKey(userMail, channelName), messageType)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("limiter error for user %s: %w", userMail, err)
+	}
+
+	return resetAt, nil
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"user_news_api/delivery"
+	"user_news_api/ratelimiter"
+	"user_news_api/schedule"
+	"user_news_api/services/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestUserNotifier_NotifyOnLimit(t *testing.T) {
+	ctx := context.Background()
+	// Notify passes its span within the context
+	spanCtx := mock.AnythingOfType("*context.valueCtx")
+	userMail := "user@example.com"
+	messageType := ratelimiter.StatusType
+	resetAt := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	limitErr := fmt.Errorf("%w: rate limit reached for user %s and message type %s", ErrLimitExceeded, userMail, messageType)
+	deferred := delivery.Record{
+		ID:          notificationID,
+		UserEmail:   userMail,
+		MessageType: messageType,
+		State:       delivery.StateScheduled,
+		Reason:      limitErr.Error(),
+		SendAt:      &resetAt,
+	}
+	item := schedule.Item{
+		ID:          notificationID,
+		UserEmail:   userMail,
+		MessageType: messageType,
+		SendAt:      resetAt,
+	}
+
+	tests := []struct {
+		name          string
+		applyMocks    func(*mocks.Limiter, *mocks.Records, *mocks.Schedules)
+		expectedID    string
+		expectedError error
+	}{
+		{
+			name: "Deferred until the limit resets",
+			applyMocks: func(ml *mocks.Limiter, mr *mocks.Records, ms *mocks.Schedules) {
+				ml.On("Reached", spanCtx, userMail, messageType).Return(true, nil).Once()
+				ml.On("OnLimit", messageType).Return(ratelimiter.OnLimitDefer).Once()
+				ml.On("ResetAt", spanCtx, userMail, messageType).Return(resetAt, nil).Once()
+				mr.On("Create", spanCtx, deferred).Return(nil).Once()
+				ms.On("Add", spanCtx, item).Return(nil).Once()
+			},
+			expectedID:    notificationID,
+			expectedError: DeferredError{SendAt: resetAt},
+		},
+		{
+			name: "Reset Error",
+			applyMocks: func(ml *mocks.Limiter, mr *mocks.Records, ms *mocks.Schedules) {
+				ml.On("Reached", spanCtx, userMail, messageType).Return(true, nil).Once()
+				ml.On("OnLimit", messageType).Return(ratelimiter.OnLimitDefer).Once()
+				ml.On("ResetAt", spanCtx, userMail, messageType).Return(time.Time{}, errors.New("limiter error")).Once()
+			},
+			expectedID:    "",
+			expectedError: fmt.Errorf("limiter error for user %s: %w", userMail, errors.New("limiter error")),
+		},
+		{
+			name: "Schedule Error",
+			applyMocks: func(ml *mocks.Limiter, mr *mocks.Records, ms *mocks.Schedules) {
+				ml.On("Reached", spanCtx, userMail, messageType).Return(true, nil).Once()
+				ml.On("OnLimit", messageType).Return(ratelimiter.OnLimitDefer).Once()
+				ml.On("ResetAt", spanCtx, userMail, messageType).Return(resetAt, nil).Once()
+				mr.On("Create", spanCtx, deferred).Return(nil).Once()
+				ms.On("Add", spanCtx, item).Return(errors.New("schedule error")).Once()
+				mr.On("MarkFailed", spanCtx, notificationID,
+					fmt.Sprintf("schedule error for user %s: schedule error", userMail), "").Return(nil).Once()
+			},
+			expectedID:    notificationID,
+			expectedError: fmt.Errorf("schedule error for user %s: %w", userMail, errors.New("schedule error")),
+		},
+		{
+			name: "Dropped silently",
+			applyMocks: func(ml *mocks.Limiter, mr *mocks.Records, ms *mocks.Schedules) {
+				ml.On("Reached", spanCtx, userMail, messageType).Return(true, nil).Once()
+				ml.On("OnLimit", messageType).Return(ratelimiter.OnLimitDropSilently).Once()
+				mr.On("Create", spanCtx, delivery.Record{
+					ID:          notificationID,
+					UserEmail:   userMail,
+					MessageType: messageType,
+					State:       delivery.StateDropped,
+					Reason:      limitErr.Error(),
+				}).Return(nil).Once()
+			},
+			expectedID:    notificationID,
+			expectedError: fmt.Errorf("%w for user %s and message type %s", ErrDropped, userMail, messageType),
+		},
+		{
+			name: "Digest without digests enabled is rejected",
+			applyMocks: func(ml *mocks.Limiter, mr *mocks.Records, ms *mocks.Schedules) {
+				ml.On("Reached", spanCtx, userMail, messageType).Return(true, nil).Once()
+				ml.On("OnLimit", messageType).Return(ratelimiter.OnLimitDigest).Once()
+				mr.On("Create", spanCtx, delivery.Record{
+					ID:          notificationID,
+					UserEmail:   userMail,
+					MessageType: messageType,
+					State:       delivery.StateRateLimited,
+					Reason:      limitErr.Error(),
+				}).Return(nil).Once()
+			},
+			expectedID:    notificationID,
+			expectedError: limitErr,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLimiter := mocks.NewLimiter(t)
+			mockRecords := mocks.NewRecords(t)
+			mockSchedules := mocks.NewSchedules(t)
+
+			tt.applyMocks(mockLimiter, mockRecords, mockSchedules)
+
+			serv := UserNotifierService{
+				limiter:   mockLimiter,
+				records:   mockRecords,
+				schedules: mockSchedules,
+				newID:     fixedID,
+			}
+
+			id, err := serv.Notify(ctx, userMail, messageType)
+
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expectedID, id)
+		})
+	}
+}
+
+func TestUserNotifier_ScheduledOnLimit(t *testing.T) {
+	ctx := context.Background()
+	userMail := "user@example.com"
+	messageType := ratelimiter.StatusType
+	resetAt := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	reason := fmt.Sprintf("limit exceeded: rate limit reached for user %s and message type %s", userMail, messageType)
+	item := schedule.Item{
+		ID:          notificationID,
+		UserEmail:   userMail,
+		MessageType: messageType,
+		SendAt:      time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+	}
+	deferred := item
+	deferred.SendAt = resetAt
+
+	tests := []struct {
+		name          string
+		applyMocks    func(*mocks.Limiter, *mocks.Records, *mocks.Schedules)
+		expectedError error
+	}{
+		{
+			name: "Deferred again",
+			applyMocks: func(ml *mocks.Limiter, mr *mocks.Records, ms *mocks.Schedules) {
+				ml.On("Reached", ctx, userMail, messageType).Return(true, nil).Once()
+				ml.On("OnLimit", messageType).Return(ratelimiter.OnLimitDefer).Once()
+				ml.On("ResetAt", ctx, userMail, messageType).Return(resetAt, nil).Once()
+				mr.On("MarkDeferred", ctx, notificationID, resetAt, reason).Return(nil).Once()
+				ms.On("Add", ctx, deferred).Return(nil).Once()
+			},
+			expectedError: nil,
+		},
+		{
+			name: "Schedule Error is retried",
+			applyMocks: func(ml *mocks.Limiter, mr *mocks.Records, ms *mocks.Schedules) {
+				ml.On("Reached", ctx, userMail, messageType).Return(true, nil).Once()
+				ml.On("OnLimit", messageType).Return(ratelimiter.OnLimitDefer).Once()
+				ml.On("ResetAt", ctx, userMail, messageType).Return(resetAt, nil).Once()
+				mr.On("MarkDeferred", ctx, notificationID, resetAt, reason).Return(nil).Once()
+				ms.On("Add", ctx, deferred).Return(errors.New("schedule error")).Once()
+			},
+			expectedError: fmt.Errorf("schedule error for user %s: %w", userMail, errors.New("schedule error")),
+		},
+		{
+			name: "Dropped silently",
+			applyMocks: func(ml *mocks.Limiter, mr *mocks.Records, ms *mocks.Schedules) {
+				ml.On("Reached", ctx, userMail, messageType).Return(true, nil).Once()
+				ml.On("OnLimit", messageType).Return(ratelimiter.OnLimitDropSilently).Once()
+				mr.On("MarkDropped", ctx, notificationID, reason).Return(nil).Once()
+			},
+			expectedError: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLimiter := mocks.NewLimiter(t)
+			mockQueue := mocks.NewQueue(t)
+			mockRecords := mocks.NewRecords(t)
+			mockSchedules := mocks.NewSchedules(t)
+
+			tt.applyMocks(mockLimiter, mockRecords, mockSchedules)
+
+			serv := UserNotifierService{
+				limiter:   mockLimiter,
+				queue:     mockQueue,
+				records:   mockRecords,
+				schedules: mockSchedules,
+			}
+
+			assert.Equal(t, tt.expectedError, serv.EnqueueScheduled(ctx, item))
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"fmt"
+)
+
+// Preferences is an abstraction for preferences.Store making it mockeable
+type Preferences interface {
+	OptedOut(context.Context, string, string) (bool, error)
+}
+
+// Unsubscribe is an abstraction for unsubscribe.Signer making it mockeable
+type Unsubscribe interface {
+	URL(string, string) string
+}
+
+// WithPreferences enables the user preferences. Notifications of the message types the user opted out of are rejected
+// before checking the rate limit, unless the message type is transactional.
+func (serv UserNotifierService) WithPreferences(preferences Preferences) UserNotifierService {
+	serv.preferences = preferences
+
+	return serv
+}
+
+// WithUnsubscribe adds one-click unsubscribe links to the notifications of the message types users can opt out of.
+// It requires the user preferences, where the unsubscriptions are saved.
+func (serv UserNotifierService) WithUnsubscribe(unsubscribe Unsubscribe) UserNotifierService {
+	serv.unsubscribe = unsubscribe
+
+	return serv
+}
+
+// unsubscribeURL returns the unsubscribe link of the notification, it is empty for transactional message types.
+func (serv UserNotifierService) unsubscribeURL(userMail string, messageType string) string {
+	if serv.unsubscribe == nil || serv.preferences == nil || serv.limiter.Transactional(messageType) {
+		return ""
+	}
+
+	return serv.unsubscribe.URL(userMail, messageType)
+}
+
+// checkRecipient tells if the user can receive the notification, checking the suppression list and its preferences.
+func (serv UserNotifierService) checkRecipient(ctx context.Context, userMail string, messageType string) error {
+	if err := serv.checkSuppressed(ctx, userMail); err != nil {
+		return err
+	}
+
+	return serv.checkOptedOut(ctx, userMail, messageType)
+}
+
+// checkOptedOut returns ErrOptedOut when the user opted out of the message type.
+func (serv UserNotifierService) checkOptedOut(ctx context.Context, userMail string, messageType string) error {
+	if serv.preferences == nil || serv.limiter.Transactional(messageType) {
+		return nil
+	}
+
+	optedOut, err := serv.preferences.OptedOut(ctx, userMail, messageType)
+	if err != nil {
+		return fmt.Errorf("preferences error for user %s: %w", userMail, err)
+	}
+
+	if optedOut {
+		return fmt.Errorf("%w: user %s opted out of message type %s", ErrOptedOut, userMail, messageType)
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"user_news_api/notifier"
+	"user_news_api/queue"
+	"user_news_api/ratelimiter"
+	"user_news_api/schedule"
+	"user_news_api/services/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestUserNotifier_NotifyOptedOut(t *testing.T) {
+	ctx := context.Background()
+	// Notify passes its span within the context
+	spanCtx := mock.AnythingOfType("*context.valueCtx")
+	userMail := "user@example.com"
+	limiterErr := errors.New("limiter error")
+
+	tests := []struct {
+		name          string
+		messageType   string
+		applyMocks    func(*mocks.Limiter, *mocks.Preferences)
+		expectedError error
+	}{
+		{
+			name:        "Opted out",
+			messageType: ratelimiter.MarketingType,
+			applyMocks: func(ml *mocks.Limiter, mp *mocks.Preferences) {
+				ml.On("Transactional", ratelimiter.MarketingType).Return(false).Once()
+				mp.On("OptedOut", spanCtx, userMail, ratelimiter.MarketingType).Return(true, nil).Once()
+			},
+			expectedError: fmt.Errorf("%w: user %s opted out of message type %s", ErrOptedOut, userMail, ratelimiter.MarketingType),
+		},
+		{
+			name:        "Preferences Error",
+			messageType: ratelimiter.MarketingType,
+			applyMocks: func(ml *mocks.Limiter, mp *mocks.Preferences) {
+				ml.On("Transactional", ratelimiter.MarketingType).Return(false).Once()
+				mp.On("OptedOut", spanCtx, userMail, ratelimiter.MarketingType).Return(false, errors.New("preferences error")).Once()
+			},
+			expectedError: fmt.Errorf("preferences error for user %s: %w", userMail, errors.New("preferences error")),
+		},
+		{
+			name:        "Opted in",
+			messageType: ratelimiter.MarketingType,
+			applyMocks: func(ml *mocks.Limiter, mp *mocks.Preferences) {
+				ml.On("Transactional", ratelimiter.MarketingType).Return(false).Once()
+				mp.On("OptedOut", spanCtx, userMail, ratelimiter.MarketingType).Return(false, nil).Once()
+				ml.On("Reached", spanCtx, userMail, ratelimiter.MarketingType).Return(false, limiterErr).Once()
+			},
+			expectedError: fmt.Errorf("limiter error for user %s: %w", userMail, limiterErr),
+		},
+		{
+			name:        "Transactional message types are not optional",
+			messageType: ratelimiter.StatusType,
+			applyMocks: func(ml *mocks.Limiter, mp *mocks.Preferences) {
+				ml.On("Transactional", ratelimiter.StatusType).Return(true).Once()
+				ml.On("Reached", spanCtx, userMail, ratelimiter.StatusType).Return(false, limiterErr).Once()
+			},
+			expectedError: fmt.Errorf("limiter error for user %s: %w", userMail, limiterErr),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLimiter := mocks.NewLimiter(t)
+			mockPreferences := mocks.NewPreferences(t)
+
+			tt.applyMocks(mockLimiter, mockPreferences)
+
+			serv := UserNotifierService{
+				limiter: mockLimiter,
+				newID:   fixedID,
+			}.WithPreferences(mockPreferences)
+
+			id, err := serv.Notify(ctx, userMail, tt.messageType)
+
+			assert.Equal(t, tt.expectedError, err)
+			assert.Empty(t, id)
+		})
+	}
+}
+
+func TestUserNotifier_ScheduledOptedOut(t *testing.T) {
+	ctx := context.Background()
+	item := schedule.Item{
+		ID:          notificationID,
+		UserEmail:   "user@example.com",
+		MessageType: ratelimiter.MarketingType,
+		SendAt:      time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+	}
+
+	mockLimiter := mocks.NewLimiter(t)
+	mockRecords := mocks.NewRecords(t)
+	mockPreferences := mocks.NewPreferences(t)
+
+	mockLimiter.On("Transactional", ratelimiter.MarketingType).Return(false).Once()
+	mockPreferences.On("OptedOut", ctx, item.UserEmail, item.MessageType).Return(true, nil).Once()
+	mockRecords.On("MarkFailed", ctx, notificationID,
+		fmt.Sprintf("opted out: user %s opted out of message type %s", item.UserEmail, item.MessageType), "").
+		Return(nil).Once()
+
+	serv := UserNotifierService{
+		limiter: mockLimiter,
+		records: mockRecords,
+	}.WithPreferences(mockPreferences)
+
+	assert.NoError(t, serv.SendScheduled(ctx, item))
+}
+
+func TestUserNotifier_DeliverUnsubscribeURL(t *testing.T) {
+	ctx := context.Background()
+	userMail := "user@example.com"
+	link := "https://api.example.com/unsubscribe?token=abc"
+
+	tests := []struct {
+		name        string
+		messageType string
+		applyMocks  func(*mocks.Limiter, *mocks.Unsubscribe)
+		expectedURL string
+	}{
+		{
+			name:        "Optional message type",
+			messageType: ratelimiter.MarketingType,
+			applyMocks: func(ml *mocks.Limiter, mu *mocks.Unsubscribe) {
+				ml.On("Transactional", ratelimiter.MarketingType).Return(false).Once()
+				mu.On("URL", userMail, ratelimiter.MarketingType).Return(link).Once()
+			},
+			expectedURL: link,
+		},
+		{
+			name:        "Transactional message type",
+			messageType: ratelimiter.StatusType,
+			applyMocks: func(ml *mocks.Limiter, mu *mocks.Unsubscribe) {
+				ml.On("Transactional", ratelimiter.StatusType).Return(true).Once()
+			},
+			expectedURL: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLimiter := mocks.NewLimiter(t)
+			mockNotifier := mocks.NewNotifier(t)
+			mockRecords := mocks.NewRecords(t)
+			mockUnsubscribe := mocks.NewUnsubscribe(t)
+
+			tt.applyMocks(mockLimiter, mockUnsubscribe)
+			mockRecords.On("MarkSending", ctx, notificationID).Return(nil).Once()
+			mockNotifier.On("Send", ctx, notifier.NotifyToOptions{
+				To:             userMail,
+				Subject:        "Notification",
+				Body:           toHTML(tt.messageType),
+				UnsubscribeURL: tt.expectedURL,
+			}).Return("primary", nil).Once()
+			mockRecords.On("MarkSent", ctx, notificationID, "primary").Return(nil).Once()
+
+			serv := UserNotifierService{
+				limiter:  mockLimiter,
+				notifier: mockNotifier,
+				records:  mockRecords,
+			}.WithPreferences(mocks.NewPreferences(t)).WithUnsubscribe(mockUnsubscribe)
+
+			err := serv.Deliver(ctx, queue.Message{ID: notificationID, UserEmail: userMail, MessageType: tt.messageType})
+
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"user_news_api/logging"
+	"user_news_api/notifier"
+	"user_news_api/suppression"
+)
+
+// Suppressions is an abstraction for suppression.Store making it mockeable
+type Suppressions interface {
+	Add(context.Context, suppression.Entry) error
+	Get(context.Context, string) (suppression.Entry, error)
+}
+
+// WithSuppressions enables the suppression list. Notifications to suppressed addresses are rejected
+// before checking the rate limit, and addresses rejected permanently by the mail server are suppressed.
+func (serv UserNotifierService) WithSuppressions(suppressions Suppressions) UserNotifierService {
+	serv.suppressions = suppressions
+
+	return serv
+}
+
+// checkSuppressed returns ErrSuppressed when the address must not receive notifications.
+func (serv UserNotifierService) checkSuppressed(ctx context.Context, userMail string) error {
+	if serv.suppressions == nil {
+		return nil
+	}
+
+	entry, err := serv.suppressions.Get(ctx, userMail)
+	if errors.Is(err, suppression.ErrNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("suppression error for user %s: %w", userMail, err)
+	}
+
+	return fmt.Errorf("%w: user %s suppressed due to %s", ErrSuppressed, userMail, entry.Reason)
+}
+
+// suppressBounce suppresses the address when the mail server rejected it permanently.
+// The error is only logged because the notification already failed.
+func (serv UserNotifierService) suppressBounce(ctx context.Context, userMail string, cause error) {
+	if serv.suppressions == nil || !errors.Is(cause, notifier.ErrPermanent) {
+		return
+	}
+
+	detail := notifier.SMTPResponse(cause)
+	if detail == "" {
+		detail = cause.Error()
+	}
+
+	err := serv.suppressions.Add(ctx, suppression.Entry{
+		Email:     userMail,
+		Reason:    suppression.ReasonBounce,
+		Detail:    detail,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "error suppressing address",
+			slog.String(logging.KeyUser, logging.MaskEmail(userMail)), logging.Error(err))
+	}
+}
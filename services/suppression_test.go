@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"user_news_api/delivery"
+	"user_news_api/notifier"
+	"user_news_api/queue"
+	"user_news_api/ratelimiter"
+	"user_news_api/schedule"
+	"user_news_api/services/mocks"
+	"user_news_api/suppression"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestUserNotifier_NotifySuppressed(t *testing.T) {
+	ctx := context.Background()
+	// Notify passes its span within the context
+	spanCtx := mock.AnythingOfType("*context.valueCtx")
+	userMail := "user@example.com"
+	messageType := ratelimiter.NewsType
+	entry := suppression.Entry{Email: userMail, Reason: suppression.ReasonBounce}
+
+	tests := []struct {
+		name          string
+		applyMocks    func(*mocks.Limiter, *mocks.Records, *mocks.Suppressions)
+		expectedError error
+	}{
+		{
+			name: "Suppressed address",
+			applyMocks: func(ml *mocks.Limiter, mr *mocks.Records, ms *mocks.Suppressions) {
+				ms.On("Get", spanCtx, userMail).Return(entry, nil).Once()
+			},
+			expectedError: fmt.Errorf("%w: user %s suppressed due to %s", ErrSuppressed, userMail, suppression.ReasonBounce),
+		},
+		{
+			name: "Suppression Error",
+			applyMocks: func(ml *mocks.Limiter, mr *mocks.Records, ms *mocks.Suppressions) {
+				ms.On("Get", spanCtx, userMail).Return(suppression.Entry{}, errors.New("suppression error")).Once()
+			},
+			expectedError: fmt.Errorf("suppression error for user %s: %w", userMail, errors.New("suppression error")),
+		},
+		{
+			name: "Address not suppressed",
+			applyMocks: func(ml *mocks.Limiter, mr *mocks.Records, ms *mocks.Suppressions) {
+				ms.On("Get", spanCtx, userMail).Return(suppression.Entry{}, suppression.ErrNotFound).Once()
+				ml.On("Reached", spanCtx, userMail, messageType).Return(false, errors.New("limiter error")).Once()
+			},
+			expectedError: fmt.Errorf("limiter error for user %s: %w", userMail, errors.New("limiter error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLimiter := mocks.NewLimiter(t)
+			mockRecords := mocks.NewRecords(t)
+			mockSuppressions := mocks.NewSuppressions(t)
+
+			tt.applyMocks(mockLimiter, mockRecords, mockSuppressions)
+
+			serv := UserNotifierService{
+				limiter: mockLimiter,
+				records: mockRecords,
+				newID:   fixedID,
+			}.WithSuppressions(mockSuppressions)
+
+			id, err := serv.Notify(ctx, userMail, messageType)
+
+			assert.Equal(t, tt.expectedError, err)
+			assert.Empty(t, id)
+		})
+	}
+}
+
+func TestUserNotifier_DeliverSuppressesBounces(t *testing.T) {
+	ctx := context.Background()
+	msg := queue.Message{ID: notificationID, UserEmail: "user@example.com", MessageType: ratelimiter.NewsType}
+	bounce := mock.MatchedBy(func(entry suppression.Entry) bool {
+		return entry.Email == msg.UserEmail && entry.Reason == suppression.ReasonBounce &&
+			entry.Detail == fmt.Sprintf("notifier error for user %s: permanent notifier error", msg.UserEmail)
+	})
+
+	tests := []struct {
+		name        string
+		notifyError error
+		applyMocks  func(*mocks.Suppressions)
+	}{
+		{
+			name:        "Permanent failure",
+			notifyError: notifier.ErrPermanent,
+			applyMocks: func(ms *mocks.Suppressions) {
+				ms.On("Add", ctx, bounce).Return(nil).Once()
+			},
+		},
+		{
+			name:        "Suppression errors are ignored",
+			notifyError: notifier.ErrPermanent,
+			applyMocks: func(ms *mocks.Suppressions) {
+				ms.On("Add", ctx, bounce).Return(errors.New("suppression error")).Once()
+			},
+		},
+		{
+			name:        "Temporary failure",
+			notifyError: notifier.ErrTimeout,
+			applyMocks:  func(ms *mocks.Suppressions) {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockNotifier := mocks.NewNotifier(t)
+			mockRecords := mocks.NewRecords(t)
+			mockSuppressions := mocks.NewSuppressions(t)
+
+			mockRecords.On("MarkSending", ctx, notificationID).Return(nil).Once()
+			mockNotifier.On("Send", ctx, mock.Anything).Return("", tt.notifyError).Once()
+			mockRecords.On("MarkFailed", ctx, notificationID, mock.Anything, "").Return(nil).Once()
+			tt.applyMocks(mockSuppressions)
+
+			serv := UserNotifierService{
+				notifier: mockNotifier,
+				records:  mockRecords,
+			}.WithSuppressions(mockSuppressions)
+
+			err := serv.Deliver(ctx, msg)
+
+			assert.ErrorIs(t, err, tt.notifyError)
+		})
+	}
+}
+
+func TestUserNotifier_EnqueueBatchSuppressed(t *testing.T) {
+	ctx := context.Background()
+	items := []BatchItem{
+		{UserEmail: "first@example.com", MessageType: ratelimiter.NewsType},
+		{UserEmail: "second@example.com", MessageType: ratelimiter.NewsType},
+	}
+	suppressedErr := fmt.Errorf(
+		"%w: user %s suppressed due to %s", ErrSuppressed, "first@example.com", suppression.ReasonComplaint)
+
+	mockLimiter := mocks.NewLimiter(t)
+	mockQueue := mocks.NewQueue(t)
+	mockRecords := mocks.NewRecords(t)
+	mockSuppressions := mocks.NewSuppressions(t)
+
+	mockSuppressions.On("Get", ctx, "first@example.com").
+		Return(suppression.Entry{Email: "first@example.com", Reason: suppression.ReasonComplaint}, nil).Once()
+	mockSuppressions.On("Get", ctx, "second@example.com").Return(suppression.Entry{}, suppression.ErrNotFound).Once()
+	mockLimiter.On("ReachedBatch", ctx, []ratelimiter.Hit{
+		{User: "second@example.com", MessageType: ratelimiter.NewsType},
+	}).Return([]ratelimiter.HitResult{{Reached: false}}, nil).Once()
+	mockRecords.On("Create", ctx, delivery.Record{
+		ID:          notificationID,
+		UserEmail:   "second@example.com",
+		MessageType: ratelimiter.NewsType,
+		State:       delivery.StateQueued,
+	}).Return(nil).Once()
+	mockQueue.On("Enqueue", ctx, queue.Message{
+		ID:          notificationID,
+		UserEmail:   "second@example.com",
+		MessageType: ratelimiter.NewsType,
+	}).Return(notificationID, nil).Once()
+
+	serv := UserNotifierService{
+		limiter: mockLimiter,
+		queue:   mockQueue,
+		records: mockRecords,
+		newID:   fixedID,
+	}.WithSuppressions(mockSuppressions)
+
+	assert.Equal(t, []BatchResult{
+		{Err: suppressedErr},
+		{ID: notificationID},
+	}, serv.EnqueueBatch(ctx, items))
+}
+
+func TestUserNotifier_ScheduledSuppressed(t *testing.T) {
+	ctx := context.Background()
+	item := schedule.Item{
+		ID:          notificationID,
+		UserEmail:   "user@example.com",
+		MessageType: ratelimiter.NewsType,
+		SendAt:      time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+	}
+
+	mockRecords := mocks.NewRecords(t)
+	mockSuppressions := mocks.NewSuppressions(t)
+
+	mockSuppressions.On("Get", ctx, item.UserEmail).
+		Return(suppression.Entry{Email: item.UserEmail, Reason: suppression.ReasonManual}, nil).Once()
+	mockRecords.On("MarkFailed", ctx, notificationID,
+		fmt.Sprintf("address suppressed: user %s suppressed due to manual", item.UserEmail), "").Return(nil).Once()
+
+	serv := UserNotifierService{records: mockRecords}.WithSuppressions(mockSuppressions)
+
+	assert.NoError(t, serv.EnqueueScheduled(ctx, item))
+}
@@ -4,38 +4,524 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+	"user_news_api/channel"
+	"user_news_api/delivery"
+	"user_news_api/logging"
 	"user_news_api/notifier"
+	"user_news_api/queue"
 	"user_news_api/ratelimiter"
+	"user_news_api/schedule"
+	"user_news_api/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
 	ErrLimitExceeded = errors.New("limit exceeded")
+	ErrDigested      = errors.New("buffered for the next digest")
+	ErrDropped       = errors.New("dropped silently")
+	ErrSuppressed    = errors.New("address suppressed")
+	ErrOptedOut      = errors.New("opted out")
 )
 
+// DefaultBatchConcurrency is the amount of notifications of a batch handled at the same time.
+const DefaultBatchConcurrency = 10
+
 // Limiter is an abstraction for ratelimiter.LimiterPool making it mockeable
 type Limiter interface {
+	Valid(string) bool
+	OnLimit(string) ratelimiter.OnLimitPolicy
+	Transactional(string) bool
+	ResetAt(context.Context, string, string) (time.Time, error)
 	Reached(context.Context, string, string) (bool, error)
+	ReachedBatch(context.Context, []ratelimiter.Hit) ([]ratelimiter.HitResult, error)
 }
 
-// Notifier is an abstraction for notifier.Client making it mockeable
+// Notifier is an abstraction for notifier.FailoverClient making it mockeable
 type Notifier interface {
-	NotifyTo(context.Context, notifier.NotifyToOptions) error
+	Send(context.Context, notifier.NotifyToOptions) (string, error)
+}
+
+// Queue is an abstraction for queue.Stream making it mockeable
+type Queue interface {
+	Enqueue(context.Context, queue.Message) (string, error)
+}
+
+// Records is an abstraction for delivery.Store making it mockeable
+type Records interface {
+	Create(context.Context, delivery.Record) error
+	MarkSending(context.Context, string) error
+	MarkSent(context.Context, string, string) error
+	MarkFailed(context.Context, string, string, string) error
+	MarkQueued(context.Context, string) error
+	MarkRateLimited(context.Context, string, string) error
+	MarkBuffered(context.Context, string, string) error
+	MarkDeferred(context.Context, string, time.Time, string) error
+	MarkDropped(context.Context, string, string) error
+	MarkCanceled(context.Context, string) error
+	Get(context.Context, string) (delivery.Record, error)
 }
 
-func NewUserNotifier(limiter Limiter, notifier Notifier) UserNotifierService {
+// Schedules is an abstraction for schedule.Store making it mockeable
+type Schedules interface {
+	Add(context.Context, schedule.Item) error
+	Cancel(context.Context, string) error
+	Get(context.Context, string) (schedule.Item, error)
+	List(context.Context, string, int64) ([]schedule.Item, error)
+}
+
+func NewUserNotifier(
+	limiter Limiter, notifier Notifier, stream Queue, records Records, schedules Schedules,
+) UserNotifierService {
 	return UserNotifierService{
-		limiter:  limiter,
-		notifier: notifier,
+		limiter:   limiter,
+		notifier:  notifier,
+		queue:     stream,
+		records:   records,
+		schedules: schedules,
+		newID:     queue.NewID,
+
+		batchConcurrency: DefaultBatchConcurrency,
 	}
 }
 
 type UserNotifierService struct {
-	limiter  Limiter
-	notifier Notifier
+	limiter   Limiter
+	notifier  Notifier
+	queue     Queue
+	records   Records
+	schedules Schedules
+	digests   Digests   // digests is nil when the digest policy is not enabled
+	flushes   Schedules // flushes schedules the digests when the rate limits reset
+	newID     func() (string, error)
+
+	suppressions Suppressions // suppressions is nil when the suppression list is not enabled
+	preferences  Preferences  // preferences is nil when the user preferences are not enabled
+	unsubscribe  Unsubscribe  // unsubscribe is nil when the unsubscribe links are not enabled
+	channels     Channels     // channels is nil when notifications are only sent by email
+	metrics      Metrics      // metrics is nil when the deliveries are not observed
+
+	batchConcurrency int
+}
+
+// BatchItem is a single notification of a batch.
+type BatchItem struct {
+	UserEmail   string
+	MessageType string
+	Channel     string // Channel is empty for email
+}
+
+// BatchResult is the outcome of a BatchItem. ID is empty when no delivery record was created.
+type BatchResult struct {
+	ID  string
+	Err error
+}
+
+// Notify checks the rate limit and sends the notification right away.
+// The notification ID is returned whenever a delivery record was created, even when it fails.
+func (serv UserNotifierService) Notify(ctx context.Context, userMail string, messageType string) (id string, err error) {
+	ctx, span := tracing.Start(ctx, "UserNotifierService.Notify",
+		trace.WithAttributes(attribute.String("message_type", messageType)))
+	defer func() {
+		span.SetAttributes(attribute.String("notification_id", id))
+		tracing.End(span, err)
+	}()
+
+	msg, err := serv.admit(ctx, userMail, messageType)
+	if err != nil {
+		return msg.ID, err
+	}
+
+	return msg.ID, serv.Deliver(ctx, msg)
+}
+
+// Enqueue checks the rate limit and leaves the notification to the workers, it returns the notification ID.
+func (serv UserNotifierService) Enqueue(ctx context.Context, userMail string, messageType string) (string, error) {
+	msg, err := serv.admit(ctx, userMail, messageType)
+	if err != nil {
+		return msg.ID, err
+	}
+
+	return msg.ID, serv.enqueue(ctx, msg)
+}
+
+// NotifyBatch checks the rate limits of all the items at once, and then it sends the admitted ones concurrently.
+// The results keep the order of the items.
+func (serv UserNotifierService) NotifyBatch(ctx context.Context, items []BatchItem) []BatchResult {
+	return serv.batch(ctx, items, serv.Deliver)
+}
+
+// EnqueueBatch checks the rate limits of all the items at once, and then it enqueues the admitted ones.
+// The results keep the order of the items.
+func (serv UserNotifierService) EnqueueBatch(ctx context.Context, items []BatchItem) []BatchResult {
+	return serv.batch(ctx, items, serv.enqueue)
+}
+
+// Check returns the error the notification would be rejected with regardless of the rate limit: a message type
+// or channel not valid, a suppressed address or a user opted out of the message type. It does not count towards
+// the rate limit, so callers can check a notification before charging it. An empty channelName stands for email.
+func (serv UserNotifierService) Check(ctx context.Context, userMail string, messageType string, channelName string) error {
+	if !serv.limiter.Valid(messageType) {
+		return fmt.Errorf("limiter error for user %s: %w", userMail, ratelimiter.ErrMessageTypeNotValid)
+	}
+
+	if err := serv.checkChannel(userMail, channelName); err != nil {
+		return err
+	}
+
+	return serv.checkRecipient(ctx, userMail, messageType)
 }
 
-func (serv UserNotifierService) Notify(ctx context.Context, userMail string, messageType string) error {
-	reached, err := serv.limiter.Reached(ctx, userMail, messageType)
+// Schedule leaves the notification for the scheduler until sendAt, it returns the notification ID.
+// Only the message type and the recipient are checked now, the rate limit is checked when the notification is due.
+func (serv UserNotifierService) Schedule(
+	ctx context.Context, userMail string, messageType string, sendAt time.Time,
+) (string, error) {
+	if err := serv.Check(ctx, userMail, messageType, ""); err != nil {
+		return "", err
+	}
+
+	id, err := serv.newID()
+	if err != nil {
+		return "", err
+	}
+
+	sendAt = sendAt.UTC()
+
+	err = serv.records.Create(ctx, delivery.Record{
+		ID:          id,
+		UserEmail:   userMail,
+		MessageType: messageType,
+		Client:      delivery.Client(ctx),
+		State:       delivery.StateScheduled,
+		SendAt:      &sendAt,
+	})
+	if err != nil {
+		return "", fmt.Errorf("records error for user %s: %w", userMail, err)
+	}
+
+	err = serv.schedules.Add(ctx, schedule.Item{
+		ID:          id,
+		UserEmail:   userMail,
+		MessageType: messageType,
+		Client:      delivery.Client(ctx),
+		SendAt:      sendAt,
+	})
+	if err != nil {
+		err = fmt.Errorf("schedule error for user %s: %w", userMail, err)
+		serv.markFailed(ctx, id, err)
+
+		return id, err
+	}
+
+	return id, nil
+}
+
+// Cancel drops a scheduled notification that is not due yet.
+// An authenticated client can only cancel its own notifications, the other ones are not found.
+func (serv UserNotifierService) Cancel(ctx context.Context, id string) error {
+	if client := delivery.Client(ctx); client != "" {
+		item, err := serv.schedules.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if item.Client != client {
+			return fmt.Errorf("%w: %s", schedule.ErrNotFound, id)
+		}
+	}
+
+	if err := serv.schedules.Cancel(ctx, id); err != nil {
+		return err
+	}
+
+	if err := serv.records.MarkCanceled(ctx, id); err != nil {
+		slog.WarnContext(ctx, "error recording notification as canceled", slog.String(logging.KeyNotificationID, id), logging.Error(err))
+	}
+
+	return nil
+}
+
+// Scheduled lists the notifications that are not due yet, the first to be sent first.
+// An authenticated client only lists its own notifications.
+func (serv UserNotifierService) Scheduled(ctx context.Context, limit int64) ([]schedule.Item, error) {
+	return serv.schedules.List(ctx, delivery.Client(ctx), limit)
+}
+
+// SendScheduled checks the rate limit of a due notification and sends it right away.
+func (serv UserNotifierService) SendScheduled(ctx context.Context, item schedule.Item) error {
+	return serv.scheduled(ctx, item, serv.Deliver)
+}
+
+// EnqueueScheduled checks the rate limit of a due notification and leaves it to the workers.
+func (serv UserNotifierService) EnqueueScheduled(ctx context.Context, item schedule.Item) error {
+	return serv.scheduled(ctx, item, serv.enqueue)
+}
+
+// Deliver sends an admitted notification. The rate limit is not checked again because it was done when admitting it.
+func (serv UserNotifierService) Deliver(ctx context.Context, msg queue.Message) error {
+	if err := serv.records.MarkSending(ctx, msg.ID); err != nil {
+		slog.WarnContext(ctx, "error recording attempt of notification", slog.String(logging.KeyNotificationID, msg.ID), logging.Error(err))
+	}
+
+	var provider string
+	var err error
+
+	if isEmail(msg.Channel) {
+		provider, err = serv.send(ctx, msg.UserEmail, msg.MessageType)
+		serv.suppressBounce(ctx, msg.UserEmail, err)
+	} else {
+		err = serv.sendChannel(ctx, msg.UserEmail, msg.MessageType, msg.Channel)
+	}
+
+	serv.observeDelivery(msg.MessageType, msg.Channel, err)
+
+	if err != nil {
+		serv.markFailed(ctx, msg.ID, err)
+
+		return err
+	}
+
+	if err = serv.records.MarkSent(ctx, msg.ID, provider); err != nil {
+		slog.WarnContext(ctx, "error recording notification as sent", slog.String(logging.KeyNotificationID, msg.ID), logging.Error(err))
+	}
+
+	return nil
+}
+
+// Status returns the delivery record of the notification.
+// An authenticated client can only read its own notifications, the other ones are not found.
+func (serv UserNotifierService) Status(ctx context.Context, id string) (delivery.Record, error) {
+	record, err := serv.records.Get(ctx, id)
+	if err != nil {
+		return delivery.Record{}, err
+	}
+
+	if client := delivery.Client(ctx); client != "" && record.Client != client {
+		return delivery.Record{}, fmt.Errorf("%w: %s", delivery.ErrNotFound, id)
+	}
+
+	return record, nil
+}
+
+func (serv UserNotifierService) enqueue(ctx context.Context, msg queue.Message) error {
+	if _, err := serv.queue.Enqueue(ctx, msg); err != nil {
+		err = fmt.Errorf("queue error for user %s: %w", msg.UserEmail, err)
+		serv.markFailed(ctx, msg.ID, err)
+
+		return err
+	}
+
+	return nil
+}
+
+func (serv UserNotifierService) batch(
+	ctx context.Context, items []BatchItem, handle func(context.Context, queue.Message) error,
+) []BatchResult {
+	results := make([]BatchResult, len(items))
+
+	// invalid channels, suppressed and opted out users are left out before checking the rate limits
+	var hits []ratelimiter.Hit
+	var positions []int
+
+	for i, item := range items {
+		err := serv.checkChannel(item.UserEmail, item.Channel)
+		if err == nil {
+			err = serv.checkRecipient(ctx, item.UserEmail, item.MessageType)
+		}
+
+		if err != nil {
+			results[i].Err = err
+
+			continue
+		}
+
+		hits = append(hits, ratelimiter.Hit{User: limitKey(item.UserEmail, item.Channel), MessageType: item.MessageType})
+		positions = append(positions, i)
+	}
+
+	if len(hits) == 0 {
+		return results
+	}
+
+	hitResults, err := serv.limiter.ReachedBatch(ctx, hits)
+	if err != nil {
+		err = fmt.Errorf("limiter error for batch: %w", err)
+		for _, i := range positions {
+			results[i].Err = err
+		}
+
+		return results
+	}
+
+	concurrency := serv.batchConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+
+	for j, i := range positions {
+		item := items[i]
+		limitErr := limitError(item.UserEmail, item.MessageType, hitResults[j].Reached, hitResults[j].Err)
+
+		msg, err := serv.register(ctx, item.UserEmail, item.MessageType, item.Channel, limitErr)
+		if err != nil {
+			results[i] = BatchResult{ID: msg.ID, Err: err}
+
+			continue
+		}
+
+		results[i].ID = msg.ID
+
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(i int, msg queue.Message) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			results[i].Err = handle(ctx, msg)
+		}(i, msg)
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// scheduled hands a due notification to delivery. The outcome of the delivery is recorded,
+// so only the errors worth retrying later are returned, i.e. when the limiter is not available.
+func (serv UserNotifierService) scheduled(
+	ctx context.Context, item schedule.Item, handle func(context.Context, queue.Message) error,
+) error {
+	// the channel is checked again because it may have been disabled since the notification was scheduled
+	err := serv.checkChannel(item.UserEmail, item.Channel)
+	if err == nil {
+		err = serv.checkRecipient(ctx, item.UserEmail, item.MessageType)
+	}
+
+	if err == nil {
+		err = serv.checkLimit(ctx, item.UserEmail, item.MessageType, item.Channel)
+	}
+
+	switch {
+	case errors.Is(err, ErrSuppressed), errors.Is(err, ErrOptedOut), errors.Is(err, channel.ErrChannelNotValid):
+		serv.markFailed(ctx, item.ID, err)
+
+		return nil
+	case errors.Is(err, ErrLimitExceeded):
+		return serv.overLimitDue(ctx, item, err)
+	case errors.Is(err, ratelimiter.ErrMessageTypeNotValid):
+		serv.markFailed(ctx, item.ID, err)
+
+		return nil
+	case err != nil:
+		return err
+	}
+
+	if err = serv.records.MarkQueued(ctx, item.ID); err != nil {
+		slog.WarnContext(ctx, "error recording notification as queued", slog.String(logging.KeyNotificationID, item.ID), logging.Error(err))
+	}
+
+	msg := queue.Message{
+		ID:          item.ID,
+		UserEmail:   item.UserEmail,
+		MessageType: item.MessageType,
+		Channel:     item.Channel,
+	}
+
+	if err = handle(ctx, msg); err != nil {
+		slog.ErrorContext(ctx, "scheduled notification failed", slog.String(logging.KeyNotificationID, item.ID), logging.Error(err))
+	}
+
+	return nil
+}
+
+// admit checks the recipient and the rate limit of the notification and registers it.
+func (serv UserNotifierService) admit(ctx context.Context, userMail string, messageType string) (queue.Message, error) {
+	if err := serv.checkRecipient(ctx, userMail, messageType); err != nil {
+		return queue.Message{}, err
+	}
+
+	return serv.register(ctx, userMail, messageType, "", serv.checkLimit(ctx, userMail, messageType, ""))
+}
+
+// register identifies the notification and records the outcome of its rate limit check.
+// Notifications failing the check for other reasons, e.g. an invalid message type, are not recorded.
+// Notifications over the limit follow the policy of their message type, see overLimit.
+// An empty channelName stands for email.
+func (serv UserNotifierService) register(
+	ctx context.Context, userMail string, messageType string, channelName string, limitErr error,
+) (queue.Message, error) {
+	if limitErr != nil && !errors.Is(limitErr, ErrLimitExceeded) {
+		return queue.Message{}, limitErr
+	}
+
+	id, err := serv.newID()
+	if err != nil {
+		return queue.Message{}, err
+	}
+
+	msg := queue.Message{
+		ID:          id,
+		UserEmail:   userMail,
+		MessageType: messageType,
+		Channel:     channelName,
+	}
+
+	record := delivery.Record{
+		ID:          id,
+		UserEmail:   userMail,
+		MessageType: messageType,
+		Channel:     channelName,
+		Client:      delivery.Client(ctx),
+		State:       delivery.StateQueued,
+	}
+
+	if limitErr == nil {
+		if err = serv.records.Create(ctx, record); err != nil {
+			return queue.Message{}, fmt.Errorf("records error for user %s: %w", userMail, err)
+		}
+
+		return msg, nil
+	}
+
+	policy := serv.channelPolicy(messageType, channelName)
+
+	record.State = overLimitStates[policy]
+	record.Reason = limitErr.Error()
+
+	var sendAt time.Time
+	if policy == ratelimiter.OnLimitDefer {
+		if sendAt, err = serv.resetAt(ctx, userMail, messageType, channelName); err != nil {
+			return queue.Message{}, err
+		}
+
+		record.SendAt = &sendAt
+	}
+
+	if err = serv.records.Create(ctx, record); err != nil {
+		return queue.Message{}, fmt.Errorf("records error for user %s: %w", userMail, err)
+	}
+
+	return msg, serv.overLimit(ctx, msg, policy, sendAt, limitErr)
+}
+
+func (serv UserNotifierService) checkLimit(
+	ctx context.Context, userMail string, messageType string, channelName string,
+) error {
+	reached, err := serv.limiter.Reached(ctx, limitKey(userMail, channelName), messageType)
+
+	return limitError(userMail, messageType, reached, err)
+}
+
+func limitError(userMail string, messageType string, reached bool, err error) error {
 	if err != nil {
 		return fmt.Errorf("limiter error for user %s: %w", userMail, err)
 	}
@@ -45,29 +531,34 @@ func (serv UserNotifierService) Notify(ctx context.Context, userMail string, mes
 			"%w: rate limit reached for user %s and message type %s", ErrLimitExceeded, userMail, messageType)
 	}
 
-	err = serv.notifier.NotifyTo(ctx, notifier.NotifyToOptions{
-		To:      userMail,
-		Subject: "Notification",
-		Body:    toHTML(messageType),
+	return nil
+}
+
+// send mails the notification, it returns the provider that sent it.
+func (serv UserNotifierService) send(ctx context.Context, userMail string, messageType string) (string, error) {
+	provider, err := serv.notifier.Send(ctx, notifier.NotifyToOptions{
+		To:             userMail,
+		Subject:        "Notification",
+		Body:           toHTML(messageType),
+		UnsubscribeURL: serv.unsubscribeURL(userMail, messageType),
 	})
 	if err != nil {
-		return fmt.Errorf("notifier error for user %s: %w", userMail, err)
+		return "", fmt.Errorf("notifier error for user %s: %w", userMail, err)
 	}
 
-	return nil
+	return provider, nil
+}
+
+// markFailed records the failure without hiding the original error when recording fails.
+func (serv UserNotifierService) markFailed(ctx context.Context, id string, cause error) {
+	if err := serv.records.MarkFailed(ctx, id, cause.Error(), notifier.SMTPResponse(cause)); err != nil {
+		slog.WarnContext(ctx, "error recording notification as failed", slog.String(logging.KeyNotificationID, id), logging.Error(err))
+	}
 }
 
 // toHTML is only string formatter, and it is used by the service like a decorator.
 func toHTML(messageType string) string {
-	color := "black"
-	switch messageType {
-	case ratelimiter.NewsType:
-		color = "green"
-	case ratelimiter.StatusType:
-		color = "red"
-	case ratelimiter.MarketingType:
-		color = "yellow"
-	}
+	color := messageColor(messageType)
 
 	htmlTemplate := `
 <!DOCTYPE html>
@@ -88,3 +579,16 @@ func toHTML(messageType string) string {
 `
 	return fmt.Sprintf(htmlTemplate, color, messageType)
 }
+
+func messageColor(messageType string) string {
+	switch messageType {
+	case ratelimiter.NewsType:
+		return "green"
+	case ratelimiter.StatusType:
+		return "red"
+	case ratelimiter.MarketingType:
+		return "yellow"
+	}
+
+	return "black"
+}
@@ -4,79 +4,750 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/textproto"
 	"testing"
+	"time"
+	"user_news_api/channel"
+	"user_news_api/delivery"
 	"user_news_api/notifier"
+	"user_news_api/queue"
 	"user_news_api/ratelimiter"
+	"user_news_api/schedule"
 	"user_news_api/services/mocks"
+	"user_news_api/suppression"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
+const notificationID = "some-id"
+
+func fixedID() (string, error) {
+	return notificationID, nil
+}
+
 func TestUserNotifier_Notify(t *testing.T) {
 	ctx := context.Background()
+	// Notify passes its span within the context
+	spanCtx := mock.AnythingOfType("*context.valueCtx")
 	userMail := "user@example.com"
 	messageType := ratelimiter.NewsType
+	options := notifier.NotifyToOptions{
+		To:      userMail,
+		Subject: "Notification",
+		Body:    toHTML(messageType),
+	}
+	record := delivery.Record{
+		ID:          notificationID,
+		UserEmail:   userMail,
+		MessageType: messageType,
+		State:       delivery.StateQueued,
+	}
+	limitErr := fmt.Errorf("%w: rate limit reached for user %s and message type %s", ErrLimitExceeded, userMail, messageType)
+	rejectedErr := fmt.Errorf("%w: smtp server replied 550", notifier.ErrPermanent)
 
 	tests := []struct {
 		name          string
-		applyMocks    func(*mocks.Limiter, *mocks.Notifier)
+		applyMocks    func(*mocks.Limiter, *mocks.Notifier, *mocks.Records)
+		expectedID    string
 		expectedError error
 	}{
 		{
 			name: "Success",
-			applyMocks: func(ml *mocks.Limiter, mn *mocks.Notifier) {
-				ml.On("Reached", ctx, userMail, messageType).Return(false, nil).Once()
-				mn.On("NotifyTo", ctx, notifier.NotifyToOptions{
-					To:      userMail,
-					Subject: "Notification",
-					Body:    toHTML(messageType),
+			applyMocks: func(ml *mocks.Limiter, mn *mocks.Notifier, mr *mocks.Records) {
+				ml.On("Reached", spanCtx, userMail, messageType).Return(false, nil).Once()
+				mr.On("Create", spanCtx, record).Return(nil).Once()
+				mr.On("MarkSending", spanCtx, notificationID).Return(nil).Once()
+				mn.On("Send", spanCtx, options).Return("primary", nil).Once()
+				mr.On("MarkSent", spanCtx, notificationID, "primary").Return(nil).Once()
+			},
+			expectedID:    notificationID,
+			expectedError: nil,
+		},
+		{
+			name: "Limiter Error",
+			applyMocks: func(ml *mocks.Limiter, mn *mocks.Notifier, mr *mocks.Records) {
+				ml.On("Reached", spanCtx, userMail, messageType).Return(false, errors.New("limiter error")).Once()
+			},
+			expectedID:    "",
+			expectedError: fmt.Errorf("limiter error for user %s: %w", userMail, errors.New("limiter error")),
+		},
+		{
+			name: "Rate Limit Exceeded",
+			applyMocks: func(ml *mocks.Limiter, mn *mocks.Notifier, mr *mocks.Records) {
+				ml.On("Reached", spanCtx, userMail, messageType).Return(true, nil).Once()
+				ml.On("OnLimit", messageType).Return(ratelimiter.OnLimitReject).Once()
+				mr.On("Create", spanCtx, delivery.Record{
+					ID:          notificationID,
+					UserEmail:   userMail,
+					MessageType: messageType,
+					State:       delivery.StateRateLimited,
+					Reason:      limitErr.Error(),
 				}).Return(nil).Once()
 			},
+			expectedID:    notificationID,
+			expectedError: limitErr,
+		},
+		{
+			name: "Records Error",
+			applyMocks: func(ml *mocks.Limiter, mn *mocks.Notifier, mr *mocks.Records) {
+				ml.On("Reached", spanCtx, userMail, messageType).Return(false, nil).Once()
+				mr.On("Create", spanCtx, record).Return(errors.New("records error")).Once()
+			},
+			expectedID:    "",
+			expectedError: fmt.Errorf("records error for user %s: %w", userMail, errors.New("records error")),
+		},
+		{
+			name: "Notifier Error",
+			applyMocks: func(ml *mocks.Limiter, mn *mocks.Notifier, mr *mocks.Records) {
+				ml.On("Reached", spanCtx, userMail, messageType).Return(false, nil).Once()
+				mr.On("Create", spanCtx, record).Return(nil).Once()
+				mr.On("MarkSending", spanCtx, notificationID).Return(nil).Once()
+				mn.On("Send", spanCtx, options).Return("", errors.New("notifier error")).Once()
+				mr.On("MarkFailed", spanCtx, notificationID,
+					fmt.Sprintf("notifier error for user %s: notifier error", userMail), "").Return(nil).Once()
+			},
+			expectedID:    notificationID,
+			expectedError: fmt.Errorf("notifier error for user %s: %w", userMail, errors.New("notifier error")),
+		},
+		{
+			name: "Notifier Error with SMTP response",
+			applyMocks: func(ml *mocks.Limiter, mn *mocks.Notifier, mr *mocks.Records) {
+				ml.On("Reached", spanCtx, userMail, messageType).Return(false, nil).Once()
+				mr.On("Create", spanCtx, record).Return(nil).Once()
+				mr.On("MarkSending", spanCtx, notificationID).Return(nil).Once()
+				mn.On("Send", spanCtx, options).
+					Return("", fmt.Errorf("%w: %w", rejectedErr, &textproto.Error{Code: 550, Msg: "no such user"})).Once()
+				mr.On("MarkFailed", spanCtx, notificationID,
+					fmt.Sprintf("notifier error for user %s: permanent notifier error: smtp server replied 550: 550 \"no such user\"", userMail),
+					"550 no such user").Return(nil).Once()
+			},
+			expectedID: notificationID,
+			expectedError: fmt.Errorf("notifier error for user %s: %w", userMail,
+				fmt.Errorf("%w: %w", rejectedErr, &textproto.Error{Code: 550, Msg: "no such user"})),
+		},
+		{
+			name: "Records errors after sending are ignored",
+			applyMocks: func(ml *mocks.Limiter, mn *mocks.Notifier, mr *mocks.Records) {
+				ml.On("Reached", spanCtx, userMail, messageType).Return(false, nil).Once()
+				mr.On("Create", spanCtx, record).Return(nil).Once()
+				mr.On("MarkSending", spanCtx, notificationID).Return(errors.New("records error")).Once()
+				mn.On("Send", spanCtx, options).Return("primary", nil).Once()
+				mr.On("MarkSent", spanCtx, notificationID, "primary").Return(errors.New("records error")).Once()
+			},
+			expectedID:    notificationID,
+			expectedError: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLimiter := mocks.NewLimiter(t)
+			mockNotifier := mocks.NewNotifier(t)
+			mockRecords := mocks.NewRecords(t)
+
+			tt.applyMocks(mockLimiter, mockNotifier, mockRecords)
+
+			serv := UserNotifierService{
+				limiter:  mockLimiter,
+				notifier: mockNotifier,
+				records:  mockRecords,
+				newID:    fixedID,
+			}
+
+			id, err := serv.Notify(ctx, userMail, messageType)
+
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expectedID, id)
+		})
+	}
+}
+
+func TestUserNotifier_Enqueue(t *testing.T) {
+	ctx := context.Background()
+	userMail := "user@example.com"
+	messageType := ratelimiter.NewsType
+	message := queue.Message{
+		ID:          notificationID,
+		UserEmail:   userMail,
+		MessageType: messageType,
+	}
+	record := delivery.Record{
+		ID:          notificationID,
+		UserEmail:   userMail,
+		MessageType: messageType,
+		State:       delivery.StateQueued,
+	}
+
+	tests := []struct {
+		name          string
+		applyMocks    func(*mocks.Limiter, *mocks.Queue, *mocks.Records)
+		expectedID    string
+		expectedError error
+	}{
+		{
+			name: "Success",
+			applyMocks: func(ml *mocks.Limiter, mq *mocks.Queue, mr *mocks.Records) {
+				ml.On("Reached", ctx, userMail, messageType).Return(false, nil).Once()
+				mr.On("Create", ctx, record).Return(nil).Once()
+				mq.On("Enqueue", ctx, message).Return(notificationID, nil).Once()
+			},
+			expectedID:    notificationID,
 			expectedError: nil,
 		},
 		{
 			name: "Limiter Error",
-			applyMocks: func(ml *mocks.Limiter, mn *mocks.Notifier) {
+			applyMocks: func(ml *mocks.Limiter, mq *mocks.Queue, mr *mocks.Records) {
 				ml.On("Reached", ctx, userMail, messageType).Return(false, errors.New("limiter error")).Once()
 			},
 			expectedError: fmt.Errorf("limiter error for user %s: %w", userMail, errors.New("limiter error")),
 		},
 		{
 			name: "Rate Limit Exceeded",
-			applyMocks: func(ml *mocks.Limiter, mn *mocks.Notifier) {
+			applyMocks: func(ml *mocks.Limiter, mq *mocks.Queue, mr *mocks.Records) {
 				ml.On("Reached", ctx, userMail, messageType).Return(true, nil).Once()
+				ml.On("OnLimit", messageType).Return(ratelimiter.OnLimitReject).Once()
+				mr.On("Create", ctx, delivery.Record{
+					ID:          notificationID,
+					UserEmail:   userMail,
+					MessageType: messageType,
+					State:       delivery.StateRateLimited,
+					Reason: fmt.Sprintf(
+						"limit exceeded: rate limit reached for user %s and message type %s", userMail, messageType),
+				}).Return(nil).Once()
 			},
+			expectedID:    notificationID,
 			expectedError: fmt.Errorf("%w: rate limit reached for user %s and message type %s", ErrLimitExceeded, userMail, messageType),
 		},
 		{
-			name: "Notifier Error",
-			applyMocks: func(ml *mocks.Limiter, mn *mocks.Notifier) {
+			name: "Queue Error",
+			applyMocks: func(ml *mocks.Limiter, mq *mocks.Queue, mr *mocks.Records) {
 				ml.On("Reached", ctx, userMail, messageType).Return(false, nil).Once()
-				mn.On("NotifyTo", ctx, notifier.NotifyToOptions{
-					To:      userMail,
-					Subject: "Notification",
-					Body:    toHTML(messageType),
-				}).Return(errors.New("notifier error")).Once()
+				mr.On("Create", ctx, record).Return(nil).Once()
+				mq.On("Enqueue", ctx, message).Return("", errors.New("queue error")).Once()
+				mr.On("MarkFailed", ctx, notificationID,
+					fmt.Sprintf("queue error for user %s: queue error", userMail), "").Return(nil).Once()
 			},
-			expectedError: fmt.Errorf("notifier error for user %s: %w", userMail, errors.New("notifier error")),
+			expectedID:    notificationID,
+			expectedError: fmt.Errorf("queue error for user %s: %w", userMail, errors.New("queue error")),
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			mockLimiter := mocks.NewLimiter(t)
+			mockQueue := mocks.NewQueue(t)
+			mockRecords := mocks.NewRecords(t)
+
+			tt.applyMocks(mockLimiter, mockQueue, mockRecords)
+
+			serv := UserNotifierService{
+				limiter: mockLimiter,
+				queue:   mockQueue,
+				records: mockRecords,
+				newID:   fixedID,
+			}
+
+			id, err := serv.Enqueue(ctx, userMail, messageType)
+
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expectedID, id)
+		})
+	}
+}
+
+func TestUserNotifier_EnqueueBatch(t *testing.T) {
+	ctx := context.Background()
+	items := []BatchItem{
+		{UserEmail: "first@example.com", MessageType: ratelimiter.NewsType},
+		{UserEmail: "second@example.com", MessageType: ratelimiter.NewsType},
+		{UserEmail: "third@example.com", MessageType: "Other"},
+	}
+	hits := []ratelimiter.Hit{
+		{User: "first@example.com", MessageType: ratelimiter.NewsType},
+		{User: "second@example.com", MessageType: ratelimiter.NewsType},
+		{User: "third@example.com", MessageType: "Other"},
+	}
+	limitErr := fmt.Errorf(
+		"%w: rate limit reached for user %s and message type %s", ErrLimitExceeded, "second@example.com", ratelimiter.NewsType)
+	typeErr := fmt.Errorf("limiter error for user %s: %w", "third@example.com", ratelimiter.ErrMessageTypeNotValid)
+
+	tests := []struct {
+		name            string
+		applyMocks      func(*mocks.Limiter, *mocks.Queue, *mocks.Records)
+		expectedResults []BatchResult
+	}{
+		{
+			name: "Every item gets its own result",
+			applyMocks: func(ml *mocks.Limiter, mq *mocks.Queue, mr *mocks.Records) {
+				ml.On("ReachedBatch", ctx, hits).Return([]ratelimiter.HitResult{
+					{Reached: false},
+					{Reached: true},
+					{Err: ratelimiter.ErrMessageTypeNotValid},
+				}, nil).Once()
+				ml.On("OnLimit", ratelimiter.NewsType).Return(ratelimiter.OnLimitReject).Once()
+				mr.On("Create", ctx, delivery.Record{
+					ID:          notificationID,
+					UserEmail:   "first@example.com",
+					MessageType: ratelimiter.NewsType,
+					State:       delivery.StateQueued,
+				}).Return(nil).Once()
+				mr.On("Create", ctx, delivery.Record{
+					ID:          notificationID,
+					UserEmail:   "second@example.com",
+					MessageType: ratelimiter.NewsType,
+					State:       delivery.StateRateLimited,
+					Reason:      limitErr.Error(),
+				}).Return(nil).Once()
+				mq.On("Enqueue", ctx, queue.Message{
+					ID:          notificationID,
+					UserEmail:   "first@example.com",
+					MessageType: ratelimiter.NewsType,
+				}).Return(notificationID, nil).Once()
+			},
+			expectedResults: []BatchResult{
+				{ID: notificationID},
+				{ID: notificationID, Err: limitErr},
+				{Err: typeErr},
+			},
+		},
+		{
+			name: "Limiter error fails every item",
+			applyMocks: func(ml *mocks.Limiter, mq *mocks.Queue, mr *mocks.Records) {
+				ml.On("ReachedBatch", ctx, hits).Return(nil, errors.New("limiter error")).Once()
+			},
+			expectedResults: []BatchResult{
+				{Err: fmt.Errorf("limiter error for batch: %w", errors.New("limiter error"))},
+				{Err: fmt.Errorf("limiter error for batch: %w", errors.New("limiter error"))},
+				{Err: fmt.Errorf("limiter error for batch: %w", errors.New("limiter error"))},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLimiter := mocks.NewLimiter(t)
+			mockQueue := mocks.NewQueue(t)
+			mockRecords := mocks.NewRecords(t)
+
+			tt.applyMocks(mockLimiter, mockQueue, mockRecords)
+
+			serv := UserNotifierService{
+				limiter:          mockLimiter,
+				queue:            mockQueue,
+				records:          mockRecords,
+				newID:            fixedID,
+				batchConcurrency: 2,
+			}
+
+			assert.Equal(t, tt.expectedResults, serv.EnqueueBatch(ctx, items))
+		})
+	}
+}
+
+func TestUserNotifier_Deliver(t *testing.T) {
+	ctx := context.Background()
+	userMail := "user@example.com"
+	messageType := ratelimiter.StatusType
+
+	tests := []struct {
+		name            string
+		notifierErr     error
+		applyMocks      func(*mocks.Records)
+		expectedError   error
+		expectedOutcome string
+	}{
+		{
+			name:        "Success",
+			notifierErr: nil,
+			applyMocks: func(mr *mocks.Records) {
+				mr.On("MarkSending", ctx, notificationID).Return(nil).Once()
+				mr.On("MarkSent", ctx, notificationID, "primary").Return(nil).Once()
+			},
+			expectedError:   nil,
+			expectedOutcome: OutcomeSent,
+		},
+		{
+			name:        "Notifier Error",
+			notifierErr: errors.New("notifier error"),
+			applyMocks: func(mr *mocks.Records) {
+				mr.On("MarkSending", ctx, notificationID).Return(nil).Once()
+				mr.On("MarkFailed", ctx, notificationID,
+					fmt.Sprintf("notifier error for user %s: notifier error", userMail), "").Return(nil).Once()
+			},
+			expectedError:   fmt.Errorf("notifier error for user %s: %w", userMail, errors.New("notifier error")),
+			expectedOutcome: OutcomeFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the limiter is not expected to be called, so the mock fails when it is
 			mockLimiter := mocks.NewLimiter(t)
 			mockNotifier := mocks.NewNotifier(t)
+			mockRecords := mocks.NewRecords(t)
+			mockMetrics := mocks.NewMetrics(t)
 
-			tt.applyMocks(mockLimiter, mockNotifier)
+			mockMetrics.On("ObserveDelivery", messageType, channel.Email, tt.expectedOutcome).Once()
+
+			mockNotifier.On("Send", ctx, notifier.NotifyToOptions{
+				To:      userMail,
+				Subject: "Notification",
+				Body:    toHTML(messageType),
+			}).Return("primary", tt.notifierErr).Once()
+			tt.applyMocks(mockRecords)
 
 			serv := UserNotifierService{
 				limiter:  mockLimiter,
 				notifier: mockNotifier,
+				records:  mockRecords,
+			}.WithMetrics(mockMetrics)
+
+			err := serv.Deliver(ctx, queue.Message{
+				ID:          notificationID,
+				UserEmail:   userMail,
+				MessageType: messageType,
+			})
+
+			assert.Equal(t, tt.expectedError, err)
+		})
+	}
+}
+
+func TestUserNotifier_Check(t *testing.T) {
+	ctx := context.Background()
+	userMail := "user@example.com"
+	messageType := ratelimiter.NewsType
+	entry := suppression.Entry{Email: userMail, Reason: suppression.ReasonBounce}
+
+	tests := []struct {
+		name          string
+		channelName   string
+		applyMocks    func(*mocks.Limiter, *mocks.Suppressions)
+		expectedError error
+	}{
+		{
+			name: "Success",
+			applyMocks: func(ml *mocks.Limiter, ms *mocks.Suppressions) {
+				ml.On("Valid", messageType).Return(true).Once()
+				ms.On("Get", ctx, userMail).Return(suppression.Entry{}, suppression.ErrNotFound).Once()
+			},
+			expectedError: nil,
+		},
+		{
+			name: "Invalid message type",
+			applyMocks: func(ml *mocks.Limiter, ms *mocks.Suppressions) {
+				ml.On("Valid", messageType).Return(false).Once()
+			},
+			expectedError: fmt.Errorf("limiter error for user %s: %w", userMail, ratelimiter.ErrMessageTypeNotValid),
+		},
+		{
+			name:        "Channel not valid",
+			channelName: channel.Slack,
+			applyMocks: func(ml *mocks.Limiter, ms *mocks.Suppressions) {
+				ml.On("Valid", messageType).Return(true).Once()
+			},
+			expectedError: fmt.Errorf("channel error for user %s: %w: %s", userMail, channel.ErrChannelNotValid, channel.Slack),
+		},
+		{
+			name: "Suppressed address",
+			applyMocks: func(ml *mocks.Limiter, ms *mocks.Suppressions) {
+				ml.On("Valid", messageType).Return(true).Once()
+				ms.On("Get", ctx, userMail).Return(entry, nil).Once()
+			},
+			expectedError: fmt.Errorf("%w: user %s suppressed due to %s", ErrSuppressed, userMail, suppression.ReasonBounce),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLimiter := mocks.NewLimiter(t)
+			mockSuppressions := mocks.NewSuppressions(t)
+
+			tt.applyMocks(mockLimiter, mockSuppressions)
+
+			serv := UserNotifierService{limiter: mockLimiter}.WithSuppressions(mockSuppressions)
+
+			err := serv.Check(ctx, userMail, messageType, tt.channelName)
+
+			assert.Equal(t, tt.expectedError, err)
+		})
+	}
+}
+
+func TestUserNotifier_Schedule(t *testing.T) {
+	ctx := context.Background()
+	userMail := "user@example.com"
+	messageType := ratelimiter.NewsType
+	sendAt := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	record := delivery.Record{
+		ID:          notificationID,
+		UserEmail:   userMail,
+		MessageType: messageType,
+		State:       delivery.StateScheduled,
+		SendAt:      &sendAt,
+	}
+	item := schedule.Item{
+		ID:          notificationID,
+		UserEmail:   userMail,
+		MessageType: messageType,
+		SendAt:      sendAt,
+	}
+
+	tests := []struct {
+		name          string
+		applyMocks    func(*mocks.Limiter, *mocks.Records, *mocks.Schedules)
+		expectedID    string
+		expectedError error
+	}{
+		{
+			name: "Success",
+			applyMocks: func(ml *mocks.Limiter, mr *mocks.Records, ms *mocks.Schedules) {
+				ml.On("Valid", messageType).Return(true).Once()
+				mr.On("Create", ctx, record).Return(nil).Once()
+				ms.On("Add", ctx, item).Return(nil).Once()
+			},
+			expectedID:    notificationID,
+			expectedError: nil,
+		},
+		{
+			name: "Invalid message type",
+			applyMocks: func(ml *mocks.Limiter, mr *mocks.Records, ms *mocks.Schedules) {
+				ml.On("Valid", messageType).Return(false).Once()
+			},
+			expectedError: fmt.Errorf("limiter error for user %s: %w", userMail, ratelimiter.ErrMessageTypeNotValid),
+		},
+		{
+			name: "Schedule Error",
+			applyMocks: func(ml *mocks.Limiter, mr *mocks.Records, ms *mocks.Schedules) {
+				ml.On("Valid", messageType).Return(true).Once()
+				mr.On("Create", ctx, record).Return(nil).Once()
+				ms.On("Add", ctx, item).Return(errors.New("schedule error")).Once()
+				mr.On("MarkFailed", ctx, notificationID,
+					fmt.Sprintf("schedule error for user %s: schedule error", userMail), "").Return(nil).Once()
+			},
+			expectedID:    notificationID,
+			expectedError: fmt.Errorf("schedule error for user %s: %w", userMail, errors.New("schedule error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLimiter := mocks.NewLimiter(t)
+			mockRecords := mocks.NewRecords(t)
+			mockSchedules := mocks.NewSchedules(t)
+
+			tt.applyMocks(mockLimiter, mockRecords, mockSchedules)
+
+			serv := UserNotifierService{
+				limiter:   mockLimiter,
+				records:   mockRecords,
+				schedules: mockSchedules,
+				newID:     fixedID,
+			}
+
+			id, err := serv.Schedule(ctx, userMail, messageType, sendAt)
+
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expectedID, id)
+		})
+	}
+}
+
+func TestUserNotifier_Cancel(t *testing.T) {
+	ctx := context.Background()
+	clientCtx := delivery.WithClient(ctx, "api-key:abc")
+	notFound := fmt.Errorf("%w: %s", schedule.ErrNotFound, notificationID)
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		applyMocks    func(*mocks.Records, *mocks.Schedules)
+		expectedError error
+	}{
+		{
+			name: "Success",
+			ctx:  ctx,
+			applyMocks: func(mr *mocks.Records, ms *mocks.Schedules) {
+				ms.On("Cancel", ctx, notificationID).Return(nil).Once()
+				mr.On("MarkCanceled", ctx, notificationID).Return(nil).Once()
+			},
+			expectedError: nil,
+		},
+		{
+			name: "Not scheduled",
+			ctx:  ctx,
+			applyMocks: func(mr *mocks.Records, ms *mocks.Schedules) {
+				ms.On("Cancel", ctx, notificationID).Return(notFound).Once()
+			},
+			expectedError: notFound,
+		},
+		{
+			name: "Scheduled by the client",
+			ctx:  clientCtx,
+			applyMocks: func(mr *mocks.Records, ms *mocks.Schedules) {
+				ms.On("Get", clientCtx, notificationID).Return(schedule.Item{ID: notificationID, Client: "api-key:abc"}, nil).Once()
+				ms.On("Cancel", clientCtx, notificationID).Return(nil).Once()
+				mr.On("MarkCanceled", clientCtx, notificationID).Return(nil).Once()
+			},
+			expectedError: nil,
+		},
+		{
+			name: "Scheduled by another client",
+			ctx:  clientCtx,
+			applyMocks: func(mr *mocks.Records, ms *mocks.Schedules) {
+				ms.On("Get", clientCtx, notificationID).Return(schedule.Item{ID: notificationID, Client: "api-key:def"}, nil).Once()
+			},
+			expectedError: notFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRecords := mocks.NewRecords(t)
+			mockSchedules := mocks.NewSchedules(t)
+
+			tt.applyMocks(mockRecords, mockSchedules)
+
+			serv := UserNotifierService{records: mockRecords, schedules: mockSchedules}
+
+			assert.Equal(t, tt.expectedError, serv.Cancel(tt.ctx, notificationID))
+		})
+	}
+}
+
+func TestUserNotifier_Scheduled(t *testing.T) {
+	ctx := delivery.WithClient(context.Background(), "api-key:abc")
+	items := []schedule.Item{{ID: notificationID, Client: "api-key:abc"}}
+
+	mockSchedules := mocks.NewSchedules(t)
+	mockSchedules.On("List", ctx, "api-key:abc", int64(10)).Return(items, nil).Once()
+
+	serv := UserNotifierService{schedules: mockSchedules}
+
+	result, err := serv.Scheduled(ctx, 10)
+
+	assert.NoError(t, err)
+	assert.Equal(t, items, result)
+}
+
+func TestUserNotifier_EnqueueScheduled(t *testing.T) {
+	ctx := context.Background()
+	userMail := "user@example.com"
+	messageType := ratelimiter.NewsType
+	item := schedule.Item{
+		ID:          notificationID,
+		UserEmail:   userMail,
+		MessageType: messageType,
+		SendAt:      time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+	}
+	message := queue.Message{
+		ID:          notificationID,
+		UserEmail:   userMail,
+		MessageType: messageType,
+	}
+
+	tests := []struct {
+		name          string
+		applyMocks    func(*mocks.Limiter, *mocks.Queue, *mocks.Records)
+		expectedError error
+	}{
+		{
+			name: "Success",
+			applyMocks: func(ml *mocks.Limiter, mq *mocks.Queue, mr *mocks.Records) {
+				ml.On("Reached", ctx, userMail, messageType).Return(false, nil).Once()
+				mr.On("MarkQueued", ctx, notificationID).Return(nil).Once()
+				mq.On("Enqueue", ctx, message).Return(notificationID, nil).Once()
+			},
+			expectedError: nil,
+		},
+		{
+			name: "Rate Limit Exceeded when due",
+			applyMocks: func(ml *mocks.Limiter, mq *mocks.Queue, mr *mocks.Records) {
+				ml.On("Reached", ctx, userMail, messageType).Return(true, nil).Once()
+				ml.On("OnLimit", messageType).Return(ratelimiter.OnLimitReject).Once()
+				mr.On("MarkRateLimited", ctx, notificationID, fmt.Sprintf(
+					"limit exceeded: rate limit reached for user %s and message type %s", userMail, messageType)).
+					Return(nil).Once()
+			},
+			expectedError: nil,
+		},
+		{
+			name: "Limiter Error is retried",
+			applyMocks: func(ml *mocks.Limiter, mq *mocks.Queue, mr *mocks.Records) {
+				ml.On("Reached", ctx, userMail, messageType).Return(false, errors.New("limiter error")).Once()
+			},
+			expectedError: fmt.Errorf("limiter error for user %s: %w", userMail, errors.New("limiter error")),
+		},
+		{
+			name: "Queue Error is recorded",
+			applyMocks: func(ml *mocks.Limiter, mq *mocks.Queue, mr *mocks.Records) {
+				ml.On("Reached", ctx, userMail, messageType).Return(false, nil).Once()
+				mr.On("MarkQueued", ctx, notificationID).Return(nil).Once()
+				mq.On("Enqueue", ctx, message).Return("", errors.New("queue error")).Once()
+				mr.On("MarkFailed", ctx, notificationID,
+					fmt.Sprintf("queue error for user %s: queue error", userMail), "").Return(nil).Once()
+			},
+			expectedError: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLimiter := mocks.NewLimiter(t)
+			mockQueue := mocks.NewQueue(t)
+			mockRecords := mocks.NewRecords(t)
+
+			tt.applyMocks(mockLimiter, mockQueue, mockRecords)
+
+			serv := UserNotifierService{
+				limiter: mockLimiter,
+				queue:   mockQueue,
+				records: mockRecords,
 			}
 
-			err := serv.Notify(ctx, userMail, messageType)
+			assert.Equal(t, tt.expectedError, serv.EnqueueScheduled(ctx, item))
+		})
+	}
+}
+
+func TestUserNotifier_Status(t *testing.T) {
+	record := delivery.Record{ID: notificationID, State: delivery.StateSent, Client: "api-key:abc"}
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		expected      delivery.Record
+		expectedError error
+	}{
+		{
+			name:     "Without authentication",
+			ctx:      context.Background(),
+			expected: record,
+		},
+		{
+			name:     "Sent by the client",
+			ctx:      delivery.WithClient(context.Background(), "api-key:abc"),
+			expected: record,
+		},
+		{
+			name:          "Sent by another client",
+			ctx:           delivery.WithClient(context.Background(), "api-key:def"),
+			expectedError: fmt.Errorf("%w: %s", delivery.ErrNotFound, notificationID),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRecords := mocks.NewRecords(t)
+			mockRecords.On("Get", tt.ctx, notificationID).Return(record, nil).Once()
+
+			serv := UserNotifierService{records: mockRecords}
+
+			result, err := serv.Status(tt.ctx, notificationID)
 
 			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expected, result)
 		})
 	}
 }
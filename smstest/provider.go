@@ -0,0 +1,41 @@
+// Package smstest provides a fake SMS provider for tests, as smtptest does for the mail servers.
+package smstest
+
+import (
+	"context"
+	"sync"
+)
+
+// Message is a text message kept by the Provider.
+type Message struct {
+	To   string
+	Text string
+}
+
+// Provider keeps the text messages instead of sending them, it implements channel.SMSProvider.
+type Provider struct {
+	mu   sync.Mutex
+	sent []Message
+	Err  error // Err is returned by every SendSMS when it is set
+}
+
+func (p *Provider) SendSMS(_ context.Context, to string, text string) error {
+	if p.Err != nil {
+		return p.Err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.sent = append(p.sent, Message{To: to, Text: text})
+
+	return nil
+}
+
+// Sent returns the text messages kept so far.
+func (p *Provider) Sent() []Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]Message(nil), p.sent...)
+}
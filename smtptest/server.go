@@ -0,0 +1,539 @@
+// Package smtptest provides an in-process SMTP server for end-to-end tests, as net/http/httptest does for HTTP.
+package smtptest
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/mail.v2"
+)
+
+// Commands that can fail besides the SMTP verbs (e.g. "MAIL" or "RCPT"), see Failure.
+const (
+	Connect = "CONNECT"  // Connect is the greeting of the server
+	DataEnd = "DATA_END" // DataEnd is the reply once the data of the message is received
+)
+
+type Options struct {
+	Username string // Username and Password require AUTH PLAIN before MAIL when they are set
+	Password string
+	TLS      bool // TLS offers STARTTLS with a self-signed certificate, see Server.ClientTLSConfig
+}
+
+// Message is a mail accepted by the Server.
+type Message struct {
+	From     string
+	To       []string
+	Data     []byte // Data is the message as the client sent it, once the dots are unstuffed
+	Username string // Username is the authenticated one, it is empty without AUTH
+	TLS      bool   // TLS tells if the message was sent after STARTTLS
+}
+
+// Failure makes the Server fail the next Command, instead of answering it as usual.
+// A 421 reply closes the connection, as real servers do.
+type Failure struct {
+	Command string
+	Code    int
+	Text    string
+	Hang    bool // Hang stops answering until the server is closed, so the client times out
+}
+
+// NewServer starts a Server listening on a local port. It panics when it cannot listen, as httptest.NewServer does.
+func NewServer(options Options) *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("smtptest: failed to listen on a port: %v", err))
+	}
+
+	s := &Server{
+		options:  options,
+		listener: listener,
+		conns:    make(map[net.Conn]bool),
+		closed:   make(chan struct{}),
+	}
+
+	if options.TLS {
+		s.certificate = newCertificate()
+	}
+
+	s.wg.Add(1)
+	go s.accept()
+
+	return s
+}
+
+// Server captures every mail it accepts, see Messages.
+type Server struct {
+	options     Options
+	listener    net.Listener
+	certificate tls.Certificate
+	closed      chan struct{}
+	wg          sync.WaitGroup
+
+	mu       sync.Mutex
+	conns    map[net.Conn]bool
+	failures []Failure
+	messages []Message
+}
+
+func (s *Server) Host() string {
+	return s.listener.Addr().(*net.TCPAddr).IP.String()
+}
+
+func (s *Server) Port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+// Dialer returns a mail.Dialer for the server, with its credentials and trusting its certificate.
+func (s *Server) Dialer() *mail.Dialer {
+	dialer := mail.NewDialer(s.Host(), s.Port(), s.options.Username, s.options.Password)
+	if s.options.TLS {
+		dialer.TLSConfig = s.ClientTLSConfig()
+		dialer.StartTLSPolicy = mail.MandatoryStartTLS
+	}
+
+	return dialer
+}
+
+// ClientTLSConfig trusts the self-signed certificate of the server, it is nil when the server does not offer TLS.
+func (s *Server) ClientTLSConfig() *tls.Config {
+	if !s.options.TLS {
+		return nil
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(s.certificate.Leaf)
+
+	return &tls.Config{RootCAs: pool, ServerName: s.Host(), MinVersion: tls.VersionTLS12}
+}
+
+// Fail queues failures, each of them fails a single command.
+func (s *Server) Fail(failures ...Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures = append(s.failures, failures...)
+}
+
+// Messages returns the accepted mails, the oldest first.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Message{}, s.messages...)
+}
+
+// Close stops the server, closing the open connections and releasing the hanging ones.
+func (s *Server) Close() {
+	select {
+	case <-s.closed:
+		return
+	default:
+	}
+
+	close(s.closed)
+	_ = s.listener.Close()
+
+	s.mu.Lock()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *Server) accept() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = true
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+
+			s.serve(conn)
+
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+func (s *Server) nextFailure(command string) (Failure, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, failure := range s.failures {
+		if strings.EqualFold(failure.Command, command) {
+			s.failures = append(s.failures[:i], s.failures[i+1:]...)
+
+			return failure, true
+		}
+	}
+
+	return Failure{}, false
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer conn.Close()
+
+	sess := &session{server: s, conn: conn}
+	sess.setConn(conn)
+
+	if !sess.answer(Connect, 220, "smtptest ESMTP ready") {
+		return
+	}
+
+	for !sess.done {
+		line, err := sess.readLine()
+		if err != nil {
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+		verb = strings.ToUpper(verb)
+
+		switch verb {
+		case "EHLO", "HELO":
+			sess.reset()
+			sess.answer(verb, 250, sess.extensions()...)
+		case "STARTTLS":
+			sess.startTLS()
+		case "AUTH":
+			sess.auth(arg)
+		case "MAIL":
+			sess.mail(arg)
+		case "RCPT":
+			sess.rcpt(arg)
+		case "DATA":
+			sess.data()
+		case "RSET":
+			sess.reset()
+			sess.answer(verb, 250, "OK")
+		case "NOOP":
+			sess.answer(verb, 250, "OK")
+		case "QUIT":
+			sess.reply(221, "bye")
+
+			return
+		default:
+			sess.reply(502, "command not implemented")
+		}
+	}
+}
+
+// session is the state of a connection, reset by EHLO and RSET.
+type session struct {
+	server *Server
+	conn   net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+	done   bool // done closes the connection
+
+	tls      bool
+	username string
+	from     string
+	to       []string
+	hasFrom  bool
+}
+
+func (sess *session) setConn(conn net.Conn) {
+	sess.conn = conn
+	sess.reader = bufio.NewReader(conn)
+	sess.writer = bufio.NewWriter(conn)
+}
+
+func (sess *session) reset() {
+	sess.from = ""
+	sess.to = nil
+	sess.hasFrom = false
+}
+
+func (sess *session) extensions() []string {
+	lines := []string{"smtptest"}
+
+	if sess.server.options.TLS && !sess.tls {
+		lines = append(lines, "STARTTLS")
+	}
+
+	if sess.server.options.Username != "" {
+		lines = append(lines, "AUTH PLAIN")
+	}
+
+	return lines
+}
+
+func (sess *session) startTLS() {
+	if !sess.server.options.TLS || sess.tls {
+		sess.reply(502, "STARTTLS not available")
+
+		return
+	}
+
+	if !sess.answer("STARTTLS", 220, "ready to start TLS") {
+		return
+	}
+
+	tlsConn := tls.Server(sess.conn, &tls.Config{
+		Certificates: []tls.Certificate{sess.server.certificate},
+		MinVersion:   tls.VersionTLS12,
+	})
+	if err := tlsConn.Handshake(); err != nil {
+		sess.done = true
+
+		return
+	}
+
+	sess.setConn(tlsConn)
+	sess.tls = true
+	sess.username = ""
+	sess.reset()
+}
+
+// auth accepts AUTH PLAIN, with the credentials either in the command or in the next line.
+func (sess *session) auth(arg string) {
+	mechanism, credentials, _ := strings.Cut(arg, " ")
+	if !strings.EqualFold(mechanism, "PLAIN") || sess.server.options.Username == "" {
+		sess.reply(504, "authentication mechanism not supported")
+
+		return
+	}
+
+	if credentials == "" {
+		sess.reply(334, "")
+
+		line, err := sess.readLine()
+		if err != nil {
+			sess.done = true
+
+			return
+		}
+
+		credentials = line
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(credentials)
+	if err != nil {
+		sess.reply(501, "credentials not valid")
+
+		return
+	}
+
+	// the credentials are "authorization identity\x00username\x00password"
+	parts := strings.Split(string(decoded), "\x00")
+	if len(parts) != 3 || parts[1] != sess.server.options.Username || parts[2] != sess.server.options.Password {
+		sess.reply(535, "authentication failed")
+
+		return
+	}
+
+	if sess.answer("AUTH", 235, "authentication succeeded") {
+		sess.username = parts[1]
+	}
+}
+
+func (sess *session) mail(arg string) {
+	if sess.server.options.Username != "" && sess.username == "" {
+		sess.reply(530, "authentication required")
+
+		return
+	}
+
+	from, ok := address(arg, "FROM:")
+	if !ok {
+		sess.reply(501, "syntax error in MAIL")
+
+		return
+	}
+
+	if sess.answer("MAIL", 250, "OK") {
+		sess.reset()
+		sess.from = from
+		sess.hasFrom = true
+	}
+}
+
+func (sess *session) rcpt(arg string) {
+	if !sess.hasFrom {
+		sess.reply(503, "MAIL first")
+
+		return
+	}
+
+	to, ok := address(arg, "TO:")
+	if !ok {
+		sess.reply(501, "syntax error in RCPT")
+
+		return
+	}
+
+	if sess.answer("RCPT", 250, "OK") {
+		sess.to = append(sess.to, to)
+	}
+}
+
+func (sess *session) data() {
+	if len(sess.to) == 0 {
+		sess.reply(503, "RCPT first")
+
+		return
+	}
+
+	if !sess.answer("DATA", 354, "end data with <CR><LF>.<CR><LF>") {
+		return
+	}
+
+	var data []byte
+	for {
+		line, err := sess.reader.ReadString('\n')
+		if err != nil {
+			sess.done = true
+
+			return
+		}
+
+		if line == ".\r\n" {
+			break
+		}
+
+		data = append(data, strings.TrimPrefix(line, ".")...)
+	}
+
+	if !sess.answer(DataEnd, 250, "OK: queued") {
+		sess.reset()
+
+		return
+	}
+
+	sess.server.mu.Lock()
+	sess.server.messages = append(sess.server.messages, Message{
+		From:     sess.from,
+		To:       sess.to,
+		Data:     data,
+		Username: sess.username,
+		TLS:      sess.tls,
+	})
+	sess.server.mu.Unlock()
+
+	sess.reset()
+}
+
+// answer replies the command, unless a failure is queued for it. It returns false when the command failed.
+func (sess *session) answer(command string, code int, lines ...string) bool {
+	failure, ok := sess.server.nextFailure(command)
+	if !ok {
+		sess.reply(code, lines...)
+
+		return true
+	}
+
+	if failure.Hang {
+		<-sess.server.closed
+		sess.done = true
+
+		return false
+	}
+
+	sess.reply(failure.Code, failure.Text)
+	sess.done = failure.Code == 421
+
+	return false
+}
+
+func (sess *session) reply(code int, lines ...string) {
+	if len(lines) == 0 {
+		lines = []string{""}
+	}
+
+	for i, line := range lines {
+		separator := "-"
+		if i == len(lines)-1 {
+			separator = " "
+		}
+
+		_, _ = sess.writer.WriteString(strconv.Itoa(code) + separator + line + "\r\n")
+	}
+
+	if err := sess.writer.Flush(); err != nil {
+		sess.done = true
+	}
+}
+
+func (sess *session) readLine() (string, error) {
+	line, err := sess.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// address reads the path of MAIL FROM:<...> and RCPT TO:<...>, ignoring their parameters.
+func address(arg string, prefix string) (string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", false
+	}
+
+	path, _, _ := strings.Cut(strings.TrimSpace(arg[len(prefix):]), " ")
+	if !strings.HasPrefix(path, "<") || !strings.HasSuffix(path, ">") {
+		return "", false
+	}
+
+	return path[1 : len(path)-1], true
+}
+
+// newCertificate returns a self-signed certificate for the local addresses.
+func newCertificate() tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(fmt.Sprintf("smtptest: failed to generate a key: %v", err))
+	}
+
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Organization: []string{"smtptest"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		DNSNames:              []string{"localhost"},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		panic(fmt.Sprintf("smtptest: failed to create a certificate: %v", err))
+	}
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		panic(fmt.Sprintf("smtptest: failed to parse the certificate: %v", err))
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
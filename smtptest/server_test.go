@@ -0,0 +1,135 @@
+package smtptest
+
+import (
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	tests := []struct {
+		name             string
+		options          Options
+		failures         []Failure
+		auth             smtp.Auth
+		expectedCode     int
+		expectedMessages []Message
+	}{
+		{
+			name:    "message accepted",
+			options: Options{Username: "user", Password: "secret"},
+			auth:    smtp.PlainAuth("", "user", "secret", "127.0.0.1"),
+			expectedMessages: []Message{{
+				From:     "sender@example.com",
+				To:       []string{"user@example.com"},
+				Data:     []byte("Subject: test\r\n\r\n.dot stuffed\r\n"),
+				Username: "user",
+			}},
+		},
+		{
+			name:         "wrong credentials",
+			options:      Options{Username: "user", Password: "secret"},
+			auth:         smtp.PlainAuth("", "user", "other", "127.0.0.1"),
+			expectedCode: 535,
+		},
+		{
+			name:         "authentication required",
+			options:      Options{Username: "user", Password: "secret"},
+			expectedCode: 530,
+		},
+		{
+			name:         "recipient rejected",
+			failures:     []Failure{{Command: "RCPT", Code: 550, Text: "no such user"}},
+			expectedCode: 550,
+		},
+		{
+			name:         "message rejected once received",
+			failures:     []Failure{{Command: DataEnd, Code: 451, Text: "try again later"}},
+			expectedCode: 451,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServer(tt.options)
+			defer server.Close()
+
+			server.Fail(tt.failures...)
+
+			err := sendMail(server, tt.auth)
+			if tt.expectedCode != 0 {
+				var protoErr *textproto.Error
+				require.ErrorAs(t, err, &protoErr)
+				assert.Equal(t, tt.expectedCode, protoErr.Code)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, append([]Message{}, tt.expectedMessages...), server.Messages())
+		})
+	}
+}
+
+func TestServerStartTLS(t *testing.T) {
+	server := NewServer(Options{TLS: true})
+	defer server.Close()
+
+	client, err := smtp.Dial(server.Host() + ":" + strconv.Itoa(server.Port()))
+	require.NoError(t, err)
+
+	require.NoError(t, client.StartTLS(server.ClientTLSConfig()))
+	require.NoError(t, client.Mail("sender@example.com"))
+	require.NoError(t, client.Rcpt("user@example.com"))
+
+	w, err := client.Data()
+	require.NoError(t, err)
+	_, err = w.Write([]byte("Subject: test\r\n\r\nsecret\r\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, client.Quit())
+
+	messages := server.Messages()
+	require.Len(t, messages, 1)
+	assert.True(t, messages[0].TLS)
+}
+
+func sendMail(server *Server, auth smtp.Auth) error {
+	client, err := smtp.Dial(server.Host() + ":" + strconv.Itoa(server.Port()))
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail("sender@example.com"); err != nil {
+		return err
+	}
+
+	if err := client.Rcpt("user@example.com"); err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write([]byte("Subject: test\r\n\r\n.dot stuffed\r\n")); err != nil {
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
@@ -0,0 +1,175 @@
+package suppression
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"user_news_api/logging"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrNotFound = errors.New("address not suppressed")
+)
+
+const (
+	ReasonBounce    = "bounce"    // the mail server rejected the address permanently
+	ReasonComplaint = "complaint" // the user marked a notification as spam
+	ReasonManual    = "manual"    // the address was added by an admin
+)
+
+// DefaultOptions are used by the API for the suppression list.
+var DefaultOptions = Options{
+	Key: "suppressed-addresses",
+}
+
+type Options struct {
+	Key string // Key is the sorted set of addresses, the entries are kept in the hash Key-entries
+}
+
+// Entry is an address that must not receive notifications.
+type Entry struct {
+	Email     string    `json:"email"`
+	Reason    string    `json:"reason"`
+	Detail    string    `json:"detail,omitempty"` // Detail explains the reason, e.g. the reply of the mail server
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewStore(db *redis.Client, options Options) Store {
+	return Store{
+		db:      db,
+		options: options,
+	}
+}
+
+// RedisSortedSet is an abstraction for redis.Client making it mockeable
+type RedisSortedSet interface {
+	ZAdd(ctx context.Context, key string, members ...redis.Z) *redis.IntCmd
+	ZRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	ZRevRange(ctx context.Context, key string, start, stop int64) *redis.StringSliceCmd
+	HSet(ctx context.Context, key string, values ...interface{}) *redis.IntCmd
+	HGet(ctx context.Context, key, field string) *redis.StringCmd
+	HMGet(ctx context.Context, key string, fields ...string) *redis.SliceCmd
+	HDel(ctx context.Context, key string, fields ...string) *redis.IntCmd
+}
+
+// Store keeps the suppressed addresses in a sorted set scored by when they were added in Unix milliseconds.
+// Addresses are compared in lower case.
+type Store struct {
+	db      RedisSortedSet
+	options Options
+}
+
+// Add suppresses the address of the entry, an entry of the same address is replaced.
+func (s Store) Add(ctx context.Context, entry Entry) error {
+	entry.Email = normalize(entry.Email)
+
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("error marshalling suppression entry due to: %w", err)
+	}
+
+	if err = s.db.HSet(ctx, s.entriesKey(), entry.Email, payload).Err(); err != nil {
+		return fmt.Errorf("error saving suppression entry %s due to: %w", entry.Email, err)
+	}
+
+	err = s.db.ZAdd(ctx, s.options.Key, redis.Z{Score: float64(entry.CreatedAt.UnixMilli()), Member: entry.Email}).Err()
+	if err != nil {
+		return fmt.Errorf("error suppressing address %s due to: %w", entry.Email, err)
+	}
+
+	return nil
+}
+
+// Get returns the entry of a suppressed address, ErrNotFound is returned when the address is not suppressed.
+func (s Store) Get(ctx context.Context, email string) (Entry, error) {
+	email = normalize(email)
+
+	payload, err := s.db.HGet(ctx, s.entriesKey(), email).Result()
+	if errors.Is(err, redis.Nil) {
+		return Entry{}, fmt.Errorf("%w: %s", ErrNotFound, email)
+	}
+
+	if err != nil {
+		return Entry{}, fmt.Errorf("error getting suppression entry %s due to: %w", email, err)
+	}
+
+	var entry Entry
+	if err = json.Unmarshal([]byte(payload), &entry); err != nil {
+		return Entry{}, fmt.Errorf("error unmarshalling suppression entry %s due to: %w", email, err)
+	}
+
+	return entry, nil
+}
+
+// Remove lets the address receive notifications again, ErrNotFound is returned when it was not suppressed.
+func (s Store) Remove(ctx context.Context, email string) error {
+	email = normalize(email)
+
+	removed, err := s.db.ZRem(ctx, s.options.Key, email).Result()
+	if err != nil {
+		return fmt.Errorf("error removing suppressed address %s due to: %w", email, err)
+	}
+
+	if removed == 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, email)
+	}
+
+	if err = s.db.HDel(ctx, s.entriesKey(), email).Err(); err != nil {
+		return fmt.Errorf("error deleting suppression entry %s due to: %w", email, err)
+	}
+
+	return nil
+}
+
+// List returns the suppressed addresses, the last added first. limit bounds the amount of entries.
+func (s Store) List(ctx context.Context, limit int64) ([]Entry, error) {
+	emails, err := s.db.ZRevRange(ctx, s.options.Key, 0, limit-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error listing suppressed addresses due to: %w", err)
+	}
+
+	if len(emails) == 0 {
+		return []Entry{}, nil
+	}
+
+	values, err := s.db.HMGet(ctx, s.entriesKey(), emails...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error getting suppression entries due to: %w", err)
+	}
+
+	entries := make([]Entry, 0, len(values))
+	for i, value := range values {
+		payload, ok := value.(string)
+		if !ok {
+			slog.WarnContext(ctx, "suppression entry not found", slog.String(logging.KeyUser, logging.MaskEmail(emails[i])))
+
+			continue
+		}
+
+		var entry Entry
+		if err = json.Unmarshal([]byte(payload), &entry); err != nil {
+			slog.WarnContext(ctx, "discarding malformed suppression entry",
+				slog.String(logging.KeyUser, logging.MaskEmail(emails[i])), logging.Error(err))
+
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func (s Store) entriesKey() string {
+	return fmt.Sprintf("%s-entries", s.options.Key)
+}
+
+func normalize(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,229 @@
+package suppression
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"user_news_api/suppression/mocks"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var testOptions = Options{Key: "suppressed"}
+
+var testEntry = Entry{
+	Email:     "user@example.com",
+	Reason:    ReasonBounce,
+	Detail:    "550 no such user",
+	CreatedAt: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+}
+
+// testEntryPayload is testEntry as it is saved
+const testEntryPayload = `{"email":"user@example.com","reason":"bounce","detail":"550 no such user","created_at":"2024-01-01T09:00:00Z"}`
+
+func TestStoreAdd(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		entry         Entry
+		mockApplier   func(mockRedis *mocks.RedisSortedSet)
+		expectedError error
+	}{
+		{
+			name:  "address suppressed in lower case",
+			entry: Entry{Email: " User@Example.com", Reason: ReasonBounce, Detail: "550 no such user", CreatedAt: testEntry.CreatedAt},
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("HSet", mock.Anything, "suppressed-entries", "user@example.com", []byte(testEntryPayload)).
+					Return(redis.NewIntResult(1, nil)).Once()
+				mockRedis.On("ZAdd", mock.Anything, "suppressed", redis.Z{Score: 1704099600000, Member: "user@example.com"}).
+					Return(redis.NewIntResult(1, nil)).Once()
+			},
+			expectedError: nil,
+		},
+		{
+			name:  "error saving entry",
+			entry: testEntry,
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("HSet", mock.Anything, "suppressed-entries", "user@example.com", []byte(testEntryPayload)).
+					Return(redis.NewIntResult(0, errors.New("error"))).Once()
+			},
+			expectedError: fmt.Errorf("error saving suppression entry user@example.com due to: %w", errors.New("error")),
+		},
+		{
+			name:  "error suppressing address",
+			entry: testEntry,
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("HSet", mock.Anything, "suppressed-entries", "user@example.com", []byte(testEntryPayload)).
+					Return(redis.NewIntResult(1, nil)).Once()
+				mockRedis.On("ZAdd", mock.Anything, "suppressed", redis.Z{Score: 1704099600000, Member: "user@example.com"}).
+					Return(redis.NewIntResult(0, errors.New("error"))).Once()
+			},
+			expectedError: fmt.Errorf("error suppressing address user@example.com due to: %w", errors.New("error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisSortedSet(t)
+			tt.mockApplier(mockRedis)
+
+			store := Store{db: mockRedis, options: testOptions}
+
+			assert.Equal(t, tt.expectedError, store.Add(context.Background(), tt.entry))
+		})
+	}
+}
+
+func TestStoreGet(t *testing.T) {
+	tests := []struct {
+		name          string
+		mockApplier   func(mockRedis *mocks.RedisSortedSet)
+		expectedEntry Entry
+		expectedError error
+	}{
+		{
+			name: "suppressed address",
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("HGet", mock.Anything, "suppressed-entries", "user@example.com").
+					Return(redis.NewStringResult(testEntryPayload, nil)).Once()
+			},
+			expectedEntry: testEntry,
+			expectedError: nil,
+		},
+		{
+			name: "address not suppressed",
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("HGet", mock.Anything, "suppressed-entries", "user@example.com").
+					Return(redis.NewStringResult("", redis.Nil)).Once()
+			},
+			expectedError: fmt.Errorf("%w: user@example.com", ErrNotFound),
+		},
+		{
+			name: "error getting entry",
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("HGet", mock.Anything, "suppressed-entries", "user@example.com").
+					Return(redis.NewStringResult("", errors.New("error"))).Once()
+			},
+			expectedError: fmt.Errorf("error getting suppression entry user@example.com due to: %w", errors.New("error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisSortedSet(t)
+			tt.mockApplier(mockRedis)
+
+			store := Store{db: mockRedis, options: testOptions}
+
+			entry, err := store.Get(context.Background(), "USER@example.com")
+
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expectedEntry, entry)
+		})
+	}
+}
+
+func TestStoreRemove(t *testing.T) {
+	tests := []struct {
+		name          string
+		mockApplier   func(mockRedis *mocks.RedisSortedSet)
+		expectedError error
+	}{
+		{
+			name: "address removed",
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("ZRem", mock.Anything, "suppressed", "user@example.com").
+					Return(redis.NewIntResult(1, nil)).Once()
+				mockRedis.On("HDel", mock.Anything, "suppressed-entries", "user@example.com").
+					Return(redis.NewIntResult(1, nil)).Once()
+			},
+			expectedError: nil,
+		},
+		{
+			name: "address not suppressed",
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("ZRem", mock.Anything, "suppressed", "user@example.com").
+					Return(redis.NewIntResult(0, nil)).Once()
+			},
+			expectedError: fmt.Errorf("%w: user@example.com", ErrNotFound),
+		},
+		{
+			name: "error removing address",
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("ZRem", mock.Anything, "suppressed", "user@example.com").
+					Return(redis.NewIntResult(0, errors.New("error"))).Once()
+			},
+			expectedError: fmt.Errorf("error removing suppressed address user@example.com due to: %w", errors.New("error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisSortedSet(t)
+			tt.mockApplier(mockRedis)
+
+			store := Store{db: mockRedis, options: testOptions}
+
+			assert.Equal(t, tt.expectedError, store.Remove(context.Background(), "user@example.com"))
+		})
+	}
+}
+
+func TestStoreList(t *testing.T) {
+	other := Entry{Email: "other@example.com", Reason: ReasonManual, CreatedAt: testEntry.CreatedAt.Add(time.Hour)}
+	otherPayload := `{"email":"other@example.com","reason":"manual","created_at":"2024-01-01T10:00:00Z"}`
+
+	tests := []struct {
+		name            string
+		mockApplier     func(mockRedis *mocks.RedisSortedSet)
+		expectedEntries []Entry
+		expectedError   error
+	}{
+		{
+			name: "last added first, skipping the missing ones",
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("ZRevRange", mock.Anything, "suppressed", int64(0), int64(9)).
+					Return(redis.NewStringSliceResult([]string{"other@example.com", "gone@example.com", "user@example.com"}, nil)).Once()
+				mockRedis.On("HMGet", mock.Anything, "suppressed-entries", "other@example.com", "gone@example.com", "user@example.com").
+					Return(redis.NewSliceResult([]interface{}{otherPayload, nil, testEntryPayload}, nil)).Once()
+			},
+			expectedEntries: []Entry{other, testEntry},
+			expectedError:   nil,
+		},
+		{
+			name: "no suppressed addresses",
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("ZRevRange", mock.Anything, "suppressed", int64(0), int64(9)).
+					Return(redis.NewStringSliceResult([]string{}, nil)).Once()
+			},
+			expectedEntries: []Entry{},
+			expectedError:   nil,
+		},
+		{
+			name: "error listing addresses",
+			mockApplier: func(mockRedis *mocks.RedisSortedSet) {
+				mockRedis.On("ZRevRange", mock.Anything, "suppressed", int64(0), int64(9)).
+					Return(redis.NewStringSliceResult(nil, errors.New("error"))).Once()
+			},
+			expectedError: fmt.Errorf("error listing suppressed addresses due to: %w", errors.New("error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedis := mocks.NewRedisSortedSet(t)
+			tt.mockApplier(mockRedis)
+
+			store := Store{db: mockRedis, options: testOptions}
+
+			entries, err := store.List(context.Background(), 10)
+
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expectedEntries, entries)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrExporterNotValid = errors.New("tracing exporter not valid")
+)
+
+// Exporters of the spans, see Options.
+const (
+	ExporterOTLP   = "otlp"   // ExporterOTLP sends the spans through OTLP over HTTP, set by the OTEL_EXPORTER_OTLP_* variables
+	ExporterStdout = "stdout" // ExporterStdout writes the spans as JSON, for local use
+)
+
+const instrumentationName = "user_news_api"
+
+type Options struct {
+	Exporter    string
+	ServiceName string
+	Writer      io.Writer // Writer receives the spans of ExporterStdout, the standard output when it is nil
+}
+
+// Setup sets the global tracer provider and the W3C trace context propagator. The returned function flushes
+// the pending spans, and it must be called before exiting. Until Setup is called, the spans are not recorded.
+func Setup(ctx context.Context, options Options) (func(context.Context) error, error) {
+	exporter, err := newExporter(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(options.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("error creating tracing resource due to: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return provider.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, options Options) (sdktrace.SpanExporter, error) {
+	switch options.Exporter {
+	case ExporterOTLP:
+		exporter, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error creating otlp exporter due to: %w", err)
+		}
+
+		return exporter, nil
+	case ExporterStdout:
+		writer := options.Writer
+		if writer == nil {
+			writer = os.Stdout
+		}
+
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(writer))
+		if err != nil {
+			return nil, fmt.Errorf("error creating stdout exporter due to: %w", err)
+		}
+
+		return exporter, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrExporterNotValid, options.Exporter)
+}
+
+// Start starts a span of the global tracer provider, which does not record it until Setup is called.
+func Start(ctx context.Context, name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, options...)
+}
+
+// End records the error of the span, when there is one, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
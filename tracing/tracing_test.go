@@ -0,0 +1,90 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		name          string
+		options       Options
+		expectedError error
+	}{
+		{
+			name:    "Stdout exporter",
+			options: Options{Exporter: ExporterStdout, ServiceName: "news"},
+		},
+		{
+			name:          "Unknown exporter",
+			options:       Options{Exporter: "jaeger", ServiceName: "news"},
+			expectedError: ErrExporterNotValid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			tt.options.Writer = &out
+
+			shutdown, err := Setup(context.Background(), tt.options)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			_, span := Start(context.Background(), "operation")
+			End(span, nil)
+
+			require.NoError(t, shutdown(context.Background()))
+			assert.Contains(t, out.String(), `"Name":"operation"`)
+			assert.Contains(t, out.String(), `"Value":"news"`)
+		})
+	}
+}
+
+func TestEnd(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus codes.Code
+		expectedEvents int
+	}{
+		{
+			name:           "Without error",
+			expectedStatus: codes.Unset,
+		},
+		{
+			name:           "With error",
+			err:            errors.New("some error"),
+			expectedStatus: codes.Error,
+			expectedEvents: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+			_, span := provider.Tracer("test").Start(context.Background(), "operation")
+			End(span, tt.err)
+
+			ended := recorder.Ended()
+			require.Len(t, ended, 1)
+			assert.Equal(t, tt.expectedStatus, ended[0].Status().Code)
+			assert.Len(t, ended[0].Events(), tt.expectedEvents)
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package unsubscribe
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid unsubscribe token")
+)
+
+type Options struct {
+	BaseURL string // BaseURL is the public address of the API, the links point to BaseURL/unsubscribe
+	Secret  string // Secret signs the tokens, changing it invalidates the links already sent
+}
+
+func NewSigner(options Options) Signer {
+	return Signer{
+		baseURL: strings.TrimSuffix(options.BaseURL, "/"),
+		secret:  []byte(options.Secret),
+	}
+}
+
+// Signer creates the unsubscribe links of every user and message type. The tokens are signed with HMAC-SHA256,
+// so they cannot be forged for other users, and they do not expire because unsubscribe links must keep working.
+type Signer struct {
+	baseURL string
+	secret  []byte
+}
+
+// URL returns the one-click unsubscribe link of the user from the message type.
+func (s Signer) URL(user string, messageType string) string {
+	return fmt.Sprintf("%s/unsubscribe?token=%s", s.baseURL, url.QueryEscape(s.Token(user, messageType)))
+}
+
+// Token returns the signed payload with the user and the message type.
+func (s Signer) Token(user string, messageType string) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(user + "\n" + messageType))
+
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.sign(payload))
+}
+
+// Verify checks the signature of the token and returns its user and message type.
+func (s Signer) Verify(token string) (string, string, error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", "", ErrInvalidToken
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, s.sign(payload)) {
+		return "", "", ErrInvalidToken
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", "", ErrInvalidToken
+	}
+
+	user, messageType, ok := strings.Cut(string(decoded), "\n")
+	if !ok || user == "" || messageType == "" {
+		return "", "", ErrInvalidToken
+	}
+
+	return user, messageType, nil
+}
+
+func (s Signer) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+
+	return mac.Sum(nil)
+}
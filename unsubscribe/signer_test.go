@@ -0,0 +1,77 @@
+package unsubscribe
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignerURL(t *testing.T) {
+	signer := NewSigner(Options{BaseURL: "https://api.example.com/", Secret: "secret"})
+
+	link := signer.URL("user@example.com", "Marketing")
+
+	assert.True(t, strings.HasPrefix(link, "https://api.example.com/unsubscribe?token="))
+	assert.Equal(t, "https://api.example.com/unsubscribe?token="+signer.Token("user@example.com", "Marketing"), link)
+}
+
+func TestSignerVerify(t *testing.T) {
+	signer := NewSigner(Options{BaseURL: "https://api.example.com", Secret: "secret"})
+	token := signer.Token("user@example.com", "Marketing")
+
+	payload, _, ok := strings.Cut(token, ".")
+	require.True(t, ok)
+
+	tests := []struct {
+		name                string
+		token               string
+		expectedUser        string
+		expectedMessageType string
+		expectedError       error
+	}{
+		{
+			name:                "valid token",
+			token:               token,
+			expectedUser:        "user@example.com",
+			expectedMessageType: "Marketing",
+		},
+		{
+			name:          "signed with another secret",
+			token:         NewSigner(Options{Secret: "other"}).Token("user@example.com", "Marketing"),
+			expectedError: ErrInvalidToken,
+		},
+		{
+			name:          "payload of another user",
+			token:         base64.RawURLEncoding.EncodeToString([]byte("other@example.com\nMarketing")) + token[len(payload):],
+			expectedError: ErrInvalidToken,
+		},
+		{
+			name:          "without signature",
+			token:         payload,
+			expectedError: ErrInvalidToken,
+		},
+		{
+			name:          "malformed signature",
+			token:         payload + ".%%%",
+			expectedError: ErrInvalidToken,
+		},
+		{
+			name:          "signed payload without message type",
+			token:         signer.Token("user@example.com", ""),
+			expectedError: ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, messageType, err := signer.Verify(tt.token)
+
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expectedUser, user)
+			assert.Equal(t, tt.expectedMessageType, messageType)
+		})
+	}
+}